@@ -0,0 +1,107 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             (unknown)
+// source: status.proto
+
+package api
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// CanaryStatusClient is the client API for CanaryStatus service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type CanaryStatusClient interface {
+	// GetStatus returns the current canary health, per partition latency statistics and last error details
+	GetStatus(ctx context.Context, in *GetStatusRequest, opts ...grpc.CallOption) (*GetStatusResponse, error)
+}
+
+type canaryStatusClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCanaryStatusClient(cc grpc.ClientConnInterface) CanaryStatusClient {
+	return &canaryStatusClient{cc}
+}
+
+func (c *canaryStatusClient) GetStatus(ctx context.Context, in *GetStatusRequest, opts ...grpc.CallOption) (*GetStatusResponse, error) {
+	out := new(GetStatusResponse)
+	err := c.cc.Invoke(ctx, "/strimzi.canary.CanaryStatus/GetStatus", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CanaryStatusServer is the server API for CanaryStatus service.
+// All implementations must embed UnimplementedCanaryStatusServer
+// for forward compatibility
+type CanaryStatusServer interface {
+	// GetStatus returns the current canary health, per partition latency statistics and last error details
+	GetStatus(context.Context, *GetStatusRequest) (*GetStatusResponse, error)
+	mustEmbedUnimplementedCanaryStatusServer()
+}
+
+// UnimplementedCanaryStatusServer must be embedded to have forward compatible implementations.
+type UnimplementedCanaryStatusServer struct {
+}
+
+func (UnimplementedCanaryStatusServer) GetStatus(context.Context, *GetStatusRequest) (*GetStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStatus not implemented")
+}
+func (UnimplementedCanaryStatusServer) mustEmbedUnimplementedCanaryStatusServer() {}
+
+// UnsafeCanaryStatusServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CanaryStatusServer will
+// result in compilation errors.
+type UnsafeCanaryStatusServer interface {
+	mustEmbedUnimplementedCanaryStatusServer()
+}
+
+func RegisterCanaryStatusServer(s grpc.ServiceRegistrar, srv CanaryStatusServer) {
+	s.RegisterService(&CanaryStatus_ServiceDesc, srv)
+}
+
+func _CanaryStatus_GetStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CanaryStatusServer).GetStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/strimzi.canary.CanaryStatus/GetStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CanaryStatusServer).GetStatus(ctx, req.(*GetStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// CanaryStatus_ServiceDesc is the grpc.ServiceDesc for CanaryStatus service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var CanaryStatus_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "strimzi.canary.CanaryStatus",
+	HandlerType: (*CanaryStatusServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetStatus",
+			Handler:    _CanaryStatus_GetStatus_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "status.proto",
+}
@@ -2,13 +2,13 @@
 // Copyright Strimzi authors.
 // License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
 //
+
 package main
 
 import (
 	"context"
 	"flag"
 	"fmt"
-	"io"
 	"log"
 	"os"
 	"os/signal"
@@ -25,11 +25,9 @@ import (
 
 	"github.com/Shopify/sarama"
 	"github.com/golang/glog"
-	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
 
+	"github.com/strimzi/strimzi-canary/internal/clients"
 	"github.com/strimzi/strimzi-canary/internal/config"
-	"github.com/strimzi/strimzi-canary/internal/security"
 	"github.com/strimzi/strimzi-canary/internal/servers"
 	"github.com/strimzi/strimzi-canary/internal/services"
 	"github.com/strimzi/strimzi-canary/internal/workers"
@@ -37,14 +35,14 @@ import (
 
 var (
 	version = "development"
+	commit  = "unknown"
 
-	clientCreationFailed = promauto.NewCounterVec(prometheus.CounterOpts{
-		Name:      "client_creation_error_total",
-		Namespace: "strimzi_canary",
-		Help:      "Total number of errors while creating Sarama client",
-	}, nil)
+	once = flag.Bool("once", false, "Run the topic reconcile, one produce/consume round trip and a connection check, then exit with 0 (ok), 1 (warning) or 2 (critical)")
 )
-var saramaLogger = log.New(io.Discard, "[Sarama] ", log.Ldate | log.Lmicroseconds)
+
+// bridging the Sarama logging into the canary one, enabled via the dynamic configuration
+var saramaLogger *clients.SaramaLogger
+
 func initTracerProvider(exporterType string) *sdktrace.TracerProvider {
 	if exporterType == "" {
 		tp := trace.NewNoopTracerProvider()
@@ -82,8 +80,17 @@ func exporterTracing(exporterType string) sdktrace.SpanExporter {
 	return exporter
 }
 
-
 func main() {
+	// exit code set by the soak run verification or the one-shot check, deferred first so that it runs after the other deferred calls
+	exitCode := 0
+	defer func() {
+		if exitCode != 0 {
+			glog.Flush()
+			os.Exit(exitCode)
+		}
+	}()
+
+	flag.Parse()
 
 	// get canary configuration
 	canaryConfig := config.NewCanaryConfig()
@@ -92,9 +99,16 @@ func main() {
 	if err := flag.Set("logtostderr", "true"); err != nil {
 		glog.Errorf("Error on setting logtostderr to true")
 	}
+	saramaLogger = clients.NewSaramaLogger(canaryConfig.SaramaLogLevel)
 	sarama.Logger = saramaLogger
 
-	applyDynamicConfig(&canaryConfig.DynamicCanaryConfig)
+	// the state shared by the services, along with the dynamic configuration settings applied to them
+	trackers := services.NewTrackers(canaryConfig)
+
+	// the dynamic configuration is updated by the configuration file watcher and through the HTTP API
+	dynamicConfigStore := config.NewDynamicConfigStore(&canaryConfig.DynamicCanaryConfig, func(dynamicCanaryConfig *config.DynamicCanaryConfig) {
+		applyDynamicConfig(canaryConfig, dynamicCanaryConfig, trackers)
+	})
 
 	glog.Infof("Starting Strimzi canary tool [%s] with config: %+v", version, canaryConfig)
 
@@ -106,111 +120,155 @@ func main() {
 			}
 		}
 	}()
-	dynamicConfigWatcher, err := config.NewDynamicConfigWatcher(canaryConfig, applyDynamicConfig, config.NewDynamicCanaryConfig)
+	dynamicConfigWatcher, err := config.NewDynamicConfigWatcher(canaryConfig, dynamicConfigStore.Apply, config.NewDynamicCanaryConfig)
 	if err != nil {
 		glog.Fatalf("Failed to create dynamic config watcher: %v", err)
 	}
 
-	statusService := services.NewStatusServiceService(canaryConfig)
-	httpServer := servers.NewHttpServer(statusService)
-	httpServer.Start()
+	statusService := services.NewStatusServiceService(canaryConfig, trackers)
+	httpServer := servers.NewHttpServer(canaryConfig, statusService)
+	httpServer.Handle("/config", servers.DynamicConfigHandler(dynamicConfigStore))
+	// no servers needed for the one-shot check
+	if !*once {
+		httpServer.Start()
+	}
+
+	var grpcServer *servers.GrpcServer
+	if canaryConfig.GrpcServerEnabled && !*once {
+		grpcServer = servers.NewGrpcServer(canaryConfig, statusService)
+		grpcServer.Start()
+	}
 
 	signals := make(chan os.Signal, 1)
 	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
 
-	saramaConfig, err := createSaramaConfig(canaryConfig)
+	if canaryConfig.KafkaVersion == "" {
+		err = services.Bootstrap(canaryConfig, services.VersionBootstrapClient, func() (err error) {
+			canaryConfig.KafkaVersion, err = clients.NegotiateKafkaVersion(canaryConfig)
+			return err
+		})
+		if err != nil {
+			glog.Warningf("Error negotiating the Kafka version, using %s: %v", clients.DefaultKafkaVersion, err)
+			canaryConfig.KafkaVersion = clients.DefaultKafkaVersion
+		}
+	}
+	services.RecordInfo(canaryConfig, version, commit)
+	services.SetRateLimit(canaryConfig.RateLimit)
+
+	clientFactory, err := clients.NewFactory(canaryConfig)
 	if err != nil {
-		glog.Fatalf("Error creating Sarama config: %v", err)
+		glog.Fatalf("Error creating Kafka client factory: %v", err)
 	}
 
-	producerClient, err := newClientWithRetry(canaryConfig, saramaConfig)
+	var producer clients.Producer
+	err = services.Bootstrap(canaryConfig, services.ProducerBootstrapClient, func() (err error) {
+		producer, err = clientFactory.NewProducer(canaryConfig.BootstrapServers)
+		return err
+	})
 	if err != nil {
-		glog.Fatalf("Error creating producer Sarama client: %v", err)
+		glog.Fatalf("Error creating Kafka producer: %v", err)
 	}
-	consumerClient, err := newClientWithRetry(canaryConfig, saramaConfig)
+	// when the replication check is enabled, the consumer gets the mirrored records from the target cluster
+	consumerBootstrapServers := canaryConfig.BootstrapServers
+	if canaryConfig.IsReplicationCheckEnabled() {
+		consumerBootstrapServers = canaryConfig.TargetBootstrapServers
+	}
+	var consumerGroup clients.ConsumerGroup
+	err = services.Bootstrap(canaryConfig, services.ConsumerBootstrapClient, func() (err error) {
+		consumerGroup, err = clientFactory.NewConsumerGroup(consumerBootstrapServers, canaryConfig.ConsumerGroupID)
+		return err
+	})
 	if err != nil {
-		glog.Fatalf("Error creating consumer Sarama client: %v", err)
+		glog.Fatalf("Error creating Kafka consumer group: %v", err)
 	}
 
-	topicService := services.NewTopicService(canaryConfig, saramaConfig)
-	producerService := services.NewProducerService(canaryConfig, producerClient)
-	consumerService := services.NewConsumerService(canaryConfig, consumerClient)
-	connectionService := services.NewConnectionService(canaryConfig, saramaConfig)
-
-	canaryManager := workers.NewCanaryManager(canaryConfig, topicService, producerService, consumerService, connectionService, statusService)
-	canaryManager.Start()
+	topicService := services.NewTopicService(canaryConfig, clientFactory, trackers)
+	producerService := services.NewProducerService(canaryConfig, clientFactory, producer, trackers)
+	consumerService := services.NewConsumerService(canaryConfig, clientFactory, consumerGroup, trackers)
+	connectionService := services.NewConnectionService(canaryConfig, clientFactory, trackers)
+	checkService := services.NewCheckService(canaryConfig, producerService, consumerService)
 
-	sig := <-signals
-	glog.Infof("Got signal: %v", sig)
-	canaryManager.Stop()
-	httpServer.Stop()
-	dynamicConfigWatcher.Close()
-	_ = producerClient.Close()
-	_ = consumerClient.Close()
+	if *once {
+		exitCode = runOnce(canaryConfig, topicService, producerService, consumerService, connectionService)
+		dynamicConfigWatcher.Close()
+		return
+	}
 
-	glog.Infof("Strimzi canary stopped")
-}
+	// the built-in connection and status checks, then the pluggable ones (i.e. chaos leader elections, consumer group
+	// check, ...) configured through their own options, any of them can be disabled by name
+	checkScheduler := services.NewCheckScheduler(canaryConfig)
+	for _, check := range connectionService.Checks() {
+		checkScheduler.Register(check)
+	}
+	for _, check := range statusService.Checks() {
+		checkScheduler.Register(check)
+	}
+	for _, check := range services.NewChecks(canaryConfig, clientFactory) {
+		checkScheduler.Register(check)
+	}
 
-func createSaramaConfig(canaryConfig *config.CanaryConfig) (*sarama.Config, error) {
-	config := sarama.NewConfig()
-	kafkaVersion, err := sarama.ParseKafkaVersion(canaryConfig.KafkaVersion)
-	if err != nil {
-		return nil, err
-	}
-	config.Version = kafkaVersion
-	config.ClientID = canaryConfig.ClientID
-	// set manual partitioner in order to specify the destination partition on sending
-	config.Producer.Partitioner = sarama.NewManualPartitioner
-	config.Producer.Return.Successes = true
-	config.Producer.RequiredAcks = sarama.WaitForAll
-	config.Producer.Retry.Max = 0
-	config.Consumer.Return.Errors = true
-
-	if canaryConfig.TLSEnabled {
-		config.Net.TLS.Enable = true
-		if config.Net.TLS.Config, err = security.NewTLSConfig(canaryConfig); err != nil {
-			glog.Fatalf("Error configuring TLS: %v", err)
-		}
+	// publishing the failure events since the canary start up, until the producer and consumer are drained
+	var eventPublisher *services.EventPublisher
+	if canaryConfig.EventsTopic != "" {
+		eventPublisher = services.NewEventPublisher(canaryConfig, clientFactory)
+		eventPublisher.Open()
 	}
 
-	if canaryConfig.SASLMechanism != "" {
-		if err = security.SetAuthConfig(canaryConfig, config); err != nil {
-			glog.Fatalf("Error configuring SASL authentication: %v", err)
-		}
+	canaryManager := workers.NewCanaryManager(canaryConfig, topicService, producerService, consumerService, connectionService, statusService, checkScheduler, trackers)
+	canaryManager.Start()
+	// on-demand checks are available only when producer and consumer are up and running
+	httpServer.Handle("/check", checkService.CheckHandler())
+
+	var soakService *services.SoakService
+	var soakDone <-chan struct{}
+	if canaryConfig.IsSoakModeEnabled() {
+		soakService = services.NewSoakService(canaryConfig)
+		soakService.Start()
+		soakDone = soakService.Done()
 	}
 
-	return config, nil
-}
+	select {
+	case sig := <-signals:
+		glog.Infof("Got signal: %v", sig)
+	case <-soakDone:
+		glog.Infof("Soak run done, stopping")
+	}
+	// servers stopped first, so that no on-demand checks are running while draining
+	httpServer.Stop()
+	if grpcServer != nil {
+		grpcServer.Stop()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), canaryConfig.ShutdownDrainTimeout*time.Millisecond)
+	defer cancel()
+	canaryManager.Stop(ctx)
+	if eventPublisher != nil {
+		eventPublisher.Close()
+	}
+	dynamicConfigWatcher.Close()
 
-func newClientWithRetry(canaryConfig *config.CanaryConfig, config *sarama.Config) (sarama.Client, error) {
-	backoff := services.NewBackoff(canaryConfig.BootstrapBackoffMaxAttempts, canaryConfig.BootstrapBackoffScale*time.Millisecond, services.MaxDefault)
-	for {
-		client, clientErr := sarama.NewClient(canaryConfig.BootstrapServers, config)
-		if clientErr == nil {
-			return client, nil
-		}
-		delay, backoffErr := backoff.Delay()
-		if backoffErr != nil {
-			glog.Errorf("Error connecting to the Kafka cluster after %d retries: %v", canaryConfig.BootstrapBackoffMaxAttempts, backoffErr)
-			return nil, backoffErr
+	// verification after draining, so that the in-flight records are taken into account
+	if soakService != nil {
+		if err := soakService.Verify(); err != nil {
+			glog.Errorf("Soak run failed: %v", err)
+			exitCode = 1
+		} else {
+			glog.Infof("Soak run succeeded")
 		}
-		clientCreationFailed.With(nil).Inc()
-		glog.Warningf("Error creating new Sarama client, retrying in %d ms: %v", delay.Milliseconds(), clientErr)
-		time.Sleep(delay)
 	}
+
+	glog.Infof("Strimzi canary stopped")
 }
 
-func applyDynamicConfig(dynamicCanaryConfig *config.DynamicCanaryConfig) {
-	if dynamicCanaryConfig.VerbosityLogLevel != nil {
-		flag.Set("v", strconv.Itoa(*dynamicCanaryConfig.VerbosityLogLevel))
+func applyDynamicConfig(canaryConfig *config.CanaryConfig, dynamicCanaryConfig *config.DynamicCanaryConfig, trackers *services.Trackers) {
+	// the settings not set restore the ones from the environment variables
+	logSettings := canaryConfig.DynamicCanaryConfig.Merge(dynamicCanaryConfig)
+	if logSettings.VerbosityLogLevel != nil {
+		flag.Set("v", strconv.Itoa(*logSettings.VerbosityLogLevel))
 		flag.Parse()
 	}
 
-	if dynamicCanaryConfig.SaramaLogEnabled != nil && *dynamicCanaryConfig.SaramaLogEnabled {
-		saramaLogger.SetOutput(os.Stdout)
-	} else {
-		saramaLogger.SetOutput(io.Discard)
-	}
+	saramaLogger.SetEnabled(logSettings.SaramaLogEnabled != nil && *logSettings.SaramaLogEnabled)
+	trackers.ApplyDynamicConfig(dynamicCanaryConfig)
 
 	glog.Warningf("Applied dynamic config %s", dynamicCanaryConfig)
 }
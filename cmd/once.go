@@ -0,0 +1,103 @@
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/golang/glog"
+
+	"github.com/strimzi/strimzi-canary/internal/config"
+	"github.com/strimzi/strimzi-canary/internal/services"
+)
+
+const (
+	// exit codes of the one-shot check, based on the severity of the issues found
+	onceOK       = 0
+	onceWarning  = 1
+	onceCritical = 2
+)
+
+// onceResult defines the results of the one-shot check printed on the standard output
+type onceResult struct {
+	Severity   int
+	TopicError string `json:",omitempty"`
+	// error on joining the consumer group, so the round trip is not checked
+	ConsumerError string                            `json:",omitempty"`
+	RoundTrip     *services.CheckResult             `json:",omitempty"`
+	Connections   []services.BrokerConnectionResult `json:",omitempty"`
+	// error on checking the connections to the brokers, i.e. describing the cluster failed
+	ConnectionsError string `json:",omitempty"`
+}
+
+// runOnce runs the topic reconcile, one produce/consume round trip and a connection check, then it prints
+// the results and returns the exit code: 0 if everything is fine, 1 on partial failures, 2 on critical ones
+func runOnce(canaryConfig *config.CanaryConfig, topicService *services.TopicService, producerService *services.ProducerService,
+	consumerService *services.ConsumerService, connectionService *services.ConnectionService) int {
+
+	result := onceResult{Severity: onceOK}
+	defer func() {
+		output, _ := json.MarshalIndent(result, "", "  ")
+		fmt.Println(string(output))
+	}()
+
+	reconcileResult, err := topicService.Reconcile()
+	if err != nil {
+		glog.Errorf("Error reconciling the canary topic: %v", err)
+		result.TopicError = err.Error()
+		result.Severity = onceCritical
+		topicService.Close()
+		return result.Severity
+	}
+	if reconcileResult.RefreshMetadata {
+		producerService.Refresh()
+	}
+
+	if err := consumerService.Consume(); err != nil {
+		glog.Errorf("Error joining the consumer group: %v", err)
+		result.ConsumerError = err.Error()
+		result.Severity = onceCritical
+	} else {
+		roundTrip := services.NewCheckService(canaryConfig, producerService, consumerService).Check()
+		result.RoundTrip = &roundTrip
+		if !roundTrip.Success {
+			result.Severity = onceWarning
+			if !isAnyPartitionConsumed(roundTrip) {
+				result.Severity = onceCritical
+			}
+		}
+	}
+
+	connections, err := connectionService.Check()
+	result.Connections = connections
+	if err != nil {
+		result.ConnectionsError = err.Error()
+		result.Severity = onceCritical
+	}
+	for _, c := range connections {
+		if !c.Connected && result.Severity < onceWarning {
+			result.Severity = onceWarning
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), canaryConfig.ShutdownDrainTimeout*time.Millisecond)
+	defer cancel()
+	producerService.Close(ctx)
+	consumerService.Close(ctx)
+	topicService.Close()
+	connectionService.Close()
+	return result.Severity
+}
+
+// isAnyPartitionConsumed returns true if the round trip succeeded at least on one partition
+func isAnyPartitionConsumed(roundTrip services.CheckResult) bool {
+	for _, p := range roundTrip.Partitions {
+		if p.ConsumedOffset >= 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,247 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package clients defines an abstraction over the Kafka clients used by the canary and related implementations
+package clients
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/strimzi/strimzi-canary/internal/config"
+)
+
+const (
+	// Kafka client backends
+	SaramaBackend  = "sarama"
+	FranzGoBackend = "franz-go"
+
+	// producer acks, from none, the partition leader or all the in-sync replicas
+	AcksNone   = "0"
+	AcksLeader = "1"
+	AcksAll    = "all"
+)
+
+// DefaultKafkaVersion defines the Kafka version used when it's not configured and the negotiation with the Kafka cluster fails
+const DefaultKafkaVersion = "3.1.0"
+
+// ErrUnknownTopicOrPartition defines the error returned in the topic metadata when the topic doesn't exist
+var ErrUnknownTopicOrPartition = errors.New("this server does not host this topic-partition")
+
+// ErrRecordNotAvailable defines the error returned when fetching a record which isn't in the partition log anymore,
+// i.e. deleted by the retention or compacted
+var ErrRecordNotAvailable = errors.New("the record is not available in the partition log anymore")
+
+// Broker defines a Kafka broker as returned by the cluster metadata
+type Broker struct {
+	ID   int32
+	Addr string
+	Rack string
+}
+
+// PartitionMetadata defines the metadata of a topic partition
+type PartitionMetadata struct {
+	ID              int32
+	Leader          int32
+	Replicas        []int32
+	Isr             []int32
+	OfflineReplicas []int32
+}
+
+// TopicMetadata defines the metadata of a topic
+type TopicMetadata struct {
+	Name       string
+	Partitions []*PartitionMetadata
+	// topic level error, ErrUnknownTopicOrPartition if the topic doesn't exist
+	Err error
+}
+
+// PartitionReassignment defines the status of an ongoing partition reassignment
+type PartitionReassignment struct {
+	Replicas         []int32
+	AddingReplicas   []int32
+	RemovingReplicas []int32
+}
+
+// ConsumerGroupMember defines a member of a consumer group and the partitions assigned to it
+type ConsumerGroupMember struct {
+	MemberID string
+	ClientID string
+	Host     string
+	// assigned partitions by topic, empty while the group is rebalancing
+	Assignment map[string][]int32
+}
+
+// ConsumerGroupDescription defines the state, the assignment strategy and the members of a consumer group
+type ConsumerGroupDescription struct {
+	GroupID string
+	State   string
+	// partitions assignment strategy (i.e. range)
+	Protocol string
+	Members  []ConsumerGroupMember
+}
+
+// Record defines a record consumed from a topic
+type Record struct {
+	Topic     string
+	Partition int32
+	Offset    int64
+	Value     []byte
+	Timestamp time.Time
+	// context carrying the tracing information propagated by the producer
+	Context context.Context
+	// broker the record was fetched from, the leader or a follower with rack awareness, NoBrokerID if not known
+	FetchBrokerID int32
+}
+
+// NoBrokerID is the broker ID used when it's not known
+const NoBrokerID int32 = -1
+
+// Producer defines a producer sending records to specific topic partitions
+type Producer interface {
+	// Send sends synchronously the value to the topic partition and returns the record offset and the log append time
+	// assigned by the broker, which is zero when the topic uses the create time or the backend doesn't provide it.
+	// The offset is -1 when the producer doesn't wait for acks
+	Send(topic string, partition int32, value []byte) (int64, time.Time, error)
+	// Partitions returns the topic partitions from the producer metadata
+	Partitions(topic string) ([]int32, error)
+	// RefreshMetadata refreshes the producer metadata for the topic
+	RefreshMetadata(topic string) error
+	Close() error
+}
+
+// ConsumerGroupHandler defines the handler of the consumer group lifecycle and the consumed records
+type ConsumerGroupHandler interface {
+	// Setup is called when the consumer has joined the group and the partitions are assigned
+	Setup()
+	// Handle is called for each consumed record, it can be called concurrently for different partitions
+	Handle(record *Record)
+}
+
+// ConsumerGroup defines a consumer consuming records as part of a consumer group
+type ConsumerGroup interface {
+	// Consume joins the group and consumes records until the context is cancelled or a rebalance happens
+	Consume(ctx context.Context, topics []string, handler ConsumerGroupHandler) error
+	// Errors returns the channel on which errors happened while consuming are reported
+	Errors() <-chan error
+	// Close commits the offsets of the consumed records and leaves the group
+	Close() error
+}
+
+// Fetcher defines a consumer fetching single records at specific offsets, without being part of a consumer group
+type Fetcher interface {
+	// Fetch returns the record at the offset of the topic partition, ErrRecordNotAvailable if it's not in the log anymore
+	Fetch(ctx context.Context, topic string, partition int32, offset int64) (*Record, error)
+	// OffsetForTimestamp returns the offset of the first record of the topic partition with a timestamp (in ms)
+	// greater than or equal to the provided one, -1 if there is no such record
+	OffsetForTimestamp(ctx context.Context, topic string, partition int32, timestamp int64) (int64, error)
+	Close() error
+}
+
+// Admin defines the admin operations on the Kafka cluster needed by the canary
+type Admin interface {
+	DescribeCluster() ([]Broker, error)
+	DescribeTopic(topic string) (*TopicMetadata, error)
+	CreateTopic(topic string, assignments map[int32][]int32, config map[string]*string) error
+	AlterTopicConfig(topic string, config map[string]*string) error
+	CreatePartitions(topic string, count int32, assignments [][]int32) error
+	AlterPartitionReassignments(topic string, assignments [][]int32) error
+	ListPartitionReassignments(topic string, partitions []int32) (map[int32]*PartitionReassignment, error)
+	DeleteTopic(topic string) error
+	// ElectPreferredLeaders triggers the preferred replica leader election for the topic partitions
+	ElectPreferredLeaders(topic string, partitions []int32) error
+	// DescribeConsumerGroup returns the state, the assignment strategy and the members of the consumer group
+	DescribeConsumerGroup(group string) (*ConsumerGroupDescription, error)
+	// ListTopics returns the names of the topics in the cluster, the internal ones included
+	ListTopics() ([]string, error)
+	// ListConsumerGroups returns the IDs of the consumer groups in the cluster
+	ListConsumerGroups() ([]string, error)
+	// DeleteConsumerGroup deletes the consumer group, which has to be without members
+	DeleteConsumerGroup(group string) error
+	Close() error
+}
+
+// Factory defines the creation of the Kafka clients for a specific backend
+type Factory interface {
+	NewProducer(bootstrapServers []string) (Producer, error)
+	// NewProducerWithAcks returns a producer waiting for the provided acks instead of the configured ones
+	NewProducerWithAcks(bootstrapServers []string, acks string) (Producer, error)
+	NewConsumerGroup(bootstrapServers []string, groupID string) (ConsumerGroup, error)
+	// NewFetcher returns a fetcher using the consumer configuration
+	NewFetcher(bootstrapServers []string) (Fetcher, error)
+	NewAdmin(bootstrapServers []string) (Admin, error)
+	// CheckConnection opens a new connection to the broker, checks it and closes it
+	CheckConnection(broker Broker) error
+}
+
+// ProducerBatching defines the producer batching configuration
+type ProducerBatching struct {
+	// time the records are buffered before sending a batch, 0 means sending as soon as possible
+	Linger time.Duration
+	// records count triggering a batch sending, 0 means no limit
+	Messages int
+	// max size of a record batch
+	MaxMessageBytes int
+}
+
+// EffectiveProducerBatching returns the producer batching configuration in effect for the Kafka client backend,
+// the configured one or the backend default
+func EffectiveProducerBatching(canaryConfig *config.CanaryConfig) ProducerBatching {
+	switch canaryConfig.KafkaClientBackend {
+	case SaramaBackend:
+		return saramaProducerBatching(canaryConfig)
+	case FranzGoBackend:
+		return franzGoProducerBatching(canaryConfig)
+	}
+	return ProducerBatching{}
+}
+
+// ConsumerFetch defines the consumer fetch configuration
+type ConsumerFetch struct {
+	// min bytes the broker waits for before answering a fetch request
+	MinBytes int
+	// max bytes fetched per partition
+	DefaultBytes int
+	// max bytes fetched per request, 0 means no limit
+	MaxBytes int
+	// max time the broker waits for the min bytes
+	MaxWait time.Duration
+}
+
+// EffectiveConsumerFetch returns the consumer fetch configuration in effect for the Kafka client backend,
+// the configured one or the backend default
+func EffectiveConsumerFetch(canaryConfig *config.CanaryConfig) ConsumerFetch {
+	switch canaryConfig.KafkaClientBackend {
+	case SaramaBackend:
+		return saramaConsumerFetch(canaryConfig)
+	case FranzGoBackend:
+		return franzGoConsumerFetch(canaryConfig)
+	}
+	return ConsumerFetch{}
+}
+
+// AssignmentStrategy returns the consumer group partitions assignment strategy used by the Kafka client backend
+func AssignmentStrategy(backend string) string {
+	switch backend {
+	case SaramaBackend:
+		return saramaAssignmentStrategy
+	case FranzGoBackend:
+		return franzGoAssignmentStrategy
+	}
+	return ""
+}
+
+// NewFactory returns the factory for the Kafka client backend configured
+func NewFactory(canaryConfig *config.CanaryConfig) (Factory, error) {
+	switch canaryConfig.KafkaClientBackend {
+	case SaramaBackend:
+		return newSaramaFactory(canaryConfig)
+	case FranzGoBackend:
+		return newFranzGoFactory(canaryConfig)
+	}
+	return nil, fmt.Errorf("Kafka client backend %s is not supported", canaryConfig.KafkaClientBackend)
+}
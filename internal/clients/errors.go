@@ -0,0 +1,41 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package clients defines an abstraction over the Kafka clients used by the canary and related implementations
+package clients
+
+import (
+	"errors"
+
+	"github.com/Shopify/sarama"
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"github.com/strimzi/strimzi-canary/internal/util"
+)
+
+// IsFatal returns true if the err provided is an unrecoverable error for the Kafka client, which has to be recreated
+//
+// They are TCP disconnections, the client being closed and authentication failures (i.e. expired credentials)
+func IsFatal(err error) bool {
+	if err == nil {
+		return false
+	}
+	return util.IsDisconnection(err) ||
+		errors.Is(err, sarama.ErrClosedClient) || errors.Is(err, sarama.ErrSASLAuthenticationFailed) ||
+		errors.Is(err, kgo.ErrClientClosed) || errors.Is(err, kerr.SaslAuthenticationFailed)
+}
+
+// IsAuthError returns true if the err provided is an authentication or authorization failure, i.e. the client
+// credentials are wrong or expired, or the ACLs don't allow the operation on the topic, the group or the cluster
+func IsAuthError(err error) bool {
+	if err == nil {
+		return false
+	}
+	return errors.Is(err, sarama.ErrSASLAuthenticationFailed) || errors.Is(err, sarama.ErrTopicAuthorizationFailed) ||
+		errors.Is(err, sarama.ErrGroupAuthorizationFailed) || errors.Is(err, sarama.ErrClusterAuthorizationFailed) ||
+		errors.Is(err, kerr.SaslAuthenticationFailed) || errors.Is(err, kerr.TopicAuthorizationFailed) ||
+		errors.Is(err, kerr.GroupAuthorizationFailed) || errors.Is(err, kerr.ClusterAuthorizationFailed)
+}
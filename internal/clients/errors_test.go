@@ -0,0 +1,67 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// +build unit_test
+
+// Package clients defines an abstraction over the Kafka clients used by the canary and related implementations
+package clients
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+func TestIsFatal(t *testing.T) {
+	cases := []struct {
+		err      error
+		expected bool
+	}{
+		{nil, false},
+		{errors.New("foobar"), false},
+		{sarama.ErrNotLeaderForPartition, false},
+		{kerr.NotLeaderForPartition, false},
+		{io.EOF, true},
+		{sarama.ErrClosedClient, true},
+		{sarama.ErrSASLAuthenticationFailed, true},
+		{kgo.ErrClientClosed, true},
+		{fmt.Errorf("wrapped: %w", kerr.SaslAuthenticationFailed), true},
+	}
+
+	for _, c := range cases {
+		if IsFatal(c.err) != c.expected {
+			t.Errorf("IsFatal(%v) got = %t, want = %t", c.err, !c.expected, c.expected)
+		}
+	}
+}
+
+func TestIsAuthError(t *testing.T) {
+	cases := []struct {
+		err      error
+		expected bool
+	}{
+		{nil, false},
+		{errors.New("foobar"), false},
+		{io.EOF, false},
+		{sarama.ErrNotLeaderForPartition, false},
+		{sarama.ErrSASLAuthenticationFailed, true},
+		{sarama.ErrTopicAuthorizationFailed, true},
+		{&sarama.ConsumerError{Topic: "my-topic", Partition: 0, Err: sarama.ErrGroupAuthorizationFailed}, true},
+		{kerr.ClusterAuthorizationFailed, true},
+		{fmt.Errorf("wrapped: %w", kerr.TopicAuthorizationFailed), true},
+		{kerr.NotLeaderForPartition, false},
+	}
+
+	for _, c := range cases {
+		if IsAuthError(c.err) != c.expected {
+			t.Errorf("IsAuthError(%v) got = %t, want = %t", c.err, !c.expected, c.expected)
+		}
+	}
+}
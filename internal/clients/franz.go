@@ -0,0 +1,1011 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package clients defines an abstraction over the Kafka clients used by the canary and related implementations
+package clients
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/kmsg"
+	"go.opentelemetry.io/otel"
+	"golang.org/x/net/proxy"
+
+	"github.com/strimzi/strimzi-canary/internal/config"
+	"github.com/strimzi/strimzi-canary/internal/security"
+)
+
+const (
+	// timeout for the requests not bound to the consumer or producer lifecycle (i.e. admin operations)
+	franzGoRequestTimeout = 30 * time.Second
+	// consumer fetch max wait, the same as the Sarama default
+	franzGoFetchMaxWait = 250 * time.Millisecond
+	// consumer group default balancer
+	franzGoAssignmentStrategy = "cooperative-sticky"
+	// producer default record batch max size
+	franzGoBatchMaxBytes = 1000000
+	// consumer default fetch sizes
+	franzGoFetchMinBytes          = 1
+	franzGoFetchMaxPartitionBytes = 1 << 20
+	franzGoFetchMaxBytes          = 50 << 20
+)
+
+// franzGoFactory creates Kafka clients based on the franz-go library
+type franzGoFactory struct {
+	opts []kgo.Opt
+	// the producer and consumer options, with their own identities when configured
+	producerOpts []kgo.Opt
+	consumerOpts []kgo.Opt
+	// timeouts for the admin operations and the producer metadata requests
+	adminTimeout    time.Duration
+	metadataTimeout time.Duration
+	// consumer rack, for fetching from the closest replica
+	rack string
+	// producer acks, as configured
+	acks string
+	// producer batching, as configured
+	batching ProducerBatching
+	// consumer fetch, in effect
+	fetch ConsumerFetch
+}
+
+func newFranzGoFactory(canaryConfig *config.CanaryConfig) (Factory, error) {
+	opts, err := newFranzGoOpts(canaryConfig)
+	if err != nil {
+		return nil, err
+	}
+	producerOpts, err := newFranzGoOpts(canaryConfig.ProducerIdentity())
+	if err != nil {
+		return nil, fmt.Errorf("producer: %v", err)
+	}
+	consumerOpts, err := newFranzGoOpts(canaryConfig.ConsumerIdentity())
+	if err != nil {
+		return nil, fmt.Errorf("consumer: %v", err)
+	}
+	if _, err := franzGoAcks(canaryConfig.ProducerAcks); err != nil {
+		return nil, err
+	}
+	return &franzGoFactory{
+		opts:            opts,
+		producerOpts:    producerOpts,
+		consumerOpts:    consumerOpts,
+		adminTimeout:    franzGoTimeout(canaryConfig.AdminTimeout),
+		metadataTimeout: franzGoTimeout(canaryConfig.MetadataRefreshTimeout),
+		rack:            canaryConfig.ConsumerRackID,
+		acks:            canaryConfig.ProducerAcks,
+		batching: ProducerBatching{
+			Linger:          canaryConfig.ProducerLinger * time.Millisecond,
+			MaxMessageBytes: canaryConfig.ProducerMaxMessageBytes,
+		},
+		fetch: franzGoConsumerFetch(canaryConfig),
+	}, nil
+}
+
+// franzGoProducerBatching returns the effective franz-go producer batching configuration
+func franzGoProducerBatching(canaryConfig *config.CanaryConfig) ProducerBatching {
+	batching := ProducerBatching{
+		Linger:          canaryConfig.ProducerLinger * time.Millisecond,
+		MaxMessageBytes: canaryConfig.ProducerMaxMessageBytes,
+	}
+	if batching.MaxMessageBytes <= 0 {
+		batching.MaxMessageBytes = franzGoBatchMaxBytes
+	}
+	return batching
+}
+
+// franzGoConsumerFetch returns the effective franz-go consumer fetch configuration
+func franzGoConsumerFetch(canaryConfig *config.CanaryConfig) ConsumerFetch {
+	fetch := ConsumerFetch{
+		MinBytes:     franzGoFetchMinBytes,
+		DefaultBytes: franzGoFetchMaxPartitionBytes,
+		MaxBytes:     franzGoFetchMaxBytes,
+		MaxWait:      franzGoFetchMaxWait,
+	}
+	if canaryConfig.ConsumerFetchMinBytes > 0 {
+		fetch.MinBytes = canaryConfig.ConsumerFetchMinBytes
+	}
+	if canaryConfig.ConsumerFetchDefaultBytes > 0 {
+		fetch.DefaultBytes = canaryConfig.ConsumerFetchDefaultBytes
+	}
+	if canaryConfig.ConsumerFetchMaxBytes > 0 {
+		fetch.MaxBytes = canaryConfig.ConsumerFetchMaxBytes
+	}
+	if canaryConfig.ConsumerFetchMaxWait > 0 {
+		fetch.MaxWait = canaryConfig.ConsumerFetchMaxWait * time.Millisecond
+	}
+	return fetch
+}
+
+// franzGoTimeout returns the configured timeout (in ms) or the default request timeout if not configured
+func franzGoTimeout(timeout time.Duration) time.Duration {
+	if timeout > 0 {
+		return timeout * time.Millisecond
+	}
+	return franzGoRequestTimeout
+}
+
+// newFranzGoOpts returns the franz-go client options shared by all the clients
+func newFranzGoOpts(canaryConfig *config.CanaryConfig) ([]kgo.Opt, error) {
+	opts := []kgo.Opt{
+		kgo.ClientID(canaryConfig.ClientID),
+	}
+
+	var tlsConfig *tls.Config
+	if canaryConfig.TLSEnabled {
+		var err error
+		if tlsConfig, err = security.NewTLSConfig(canaryConfig); err != nil {
+			return nil, fmt.Errorf("error configuring TLS: %v", err)
+		}
+	}
+	if canaryConfig.ProxyURL != "" {
+		dialer, err := newProxyDialer(canaryConfig)
+		if err != nil {
+			return nil, fmt.Errorf("error configuring proxy: %v", err)
+		}
+		opts = append(opts, kgo.Dialer(franzGoDialer(dialer, tlsConfig)))
+	} else if canaryConfig.DialTimeout > 0 {
+		opts = append(opts, kgo.Dialer(franzGoDialer(&net.Dialer{Timeout: canaryConfig.DialTimeout * time.Millisecond}, tlsConfig)))
+	} else if tlsConfig != nil {
+		opts = append(opts, kgo.DialTLSConfig(tlsConfig))
+	}
+
+	if canaryConfig.ProducerRequestTimeout > 0 {
+		opts = append(opts, kgo.ProduceRequestTimeout(canaryConfig.ProducerRequestTimeout*time.Millisecond))
+	}
+
+	if canaryConfig.SASLMechanism != "" {
+		mechanism, err := security.NewSASLMechanism(canaryConfig)
+		if err != nil {
+			return nil, fmt.Errorf("error configuring SASL authentication: %v", err)
+		}
+		opts = append(opts, kgo.SASL(mechanism))
+	}
+
+	return opts, nil
+}
+
+// franzGoDialer returns a dial function using the provided dialer, dialing over TLS if a TLS config is provided
+//
+// As the franz-go TLS dialer, the TLS server name is set to the dialed broker host if not already configured
+func franzGoDialer(dialer proxy.ContextDialer, tlsConfig *tls.Config) func(ctx context.Context, network string, host string) (net.Conn, error) {
+	return func(ctx context.Context, network string, host string) (net.Conn, error) {
+		conn, err := dialer.DialContext(ctx, network, host)
+		if err != nil || tlsConfig == nil {
+			return conn, err
+		}
+		config := tlsConfig.Clone()
+		if config.ServerName == "" {
+			server, _, err := net.SplitHostPort(host)
+			if err != nil {
+				conn.Close()
+				return nil, fmt.Errorf("unable to split host:port for dialing: %v", err)
+			}
+			config.ServerName = server
+		}
+		tlsConn := tls.Client(conn, config)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return tlsConn, nil
+	}
+}
+
+// newClient returns a franz-go client with the provided base options, i.e. with the identity of the client role, and the additional ones
+func (f *franzGoFactory) newClient(bootstrapServers []string, baseOpts []kgo.Opt, opts ...kgo.Opt) (*kgo.Client, error) {
+	clientOpts := append([]kgo.Opt{kgo.SeedBrokers(bootstrapServers...)}, baseOpts...)
+	return kgo.NewClient(append(clientOpts, opts...)...)
+}
+
+func (f *franzGoFactory) NewProducer(bootstrapServers []string) (Producer, error) {
+	return f.NewProducerWithAcks(bootstrapServers, f.acks)
+}
+
+func (f *franzGoFactory) NewProducerWithAcks(bootstrapServers []string, acks string) (Producer, error) {
+	requiredAcks, err := franzGoAcks(acks)
+	if err != nil {
+		return nil, err
+	}
+	opts := []kgo.Opt{
+		// set manual partitioner in order to specify the destination partition on sending
+		kgo.RecordPartitioner(kgo.ManualPartitioner()),
+		kgo.RequiredAcks(requiredAcks),
+		// no retries, so that sending failures are reported, which needs idempotency disabled
+		kgo.DisableIdempotentWrite(),
+		kgo.RecordRetries(1),
+	}
+	// overriding the library defaults only when configured, the batch messages are not supported
+	if f.batching.Linger > 0 {
+		opts = append(opts, kgo.ProducerLinger(f.batching.Linger))
+	}
+	if f.batching.MaxMessageBytes > 0 {
+		opts = append(opts, kgo.ProducerBatchMaxBytes(int32(f.batching.MaxMessageBytes)))
+	}
+	client, err := f.newClient(bootstrapServers, f.producerOpts, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &franzGoProducer{client: client, metadataTimeout: f.metadataTimeout, noAcks: acks == AcksNone}, nil
+}
+
+// franzGoAcks returns the franz-go required acks for the producer acks
+func franzGoAcks(acks string) (kgo.Acks, error) {
+	switch acks {
+	case AcksNone:
+		return kgo.NoAck(), nil
+	case AcksLeader:
+		return kgo.LeaderAck(), nil
+	// waiting for all the in-sync replicas when not configured
+	case AcksAll, "-1", "":
+		return kgo.AllISRAcks(), nil
+	}
+	return kgo.Acks{}, fmt.Errorf("producer acks %s is not supported, it has to be 0, 1 or all", acks)
+}
+
+func (f *franzGoFactory) NewConsumerGroup(bootstrapServers []string, groupID string) (ConsumerGroup, error) {
+	cg := &franzGoConsumerGroup{
+		errors:       make(chan error, 16),
+		owned:        make(map[string]map[int32]bool),
+		fetchBrokers: make(map[int32]int32),
+	}
+	opts := []kgo.Opt{
+		kgo.ConsumerGroup(groupID),
+		// starting from the latest offset when no committed offsets exist, the same as the Sarama default
+		kgo.ConsumeResetOffset(kgo.NewOffset().AtEnd()),
+		kgo.FetchMinBytes(int32(f.fetch.MinBytes)),
+		kgo.FetchMaxPartitionBytes(int32(f.fetch.DefaultBytes)),
+		kgo.FetchMaxBytes(int32(f.fetch.MaxBytes)),
+		kgo.FetchMaxWait(f.fetch.MaxWait),
+		kgo.OnPartitionsAssigned(cg.onPartitionsAssigned),
+		kgo.OnPartitionsRevoked(cg.onPartitionsRevoked),
+		kgo.OnPartitionsLost(cg.onPartitionsRevoked),
+		// tracking the broker each partition is fetched from
+		kgo.WithHooks(cg),
+	}
+	if f.rack != "" {
+		// the consumer fetches from the closest replica (KIP-392) when the rack is set
+		opts = append(opts, kgo.Rack(f.rack))
+	}
+	client, err := f.newClient(bootstrapServers, f.consumerOpts, opts...)
+	if err != nil {
+		return nil, err
+	}
+	cg.client = client
+	return cg, nil
+}
+
+// NewFetcher returns a fetcher creating a client for each fetch, because the franz-go direct consumer partitions
+// can only be set on the client creation
+func (f *franzGoFactory) NewFetcher(bootstrapServers []string) (Fetcher, error) {
+	return &franzGoFetcher{factory: f, bootstrapServers: bootstrapServers}, nil
+}
+
+func (f *franzGoFactory) NewAdmin(bootstrapServers []string) (Admin, error) {
+	client, err := f.newClient(bootstrapServers, f.opts)
+	if err != nil {
+		return nil, err
+	}
+	return &franzGoAdmin{client: client, timeout: f.adminTimeout}, nil
+}
+
+func (f *franzGoFactory) CheckConnection(broker Broker) error {
+	client, err := f.newClient([]string{broker.Addr}, f.opts)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), franzGoRequestTimeout)
+	defer cancel()
+	return client.Ping(ctx)
+}
+
+// franzGoProducer is the Producer implementation based on the franz-go client
+type franzGoProducer struct {
+	client          *kgo.Client
+	metadataTimeout time.Duration
+	// if the producer doesn't wait for acks
+	noAcks bool
+}
+
+// Send doesn't provide the log append time, because it's not exposed by the franz-go client
+func (p *franzGoProducer) Send(topic string, partition int32, value []byte) (int64, time.Time, error) {
+	record := &kgo.Record{
+		Topic:     topic,
+		Partition: partition,
+		Value:     value,
+	}
+	otel.GetTextMapPropagator().Inject(context.Background(), &franzGoHeadersCarrier{record: record})
+	result, err := p.client.ProduceSync(context.Background(), record).First()
+	if err != nil {
+		return -1, time.Time{}, err
+	}
+	if p.noAcks {
+		// without acks the offset is not returned by the broker
+		return -1, time.Time{}, nil
+	}
+	return result.Offset, time.Time{}, nil
+}
+
+func (p *franzGoProducer) Partitions(topic string) ([]int32, error) {
+	topicMetadata, err := describeTopic(p.client, topic, p.metadataTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if topicMetadata.Err != nil {
+		return nil, topicMetadata.Err
+	}
+	partitions := make([]int32, 0, len(topicMetadata.Partitions))
+	for _, p := range topicMetadata.Partitions {
+		partitions = append(partitions, p.ID)
+	}
+	return partitions, nil
+}
+
+// RefreshMetadata sends a metadata request for the topic, because the franz-go forced refresh happens in the background
+// and doesn't report errors, then triggers the client metadata refresh for picking up the changes
+func (p *franzGoProducer) RefreshMetadata(topic string) error {
+	topicMetadata, err := describeTopic(p.client, topic, p.metadataTimeout)
+	if err != nil {
+		return err
+	}
+	p.client.ForceMetadataRefresh()
+	return topicMetadata.Err
+}
+
+func (p *franzGoProducer) Close() error {
+	p.client.Close()
+	return nil
+}
+
+// franzGoConsumerClient defines the franz-go client operations used by the consumer group
+type franzGoConsumerClient interface {
+	AddConsumeTopics(topics ...string)
+	PollFetches(ctx context.Context) kgo.Fetches
+	Close()
+}
+
+// franzGoConsumerGroup is the ConsumerGroup implementation based on the franz-go client
+type franzGoConsumerGroup struct {
+	client franzGoConsumerClient
+	errors chan error
+	// handler of the current Consume call, set up once on the first partitions assignment
+	mutex        sync.Mutex
+	handler      ConsumerGroupHandler
+	handlerSetup bool
+	// partitions currently owned by the client, by topic
+	owned map[string]map[int32]bool
+	// stopping the poll loop of the current Consume call and waiting for it, before closing the errors channel
+	cancel  context.CancelFunc
+	polling sync.WaitGroup
+	closed  bool
+	// broker the last batch of each partition was fetched from
+	fetchBrokersMutex sync.Mutex
+	fetchBrokers      map[int32]int32
+}
+
+// Consume consumes records until the context is cancelled
+//
+// Differently from Sarama, the franz-go client handles rebalances internally, so it doesn't return on rebalancing.
+// Consuming again on the same client (i.e. after a refresh) doesn't cause a rebalance either, so the handler is set up
+// right away when the client already owns partitions
+func (cg *franzGoConsumerGroup) Consume(ctx context.Context, topics []string, handler ConsumerGroupHandler) error {
+	cg.mutex.Lock()
+	if cg.closed {
+		cg.mutex.Unlock()
+		return errors.New("consumer group closed")
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	cg.cancel = cancel
+	cg.polling.Add(1)
+	defer cg.polling.Done()
+	cg.handler = handler
+	cg.handlerSetup = len(cg.owned) > 0
+	if cg.handlerSetup {
+		handler.Setup()
+	}
+	cg.mutex.Unlock()
+	cg.client.AddConsumeTopics(topics...)
+
+	for {
+		fetches := cg.client.PollFetches(ctx)
+		if ctx.Err() != nil || fetches.IsClientClosed() {
+			return nil
+		}
+		fetches.EachError(func(topic string, partition int32, err error) {
+			select {
+			case cg.errors <- fmt.Errorf("error fetching from %s [%d]: %v", topic, partition, err):
+			default:
+			}
+		})
+		fetches.EachRecord(func(r *kgo.Record) {
+			handler.Handle(&Record{
+				Topic:     r.Topic,
+				Partition: r.Partition,
+				Offset:    r.Offset,
+				Value:     r.Value,
+				Timestamp: r.Timestamp,
+				Context:   otel.GetTextMapPropagator().Extract(context.Background(), &franzGoHeadersCarrier{record: r}),
+				// the batches are read before being polled, so the last broker is the one the record was fetched from
+				FetchBrokerID: cg.fetchBroker(r.Partition),
+			})
+		})
+	}
+}
+
+// OnFetchBatchRead tracks the broker the batch was fetched from, implementing the franz-go fetch batch hook
+func (cg *franzGoConsumerGroup) OnFetchBatchRead(meta kgo.BrokerMetadata, topic string, partition int32, metrics kgo.FetchBatchMetrics) {
+	cg.fetchBrokersMutex.Lock()
+	defer cg.fetchBrokersMutex.Unlock()
+	cg.fetchBrokers[partition] = meta.NodeID
+}
+
+// fetchBroker returns the broker the last batch of the partition was fetched from
+func (cg *franzGoConsumerGroup) fetchBroker(partition int32) int32 {
+	cg.fetchBrokersMutex.Lock()
+	defer cg.fetchBrokersMutex.Unlock()
+	if broker, ok := cg.fetchBrokers[partition]; ok {
+		return broker
+	}
+	return NoBrokerID
+}
+
+func (cg *franzGoConsumerGroup) onPartitionsAssigned(ctx context.Context, client *kgo.Client, assigned map[string][]int32) {
+	cg.mutex.Lock()
+	defer cg.mutex.Unlock()
+	for topic, partitions := range assigned {
+		if cg.owned[topic] == nil {
+			cg.owned[topic] = make(map[int32]bool)
+		}
+		for _, p := range partitions {
+			cg.owned[topic][p] = true
+		}
+	}
+	if cg.handler != nil && !cg.handlerSetup {
+		cg.handlerSetup = true
+		cg.handler.Setup()
+	}
+}
+
+// onPartitionsRevoked tracks the partitions not owned anymore, because revoked or lost
+func (cg *franzGoConsumerGroup) onPartitionsRevoked(ctx context.Context, client *kgo.Client, revoked map[string][]int32) {
+	cg.mutex.Lock()
+	defer cg.mutex.Unlock()
+	for topic, partitions := range revoked {
+		for _, p := range partitions {
+			delete(cg.owned[topic], p)
+		}
+		if len(cg.owned[topic]) == 0 {
+			delete(cg.owned, topic)
+		}
+	}
+}
+
+func (cg *franzGoConsumerGroup) Errors() <-chan error {
+	return cg.errors
+}
+
+// Close stops the poll loop, so that no errors are sent anymore, before closing the errors channel
+func (cg *franzGoConsumerGroup) Close() error {
+	cg.mutex.Lock()
+	if cg.closed {
+		cg.mutex.Unlock()
+		return nil
+	}
+	cg.closed = true
+	if cg.cancel != nil {
+		cg.cancel()
+	}
+	cg.mutex.Unlock()
+	cg.polling.Wait()
+	// leaving the group on close revokes the partitions, committing the offsets of the consumed records
+	cg.client.Close()
+	close(cg.errors)
+	return nil
+}
+
+// franzGoFetcher is the Fetcher implementation based on the franz-go direct consumer
+type franzGoFetcher struct {
+	factory          *franzGoFactory
+	bootstrapServers []string
+}
+
+func (f *franzGoFetcher) Fetch(ctx context.Context, topic string, partition int32, offset int64) (*Record, error) {
+	client, err := f.factory.newClient(f.bootstrapServers, f.factory.consumerOpts,
+		kgo.ConsumePartitions(map[string]map[int32]kgo.Offset{topic: {partition: kgo.NewOffset().At(offset)}}),
+		kgo.FetchMaxWait(f.factory.fetch.MaxWait),
+		kgo.Rack(f.factory.rack),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	for {
+		fetches := client.PollRecords(ctx, 1)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		for _, e := range fetches.Errors() {
+			if errors.Is(e.Err, kerr.OffsetOutOfRange) {
+				return nil, ErrRecordNotAvailable
+			}
+			return nil, e.Err
+		}
+		if records := fetches.Records(); len(records) > 0 {
+			r := records[0]
+			// an offset before the log start one is reset to it, as well as a compacted record is skipped
+			if r.Offset != offset {
+				return nil, ErrRecordNotAvailable
+			}
+			return &Record{
+				Topic:         r.Topic,
+				Partition:     r.Partition,
+				Offset:        r.Offset,
+				Value:         r.Value,
+				Timestamp:     r.Timestamp,
+				Context:       otel.GetTextMapPropagator().Extract(context.Background(), &franzGoHeadersCarrier{record: r}),
+				FetchBrokerID: NoBrokerID,
+			}, nil
+		}
+	}
+}
+
+func (f *franzGoFetcher) OffsetForTimestamp(ctx context.Context, topic string, partition int32, timestamp int64) (int64, error) {
+	client, err := f.factory.newClient(f.bootstrapServers, f.factory.consumerOpts)
+	if err != nil {
+		return -1, err
+	}
+	defer client.Close()
+
+	reqPartition := kmsg.NewListOffsetsRequestTopicPartition()
+	reqPartition.Partition = partition
+	reqPartition.Timestamp = timestamp
+	reqTopic := kmsg.NewListOffsetsRequestTopic()
+	reqTopic.Topic = topic
+	reqTopic.Partitions = append(reqTopic.Partitions, reqPartition)
+	req := kmsg.NewPtrListOffsetsRequest()
+	req.Topics = append(req.Topics, reqTopic)
+	resp, err := req.RequestWith(ctx, client)
+	if err != nil {
+		return -1, err
+	}
+	for _, t := range resp.Topics {
+		for _, p := range t.Partitions {
+			if p.Partition != partition {
+				continue
+			}
+			if err := kerr.ErrorForCode(p.ErrorCode); err != nil {
+				return -1, err
+			}
+			return p.Offset, nil
+		}
+	}
+	return -1, fmt.Errorf("partition %d of topic %s missing in the list offsets response", partition, topic)
+}
+
+func (f *franzGoFetcher) Close() error {
+	return nil
+}
+
+// franzGoAdmin is the Admin implementation based on the franz-go client sending raw admin requests
+type franzGoAdmin struct {
+	client  *kgo.Client
+	timeout time.Duration
+}
+
+func (a *franzGoAdmin) DescribeCluster() ([]Broker, error) {
+	req := kmsg.NewPtrMetadataRequest()
+	// empty (not nil) topics for getting just the brokers
+	req.Topics = []kmsg.MetadataRequestTopic{}
+	resp, err := a.request(req)
+	if err != nil {
+		return nil, err
+	}
+	metadata := resp.(*kmsg.MetadataResponse)
+	brokers := make([]Broker, 0, len(metadata.Brokers))
+	for _, b := range metadata.Brokers {
+		broker := Broker{
+			ID:   b.NodeID,
+			Addr: net.JoinHostPort(b.Host, strconv.Itoa(int(b.Port))),
+		}
+		if b.Rack != nil {
+			broker.Rack = *b.Rack
+		}
+		brokers = append(brokers, broker)
+	}
+	return brokers, nil
+}
+
+func (a *franzGoAdmin) DescribeTopic(topic string) (*TopicMetadata, error) {
+	return describeTopic(a.client, topic, a.timeout)
+}
+
+func (a *franzGoAdmin) CreateTopic(topic string, assignments map[int32][]int32, config map[string]*string) error {
+	reqTopic := kmsg.NewCreateTopicsRequestTopic()
+	reqTopic.Topic = topic
+	reqTopic.NumPartitions = -1
+	reqTopic.ReplicationFactor = -1
+	for partition, replicas := range assignments {
+		assignment := kmsg.NewCreateTopicsRequestTopicReplicaAssignment()
+		assignment.Partition = partition
+		assignment.Replicas = replicas
+		reqTopic.ReplicaAssignment = append(reqTopic.ReplicaAssignment, assignment)
+	}
+	for name, value := range config {
+		c := kmsg.NewCreateTopicsRequestTopicConfig()
+		c.Name = name
+		c.Value = value
+		reqTopic.Configs = append(reqTopic.Configs, c)
+	}
+	req := kmsg.NewPtrCreateTopicsRequest()
+	req.Topics = append(req.Topics, reqTopic)
+	req.TimeoutMillis = int32(a.timeout.Milliseconds())
+	resp, err := a.request(req)
+	if err != nil {
+		return err
+	}
+	for _, t := range resp.(*kmsg.CreateTopicsResponse).Topics {
+		if err := responseError(t.ErrorCode, t.ErrorMessage); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *franzGoAdmin) AlterTopicConfig(topic string, config map[string]*string) error {
+	resource := kmsg.NewAlterConfigsRequestResource()
+	resource.ResourceType = kmsg.ConfigResourceTypeTopic
+	resource.ResourceName = topic
+	for name, value := range config {
+		c := kmsg.NewAlterConfigsRequestResourceConfig()
+		c.Name = name
+		c.Value = value
+		resource.Configs = append(resource.Configs, c)
+	}
+	req := kmsg.NewPtrAlterConfigsRequest()
+	req.Resources = append(req.Resources, resource)
+	resp, err := a.request(req)
+	if err != nil {
+		return err
+	}
+	for _, r := range resp.(*kmsg.AlterConfigsResponse).Resources {
+		if err := responseError(r.ErrorCode, r.ErrorMessage); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *franzGoAdmin) CreatePartitions(topic string, count int32, assignments [][]int32) error {
+	reqTopic := kmsg.NewCreatePartitionsRequestTopic()
+	reqTopic.Topic = topic
+	reqTopic.Count = count
+	for _, replicas := range assignments {
+		assignment := kmsg.NewCreatePartitionsRequestTopicAssignment()
+		assignment.Replicas = replicas
+		reqTopic.Assignment = append(reqTopic.Assignment, assignment)
+	}
+	req := kmsg.NewPtrCreatePartitionsRequest()
+	req.Topics = append(req.Topics, reqTopic)
+	req.TimeoutMillis = int32(a.timeout.Milliseconds())
+	resp, err := a.request(req)
+	if err != nil {
+		return err
+	}
+	for _, t := range resp.(*kmsg.CreatePartitionsResponse).Topics {
+		if err := responseError(t.ErrorCode, t.ErrorMessage); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *franzGoAdmin) AlterPartitionReassignments(topic string, assignments [][]int32) error {
+	reqTopic := kmsg.NewAlterPartitionAssignmentsRequestTopic()
+	reqTopic.Topic = topic
+	for partition, replicas := range assignments {
+		p := kmsg.NewAlterPartitionAssignmentsRequestTopicPartition()
+		p.Partition = int32(partition)
+		p.Replicas = replicas
+		reqTopic.Partitions = append(reqTopic.Partitions, p)
+	}
+	req := kmsg.NewPtrAlterPartitionAssignmentsRequest()
+	req.Topics = append(req.Topics, reqTopic)
+	req.TimeoutMillis = int32(a.timeout.Milliseconds())
+	resp, err := a.request(req)
+	if err != nil {
+		return err
+	}
+	alterResp := resp.(*kmsg.AlterPartitionAssignmentsResponse)
+	if err := responseError(alterResp.ErrorCode, alterResp.ErrorMessage); err != nil {
+		return err
+	}
+	for _, t := range alterResp.Topics {
+		for _, p := range t.Partitions {
+			if err := responseError(p.ErrorCode, p.ErrorMessage); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (a *franzGoAdmin) ListPartitionReassignments(topic string, partitions []int32) (map[int32]*PartitionReassignment, error) {
+	reqTopic := kmsg.NewListPartitionReassignmentsRequestTopic()
+	reqTopic.Topic = topic
+	reqTopic.Partitions = partitions
+	req := kmsg.NewPtrListPartitionReassignmentsRequest()
+	req.Topics = append(req.Topics, reqTopic)
+	req.TimeoutMillis = int32(a.timeout.Milliseconds())
+	resp, err := a.request(req)
+	if err != nil {
+		return nil, err
+	}
+	listResp := resp.(*kmsg.ListPartitionReassignmentsResponse)
+	if err := responseError(listResp.ErrorCode, listResp.ErrorMessage); err != nil {
+		return nil, err
+	}
+	reassignments := make(map[int32]*PartitionReassignment)
+	for _, t := range listResp.Topics {
+		if t.Topic != topic {
+			continue
+		}
+		for _, p := range t.Partitions {
+			reassignments[p.Partition] = &PartitionReassignment{
+				Replicas:         p.Replicas,
+				AddingReplicas:   p.AddingReplicas,
+				RemovingReplicas: p.RemovingReplicas,
+			}
+		}
+	}
+	return reassignments, nil
+}
+
+func (a *franzGoAdmin) DeleteTopic(topic string) error {
+	reqTopic := kmsg.NewDeleteTopicsRequestTopic()
+	reqTopic.Topic = kmsg.StringPtr(topic)
+	req := kmsg.NewPtrDeleteTopicsRequest()
+	// topic names for older versions, topics for the newer ones
+	req.TopicNames = []string{topic}
+	req.Topics = append(req.Topics, reqTopic)
+	req.TimeoutMillis = int32(a.timeout.Milliseconds())
+	resp, err := a.request(req)
+	if err != nil {
+		return err
+	}
+	for _, t := range resp.(*kmsg.DeleteTopicsResponse).Topics {
+		if err := responseError(t.ErrorCode, t.ErrorMessage); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *franzGoAdmin) ElectPreferredLeaders(topic string, partitions []int32) error {
+	return electPreferredLeaders(a.client, topic, partitions, a.timeout)
+}
+
+func (a *franzGoAdmin) DescribeConsumerGroup(group string) (*ConsumerGroupDescription, error) {
+	req := kmsg.NewPtrDescribeGroupsRequest()
+	req.Groups = []string{group}
+	resp, err := a.request(req)
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range resp.(*kmsg.DescribeGroupsResponse).Groups {
+		if g.Group != group {
+			continue
+		}
+		if err := kerr.ErrorForCode(g.ErrorCode); err != nil {
+			return nil, err
+		}
+		description := &ConsumerGroupDescription{
+			GroupID:  g.Group,
+			State:    g.State,
+			Protocol: g.Protocol,
+			Members:  make([]ConsumerGroupMember, 0, len(g.Members)),
+		}
+		for _, m := range g.Members {
+			member := ConsumerGroupMember{MemberID: m.MemberID, ClientID: m.ClientID, Host: m.ClientHost}
+			if len(m.MemberAssignment) > 0 {
+				assignment := kmsg.NewConsumerMemberAssignment()
+				if err := assignment.ReadFrom(m.MemberAssignment); err != nil {
+					return nil, err
+				}
+				member.Assignment = make(map[string][]int32, len(assignment.Topics))
+				for _, t := range assignment.Topics {
+					member.Assignment[t.Topic] = t.Partitions
+				}
+			}
+			description.Members = append(description.Members, member)
+		}
+		sort.Slice(description.Members, func(i, j int) bool {
+			return description.Members[i].MemberID < description.Members[j].MemberID
+		})
+		return description, nil
+	}
+	return nil, fmt.Errorf("consumer group %s not described", group)
+}
+
+func (a *franzGoAdmin) ListTopics() ([]string, error) {
+	req := kmsg.NewPtrMetadataRequest()
+	// nil topics for getting all the topics
+	req.Topics = nil
+	resp, err := a.request(req)
+	if err != nil {
+		return nil, err
+	}
+	metadata := resp.(*kmsg.MetadataResponse)
+	names := make([]string, 0, len(metadata.Topics))
+	for _, t := range metadata.Topics {
+		if t.Topic != nil {
+			names = append(names, *t.Topic)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (a *franzGoAdmin) ListConsumerGroups() ([]string, error) {
+	// sent to all the brokers, the responses are merged by the client
+	resp, err := a.request(kmsg.NewPtrListGroupsRequest())
+	if err != nil {
+		return nil, err
+	}
+	listResp := resp.(*kmsg.ListGroupsResponse)
+	if err := kerr.ErrorForCode(listResp.ErrorCode); err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(listResp.Groups))
+	for _, g := range listResp.Groups {
+		ids = append(ids, g.Group)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func (a *franzGoAdmin) DeleteConsumerGroup(group string) error {
+	req := kmsg.NewPtrDeleteGroupsRequest()
+	req.Groups = []string{group}
+	resp, err := a.request(req)
+	if err != nil {
+		return err
+	}
+	for _, g := range resp.(*kmsg.DeleteGroupsResponse).Groups {
+		if err := kerr.ErrorForCode(g.ErrorCode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *franzGoAdmin) Close() error {
+	a.client.Close()
+	return nil
+}
+
+func (a *franzGoAdmin) request(req kmsg.Request) (kmsg.Response, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), a.timeout)
+	defer cancel()
+	return a.client.Request(ctx, req)
+}
+
+func describeTopic(client *kgo.Client, topic string, timeout time.Duration) (*TopicMetadata, error) {
+	reqTopic := kmsg.NewMetadataRequestTopic()
+	reqTopic.Topic = kmsg.StringPtr(topic)
+	req := kmsg.NewPtrMetadataRequest()
+	req.Topics = append(req.Topics, reqTopic)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	resp, err := req.RequestWith(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	topicMetadata := &TopicMetadata{Name: topic}
+	for _, t := range resp.Topics {
+		if t.Topic == nil || *t.Topic != topic {
+			continue
+		}
+		if err := kerr.ErrorForCode(t.ErrorCode); err == kerr.UnknownTopicOrPartition {
+			topicMetadata.Err = ErrUnknownTopicOrPartition
+		} else {
+			topicMetadata.Err = err
+		}
+		for _, p := range t.Partitions {
+			topicMetadata.Partitions = append(topicMetadata.Partitions, &PartitionMetadata{
+				ID:              p.Partition,
+				Leader:          p.Leader,
+				Replicas:        p.Replicas,
+				Isr:             p.ISR,
+				OfflineReplicas: p.OfflineReplicas,
+			})
+		}
+	}
+	return topicMetadata, nil
+}
+
+// electPreferredLeaders sends the ElectLeaders request, ignoring the partitions already led by the preferred replica
+func electPreferredLeaders(client *kgo.Client, topic string, partitions []int32, timeout time.Duration) error {
+	reqTopic := kmsg.NewElectLeadersRequestTopic()
+	reqTopic.Topic = topic
+	reqTopic.Partitions = partitions
+	req := kmsg.NewPtrElectLeadersRequest()
+	// preferred replica election
+	req.ElectionType = 0
+	req.Topics = append(req.Topics, reqTopic)
+	req.TimeoutMillis = int32(timeout.Milliseconds())
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	resp, err := req.RequestWith(ctx, client)
+	if err != nil {
+		return err
+	}
+	if err := kerr.ErrorForCode(resp.ErrorCode); err != nil {
+		return err
+	}
+	for _, t := range resp.Topics {
+		for _, p := range t.Partitions {
+			if p.ErrorCode == kerr.ElectionNotNeeded.Code {
+				continue
+			}
+			if err := responseError(p.ErrorCode, p.ErrorMessage); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func responseError(errorCode int16, errorMessage *string) error {
+	err := kerr.ErrorForCode(errorCode)
+	if err != nil && errorMessage != nil {
+		return fmt.Errorf("%v: %s", err, *errorMessage)
+	}
+	return err
+}
+
+// franzGoHeadersCarrier adapts the franz-go record headers to the OpenTelemetry TextMapCarrier
+// for propagating the tracing information
+type franzGoHeadersCarrier struct {
+	record *kgo.Record
+}
+
+func (c *franzGoHeadersCarrier) Get(key string) string {
+	for _, h := range c.record.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+func (c *franzGoHeadersCarrier) Set(key string, value string) {
+	for i, h := range c.record.Headers {
+		if h.Key == key {
+			c.record.Headers[i].Value = []byte(value)
+			return
+		}
+	}
+	c.record.Headers = append(c.record.Headers, kgo.RecordHeader{Key: key, Value: []byte(value)})
+}
+
+func (c *franzGoHeadersCarrier) Keys() []string {
+	keys := make([]string, 0, len(c.record.Headers))
+	for _, h := range c.record.Headers {
+		keys = append(keys, h.Key)
+	}
+	return keys
+}
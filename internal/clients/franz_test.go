@@ -0,0 +1,136 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// +build unit_test
+
+// Package clients defines an abstraction over the Kafka clients used by the canary and related implementations
+package clients
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// fakeFranzGoClient returns the fetch errors, if any, on each poll, otherwise it blocks until the context is done
+type fakeFranzGoClient struct {
+	fetchErr error
+}
+
+func (c *fakeFranzGoClient) AddConsumeTopics(topics ...string) {}
+
+func (c *fakeFranzGoClient) PollFetches(ctx context.Context) kgo.Fetches {
+	if c.fetchErr != nil {
+		return kgo.Fetches{{Topics: []kgo.FetchTopic{{Topic: "topic", Partitions: []kgo.FetchPartition{{Partition: 0, Err: c.fetchErr}}}}}}
+	}
+	<-ctx.Done()
+	return nil
+}
+
+func (c *fakeFranzGoClient) Close() {}
+
+// countingHandler counts the handler setups
+type countingHandler struct {
+	mutex  sync.Mutex
+	setups int
+}
+
+func (h *countingHandler) Setup() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.setups++
+}
+
+func (h *countingHandler) Handle(record *Record) {}
+
+func (h *countingHandler) Setups() int {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.setups
+}
+
+func newFakeFranzGoConsumerGroup(client franzGoConsumerClient) *franzGoConsumerGroup {
+	return &franzGoConsumerGroup{
+		client:       client,
+		errors:       make(chan error, 16),
+		owned:        make(map[string]map[int32]bool),
+		fetchBrokers: make(map[int32]int32),
+	}
+}
+
+// consume runs Consume until the context is cancelled, returning when it's done
+func consume(cg *franzGoConsumerGroup, handler ConsumerGroupHandler) (context.CancelFunc, <-chan error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- cg.Consume(ctx, []string{"topic"}, handler)
+	}()
+	return cancel, done
+}
+
+func TestFranzGoConsumeAgainSetsUp(t *testing.T) {
+	cg := newFakeFranzGoConsumerGroup(&fakeFranzGoClient{})
+
+	first := &countingHandler{}
+	cancel, done := consume(cg, first)
+	// no partitions owned yet, the handler is set up on the assignment
+	time.Sleep(10 * time.Millisecond)
+	if first.Setups() != 0 {
+		t.Fatalf("setups before the assignment: got = %d, want = 0", first.Setups())
+	}
+	cg.onPartitionsAssigned(context.Background(), nil, map[string][]int32{"topic": {0, 1}})
+	if first.Setups() != 1 {
+		t.Fatalf("setups after the assignment: got = %d, want = 1", first.Setups())
+	}
+	cancel()
+	<-done
+
+	// consuming again on the same client, without any rebalance, sets up the new handler right away
+	second := &countingHandler{}
+	cancel, done = consume(cg, second)
+	time.Sleep(10 * time.Millisecond)
+	if second.Setups() != 1 {
+		t.Fatalf("setups consuming again: got = %d, want = 1", second.Setups())
+	}
+	cancel()
+	<-done
+
+	// all the partitions revoked, the handler is set up on the next assignment only
+	cg.onPartitionsRevoked(context.Background(), nil, map[string][]int32{"topic": {0, 1}})
+	third := &countingHandler{}
+	cancel, done = consume(cg, third)
+	time.Sleep(10 * time.Millisecond)
+	if third.Setups() != 0 {
+		t.Fatalf("setups with no partitions: got = %d, want = 0", third.Setups())
+	}
+	cancel()
+	<-done
+}
+
+func TestFranzGoConsumerGroupClose(t *testing.T) {
+	cg := newFakeFranzGoConsumerGroup(&fakeFranzGoClient{fetchErr: errors.New("fetch error")})
+
+	_, done := consume(cg, &countingHandler{})
+	// draining the errors while the poll loop is sending them
+	go func() {
+		for range cg.Errors() {
+		}
+	}()
+	time.Sleep(10 * time.Millisecond)
+	// the poll loop is stopped before closing the errors channel, so no send on a closed channel
+	if err := cg.Close(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("unexpected consume error %v", err)
+	}
+	if err := cg.Consume(context.Background(), []string{"topic"}, &countingHandler{}); err == nil {
+		t.Errorf("expected error consuming after close")
+	}
+}
@@ -0,0 +1,132 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package clients defines an abstraction over the Kafka clients used by the canary and related implementations
+package clients
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/proxy"
+
+	"github.com/strimzi/strimzi-canary/internal/config"
+)
+
+const (
+	// dial timeout for the connection to the proxy when not configured, the same as the Sarama default
+	proxyDialTimeout = 30 * time.Second
+)
+
+// proxyDialer dials the brokers through a proxy, with and without context, as needed by Sarama and franz-go
+type proxyDialer interface {
+	proxy.Dialer
+	proxy.ContextDialer
+}
+
+// newProxyDialer returns the dialer connecting to the brokers through the configured SOCKS5 or HTTP proxy
+//
+// The proxy credentials can be provided within the URL or through the dedicated username and password,
+// which take precedence
+func newProxyDialer(canaryConfig *config.CanaryConfig) (proxyDialer, error) {
+	proxyURL, err := url.Parse(canaryConfig.ProxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %v", err)
+	}
+	if canaryConfig.ProxyUsername != "" {
+		proxyURL.User = url.UserPassword(canaryConfig.ProxyUsername, canaryConfig.ProxyPassword)
+	}
+	forward := &net.Dialer{Timeout: proxyDialTimeout}
+	if canaryConfig.DialTimeout > 0 {
+		forward.Timeout = canaryConfig.DialTimeout * time.Millisecond
+	}
+
+	switch proxyURL.Scheme {
+	case "socks5", "socks5h":
+		dialer, err := proxy.FromURL(proxyURL, forward)
+		if err != nil {
+			return nil, err
+		}
+		socksDialer, ok := dialer.(proxyDialer)
+		if !ok {
+			return nil, fmt.Errorf("the SOCKS5 proxy dialer doesn't support dialing with context")
+		}
+		return socksDialer, nil
+	case "http":
+		return &httpProxyDialer{proxyURL: proxyURL, forward: forward}, nil
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %s, it has to be socks5 or http", proxyURL.Scheme)
+	}
+}
+
+// NewDialer returns the dialer connecting to the brokers the same way the Kafka clients do, through the configured
+// proxy if any, for checking the brokers reachability at the network level
+func NewDialer(canaryConfig *config.CanaryConfig) (proxy.ContextDialer, error) {
+	if canaryConfig.ProxyURL != "" {
+		return newProxyDialer(canaryConfig)
+	}
+	dialer := &net.Dialer{Timeout: proxyDialTimeout}
+	if canaryConfig.DialTimeout > 0 {
+		dialer.Timeout = canaryConfig.DialTimeout * time.Millisecond
+	}
+	return dialer, nil
+}
+
+// httpProxyDialer connects to the brokers through an HTTP proxy, tunneling the connections with the CONNECT method
+type httpProxyDialer struct {
+	proxyURL *url.URL
+	forward  *net.Dialer
+}
+
+// Dial connects to the address through the HTTP proxy
+func (d *httpProxyDialer) Dial(network string, addr string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, addr)
+}
+
+// DialContext connects to the address through the HTTP proxy using the provided context
+func (d *httpProxyDialer) DialContext(ctx context.Context, network string, addr string) (net.Conn, error) {
+	conn, err := d.forward.DialContext(ctx, network, d.proxyURL.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if d.proxyURL.User != nil {
+		password, _ := d.proxyURL.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(d.proxyURL.User.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	// the broker doesn't send anything before the client, so nothing is buffered after the proxy response
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy %s refused the connection to %s: %s", d.proxyURL.Host, addr, resp.Status)
+	}
+	return conn, nil
+}
@@ -0,0 +1,582 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package clients defines an abstraction over the Kafka clients used by the canary and related implementations
+package clients
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/github.com/Shopify/sarama/otelsarama"
+	"go.opentelemetry.io/otel"
+
+	"github.com/Shopify/sarama"
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"github.com/strimzi/strimzi-canary/internal/config"
+	"github.com/strimzi/strimzi-canary/internal/security"
+)
+
+// consumer group default rebalance strategy
+const saramaAssignmentStrategy = sarama.RangeBalanceStrategyName
+
+// saramaFactory creates Kafka clients based on the Sarama library
+type saramaFactory struct {
+	saramaConfig *sarama.Config
+	// the producer and consumer configurations, with their own identities when configured
+	producerSaramaConfig *sarama.Config
+	consumerSaramaConfig *sarama.Config
+	// franz-go client options and timeout, for the admin operations not supported by Sarama
+	franzGoOpts         []kgo.Opt
+	franzGoAdminTimeout time.Duration
+}
+
+func newSaramaFactory(canaryConfig *config.CanaryConfig) (Factory, error) {
+	saramaConfig, err := newSaramaConfig(canaryConfig)
+	if err != nil {
+		return nil, err
+	}
+	producerSaramaConfig, err := newSaramaConfig(canaryConfig.ProducerIdentity())
+	if err != nil {
+		return nil, fmt.Errorf("producer: %v", err)
+	}
+	consumerSaramaConfig, err := newSaramaConfig(canaryConfig.ConsumerIdentity())
+	if err != nil {
+		return nil, fmt.Errorf("consumer: %v", err)
+	}
+	franzGoOpts, err := newFranzGoOpts(canaryConfig)
+	if err != nil {
+		return nil, err
+	}
+	return &saramaFactory{
+		saramaConfig:         saramaConfig,
+		producerSaramaConfig: producerSaramaConfig,
+		consumerSaramaConfig: consumerSaramaConfig,
+		franzGoOpts:          franzGoOpts,
+		franzGoAdminTimeout:  franzGoTimeout(canaryConfig.AdminTimeout),
+	}, nil
+}
+
+func newSaramaConfig(canaryConfig *config.CanaryConfig) (*sarama.Config, error) {
+	config := sarama.NewConfig()
+	kafkaVersion, err := sarama.ParseKafkaVersion(canaryConfig.KafkaVersion)
+	if err != nil {
+		return nil, err
+	}
+	config.Version = kafkaVersion
+	config.ClientID = canaryConfig.ClientID
+	// set manual partitioner in order to specify the destination partition on sending
+	config.Producer.Partitioner = sarama.NewManualPartitioner
+	config.Producer.Return.Successes = true
+	if config.Producer.RequiredAcks, err = saramaRequiredAcks(canaryConfig.ProducerAcks); err != nil {
+		return nil, err
+	}
+	config.Producer.Retry.Max = 0
+	config.Consumer.Return.Errors = true
+	// the consumer fetches from the closest replica (KIP-392) when the rack is set
+	config.RackID = canaryConfig.ConsumerRackID
+
+	// overriding the library defaults only when configured
+	if canaryConfig.ProducerRequestTimeout > 0 {
+		config.Producer.Timeout = canaryConfig.ProducerRequestTimeout * time.Millisecond
+	}
+	if canaryConfig.AdminTimeout > 0 {
+		config.Admin.Timeout = canaryConfig.AdminTimeout * time.Millisecond
+	}
+	if canaryConfig.DialTimeout > 0 {
+		config.Net.DialTimeout = canaryConfig.DialTimeout * time.Millisecond
+	}
+	if canaryConfig.MetadataRefreshTimeout > 0 {
+		config.Metadata.Timeout = canaryConfig.MetadataRefreshTimeout * time.Millisecond
+	}
+	setSaramaProducerBatching(config, canaryConfig)
+	setSaramaConsumerFetch(config, canaryConfig)
+
+	if canaryConfig.ProxyURL != "" {
+		config.Net.Proxy.Enable = true
+		if config.Net.Proxy.Dialer, err = newProxyDialer(canaryConfig); err != nil {
+			return nil, fmt.Errorf("error configuring proxy: %v", err)
+		}
+	}
+
+	if canaryConfig.TLSEnabled {
+		config.Net.TLS.Enable = true
+		if config.Net.TLS.Config, err = security.NewTLSConfig(canaryConfig); err != nil {
+			return nil, fmt.Errorf("error configuring TLS: %v", err)
+		}
+	}
+
+	if canaryConfig.SASLMechanism != "" {
+		if err = security.SetAuthConfig(canaryConfig, config); err != nil {
+			return nil, fmt.Errorf("error configuring SASL authentication: %v", err)
+		}
+	}
+
+	return config, nil
+}
+
+// setSaramaProducerBatching overrides the Sarama producer batching defaults, only when configured
+func setSaramaProducerBatching(config *sarama.Config, canaryConfig *config.CanaryConfig) {
+	if canaryConfig.ProducerLinger > 0 {
+		config.Producer.Flush.Frequency = canaryConfig.ProducerLinger * time.Millisecond
+	}
+	if canaryConfig.ProducerBatchMessages > 0 {
+		config.Producer.Flush.Messages = canaryConfig.ProducerBatchMessages
+	}
+	if canaryConfig.ProducerMaxMessageBytes > 0 {
+		config.Producer.MaxMessageBytes = canaryConfig.ProducerMaxMessageBytes
+	}
+}
+
+// saramaProducerBatching returns the effective Sarama producer batching configuration
+func saramaProducerBatching(canaryConfig *config.CanaryConfig) ProducerBatching {
+	config := sarama.NewConfig()
+	setSaramaProducerBatching(config, canaryConfig)
+	return ProducerBatching{
+		Linger:          config.Producer.Flush.Frequency,
+		Messages:        config.Producer.Flush.Messages,
+		MaxMessageBytes: config.Producer.MaxMessageBytes,
+	}
+}
+
+// setSaramaConsumerFetch overrides the Sarama consumer fetch defaults, only when configured
+func setSaramaConsumerFetch(config *sarama.Config, canaryConfig *config.CanaryConfig) {
+	if canaryConfig.ConsumerFetchMinBytes > 0 {
+		config.Consumer.Fetch.Min = int32(canaryConfig.ConsumerFetchMinBytes)
+	}
+	if canaryConfig.ConsumerFetchDefaultBytes > 0 {
+		config.Consumer.Fetch.Default = int32(canaryConfig.ConsumerFetchDefaultBytes)
+	}
+	if canaryConfig.ConsumerFetchMaxBytes > 0 {
+		config.Consumer.Fetch.Max = int32(canaryConfig.ConsumerFetchMaxBytes)
+	}
+	if canaryConfig.ConsumerFetchMaxWait > 0 {
+		config.Consumer.MaxWaitTime = canaryConfig.ConsumerFetchMaxWait * time.Millisecond
+	}
+}
+
+// saramaConsumerFetch returns the effective Sarama consumer fetch configuration
+func saramaConsumerFetch(canaryConfig *config.CanaryConfig) ConsumerFetch {
+	config := sarama.NewConfig()
+	setSaramaConsumerFetch(config, canaryConfig)
+	return ConsumerFetch{
+		MinBytes:     int(config.Consumer.Fetch.Min),
+		DefaultBytes: int(config.Consumer.Fetch.Default),
+		MaxBytes:     int(config.Consumer.Fetch.Max),
+		MaxWait:      config.Consumer.MaxWaitTime,
+	}
+}
+
+func (f *saramaFactory) NewProducer(bootstrapServers []string) (Producer, error) {
+	return f.newProducer(bootstrapServers, f.producerSaramaConfig)
+}
+
+func (f *saramaFactory) NewProducerWithAcks(bootstrapServers []string, acks string) (Producer, error) {
+	requiredAcks, err := saramaRequiredAcks(acks)
+	if err != nil {
+		return nil, err
+	}
+	config := *f.producerSaramaConfig
+	config.Producer.RequiredAcks = requiredAcks
+	return f.newProducer(bootstrapServers, &config)
+}
+
+func (f *saramaFactory) newProducer(bootstrapServers []string, config *sarama.Config) (Producer, error) {
+	client, err := sarama.NewClient(bootstrapServers, config)
+	if err != nil {
+		return nil, err
+	}
+	producer, err := sarama.NewSyncProducerFromClient(client)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	producer = otelsarama.WrapSyncProducer(client.Config(), producer)
+	return &saramaProducer{client: client, producer: producer, noAcks: config.Producer.RequiredAcks == sarama.NoResponse}, nil
+}
+
+// saramaRequiredAcks returns the Sarama required acks for the producer acks
+func saramaRequiredAcks(acks string) (sarama.RequiredAcks, error) {
+	switch acks {
+	case AcksNone:
+		return sarama.NoResponse, nil
+	case AcksLeader:
+		return sarama.WaitForLocal, nil
+	// waiting for all the in-sync replicas when not configured
+	case AcksAll, "-1", "":
+		return sarama.WaitForAll, nil
+	}
+	return 0, fmt.Errorf("producer acks %s is not supported, it has to be 0, 1 or all", acks)
+}
+
+func (f *saramaFactory) NewConsumerGroup(bootstrapServers []string, groupID string) (ConsumerGroup, error) {
+	client, err := sarama.NewClient(bootstrapServers, f.consumerSaramaConfig)
+	if err != nil {
+		return nil, err
+	}
+	consumerGroup, err := sarama.NewConsumerGroupFromClient(groupID, client)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	return &saramaConsumerGroup{client: client, consumerGroup: consumerGroup}, nil
+}
+
+func (f *saramaFactory) NewFetcher(bootstrapServers []string) (Fetcher, error) {
+	client, err := sarama.NewClient(bootstrapServers, f.consumerSaramaConfig)
+	if err != nil {
+		return nil, err
+	}
+	consumer, err := sarama.NewConsumerFromClient(client)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	return &saramaFetcher{client: client, consumer: consumer}, nil
+}
+
+func (f *saramaFactory) NewAdmin(bootstrapServers []string) (Admin, error) {
+	admin, err := sarama.NewClusterAdmin(bootstrapServers, f.saramaConfig)
+	if err != nil {
+		return nil, err
+	}
+	return &saramaAdmin{admin: admin, bootstrapServers: bootstrapServers, franzGoOpts: f.franzGoOpts, franzGoTimeout: f.franzGoAdminTimeout}, nil
+}
+
+func (f *saramaFactory) CheckConnection(broker Broker) error {
+	b := sarama.NewBroker(broker.Addr)
+	// ignore error because it will be reported by Connected() call if "not connected"
+	b.Open(f.saramaConfig)
+	connected, err := b.Connected()
+	if !connected {
+		if err == nil {
+			err = errors.New("not connected")
+		}
+		return err
+	}
+	b.Close()
+	return nil
+}
+
+// saramaProducer is the Producer implementation based on the Sarama sync producer
+type saramaProducer struct {
+	client   sarama.Client
+	producer sarama.SyncProducer
+	// if the producer doesn't wait for acks
+	noAcks bool
+}
+
+func (p *saramaProducer) Send(topic string, partition int32, value []byte) (int64, time.Time, error) {
+	msg := &sarama.ProducerMessage{
+		Topic:     topic,
+		Value:     sarama.ByteEncoder(value),
+		Partition: partition,
+	}
+	otel.GetTextMapPropagator().Inject(context.Background(), otelsarama.NewProducerMessageCarrier(msg))
+	_, offset, err := p.producer.SendMessage(msg)
+	if err == nil && p.noAcks {
+		// without acks the offset is not returned by the broker
+		offset = -1
+	}
+	// the message timestamp is set by the producer only when the broker returns the log append time
+	return offset, msg.Timestamp, err
+}
+
+func (p *saramaProducer) Partitions(topic string) ([]int32, error) {
+	return p.client.Partitions(topic)
+}
+
+func (p *saramaProducer) RefreshMetadata(topic string) error {
+	return p.client.RefreshMetadata(topic)
+}
+
+func (p *saramaProducer) Close() error {
+	if err := p.producer.Close(); err != nil {
+		return err
+	}
+	return p.client.Close()
+}
+
+// saramaConsumerGroup is the ConsumerGroup implementation based on the Sarama consumer group
+type saramaConsumerGroup struct {
+	client        sarama.Client
+	consumerGroup sarama.ConsumerGroup
+}
+
+func (cg *saramaConsumerGroup) Consume(ctx context.Context, topics []string, handler ConsumerGroupHandler) error {
+	h := otelsarama.WrapConsumerGroupHandler(&saramaConsumerGroupHandler{handler: handler})
+	return cg.consumerGroup.Consume(ctx, topics, h)
+}
+
+func (cg *saramaConsumerGroup) Errors() <-chan error {
+	return cg.consumerGroup.Errors()
+}
+
+func (cg *saramaConsumerGroup) Close() error {
+	if err := cg.consumerGroup.Close(); err != nil {
+		return err
+	}
+	return cg.client.Close()
+}
+
+// saramaFetcher is the Fetcher implementation based on the Sarama partition consumer
+type saramaFetcher struct {
+	client   sarama.Client
+	consumer sarama.Consumer
+}
+
+func (f *saramaFetcher) Fetch(ctx context.Context, topic string, partition int32, offset int64) (*Record, error) {
+	partitionConsumer, err := f.consumer.ConsumePartition(topic, partition, offset)
+	if err != nil {
+		if errors.Is(err, sarama.ErrOffsetOutOfRange) {
+			return nil, ErrRecordNotAvailable
+		}
+		return nil, err
+	}
+	defer partitionConsumer.AsyncClose()
+
+	select {
+	case message := <-partitionConsumer.Messages():
+		// the record was compacted, the first one after it is returned
+		if message.Offset != offset {
+			return nil, ErrRecordNotAvailable
+		}
+		return &Record{
+			Topic:     message.Topic,
+			Partition: message.Partition,
+			Offset:    message.Offset,
+			Value:     message.Value,
+			Timestamp: message.Timestamp,
+			Context:   context.Background(),
+			// not exposed by Sarama
+			FetchBrokerID: NoBrokerID,
+		}, nil
+	case err := <-partitionConsumer.Errors():
+		if errors.Is(err, sarama.ErrOffsetOutOfRange) {
+			return nil, ErrRecordNotAvailable
+		}
+		return nil, err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (f *saramaFetcher) OffsetForTimestamp(ctx context.Context, topic string, partition int32, timestamp int64) (int64, error) {
+	// the Sarama client doesn't support a context, the request is bound to the Net timeouts
+	return f.client.GetOffset(topic, partition, timestamp)
+}
+
+func (f *saramaFetcher) Close() error {
+	if err := f.consumer.Close(); err != nil {
+		return err
+	}
+	return f.client.Close()
+}
+
+// saramaConsumerGroupHandler adapts a ConsumerGroupHandler to the Sarama consumer group handler
+type saramaConsumerGroupHandler struct {
+	handler ConsumerGroupHandler
+}
+
+func (h *saramaConsumerGroupHandler) Setup(sarama.ConsumerGroupSession) error {
+	h.handler.Setup()
+	return nil
+}
+
+func (h *saramaConsumerGroupHandler) Cleanup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+func (h *saramaConsumerGroupHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	for message := range claim.Messages() {
+		ctx := otel.GetTextMapPropagator().Extract(context.Background(), otelsarama.NewConsumerMessageCarrier(message))
+		h.handler.Handle(&Record{
+			Topic:     message.Topic,
+			Partition: message.Partition,
+			Offset:    message.Offset,
+			Value:     message.Value,
+			Timestamp: message.Timestamp,
+			Context:   ctx,
+			// not exposed by Sarama
+			FetchBrokerID: NoBrokerID,
+		})
+		session.MarkMessage(message, "")
+	}
+	return nil
+}
+
+// saramaAdmin is the Admin implementation based on the Sarama cluster admin
+type saramaAdmin struct {
+	admin            sarama.ClusterAdmin
+	bootstrapServers []string
+	franzGoOpts      []kgo.Opt
+	franzGoTimeout   time.Duration
+}
+
+func (a *saramaAdmin) DescribeCluster() ([]Broker, error) {
+	saramaBrokers, _, err := a.admin.DescribeCluster()
+	if err != nil {
+		return nil, err
+	}
+	brokers := make([]Broker, 0, len(saramaBrokers))
+	for _, b := range saramaBrokers {
+		brokers = append(brokers, Broker{ID: b.ID(), Addr: b.Addr(), Rack: b.Rack()})
+	}
+	return brokers, nil
+}
+
+func (a *saramaAdmin) DescribeTopic(topic string) (*TopicMetadata, error) {
+	metadata, err := a.admin.DescribeTopics([]string{topic})
+	if err != nil {
+		return nil, err
+	}
+	tm := metadata[0]
+	topicMetadata := &TopicMetadata{
+		Name:       tm.Name,
+		Partitions: make([]*PartitionMetadata, 0, len(tm.Partitions)),
+	}
+	switch tm.Err {
+	case sarama.ErrNoError:
+	case sarama.ErrUnknownTopicOrPartition:
+		topicMetadata.Err = ErrUnknownTopicOrPartition
+	default:
+		topicMetadata.Err = tm.Err
+	}
+	for _, p := range tm.Partitions {
+		topicMetadata.Partitions = append(topicMetadata.Partitions, &PartitionMetadata{
+			ID:              p.ID,
+			Leader:          p.Leader,
+			Replicas:        p.Replicas,
+			Isr:             p.Isr,
+			OfflineReplicas: p.OfflineReplicas,
+		})
+	}
+	return topicMetadata, nil
+}
+
+func (a *saramaAdmin) CreateTopic(topic string, assignments map[int32][]int32, config map[string]*string) error {
+	topicDetail := sarama.TopicDetail{
+		NumPartitions:     -1,
+		ReplicationFactor: -1,
+		ReplicaAssignment: assignments,
+		ConfigEntries:     config,
+	}
+	return a.admin.CreateTopic(topic, &topicDetail, false)
+}
+
+func (a *saramaAdmin) AlterTopicConfig(topic string, config map[string]*string) error {
+	return a.admin.AlterConfig(sarama.TopicResource, topic, config, false)
+}
+
+func (a *saramaAdmin) CreatePartitions(topic string, count int32, assignments [][]int32) error {
+	return a.admin.CreatePartitions(topic, count, assignments, false)
+}
+
+func (a *saramaAdmin) AlterPartitionReassignments(topic string, assignments [][]int32) error {
+	return a.admin.AlterPartitionReassignments(topic, assignments)
+}
+
+func (a *saramaAdmin) ListPartitionReassignments(topic string, partitions []int32) (map[int32]*PartitionReassignment, error) {
+	saramaReassignments, err := a.admin.ListPartitionReassignments(topic, partitions)
+	if err != nil {
+		return nil, err
+	}
+	reassignments := make(map[int32]*PartitionReassignment)
+	for partition, status := range saramaReassignments[topic] {
+		reassignments[partition] = &PartitionReassignment{
+			Replicas:         status.Replicas,
+			AddingReplicas:   status.AddingReplicas,
+			RemovingReplicas: status.RemovingReplicas,
+		}
+	}
+	return reassignments, nil
+}
+
+func (a *saramaAdmin) DeleteTopic(topic string) error {
+	return a.admin.DeleteTopic(topic)
+}
+
+// ElectPreferredLeaders uses a short-lived franz-go client, because the leader election is not supported by Sarama
+func (a *saramaAdmin) ElectPreferredLeaders(topic string, partitions []int32) error {
+	client, err := kgo.NewClient(append([]kgo.Opt{kgo.SeedBrokers(a.bootstrapServers...)}, a.franzGoOpts...)...)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	return electPreferredLeaders(client, topic, partitions, a.franzGoTimeout)
+}
+
+func (a *saramaAdmin) DescribeConsumerGroup(group string) (*ConsumerGroupDescription, error) {
+	groups, err := a.admin.DescribeConsumerGroups([]string{group})
+	if err != nil {
+		return nil, err
+	}
+	if len(groups) == 0 {
+		return nil, fmt.Errorf("consumer group %s not described", group)
+	}
+	g := groups[0]
+	if g.Err != sarama.ErrNoError {
+		return nil, g.Err
+	}
+	description := &ConsumerGroupDescription{
+		GroupID:  g.GroupId,
+		State:    g.State,
+		Protocol: g.Protocol,
+		Members:  make([]ConsumerGroupMember, 0, len(g.Members)),
+	}
+	for memberID, m := range g.Members {
+		member := ConsumerGroupMember{MemberID: memberID, ClientID: m.ClientId, Host: m.ClientHost}
+		assignment, err := m.GetMemberAssignment()
+		if err != nil {
+			return nil, err
+		}
+		if assignment != nil {
+			member.Assignment = assignment.Topics
+		}
+		description.Members = append(description.Members, member)
+	}
+	sort.Slice(description.Members, func(i, j int) bool {
+		return description.Members[i].MemberID < description.Members[j].MemberID
+	})
+	return description, nil
+}
+
+func (a *saramaAdmin) ListTopics() ([]string, error) {
+	topics, err := a.admin.ListTopics()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(topics))
+	for name := range topics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (a *saramaAdmin) ListConsumerGroups() ([]string, error) {
+	groups, err := a.admin.ListConsumerGroups()
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(groups))
+	for id := range groups {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func (a *saramaAdmin) DeleteConsumerGroup(group string) error {
+	return a.admin.DeleteConsumerGroup(group)
+}
+
+func (a *saramaAdmin) Close() error {
+	return a.admin.Close()
+}
@@ -0,0 +1,96 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package clients defines an abstraction over the Kafka clients used by the canary and related implementations
+package clients
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/golang/glog"
+)
+
+const (
+	saramaLogComponent = "[Sarama] "
+	// call depth for reporting the Sarama source file and line, instead of the logger ones
+	saramaLogDepth = 2
+
+	SaramaLogLevelInfo    = "info"
+	SaramaLogLevelWarning = "warning"
+)
+
+// words identifying a Sarama log line as reporting an error, Sarama doesn't provide log levels
+var saramaErrorWords = []string{"error", "failed", "failure", "unable", "cannot", "closed by", "timeout", "timed out"}
+
+// SaramaLogger bridges the Sarama library logging into the canary one, with the Sarama component prefix.
+// Sarama doesn't provide levels, so the lines reporting errors (i.e. broker connection errors) are logged
+// as warnings and the other ones as info, the latter being filtered out with the warning level
+type SaramaLogger struct {
+	enabled int32
+	// logging info lines as well
+	info bool
+}
+
+// NewSaramaLogger returns an instance of SaramaLogger, initially disabled, with the provided level
+func NewSaramaLogger(level string) *SaramaLogger {
+	switch level {
+	case SaramaLogLevelInfo, SaramaLogLevelWarning:
+	default:
+		glog.Warningf("Invalid Sarama log level %q, using %s", level, SaramaLogLevelInfo)
+		level = SaramaLogLevelInfo
+	}
+	return &SaramaLogger{
+		info: level == SaramaLogLevelInfo,
+	}
+}
+
+// SetEnabled enables or disables the Sarama logging
+func (sl *SaramaLogger) SetEnabled(enabled bool) {
+	var value int32
+	if enabled {
+		value = 1
+	}
+	atomic.StoreInt32(&sl.enabled, value)
+}
+
+// Print logs the arguments in the manner of fmt.Print
+func (sl *SaramaLogger) Print(v ...interface{}) {
+	sl.log(fmt.Sprint(v...))
+}
+
+// Printf logs the arguments in the manner of fmt.Printf
+func (sl *SaramaLogger) Printf(format string, v ...interface{}) {
+	sl.log(fmt.Sprintf(format, v...))
+}
+
+// Println logs the arguments in the manner of fmt.Println
+func (sl *SaramaLogger) Println(v ...interface{}) {
+	sl.log(fmt.Sprintln(v...))
+}
+
+func (sl *SaramaLogger) log(line string) {
+	if atomic.LoadInt32(&sl.enabled) == 0 {
+		return
+	}
+	line = strings.TrimSuffix(line, "\n")
+	if isSaramaErrorLine(line) {
+		glog.WarningDepth(saramaLogDepth, saramaLogComponent, line)
+	} else if sl.info {
+		glog.InfoDepth(saramaLogDepth, saramaLogComponent, line)
+	}
+}
+
+// isSaramaErrorLine returns if the Sarama log line reports an error
+func isSaramaErrorLine(line string) bool {
+	lower := strings.ToLower(line)
+	for _, word := range saramaErrorWords {
+		if strings.Contains(lower, word) {
+			return true
+		}
+	}
+	return false
+}
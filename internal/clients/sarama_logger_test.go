@@ -0,0 +1,52 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// +build unit_test
+
+// Package clients defines an abstraction over the Kafka clients used by the canary and related implementations
+package clients
+
+import (
+	"testing"
+)
+
+func TestIsSaramaErrorLine(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want bool
+	}{
+		{"connected", "Connected to broker at my-cluster-kafka-0:9092 (registered as #0)", false},
+		{"metadata", "client/metadata fetching metadata for all topics from broker my-cluster-kafka-0:9092", false},
+		{"connection error", "Failed to connect to broker my-cluster-kafka-0:9092: dial tcp: connection refused", true},
+		{"closed", "Error while sending ApiVersionsRequest to broker my-cluster-kafka-0:9092: EOF", true},
+		{"timeout", "client/metadata got error from broker 0 while fetching metadata: i/o timeout", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isSaramaErrorLine(tt.line); got != tt.want {
+				t.Errorf("got = %t, want = %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSaramaLoggerLevel(t *testing.T) {
+	tests := []struct {
+		level string
+		info  bool
+	}{
+		{SaramaLogLevelInfo, true},
+		{SaramaLogLevelWarning, false},
+		{"debug", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.level, func(t *testing.T) {
+			if sl := NewSaramaLogger(tt.level); sl.info != tt.info {
+				t.Errorf("got = %t, want = %t", sl.info, tt.info)
+			}
+		})
+	}
+}
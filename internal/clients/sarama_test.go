@@ -0,0 +1,136 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// +build unit_test
+
+// Package clients defines an abstraction over the Kafka clients used by the canary and related implementations
+package clients
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+
+	"github.com/strimzi/strimzi-canary/internal/config"
+)
+
+func TestSaramaConfigTimeouts(t *testing.T) {
+	canaryConfig := &config.CanaryConfig{
+		KafkaVersion:           "3.1.0",
+		ProducerRequestTimeout: 5000,
+		AdminTimeout:           2000,
+		DialTimeout:            3000,
+		MetadataRefreshTimeout: 10000,
+	}
+	saramaConfig, err := newSaramaConfig(canaryConfig)
+	if err != nil {
+		t.Fatalf("unexpected error creating the Sarama config: %v", err)
+	}
+	if saramaConfig.Producer.Timeout != 5*time.Second {
+		t.Errorf("Producer.Timeout: got = %v, want = %v", saramaConfig.Producer.Timeout, 5*time.Second)
+	}
+	if saramaConfig.Admin.Timeout != 2*time.Second {
+		t.Errorf("Admin.Timeout: got = %v, want = %v", saramaConfig.Admin.Timeout, 2*time.Second)
+	}
+	if saramaConfig.Net.DialTimeout != 3*time.Second {
+		t.Errorf("Net.DialTimeout: got = %v, want = %v", saramaConfig.Net.DialTimeout, 3*time.Second)
+	}
+	if saramaConfig.Metadata.Timeout != 10*time.Second {
+		t.Errorf("Metadata.Timeout: got = %v, want = %v", saramaConfig.Metadata.Timeout, 10*time.Second)
+	}
+}
+
+func TestEffectiveProducerBatching(t *testing.T) {
+	tests := []struct {
+		name         string
+		canaryConfig *config.CanaryConfig
+		expected     ProducerBatching
+	}{
+		{"sarama defaults", &config.CanaryConfig{KafkaClientBackend: SaramaBackend}, ProducerBatching{MaxMessageBytes: sarama.NewConfig().Producer.MaxMessageBytes}},
+		{"sarama configured", &config.CanaryConfig{KafkaClientBackend: SaramaBackend, ProducerLinger: 5, ProducerBatchMessages: 10, ProducerMaxMessageBytes: 2000000},
+			ProducerBatching{Linger: 5 * time.Millisecond, Messages: 10, MaxMessageBytes: 2000000}},
+		{"franz-go defaults", &config.CanaryConfig{KafkaClientBackend: FranzGoBackend}, ProducerBatching{MaxMessageBytes: franzGoBatchMaxBytes}},
+		{"franz-go configured", &config.CanaryConfig{KafkaClientBackend: FranzGoBackend, ProducerLinger: 5, ProducerBatchMessages: 10, ProducerMaxMessageBytes: 2000000},
+			ProducerBatching{Linger: 5 * time.Millisecond, MaxMessageBytes: 2000000}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if batching := EffectiveProducerBatching(tt.canaryConfig); batching != tt.expected {
+				t.Errorf("got = %+v, want = %+v", batching, tt.expected)
+			}
+		})
+	}
+}
+
+func TestEffectiveConsumerFetch(t *testing.T) {
+	defaults := sarama.NewConfig()
+	tests := []struct {
+		name         string
+		canaryConfig *config.CanaryConfig
+		expected     ConsumerFetch
+	}{
+		{"sarama defaults", &config.CanaryConfig{KafkaClientBackend: SaramaBackend},
+			ConsumerFetch{MinBytes: int(defaults.Consumer.Fetch.Min), DefaultBytes: int(defaults.Consumer.Fetch.Default), MaxWait: defaults.Consumer.MaxWaitTime}},
+		{"sarama configured", &config.CanaryConfig{KafkaClientBackend: SaramaBackend, ConsumerFetchMinBytes: 1024, ConsumerFetchDefaultBytes: 524288, ConsumerFetchMaxBytes: 10485760, ConsumerFetchMaxWait: 100},
+			ConsumerFetch{MinBytes: 1024, DefaultBytes: 524288, MaxBytes: 10485760, MaxWait: 100 * time.Millisecond}},
+		{"franz-go defaults", &config.CanaryConfig{KafkaClientBackend: FranzGoBackend},
+			ConsumerFetch{MinBytes: franzGoFetchMinBytes, DefaultBytes: franzGoFetchMaxPartitionBytes, MaxBytes: franzGoFetchMaxBytes, MaxWait: franzGoFetchMaxWait}},
+		{"franz-go configured", &config.CanaryConfig{KafkaClientBackend: FranzGoBackend, ConsumerFetchMinBytes: 1024, ConsumerFetchDefaultBytes: 524288, ConsumerFetchMaxBytes: 10485760, ConsumerFetchMaxWait: 100},
+			ConsumerFetch{MinBytes: 1024, DefaultBytes: 524288, MaxBytes: 10485760, MaxWait: 100 * time.Millisecond}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if fetch := EffectiveConsumerFetch(tt.canaryConfig); fetch != tt.expected {
+				t.Errorf("got = %+v, want = %+v", fetch, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSaramaConfigDefaultTimeouts(t *testing.T) {
+	saramaConfig, err := newSaramaConfig(&config.CanaryConfig{KafkaVersion: "3.1.0"})
+	if err != nil {
+		t.Fatalf("unexpected error creating the Sarama config: %v", err)
+	}
+	defaults := sarama.NewConfig()
+	if saramaConfig.Producer.Timeout != defaults.Producer.Timeout {
+		t.Errorf("Producer.Timeout: got = %v, want = %v", saramaConfig.Producer.Timeout, defaults.Producer.Timeout)
+	}
+	if saramaConfig.Admin.Timeout != defaults.Admin.Timeout {
+		t.Errorf("Admin.Timeout: got = %v, want = %v", saramaConfig.Admin.Timeout, defaults.Admin.Timeout)
+	}
+	if saramaConfig.Net.DialTimeout != defaults.Net.DialTimeout {
+		t.Errorf("Net.DialTimeout: got = %v, want = %v", saramaConfig.Net.DialTimeout, defaults.Net.DialTimeout)
+	}
+	if saramaConfig.Metadata.Timeout != defaults.Metadata.Timeout {
+		t.Errorf("Metadata.Timeout: got = %v, want = %v", saramaConfig.Metadata.Timeout, defaults.Metadata.Timeout)
+	}
+}
+
+func TestSaramaRequiredAcks(t *testing.T) {
+	tests := []struct {
+		acks     string
+		expected sarama.RequiredAcks
+	}{
+		{AcksNone, sarama.NoResponse},
+		{AcksLeader, sarama.WaitForLocal},
+		{AcksAll, sarama.WaitForAll},
+		{"-1", sarama.WaitForAll},
+		{"", sarama.WaitForAll},
+	}
+	for _, tt := range tests {
+		requiredAcks, err := saramaRequiredAcks(tt.acks)
+		if err != nil {
+			t.Errorf("acks %q: unexpected error %v", tt.acks, err)
+		}
+		if requiredAcks != tt.expected {
+			t.Errorf("acks %q: got = %v, want = %v", tt.acks, requiredAcks, tt.expected)
+		}
+	}
+	if _, err := saramaRequiredAcks("2"); err == nil {
+		t.Errorf("acks \"2\": expected error")
+	}
+}
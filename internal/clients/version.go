@@ -0,0 +1,108 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package clients defines an abstraction over the Kafka clients used by the canary and related implementations
+package clients
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Shopify/sarama"
+	"github.com/golang/glog"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/kmsg"
+	"github.com/twmb/franz-go/pkg/kversion"
+
+	"github.com/strimzi/strimzi-canary/internal/config"
+)
+
+var (
+	kafkaVersionInfo = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name:      "kafka_version_info",
+		Namespace: "strimzi_canary",
+		Help:      "Kafka protocol version negotiated with the Kafka cluster, the value is always 1",
+	}, []string{"cluster_version", "negotiated_version"})
+
+	// first version in a franz-go version guess (i.e. "v3.1", "at least v3.2", "between v2.8 and v3.0")
+	versionGuessRegexp = regexp.MustCompile(`v(\d+)\.(\d+)(\.\d+)?`)
+)
+
+// NegotiateKafkaVersion returns the highest Kafka version supported by both the Kafka cluster and the configured client backend
+//
+// It sends an ApiVersions request to one of the bootstrap servers and guesses the cluster version from the supported API keys versions.
+// A warning is logged when the cluster is newer than the client backend supports.
+func NegotiateKafkaVersion(canaryConfig *config.CanaryConfig) (string, error) {
+	opts, err := newFranzGoOpts(canaryConfig)
+	if err != nil {
+		return "", err
+	}
+	client, err := kgo.NewClient(append(opts, kgo.SeedBrokers(canaryConfig.BootstrapServers...))...)
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), franzGoRequestTimeout)
+	defer cancel()
+	resp, err := kmsg.NewPtrApiVersionsRequest().RequestWith(ctx, client)
+	if err != nil {
+		return "", err
+	}
+	if err := responseError(resp.ErrorCode, nil); err != nil {
+		return "", err
+	}
+
+	clusterVersion, err := parseVersionGuess(kversion.FromApiVersionsResponse(resp).VersionGuess())
+	if err != nil {
+		return "", err
+	}
+	clientVersion, err := maxKafkaVersion(canaryConfig.KafkaClientBackend)
+	if err != nil {
+		return "", err
+	}
+
+	negotiatedVersion := clusterVersion
+	if !clientVersion.IsAtLeast(clusterVersion) {
+		glog.Warningf("Kafka cluster version %s is newer than the %s client backend supports, using %s",
+			clusterVersion, canaryConfig.KafkaClientBackend, clientVersion)
+		negotiatedVersion = clientVersion
+	}
+	glog.Infof("Negotiated Kafka version %s with Kafka cluster version %s", negotiatedVersion, clusterVersion)
+
+	labels := prometheus.Labels{
+		"cluster_version":    clusterVersion.String(),
+		"negotiated_version": negotiatedVersion.String(),
+	}
+	kafkaVersionInfo.With(labels).Set(1)
+	return negotiatedVersion.String(), nil
+}
+
+// maxKafkaVersion returns the highest Kafka version known by the client backend
+func maxKafkaVersion(backend string) (sarama.KafkaVersion, error) {
+	if backend == FranzGoBackend {
+		return parseVersionGuess(kversion.Tip().VersionGuess())
+	}
+	return sarama.MaxVersion, nil
+}
+
+// parseVersionGuess returns the Kafka version from a franz-go version guess, taking the lower bound when it's a range
+func parseVersionGuess(guess string) (sarama.KafkaVersion, error) {
+	match := versionGuessRegexp.FindStringSubmatch(guess)
+	if match == nil || strings.HasPrefix(guess, "not even") {
+		return sarama.KafkaVersion{}, fmt.Errorf("unknown Kafka cluster version: %s", guess)
+	}
+	version := match[1] + "." + match[2]
+	if match[3] != "" {
+		version += match[3]
+	}
+	// patch version is not part of the guess
+	version += ".0"
+	return sarama.ParseKafkaVersion(version)
+}
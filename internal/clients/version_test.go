@@ -0,0 +1,43 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// +build unit_test
+
+// Package clients defines an abstraction over the Kafka clients used by the canary and related implementations
+package clients
+
+import (
+	"testing"
+)
+
+func TestParseVersionGuess(t *testing.T) {
+	var tests = []struct {
+		guess    string
+		expected string
+	}{
+		{"v3.1", "3.1.0"},
+		{"v0.10.2", "0.10.2.0"},
+		{"at least v3.2", "3.2.0"},
+		{"between v2.8 and v3.0", "2.8.0"},
+		{"unknown custom version at least v2.7", "2.7.0"},
+	}
+
+	for _, tt := range tests {
+		version, err := parseVersionGuess(tt.guess)
+		if err != nil {
+			t.Errorf("unexpected error parsing %s: %v", tt.guess, err)
+		} else if version.String() != tt.expected {
+			t.Errorf("got = %s, want = %s", version, tt.expected)
+		}
+	}
+}
+
+func TestParseVersionGuessUnknown(t *testing.T) {
+	for _, guess := range []string{"not even v0.8.0", "unknown custom version"} {
+		if _, err := parseVersionGuess(guess); err == nil {
+			t.Errorf("expected error parsing %s", guess)
+		}
+	}
+}
@@ -8,6 +8,7 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
@@ -19,112 +20,397 @@ import (
 
 const (
 	// environment variables declaration
-	BootstrapServersEnvVar              = "KAFKA_BOOTSTRAP_SERVERS"
-	BootstrapBackoffMaxAttemptsEnvVar   = "KAFKA_BOOTSTRAP_BACKOFF_MAX_ATTEMPTS"
-	BootstrapBackoffScaleEnvVar         = "KAFKA_BOOTSTRAP_BACKOFF_SCALE"
-	TopicEnvVar                         = "TOPIC"
-	TopicConfigEnvVar                   = "TOPIC_CONFIG"
-	ReconcileIntervalEnvVar             = "RECONCILE_INTERVAL_MS"
-	ClientIDEnvVar                      = "CLIENT_ID"
-	ConsumerGroupIDEnvVar               = "CONSUMER_GROUP_ID"
-	ProducerLatencyBucketsEnvVar        = "PRODUCER_LATENCY_BUCKETS"
-	EndToEndLatencyBucketsEnvVar        = "ENDTOEND_LATENCY_BUCKETS"
-	ExpectedClusterSizeEnvVar           = "EXPECTED_CLUSTER_SIZE"
-	KafkaVersionEnvVar                  = "KAFKA_VERSION"
-	SaramaLogEnabledEnvVar              = "SARAMA_LOG_ENABLED"
-	VerbosityLogLevelEnvVar             = "VERBOSITY_LOG_LEVEL"
-	TLSEnabledEnvVar                    = "TLS_ENABLED"
-	TLSCACertEnvVar                     = "TLS_CA_CERT"
-	TLSClientCertEnvVar                 = "TLS_CLIENT_CERT"
-	TLSClientKeyEnvVar                  = "TLS_CLIENT_KEY"
-	TLSInsecureSkipVerifyEnvVar         = "TLS_INSECURE_SKIP_VERIFY"
-	SASLMechanismEnvVar                 = "SASL_MECHANISM"
-	SASLUserEnvVar                      = "SASL_USER"
-	SASLPasswordEnvVar                  = "SASL_PASSWORD"
-	ConnectionCheckIntervalEnvVar       = "CONNECTION_CHECK_INTERVAL_MS"
-	ConnectionCheckLatencyBucketsEnvVar = "CONNECTION_CHECK_LATENCY_BUCKETS"
-	StatusCheckIntervalEnvVar           = "STATUS_CHECK_INTERVAL_MS"
-	StatusTimeWindowEnvVar              = "STATUS_TIME_WINDOW_MS"
-	DynamicConfigFileEnvVar             = "DYNAMIC_CONFIG_FILE"
-	DynamicConfigWatcherIntervalEnvVar  = "DYNAMIC_CONFIG_WATCHER_INTERVAL"
+	BootstrapServersEnvVar                = "KAFKA_BOOTSTRAP_SERVERS"
+	BootstrapBackoffMaxAttemptsEnvVar     = "KAFKA_BOOTSTRAP_BACKOFF_MAX_ATTEMPTS"
+	BootstrapBackoffScaleEnvVar           = "KAFKA_BOOTSTRAP_BACKOFF_SCALE"
+	TopicEnvVar                           = "TOPIC"
+	TopicConfigEnvVar                     = "TOPIC_CONFIG"
+	ReconcileIntervalEnvVar               = "RECONCILE_INTERVAL_MS"
+	ClientIDEnvVar                        = "CLIENT_ID"
+	ConsumerGroupIDEnvVar                 = "CONSUMER_GROUP_ID"
+	ProducerLatencyBucketsEnvVar          = "PRODUCER_LATENCY_BUCKETS"
+	EndToEndLatencyBucketsEnvVar          = "ENDTOEND_LATENCY_BUCKETS"
+	ExpectedClusterSizeEnvVar             = "EXPECTED_CLUSTER_SIZE"
+	KafkaVersionEnvVar                    = "KAFKA_VERSION"
+	SaramaLogEnabledEnvVar                = "SARAMA_LOG_ENABLED"
+	VerbosityLogLevelEnvVar               = "VERBOSITY_LOG_LEVEL"
+	TLSEnabledEnvVar                      = "TLS_ENABLED"
+	TLSCACertEnvVar                       = "TLS_CA_CERT"
+	TLSClientCertEnvVar                   = "TLS_CLIENT_CERT"
+	TLSClientKeyEnvVar                    = "TLS_CLIENT_KEY"
+	TLSInsecureSkipVerifyEnvVar           = "TLS_INSECURE_SKIP_VERIFY"
+	SASLMechanismEnvVar                   = "SASL_MECHANISM"
+	SASLUserEnvVar                        = "SASL_USER"
+	SASLPasswordEnvVar                    = "SASL_PASSWORD"
+	ConnectionCheckIntervalEnvVar         = "CONNECTION_CHECK_INTERVAL_MS"
+	ConnectionCheckLatencyBucketsEnvVar   = "CONNECTION_CHECK_LATENCY_BUCKETS"
+	StatusCheckIntervalEnvVar             = "STATUS_CHECK_INTERVAL_MS"
+	StatusTimeWindowEnvVar                = "STATUS_TIME_WINDOW_MS"
+	DynamicConfigFileEnvVar               = "DYNAMIC_CONFIG_FILE"
+	DynamicConfigWatcherIntervalEnvVar    = "DYNAMIC_CONFIG_WATCHER_INTERVAL"
+	TargetBootstrapServersEnvVar          = "TARGET_KAFKA_BOOTSTRAP_SERVERS"
+	SourceClusterAliasEnvVar              = "SOURCE_CLUSTER_ALIAS"
+	TargetTopicEnvVar                     = "TARGET_TOPIC"
+	ReplicationLatencyBucketsEnvVar       = "REPLICATION_LATENCY_BUCKETS"
+	GrpcServerEnabledEnvVar               = "GRPC_SERVER_ENABLED"
+	GrpcServerPortEnvVar                  = "GRPC_SERVER_PORT"
+	OnDemandCheckTimeoutEnvVar            = "ON_DEMAND_CHECK_TIMEOUT_MS"
+	KafkaClientBackendEnvVar              = "KAFKA_CLIENT_BACKEND"
+	ShutdownDrainTimeoutEnvVar            = "SHUTDOWN_DRAIN_TIMEOUT_MS"
+	DeleteTopicOnShutdownEnvVar           = "DELETE_TOPIC_ON_SHUTDOWN"
+	BootstrapBackoffMaxWaitEnvVar         = "KAFKA_BOOTSTRAP_BACKOFF_MAX_WAIT_MS"
+	CircuitBreakerThresholdEnvVar         = "CIRCUIT_BREAKER_THRESHOLD"
+	AdminLatencyBucketsEnvVar             = "ADMIN_LATENCY_BUCKETS"
+	NativeHistogramsEnabledEnvVar         = "NATIVE_HISTOGRAMS_ENABLED"
+	MetricsPartitionsLimitEnvVar          = "METRICS_PARTITIONS_LIMIT"
+	SLOWindowEnvVar                       = "SLO_WINDOW_MS"
+	SLOAvailabilityTargetEnvVar           = "SLO_AVAILABILITY_TARGET"
+	SLOLatencyTargetEnvVar                = "SLO_LATENCY_TARGET"
+	SLOLatencyThresholdEnvVar             = "SLO_LATENCY_THRESHOLD_MS"
+	EventsBufferSizeEnvVar                = "EVENTS_BUFFER_SIZE"
+	SoakDurationEnvVar                    = "SOAK_DURATION_MS"
+	SoakMessageCountEnvVar                = "SOAK_MESSAGE_COUNT"
+	SoakMinConsumedPercentageEnvVar       = "SOAK_MIN_CONSUMED_PERCENTAGE"
+	SoakMaxLatencyEnvVar                  = "SOAK_MAX_LATENCY_MS"
+	ChaosLeaderElectionIntervalEnvVar     = "CHAOS_LEADER_ELECTION_INTERVAL_MS"
+	ReconcileJitterPercentageEnvVar       = "RECONCILE_JITTER_PERCENTAGE"
+	ReconcileMaxIntervalEnvVar            = "RECONCILE_MAX_INTERVAL_MS"
+	ProducerRequestTimeoutEnvVar          = "PRODUCER_REQUEST_TIMEOUT_MS"
+	AdminTimeoutEnvVar                    = "ADMIN_TIMEOUT_MS"
+	DialTimeoutEnvVar                     = "DIAL_TIMEOUT_MS"
+	MetadataRefreshTimeoutEnvVar          = "METADATA_REFRESH_TIMEOUT_MS"
+	DNSReResolutionThresholdEnvVar        = "DNS_RERESOLUTION_THRESHOLD"
+	ProxyURLEnvVar                        = "PROXY_URL"
+	ProxyUsernameEnvVar                   = "PROXY_USERNAME"
+	ProxyPasswordEnvVar                   = "PROXY_PASSWORD"
+	TLSServerNameEnvVar                   = "TLS_SERVER_NAME"
+	TLSSkipHostnameVerifyEnvVar           = "TLS_SKIP_HOSTNAME_VERIFICATION"
+	ConsumerRackIDEnvVar                  = "CONSUMER_RACK_ID"
+	PerBrokerCheckEnabledEnvVar           = "PER_BROKER_CHECK_ENABLED"
+	StatusHistoryFileEnvVar               = "STATUS_HISTORY_FILE"
+	HTTPAuthTokenEnvVar                   = "HTTP_AUTH_TOKEN"
+	HTTPTLSCertEnvVar                     = "HTTP_TLS_CERT"
+	HTTPTLSKeyEnvVar                      = "HTTP_TLS_KEY"
+	HTTPTLSClientCAEnvVar                 = "HTTP_TLS_CLIENT_CA"
+	ProducerLatencyLabelsEnvVar           = "PRODUCER_LATENCY_LABELS"
+	SaramaLogLevelEnvVar                  = "SARAMA_LOG_LEVEL"
+	ConsumerGroupCheckIntervalEnvVar      = "CONSUMER_GROUP_CHECK_INTERVAL_MS"
+	ConsumerGroupExpectedMembersEnvVar    = "CONSUMER_GROUP_EXPECTED_MEMBERS"
+	ConsumerGroupExpectedStrategyEnvVar   = "CONSUMER_GROUP_EXPECTED_STRATEGY"
+	ProducerLingerEnvVar                  = "PRODUCER_LINGER_MS"
+	ProducerBatchMessagesEnvVar           = "PRODUCER_BATCH_MESSAGES"
+	ProducerMaxMessageBytesEnvVar         = "PRODUCER_MAX_MESSAGE_BYTES"
+	ConsumerFetchMinBytesEnvVar           = "CONSUMER_FETCH_MIN_BYTES"
+	ConsumerFetchDefaultBytesEnvVar       = "CONSUMER_FETCH_DEFAULT_BYTES"
+	ConsumerFetchMaxBytesEnvVar           = "CONSUMER_FETCH_MAX_BYTES"
+	ConsumerFetchMaxWaitEnvVar            = "CONSUMER_FETCH_MAX_WAIT_MS"
+	ProducerSASLUserEnvVar                = "PRODUCER_SASL_USER"
+	ProducerSASLPasswordEnvVar            = "PRODUCER_SASL_PASSWORD"
+	ProducerTLSClientCertEnvVar           = "PRODUCER_TLS_CLIENT_CERT"
+	ProducerTLSClientKeyEnvVar            = "PRODUCER_TLS_CLIENT_KEY"
+	ConsumerSASLUserEnvVar                = "CONSUMER_SASL_USER"
+	ConsumerSASLPasswordEnvVar            = "CONSUMER_SASL_PASSWORD"
+	ConsumerTLSClientCertEnvVar           = "CONSUMER_TLS_CLIENT_CERT"
+	ConsumerTLSClientKeyEnvVar            = "CONSUMER_TLS_CLIENT_KEY"
+	DelayedConsumeDelayEnvVar             = "DELAYED_CONSUME_DELAY_MS"
+	DelayedConsumeIntervalEnvVar          = "DELAYED_CONSUME_INTERVAL_MS"
+	TieredStorageCheckIntervalEnvVar      = "TIERED_STORAGE_CHECK_INTERVAL_MS"
+	TieredStorageCheckAgeEnvVar           = "TIERED_STORAGE_CHECK_AGE_MS"
+	TieredStorageLatencyBucketsEnvVar     = "TIERED_STORAGE_LATENCY_BUCKETS"
+	EventsTopicEnvVar                     = "EVENTS_TOPIC"
+	RebalanceStormThresholdEnvVar         = "REBALANCE_STORM_THRESHOLD"
+	RebalanceStormWindowEnvVar            = "REBALANCE_STORM_WINDOW_MS"
+	ConnectionCheckListenersEnvVar        = "CONNECTION_CHECK_LISTENERS"
+	AdvertisedListenerCheckIntervalEnvVar = "ADVERTISED_LISTENER_CHECK_INTERVAL_MS"
+	LatencyWarningThresholdEnvVar         = "LATENCY_WARNING_THRESHOLD_MS"
+	LatencyCriticalThresholdEnvVar        = "LATENCY_CRITICAL_THRESHOLD_MS"
+	ProducerAcksEnvVar                    = "PRODUCER_ACKS"
+	ProducerAcksComparisonEnabledEnvVar   = "PRODUCER_ACKS_COMPARISON_ENABLED"
+	OrphanCleanupPrefixEnvVar             = "ORPHAN_CLEANUP_PREFIX"
+	RateLimitEnvVar                       = "RATE_LIMIT_OPS_PER_SECOND"
+	BrokerRollWindowEnvVar                = "BROKER_ROLL_WINDOW_MS"
+	SuccessRatioWindowsEnvVar             = "SUCCESS_RATIO_WINDOWS_MS"
+	SafeToRollWindowEnvVar                = "SAFE_TO_ROLL_WINDOW_MS"
+	MessagePayloadTemplateEnvVar          = "MESSAGE_PAYLOAD_TEMPLATE"
+	ConsumerProcessingDelayEnvVar         = "CONSUMER_PROCESSING_DELAY_MS"
+	ChecksDisabledEnvVar                  = "CHECKS_DISABLED"
+	OrphanCleanupDeleteEnabledEnvVar      = "ORPHAN_CLEANUP_DELETE_ENABLED"
+	OrphanCleanupIdleTimeEnvVar           = "ORPHAN_CLEANUP_IDLE_MS"
 	//TODO: This will be removed when Support the OTEL_TRACES_EXPORTER env var is available in the SDK see: https://github.com/open-telemetry/opentelemetry-go/issues/2310
 	ExporterTypeTracing = "EXPORTER_TYPE_TRACING"
 	// default values for environment variables
-	BootstrapServersDefault              = "localhost:9092"
-	BootstrapBackoffMaxAttemptsDefault   = 10
-	BootstrapBackoffScaleDefault         = 5000
-	TopicDefault                         = "__strimzi_canary"
-	TopicConfigDefault                   = ""
-	ReconcileIntervalDefault             = 30000
-	ClientIDDefault                      = "strimzi-canary-client"
-	ConsumerGroupIDDefault               = "strimzi-canary-group"
-	ProducerLatencyBucketsDefault        = "2,5,10,20,50,100,200,400"
-	EndToEndLatencyBucketsDefault        = "5,10,20,50,100,200,400,800"
-	ExpectedClusterSizeDefault           = -1 // "dynamic" reassignment is enabled
-	KafkaVersionDefault                  = "3.1.0"
-	SaramaLogEnabledDefault              = false
-	VerbosityLogLevelDefault             = 0 // default 0 = INFO, 1 = DEBUG, 2 = TRACE
-	TLSEnabledDefault                    = false
-	TLSCACertDefault                     = ""
-	TLSClientCertDefault                 = ""
-	TLSClientKeyDefault                  = ""
-	TLSInsecureSkipVerifyDefault         = false
-	SASLMechanismDefault                 = ""
-	SASLUserDefault                      = ""
-	SASLPasswordDefault                  = ""
-	ConnectionCheckIntervalDefault       = 120000
-	ConnectionCheckLatencyBucketsDefault = "100,200,400,800,1600"
-	StatusCheckIntervalDefault           = 30000
-	StatusTimeWindowDefault              = 300000
-	DynamicConfigFileDefault             = ""
-	DynamicConfigWatcherIntervalDefault  = 30000
-	ExporterTypeTracingDefault           = "" //if empty no tracing for now, possible values : "otlp" or "jaeger"
+	BootstrapServersDefault                = "localhost:9092"
+	BootstrapBackoffMaxAttemptsDefault     = 10
+	BootstrapBackoffScaleDefault           = 5000
+	TopicDefault                           = "__strimzi_canary"
+	TopicConfigDefault                     = ""
+	ReconcileIntervalDefault               = 30000
+	ClientIDDefault                        = "strimzi-canary-client"
+	ConsumerGroupIDDefault                 = "strimzi-canary-group"
+	ProducerLatencyBucketsDefault          = "2,5,10,20,50,100,200,400"
+	EndToEndLatencyBucketsDefault          = "5,10,20,50,100,200,400,800"
+	ExpectedClusterSizeDefault             = -1 // "dynamic" reassignment is enabled
+	KafkaVersionDefault                    = "" // negotiated with the Kafka cluster at startup
+	SaramaLogEnabledDefault                = false
+	VerbosityLogLevelDefault               = 0 // default 0 = INFO, 1 = DEBUG, 2 = TRACE
+	TLSEnabledDefault                      = false
+	TLSCACertDefault                       = ""
+	TLSClientCertDefault                   = ""
+	TLSClientKeyDefault                    = ""
+	TLSInsecureSkipVerifyDefault           = false
+	SASLMechanismDefault                   = ""
+	SASLUserDefault                        = ""
+	SASLPasswordDefault                    = ""
+	ConnectionCheckIntervalDefault         = 120000
+	ConnectionCheckLatencyBucketsDefault   = "100,200,400,800,1600"
+	StatusCheckIntervalDefault             = 30000
+	StatusTimeWindowDefault                = 300000
+	DynamicConfigFileDefault               = ""
+	DynamicConfigWatcherIntervalDefault    = 30000
+	TargetBootstrapServersDefault          = "" // cross-cluster replication check disabled
+	SourceClusterAliasDefault              = "source"
+	TargetTopicDefault                     = "" // "<source cluster alias>.<topic>" as for the MirrorMaker 2 default replication policy
+	ReplicationLatencyBucketsDefault       = "100,200,400,800,1600,3200,6400,12800"
+	GrpcServerEnabledDefault               = false
+	GrpcServerPortDefault                  = 9090
+	OnDemandCheckTimeoutDefault            = 10000
+	KafkaClientBackendDefault              = "sarama" // possible values: "sarama" or "franz-go"
+	ShutdownDrainTimeoutDefault            = 10000
+	DeleteTopicOnShutdownDefault           = false
+	BootstrapBackoffMaxWaitDefault         = 300000
+	CircuitBreakerThresholdDefault         = 3 // 0 = circuit breaker disabled
+	AdminLatencyBucketsDefault             = "10,20,50,100,200,500,1000,2000,5000"
+	NativeHistogramsEnabledDefault         = false
+	MetricsPartitionsLimitDefault          = -1         // no limit
+	SLOWindowDefault                       = 2592000000 // 30 days
+	SLOAvailabilityTargetDefault           = 99.9
+	SLOLatencyTargetDefault                = 99.0
+	SLOLatencyThresholdDefault             = 500
+	EventsBufferSizeDefault                = 20000
+	SoakDurationDefault                    = 0 // no duration bound
+	SoakMessageCountDefault                = 0 // no message count bound
+	SoakMinConsumedPercentageDefault       = 100.0
+	SoakMaxLatencyDefault                  = 0 // no latency check
+	ChaosLeaderElectionIntervalDefault     = 0 // disabled
+	ReconcileJitterPercentageDefault       = 0.0
+	ReconcileMaxIntervalDefault            = 0
+	ProducerRequestTimeoutDefault          = 0  // 0 means the Kafka client library default
+	AdminTimeoutDefault                    = 0  // 0 means the Kafka client library default
+	DialTimeoutDefault                     = 0  // 0 means the Kafka client library default
+	MetadataRefreshTimeoutDefault          = 0  // 0 means the Kafka client library default
+	DNSReResolutionThresholdDefault        = 5  // 0 means disabled
+	ProxyURLDefault                        = "" // no proxy
+	ProxyUsernameDefault                   = ""
+	ProxyPasswordDefault                   = ""
+	TLSServerNameDefault                   = "" // the broker host name
+	TLSSkipHostnameVerifyDefault           = false
+	ConsumerRackIDDefault                  = "" // no rack, fetching from the leaders
+	PerBrokerCheckEnabledDefault           = false
+	StatusHistoryFileDefault               = "" // not persisted
+	HTTPAuthTokenDefault                   = "" // no bearer token authentication
+	HTTPTLSCertDefault                     = "" // plain HTTP
+	HTTPTLSKeyDefault                      = ""
+	HTTPTLSClientCADefault                 = ""          // no client certificate authentication
+	ProducerLatencyLabelsDefault           = "partition" // possible values: "partition", "broker" or "partition,broker"
+	SaramaLogLevelDefault                  = "info"      // possible values: "info" or "warning"
+	ConsumerGroupCheckIntervalDefault      = 0           // consumer group check disabled
+	ConsumerGroupExpectedMembersDefault    = 1
+	ConsumerGroupExpectedStrategyDefault   = "" // the Kafka client backend default
+	ProducerLingerDefault                  = 0  // no linger, the Kafka client backend default
+	ProducerBatchMessagesDefault           = 0  // the Kafka client backend default
+	ProducerMaxMessageBytesDefault         = 0  // the Kafka client backend default
+	ConsumerFetchMinBytesDefault           = 0  // the Kafka client backend default
+	ConsumerFetchDefaultBytesDefault       = 0  // the Kafka client backend default
+	ConsumerFetchMaxBytesDefault           = 0  // the Kafka client backend default
+	ConsumerFetchMaxWaitDefault            = 0  // the Kafka client backend default
+	ProducerSASLUserDefault                = "" // the shared SASL user
+	ProducerSASLPasswordDefault            = "" // the shared SASL password
+	ProducerTLSClientCertDefault           = "" // the shared TLS client certificate
+	ProducerTLSClientKeyDefault            = "" // the shared TLS client key
+	ConsumerSASLUserDefault                = "" // the shared SASL user
+	ConsumerSASLPasswordDefault            = "" // the shared SASL password
+	ConsumerTLSClientCertDefault           = "" // the shared TLS client certificate
+	ConsumerTLSClientKeyDefault            = "" // the shared TLS client key
+	DelayedConsumeDelayDefault             = 0  // disabled
+	DelayedConsumeIntervalDefault          = 60000
+	TieredStorageCheckIntervalDefault      = 0        // disabled
+	TieredStorageCheckAgeDefault           = 86400000 // 1 day
+	TieredStorageLatencyBucketsDefault     = "50,100,200,500,1000,2000,5000,10000"
+	EventsTopicDefault                     = "" // failure events not published
+	RebalanceStormThresholdDefault         = 3
+	RebalanceStormWindowDefault            = 300000
+	ConnectionCheckListenersDefault        = ""    // no additional listeners checked
+	AdvertisedListenerCheckIntervalDefault = 0     // advertised listener check disabled
+	LatencyWarningThresholdDefault         = 0     // latency warning threshold disabled
+	LatencyCriticalThresholdDefault        = 0     // latency critical threshold disabled
+	ProducerAcksDefault                    = "all" // acks from all the in-sync replicas
+	ProducerAcksComparisonEnabledDefault   = false
+	OrphanCleanupPrefixDefault             = ""               // orphaned canary topics and consumer groups not cleaned up
+	RateLimitDefault                       = 0                // produce and admin operations not rate limited
+	BrokerRollWindowDefault                = 300000           // 5 minutes
+	SuccessRatioWindowsDefault             = "300000,3600000" // 5 minutes and 1 hour
+	SafeToRollWindowDefault                = 60000            // 1 minute
+	MessagePayloadTemplateDefault          = ""               // canary messages without payload
+	ConsumerProcessingDelayDefault         = 0                // no processing delay
+	ChecksDisabledDefault                  = ""               // all the configured checks enabled
+	OrphanCleanupDeleteEnabledDefault      = false            // orphaned canary topics and consumer groups only logged
+	OrphanCleanupIdleTimeDefault           = 3600000          // 1 hour
+	ExporterTypeTracingDefault             = ""               //if empty no tracing for now, possible values : "otlp" or "jaeger"
 )
 
 type DynamicCanaryConfig struct {
-	SaramaLogEnabled  *bool `json:"saramaLogEnabled"`
-	VerbosityLogLevel *int  `json:"verbosityLogLevel"`
+	SaramaLogEnabled         *bool `json:"saramaLogEnabled"`
+	VerbosityLogLevel        *int  `json:"verbosityLogLevel"`
+	ReconcileInterval        *int  `json:"reconcileIntervalMs"`
+	CircuitBreakerThreshold  *int  `json:"circuitBreakerThreshold"`
+	LatencyWarningThreshold  *int  `json:"latencyWarningThresholdMs"`
+	LatencyCriticalThreshold *int  `json:"latencyCriticalThresholdMs"`
+}
+
+// ConnectionCheckListener defines an additional listener, i.e. an external access path through a route or an ingress,
+// whose brokers addresses are checked by the connection check
+type ConnectionCheckListener struct {
+	Name             string
+	BootstrapServers []string
+	// interval (in ms) between the checks, 0 means using the connection check one
+	Interval time.Duration
 }
 
 // CanaryConfig defines the canary tool configuration
 type CanaryConfig struct {
 	DynamicCanaryConfig
-	BootstrapServers              []string
-	BootstrapBackoffMaxAttempts   int
-	BootstrapBackoffScale         time.Duration
-	Topic                         string
-	TopicConfig                   map[string]string
-	ReconcileInterval             time.Duration
-	ClientID                      string
-	ConsumerGroupID               string
-	ProducerLatencyBuckets        []float64
-	EndToEndLatencyBuckets        []float64
-	ExpectedClusterSize           int
-	KafkaVersion                  string
-	DynamicConfigFile             string
-	TLSEnabled                    bool
-	TLSCACert                     string
-	TLSClientCert                 string
-	TLSClientKey                  string
-	TLSInsecureSkipVerify         bool
-	SASLMechanism                 string
-	SASLUser                      string
-	SASLPassword                  string
-	ConnectionCheckInterval       time.Duration
-	ConnectionCheckLatencyBuckets []float64
-	StatusCheckInterval           time.Duration
-	StatusTimeWindow              time.Duration
-	DynamicConfigWatcherInterval  time.Duration
-	ExporterTypeTracing           string
+	BootstrapServers                []string
+	BootstrapBackoffMaxAttempts     int
+	BootstrapBackoffScale           time.Duration
+	Topic                           string
+	TopicConfig                     map[string]string
+	ReconcileInterval               time.Duration
+	ClientID                        string
+	ConsumerGroupID                 string
+	ProducerLatencyBuckets          []float64
+	EndToEndLatencyBuckets          []float64
+	ExpectedClusterSize             int
+	KafkaVersion                    string
+	DynamicConfigFile               string
+	TLSEnabled                      bool
+	TLSCACert                       string
+	TLSClientCert                   string
+	TLSClientKey                    string
+	TLSInsecureSkipVerify           bool
+	SASLMechanism                   string
+	SASLUser                        string
+	SASLPassword                    string
+	ConnectionCheckInterval         time.Duration
+	ConnectionCheckLatencyBuckets   []float64
+	StatusCheckInterval             time.Duration
+	StatusTimeWindow                time.Duration
+	DynamicConfigWatcherInterval    time.Duration
+	ExporterTypeTracing             string
+	TargetBootstrapServers          []string
+	SourceClusterAlias              string
+	TargetTopic                     string
+	ReplicationLatencyBuckets       []float64
+	GrpcServerEnabled               bool
+	GrpcServerPort                  int
+	OnDemandCheckTimeout            time.Duration
+	KafkaClientBackend              string
+	ShutdownDrainTimeout            time.Duration
+	DeleteTopicOnShutdown           bool
+	BootstrapBackoffMaxWait         time.Duration
+	CircuitBreakerThreshold         int
+	AdminLatencyBuckets             []float64
+	NativeHistogramsEnabled         bool
+	MetricsPartitionsLimit          int
+	SLOWindow                       time.Duration
+	SLOAvailabilityTarget           float64
+	SLOLatencyTarget                float64
+	SLOLatencyThreshold             time.Duration
+	EventsBufferSize                int
+	SoakDuration                    time.Duration
+	SoakMessageCount                int
+	SoakMinConsumedPercentage       float64
+	SoakMaxLatency                  time.Duration
+	ChaosLeaderElectionInterval     time.Duration
+	ReconcileJitterPercentage       float64
+	ReconcileMaxInterval            time.Duration
+	ProducerRequestTimeout          time.Duration
+	AdminTimeout                    time.Duration
+	DialTimeout                     time.Duration
+	MetadataRefreshTimeout          time.Duration
+	DNSReResolutionThreshold        int
+	ProxyURL                        string
+	ProxyUsername                   string
+	ProxyPassword                   string
+	TLSServerName                   string
+	TLSSkipHostnameVerify           bool
+	ConsumerRackID                  string
+	PerBrokerCheckEnabled           bool
+	StatusHistoryFile               string
+	HTTPAuthToken                   string
+	HTTPTLSCert                     string
+	HTTPTLSKey                      string
+	HTTPTLSClientCA                 string
+	ProducerLatencyLabels           string
+	SaramaLogLevel                  string
+	ConsumerGroupCheckInterval      time.Duration
+	ConsumerGroupExpectedMembers    int
+	ConsumerGroupExpectedStrategy   string
+	ProducerLinger                  time.Duration
+	ProducerBatchMessages           int
+	ProducerMaxMessageBytes         int
+	ConsumerFetchMinBytes           int
+	ConsumerFetchDefaultBytes       int
+	ConsumerFetchMaxBytes           int
+	ConsumerFetchMaxWait            time.Duration
+	ProducerSASLUser                string
+	ProducerSASLPassword            string
+	ProducerTLSClientCert           string
+	ProducerTLSClientKey            string
+	ConsumerSASLUser                string
+	ConsumerSASLPassword            string
+	ConsumerTLSClientCert           string
+	ConsumerTLSClientKey            string
+	DelayedConsumeDelay             time.Duration
+	DelayedConsumeInterval          time.Duration
+	TieredStorageCheckInterval      time.Duration
+	TieredStorageCheckAge           time.Duration
+	TieredStorageLatencyBuckets     []float64
+	EventsTopic                     string
+	RebalanceStormThreshold         int
+	RebalanceStormWindow            time.Duration
+	ConnectionCheckListeners        []ConnectionCheckListener
+	AdvertisedListenerCheckInterval time.Duration
+	LatencyWarningThreshold         time.Duration
+	LatencyCriticalThreshold        time.Duration
+	ProducerAcks                    string
+	ProducerAcksComparisonEnabled   bool
+	OrphanCleanupPrefix             string
+	RateLimit                       int
+	BrokerRollWindow                time.Duration
+	SuccessRatioWindows             []time.Duration
+	SafeToRollWindow                time.Duration
+	MessagePayloadTemplate          string
+	ConsumerProcessingDelay         time.Duration
+	ChecksDisabled                  []string
+	OrphanCleanupDeleteEnabled      bool
+	OrphanCleanupIdleTime           time.Duration
 }
 
 func NewDynamicCanaryConfig() *DynamicCanaryConfig {
 	saramaLogEnabled := lookupBoolEnv(SaramaLogEnabledEnvVar, SaramaLogEnabledDefault)
 	verbosityLogLevel := lookupIntEnv(VerbosityLogLevelEnvVar, VerbosityLogLevelDefault)
+	reconcileInterval := lookupIntEnv(ReconcileIntervalEnvVar, ReconcileIntervalDefault)
+	circuitBreakerThreshold := lookupIntEnv(CircuitBreakerThresholdEnvVar, CircuitBreakerThresholdDefault)
+	latencyWarningThreshold := lookupIntEnv(LatencyWarningThresholdEnvVar, LatencyWarningThresholdDefault)
+	latencyCriticalThreshold := lookupIntEnv(LatencyCriticalThresholdEnvVar, LatencyCriticalThresholdDefault)
 
 	dynamicCanaryConfig := DynamicCanaryConfig{
-		SaramaLogEnabled:  &saramaLogEnabled,
-		VerbosityLogLevel: &verbosityLogLevel,
+		SaramaLogEnabled:         &saramaLogEnabled,
+		VerbosityLogLevel:        &verbosityLogLevel,
+		ReconcileInterval:        &reconcileInterval,
+		CircuitBreakerThreshold:  &circuitBreakerThreshold,
+		LatencyWarningThreshold:  &latencyWarningThreshold,
+		LatencyCriticalThreshold: &latencyCriticalThreshold,
 	}
 	return &dynamicCanaryConfig
 }
@@ -144,47 +430,314 @@ func (c DynamicCanaryConfig) String() string {
 	if c.VerbosityLogLevel != nil {
 		str = commaPad(str) + fmt.Sprintf("VerbosityLogLevel:%d", *c.VerbosityLogLevel)
 	}
+	if c.ReconcileInterval != nil {
+		str = commaPad(str) + fmt.Sprintf("ReconcileInterval:%d ms", *c.ReconcileInterval)
+	}
+	if c.CircuitBreakerThreshold != nil {
+		str = commaPad(str) + fmt.Sprintf("CircuitBreakerThreshold:%d", *c.CircuitBreakerThreshold)
+	}
+	if c.LatencyWarningThreshold != nil {
+		str = commaPad(str) + fmt.Sprintf("LatencyWarningThreshold:%d ms", *c.LatencyWarningThreshold)
+	}
+	if c.LatencyCriticalThreshold != nil {
+		str = commaPad(str) + fmt.Sprintf("LatencyCriticalThreshold:%d ms", *c.LatencyCriticalThreshold)
+	}
 	str = str + "}"
 	return str
 }
 
+// Validate returns an error if any of the set values is not valid
+func (c DynamicCanaryConfig) Validate() error {
+	if c.VerbosityLogLevel != nil && *c.VerbosityLogLevel < 0 {
+		return fmt.Errorf("verbosityLogLevel has to be 0 or greater, got %d", *c.VerbosityLogLevel)
+	}
+	if c.ReconcileInterval != nil && *c.ReconcileInterval <= 0 {
+		return fmt.Errorf("reconcileIntervalMs has to be greater than 0, got %d", *c.ReconcileInterval)
+	}
+	if c.CircuitBreakerThreshold != nil && *c.CircuitBreakerThreshold < 0 {
+		return fmt.Errorf("circuitBreakerThreshold has to be 0 or greater, got %d", *c.CircuitBreakerThreshold)
+	}
+	if c.LatencyWarningThreshold != nil && *c.LatencyWarningThreshold < 0 {
+		return fmt.Errorf("latencyWarningThresholdMs has to be 0 or greater, got %d", *c.LatencyWarningThreshold)
+	}
+	if c.LatencyCriticalThreshold != nil && *c.LatencyCriticalThreshold < 0 {
+		return fmt.Errorf("latencyCriticalThresholdMs has to be 0 or greater, got %d", *c.LatencyCriticalThreshold)
+	}
+	return nil
+}
+
+// Merge returns a copy of the configuration with the values set in the patch overriding the current ones
+func (c DynamicCanaryConfig) Merge(patch *DynamicCanaryConfig) *DynamicCanaryConfig {
+	merged := c
+	if patch.SaramaLogEnabled != nil {
+		merged.SaramaLogEnabled = patch.SaramaLogEnabled
+	}
+	if patch.VerbosityLogLevel != nil {
+		merged.VerbosityLogLevel = patch.VerbosityLogLevel
+	}
+	if patch.ReconcileInterval != nil {
+		merged.ReconcileInterval = patch.ReconcileInterval
+	}
+	if patch.CircuitBreakerThreshold != nil {
+		merged.CircuitBreakerThreshold = patch.CircuitBreakerThreshold
+	}
+	if patch.LatencyWarningThreshold != nil {
+		merged.LatencyWarningThreshold = patch.LatencyWarningThreshold
+	}
+	if patch.LatencyCriticalThreshold != nil {
+		merged.LatencyCriticalThreshold = patch.LatencyCriticalThreshold
+	}
+	return &merged
+}
+
+// Changed returns the values set in the configuration which are not set, or set to a different value, in the previous one
+func (c DynamicCanaryConfig) Changed(previous *DynamicCanaryConfig) *DynamicCanaryConfig {
+	changed := DynamicCanaryConfig{}
+	if c.SaramaLogEnabled != nil && (previous.SaramaLogEnabled == nil || *c.SaramaLogEnabled != *previous.SaramaLogEnabled) {
+		changed.SaramaLogEnabled = c.SaramaLogEnabled
+	}
+	if c.VerbosityLogLevel != nil && (previous.VerbosityLogLevel == nil || *c.VerbosityLogLevel != *previous.VerbosityLogLevel) {
+		changed.VerbosityLogLevel = c.VerbosityLogLevel
+	}
+	if c.ReconcileInterval != nil && (previous.ReconcileInterval == nil || *c.ReconcileInterval != *previous.ReconcileInterval) {
+		changed.ReconcileInterval = c.ReconcileInterval
+	}
+	if c.CircuitBreakerThreshold != nil && (previous.CircuitBreakerThreshold == nil || *c.CircuitBreakerThreshold != *previous.CircuitBreakerThreshold) {
+		changed.CircuitBreakerThreshold = c.CircuitBreakerThreshold
+	}
+	if c.LatencyWarningThreshold != nil && (previous.LatencyWarningThreshold == nil || *c.LatencyWarningThreshold != *previous.LatencyWarningThreshold) {
+		changed.LatencyWarningThreshold = c.LatencyWarningThreshold
+	}
+	if c.LatencyCriticalThreshold != nil && (previous.LatencyCriticalThreshold == nil || *c.LatencyCriticalThreshold != *previous.LatencyCriticalThreshold) {
+		changed.LatencyCriticalThreshold = c.LatencyCriticalThreshold
+	}
+	return &changed
+}
+
+// Removed returns the names of the values set in the previous configuration which are not set anymore in this one
+func (c DynamicCanaryConfig) Removed(previous *DynamicCanaryConfig) []string {
+	removed := []string{}
+	if c.SaramaLogEnabled == nil && previous.SaramaLogEnabled != nil {
+		removed = append(removed, "saramaLogEnabled")
+	}
+	if c.VerbosityLogLevel == nil && previous.VerbosityLogLevel != nil {
+		removed = append(removed, "verbosityLogLevel")
+	}
+	if c.ReconcileInterval == nil && previous.ReconcileInterval != nil {
+		removed = append(removed, "reconcileIntervalMs")
+	}
+	if c.CircuitBreakerThreshold == nil && previous.CircuitBreakerThreshold != nil {
+		removed = append(removed, "circuitBreakerThreshold")
+	}
+	if c.LatencyWarningThreshold == nil && previous.LatencyWarningThreshold != nil {
+		removed = append(removed, "latencyWarningThresholdMs")
+	}
+	if c.LatencyCriticalThreshold == nil && previous.LatencyCriticalThreshold != nil {
+		removed = append(removed, "latencyCriticalThresholdMs")
+	}
+	return removed
+}
+
+// Unset returns a copy of the configuration with the named values unset, so that the ones from the environment
+// variables are restored when applied. It returns an error if any of the names is not a dynamic configuration field
+func (c DynamicCanaryConfig) Unset(names []string) (*DynamicCanaryConfig, error) {
+	unset := c
+	for _, name := range names {
+		switch name {
+		case "saramaLogEnabled":
+			unset.SaramaLogEnabled = nil
+		case "verbosityLogLevel":
+			unset.VerbosityLogLevel = nil
+		case "reconcileIntervalMs":
+			unset.ReconcileInterval = nil
+		case "circuitBreakerThreshold":
+			unset.CircuitBreakerThreshold = nil
+		case "latencyWarningThresholdMs":
+			unset.LatencyWarningThreshold = nil
+		case "latencyCriticalThresholdMs":
+			unset.LatencyCriticalThreshold = nil
+		default:
+			return nil, fmt.Errorf("%s is not a dynamic configuration field", name)
+		}
+	}
+	return &unset, nil
+}
+
 // NewCanaryConfig returns an configuration instance from environment variables
 func NewCanaryConfig() *CanaryConfig {
 	dynamicCanaryConfig := NewDynamicCanaryConfig()
 
 	config := CanaryConfig{
-		DynamicCanaryConfig:           *dynamicCanaryConfig,
-		BootstrapServers:              strings.Split(lookupStringEnv(BootstrapServersEnvVar, BootstrapServersDefault), ","),
-		BootstrapBackoffMaxAttempts:   lookupIntEnv(BootstrapBackoffMaxAttemptsEnvVar, BootstrapBackoffMaxAttemptsDefault),
-		BootstrapBackoffScale:         time.Duration(lookupIntEnv(BootstrapBackoffScaleEnvVar, BootstrapBackoffScaleDefault)),
-		Topic:                         lookupStringEnv(TopicEnvVar, TopicDefault),
-		TopicConfig:                   topicConfig(lookupStringEnv(TopicConfigEnvVar, TopicConfigDefault)),
-		ReconcileInterval:             time.Duration(lookupIntEnv(ReconcileIntervalEnvVar, ReconcileIntervalDefault)),
-		ClientID:                      lookupStringEnv(ClientIDEnvVar, ClientIDDefault),
-		ConsumerGroupID:               lookupStringEnv(ConsumerGroupIDEnvVar, ConsumerGroupIDDefault),
-		ProducerLatencyBuckets:        latencyBuckets(lookupStringEnv(ProducerLatencyBucketsEnvVar, ProducerLatencyBucketsDefault)),
-		EndToEndLatencyBuckets:        latencyBuckets(lookupStringEnv(EndToEndLatencyBucketsEnvVar, EndToEndLatencyBucketsDefault)),
-		ExpectedClusterSize:           lookupIntEnv(ExpectedClusterSizeEnvVar, ExpectedClusterSizeDefault),
-		KafkaVersion:                  lookupStringEnv(KafkaVersionEnvVar, KafkaVersionDefault),
-		TLSEnabled:                    lookupBoolEnv(TLSEnabledEnvVar, TLSEnabledDefault),
-		TLSCACert:                     lookupStringEnv(TLSCACertEnvVar, TLSCACertDefault),
-		TLSClientCert:                 lookupStringEnv(TLSClientCertEnvVar, TLSClientCertDefault),
-		TLSClientKey:                  lookupStringEnv(TLSClientKeyEnvVar, TLSClientKeyDefault),
-		TLSInsecureSkipVerify:         lookupBoolEnv(TLSInsecureSkipVerifyEnvVar, TLSInsecureSkipVerifyDefault),
-		SASLMechanism:                 lookupStringEnv(SASLMechanismEnvVar, SASLMechanismDefault),
-		SASLUser:                      lookupStringEnv(SASLUserEnvVar, SASLUserDefault),
-		SASLPassword:                  lookupStringEnv(SASLPasswordEnvVar, SASLPasswordDefault),
-		ConnectionCheckInterval:       time.Duration(lookupIntEnv(ConnectionCheckIntervalEnvVar, ConnectionCheckIntervalDefault)),
-		ConnectionCheckLatencyBuckets: latencyBuckets(lookupStringEnv(ConnectionCheckLatencyBucketsEnvVar, ConnectionCheckLatencyBucketsDefault)),
-		StatusCheckInterval:           time.Duration(lookupIntEnv(StatusCheckIntervalEnvVar, StatusCheckIntervalDefault)),
-		StatusTimeWindow:              time.Duration(lookupIntEnv(StatusTimeWindowEnvVar, StatusTimeWindowDefault)),
-		DynamicConfigFile:             lookupStringEnv(DynamicConfigFileEnvVar, DynamicConfigFileDefault),
-		DynamicConfigWatcherInterval:  time.Duration(lookupIntEnv(DynamicConfigWatcherIntervalEnvVar, DynamicConfigWatcherIntervalDefault)),
-		ExporterTypeTracing:           exporterTypeTracing(),
+		DynamicCanaryConfig:             *dynamicCanaryConfig,
+		BootstrapServers:                strings.Split(lookupStringEnv(BootstrapServersEnvVar, BootstrapServersDefault), ","),
+		BootstrapBackoffMaxAttempts:     lookupIntEnv(BootstrapBackoffMaxAttemptsEnvVar, BootstrapBackoffMaxAttemptsDefault),
+		BootstrapBackoffScale:           time.Duration(lookupIntEnv(BootstrapBackoffScaleEnvVar, BootstrapBackoffScaleDefault)),
+		Topic:                           lookupStringEnv(TopicEnvVar, TopicDefault),
+		TopicConfig:                     topicConfig(lookupStringEnv(TopicConfigEnvVar, TopicConfigDefault)),
+		ReconcileInterval:               time.Duration(lookupIntEnv(ReconcileIntervalEnvVar, ReconcileIntervalDefault)),
+		ClientID:                        lookupStringEnv(ClientIDEnvVar, ClientIDDefault),
+		ConsumerGroupID:                 lookupStringEnv(ConsumerGroupIDEnvVar, ConsumerGroupIDDefault),
+		ProducerLatencyBuckets:          latencyBuckets(lookupStringEnv(ProducerLatencyBucketsEnvVar, ProducerLatencyBucketsDefault)),
+		EndToEndLatencyBuckets:          latencyBuckets(lookupStringEnv(EndToEndLatencyBucketsEnvVar, EndToEndLatencyBucketsDefault)),
+		ExpectedClusterSize:             lookupIntEnv(ExpectedClusterSizeEnvVar, ExpectedClusterSizeDefault),
+		KafkaVersion:                    lookupStringEnv(KafkaVersionEnvVar, KafkaVersionDefault),
+		TLSEnabled:                      lookupBoolEnv(TLSEnabledEnvVar, TLSEnabledDefault),
+		TLSCACert:                       lookupStringEnv(TLSCACertEnvVar, TLSCACertDefault),
+		TLSClientCert:                   lookupStringEnv(TLSClientCertEnvVar, TLSClientCertDefault),
+		TLSClientKey:                    lookupStringEnv(TLSClientKeyEnvVar, TLSClientKeyDefault),
+		TLSInsecureSkipVerify:           lookupBoolEnv(TLSInsecureSkipVerifyEnvVar, TLSInsecureSkipVerifyDefault),
+		SASLMechanism:                   lookupStringEnv(SASLMechanismEnvVar, SASLMechanismDefault),
+		SASLUser:                        lookupStringEnv(SASLUserEnvVar, SASLUserDefault),
+		SASLPassword:                    lookupStringEnv(SASLPasswordEnvVar, SASLPasswordDefault),
+		ConnectionCheckInterval:         time.Duration(lookupIntEnv(ConnectionCheckIntervalEnvVar, ConnectionCheckIntervalDefault)),
+		ConnectionCheckLatencyBuckets:   latencyBuckets(lookupStringEnv(ConnectionCheckLatencyBucketsEnvVar, ConnectionCheckLatencyBucketsDefault)),
+		StatusCheckInterval:             time.Duration(lookupIntEnv(StatusCheckIntervalEnvVar, StatusCheckIntervalDefault)),
+		StatusTimeWindow:                time.Duration(lookupIntEnv(StatusTimeWindowEnvVar, StatusTimeWindowDefault)),
+		DynamicConfigFile:               lookupStringEnv(DynamicConfigFileEnvVar, DynamicConfigFileDefault),
+		DynamicConfigWatcherInterval:    time.Duration(lookupIntEnv(DynamicConfigWatcherIntervalEnvVar, DynamicConfigWatcherIntervalDefault)),
+		ExporterTypeTracing:             exporterTypeTracing(),
+		TargetBootstrapServers:          bootstrapServers(lookupStringEnv(TargetBootstrapServersEnvVar, TargetBootstrapServersDefault)),
+		SourceClusterAlias:              lookupStringEnv(SourceClusterAliasEnvVar, SourceClusterAliasDefault),
+		TargetTopic:                     lookupStringEnv(TargetTopicEnvVar, TargetTopicDefault),
+		ReplicationLatencyBuckets:       latencyBuckets(lookupStringEnv(ReplicationLatencyBucketsEnvVar, ReplicationLatencyBucketsDefault)),
+		GrpcServerEnabled:               lookupBoolEnv(GrpcServerEnabledEnvVar, GrpcServerEnabledDefault),
+		GrpcServerPort:                  lookupIntEnv(GrpcServerPortEnvVar, GrpcServerPortDefault),
+		OnDemandCheckTimeout:            time.Duration(lookupIntEnv(OnDemandCheckTimeoutEnvVar, OnDemandCheckTimeoutDefault)),
+		KafkaClientBackend:              lookupStringEnv(KafkaClientBackendEnvVar, KafkaClientBackendDefault),
+		ShutdownDrainTimeout:            time.Duration(lookupIntEnv(ShutdownDrainTimeoutEnvVar, ShutdownDrainTimeoutDefault)),
+		DeleteTopicOnShutdown:           lookupBoolEnv(DeleteTopicOnShutdownEnvVar, DeleteTopicOnShutdownDefault),
+		BootstrapBackoffMaxWait:         time.Duration(lookupIntEnv(BootstrapBackoffMaxWaitEnvVar, BootstrapBackoffMaxWaitDefault)),
+		CircuitBreakerThreshold:         lookupIntEnv(CircuitBreakerThresholdEnvVar, CircuitBreakerThresholdDefault),
+		AdminLatencyBuckets:             latencyBuckets(lookupStringEnv(AdminLatencyBucketsEnvVar, AdminLatencyBucketsDefault)),
+		NativeHistogramsEnabled:         lookupBoolEnv(NativeHistogramsEnabledEnvVar, NativeHistogramsEnabledDefault),
+		MetricsPartitionsLimit:          lookupIntEnv(MetricsPartitionsLimitEnvVar, MetricsPartitionsLimitDefault),
+		SLOWindow:                       time.Duration(lookupIntEnv(SLOWindowEnvVar, SLOWindowDefault)),
+		SLOAvailabilityTarget:           lookupFloatEnv(SLOAvailabilityTargetEnvVar, SLOAvailabilityTargetDefault),
+		SLOLatencyTarget:                lookupFloatEnv(SLOLatencyTargetEnvVar, SLOLatencyTargetDefault),
+		SLOLatencyThreshold:             time.Duration(lookupIntEnv(SLOLatencyThresholdEnvVar, SLOLatencyThresholdDefault)),
+		EventsBufferSize:                lookupIntEnv(EventsBufferSizeEnvVar, EventsBufferSizeDefault),
+		SoakDuration:                    time.Duration(lookupIntEnv(SoakDurationEnvVar, SoakDurationDefault)),
+		SoakMessageCount:                lookupIntEnv(SoakMessageCountEnvVar, SoakMessageCountDefault),
+		SoakMinConsumedPercentage:       lookupFloatEnv(SoakMinConsumedPercentageEnvVar, SoakMinConsumedPercentageDefault),
+		SoakMaxLatency:                  time.Duration(lookupIntEnv(SoakMaxLatencyEnvVar, SoakMaxLatencyDefault)),
+		ChaosLeaderElectionInterval:     time.Duration(lookupIntEnv(ChaosLeaderElectionIntervalEnvVar, ChaosLeaderElectionIntervalDefault)),
+		ReconcileJitterPercentage:       lookupFloatEnv(ReconcileJitterPercentageEnvVar, ReconcileJitterPercentageDefault),
+		ReconcileMaxInterval:            time.Duration(lookupIntEnv(ReconcileMaxIntervalEnvVar, ReconcileMaxIntervalDefault)),
+		ProducerRequestTimeout:          time.Duration(lookupIntEnv(ProducerRequestTimeoutEnvVar, ProducerRequestTimeoutDefault)),
+		AdminTimeout:                    time.Duration(lookupIntEnv(AdminTimeoutEnvVar, AdminTimeoutDefault)),
+		DialTimeout:                     time.Duration(lookupIntEnv(DialTimeoutEnvVar, DialTimeoutDefault)),
+		MetadataRefreshTimeout:          time.Duration(lookupIntEnv(MetadataRefreshTimeoutEnvVar, MetadataRefreshTimeoutDefault)),
+		DNSReResolutionThreshold:        lookupIntEnv(DNSReResolutionThresholdEnvVar, DNSReResolutionThresholdDefault),
+		ProxyURL:                        lookupStringEnv(ProxyURLEnvVar, ProxyURLDefault),
+		ProxyUsername:                   lookupStringEnv(ProxyUsernameEnvVar, ProxyUsernameDefault),
+		ProxyPassword:                   lookupStringEnv(ProxyPasswordEnvVar, ProxyPasswordDefault),
+		TLSServerName:                   lookupStringEnv(TLSServerNameEnvVar, TLSServerNameDefault),
+		TLSSkipHostnameVerify:           lookupBoolEnv(TLSSkipHostnameVerifyEnvVar, TLSSkipHostnameVerifyDefault),
+		ConsumerRackID:                  lookupStringEnv(ConsumerRackIDEnvVar, ConsumerRackIDDefault),
+		PerBrokerCheckEnabled:           lookupBoolEnv(PerBrokerCheckEnabledEnvVar, PerBrokerCheckEnabledDefault),
+		StatusHistoryFile:               lookupStringEnv(StatusHistoryFileEnvVar, StatusHistoryFileDefault),
+		HTTPAuthToken:                   lookupStringEnv(HTTPAuthTokenEnvVar, HTTPAuthTokenDefault),
+		HTTPTLSCert:                     lookupStringEnv(HTTPTLSCertEnvVar, HTTPTLSCertDefault),
+		HTTPTLSKey:                      lookupStringEnv(HTTPTLSKeyEnvVar, HTTPTLSKeyDefault),
+		HTTPTLSClientCA:                 lookupStringEnv(HTTPTLSClientCAEnvVar, HTTPTLSClientCADefault),
+		ProducerLatencyLabels:           lookupStringEnv(ProducerLatencyLabelsEnvVar, ProducerLatencyLabelsDefault),
+		SaramaLogLevel:                  lookupStringEnv(SaramaLogLevelEnvVar, SaramaLogLevelDefault),
+		ConsumerGroupCheckInterval:      time.Duration(lookupIntEnv(ConsumerGroupCheckIntervalEnvVar, ConsumerGroupCheckIntervalDefault)),
+		ConsumerGroupExpectedMembers:    lookupIntEnv(ConsumerGroupExpectedMembersEnvVar, ConsumerGroupExpectedMembersDefault),
+		ConsumerGroupExpectedStrategy:   lookupStringEnv(ConsumerGroupExpectedStrategyEnvVar, ConsumerGroupExpectedStrategyDefault),
+		ProducerLinger:                  time.Duration(lookupIntEnv(ProducerLingerEnvVar, ProducerLingerDefault)),
+		ProducerBatchMessages:           lookupIntEnv(ProducerBatchMessagesEnvVar, ProducerBatchMessagesDefault),
+		ProducerMaxMessageBytes:         lookupIntEnv(ProducerMaxMessageBytesEnvVar, ProducerMaxMessageBytesDefault),
+		ConsumerFetchMinBytes:           lookupIntEnv(ConsumerFetchMinBytesEnvVar, ConsumerFetchMinBytesDefault),
+		ConsumerFetchDefaultBytes:       lookupIntEnv(ConsumerFetchDefaultBytesEnvVar, ConsumerFetchDefaultBytesDefault),
+		ConsumerFetchMaxBytes:           lookupIntEnv(ConsumerFetchMaxBytesEnvVar, ConsumerFetchMaxBytesDefault),
+		ConsumerFetchMaxWait:            time.Duration(lookupIntEnv(ConsumerFetchMaxWaitEnvVar, ConsumerFetchMaxWaitDefault)),
+		ProducerSASLUser:                lookupStringEnv(ProducerSASLUserEnvVar, ProducerSASLUserDefault),
+		ProducerSASLPassword:            lookupStringEnv(ProducerSASLPasswordEnvVar, ProducerSASLPasswordDefault),
+		ProducerTLSClientCert:           lookupStringEnv(ProducerTLSClientCertEnvVar, ProducerTLSClientCertDefault),
+		ProducerTLSClientKey:            lookupStringEnv(ProducerTLSClientKeyEnvVar, ProducerTLSClientKeyDefault),
+		ConsumerSASLUser:                lookupStringEnv(ConsumerSASLUserEnvVar, ConsumerSASLUserDefault),
+		ConsumerSASLPassword:            lookupStringEnv(ConsumerSASLPasswordEnvVar, ConsumerSASLPasswordDefault),
+		ConsumerTLSClientCert:           lookupStringEnv(ConsumerTLSClientCertEnvVar, ConsumerTLSClientCertDefault),
+		ConsumerTLSClientKey:            lookupStringEnv(ConsumerTLSClientKeyEnvVar, ConsumerTLSClientKeyDefault),
+		DelayedConsumeDelay:             time.Duration(lookupIntEnv(DelayedConsumeDelayEnvVar, DelayedConsumeDelayDefault)),
+		DelayedConsumeInterval:          time.Duration(lookupIntEnv(DelayedConsumeIntervalEnvVar, DelayedConsumeIntervalDefault)),
+		TieredStorageCheckInterval:      time.Duration(lookupIntEnv(TieredStorageCheckIntervalEnvVar, TieredStorageCheckIntervalDefault)),
+		TieredStorageCheckAge:           time.Duration(lookupIntEnv(TieredStorageCheckAgeEnvVar, TieredStorageCheckAgeDefault)),
+		TieredStorageLatencyBuckets:     latencyBuckets(lookupStringEnv(TieredStorageLatencyBucketsEnvVar, TieredStorageLatencyBucketsDefault)),
+		EventsTopic:                     lookupStringEnv(EventsTopicEnvVar, EventsTopicDefault),
+		RebalanceStormThreshold:         lookupIntEnv(RebalanceStormThresholdEnvVar, RebalanceStormThresholdDefault),
+		RebalanceStormWindow:            time.Duration(lookupIntEnv(RebalanceStormWindowEnvVar, RebalanceStormWindowDefault)),
+		ConnectionCheckListeners:        connectionCheckListeners(lookupStringEnv(ConnectionCheckListenersEnvVar, ConnectionCheckListenersDefault)),
+		AdvertisedListenerCheckInterval: time.Duration(lookupIntEnv(AdvertisedListenerCheckIntervalEnvVar, AdvertisedListenerCheckIntervalDefault)),
+		LatencyWarningThreshold:         time.Duration(lookupIntEnv(LatencyWarningThresholdEnvVar, LatencyWarningThresholdDefault)),
+		LatencyCriticalThreshold:        time.Duration(lookupIntEnv(LatencyCriticalThresholdEnvVar, LatencyCriticalThresholdDefault)),
+		ProducerAcks:                    lookupStringEnv(ProducerAcksEnvVar, ProducerAcksDefault),
+		ProducerAcksComparisonEnabled:   lookupBoolEnv(ProducerAcksComparisonEnabledEnvVar, ProducerAcksComparisonEnabledDefault),
+		OrphanCleanupPrefix:             lookupStringEnv(OrphanCleanupPrefixEnvVar, OrphanCleanupPrefixDefault),
+		RateLimit:                       lookupIntEnv(RateLimitEnvVar, RateLimitDefault),
+		BrokerRollWindow:                time.Duration(lookupIntEnv(BrokerRollWindowEnvVar, BrokerRollWindowDefault)),
+		SuccessRatioWindows:             successRatioWindows(lookupStringEnv(SuccessRatioWindowsEnvVar, SuccessRatioWindowsDefault)),
+		SafeToRollWindow:                time.Duration(lookupIntEnv(SafeToRollWindowEnvVar, SafeToRollWindowDefault)),
+		MessagePayloadTemplate:          lookupStringEnv(MessagePayloadTemplateEnvVar, MessagePayloadTemplateDefault),
+		ConsumerProcessingDelay:         time.Duration(lookupIntEnv(ConsumerProcessingDelayEnvVar, ConsumerProcessingDelayDefault)),
+		ChecksDisabled:                  checkNames(lookupStringEnv(ChecksDisabledEnvVar, ChecksDisabledDefault)),
+		OrphanCleanupDeleteEnabled:      lookupBoolEnv(OrphanCleanupDeleteEnabledEnvVar, OrphanCleanupDeleteEnabledDefault),
+		OrphanCleanupIdleTime:           time.Duration(lookupIntEnv(OrphanCleanupIdleTimeEnvVar, OrphanCleanupIdleTimeDefault)),
+	}
+	if config.TargetTopic == "" {
+		config.TargetTopic = config.SourceClusterAlias + "." + config.Topic
 	}
 	return &config
 }
 
+// IsSoakModeEnabled returns true if the canary has to run for a bounded duration or message count and then exit
+func (c *CanaryConfig) IsSoakModeEnabled() bool {
+	return c.SoakDuration > 0 || c.SoakMessageCount > 0
+}
+
+// IsCheckDisabled returns true if the check, by name, was disabled explicitly
+func (c *CanaryConfig) IsCheckDisabled(name string) bool {
+	for _, disabled := range c.ChecksDisabled {
+		if disabled == name {
+			return true
+		}
+	}
+	return false
+}
+
+// IsReplicationCheckEnabled returns true if the canary has to consume the mirrored topic from a target cluster
+func (c *CanaryConfig) IsReplicationCheckEnabled() bool {
+	return len(c.TargetBootstrapServers) > 0
+}
+
+// ProducerIdentity returns a copy of the canary configuration with the producer SASL user/password and TLS client
+// certificate/key, when configured, in place of the shared ones
+func (c *CanaryConfig) ProducerIdentity() *CanaryConfig {
+	return c.withIdentity(c.ProducerSASLUser, c.ProducerSASLPassword, c.ProducerTLSClientCert, c.ProducerTLSClientKey)
+}
+
+// ConsumerIdentity returns a copy of the canary configuration with the consumer SASL user/password and TLS client
+// certificate/key, when configured, in place of the shared ones
+func (c *CanaryConfig) ConsumerIdentity() *CanaryConfig {
+	return c.withIdentity(c.ConsumerSASLUser, c.ConsumerSASLPassword, c.ConsumerTLSClientCert, c.ConsumerTLSClientKey)
+}
+
+// withIdentity returns a copy of the canary configuration overriding the SASL credentials and the TLS client
+// certificate as pairs, so that a role specific user is never mixed with the shared password (and the same for the key)
+func (c *CanaryConfig) withIdentity(saslUser string, saslPassword string, tlsClientCert string, tlsClientKey string) *CanaryConfig {
+	identity := *c
+	if saslUser != "" || saslPassword != "" {
+		identity.SASLUser = saslUser
+		identity.SASLPassword = saslPassword
+	}
+	if tlsClientCert != "" || tlsClientKey != "" {
+		identity.TLSClientCert = tlsClientCert
+		identity.TLSClientKey = tlsClientKey
+	}
+	return &identity
+}
+
 func lookupStringEnv(envVar string, defaultValue string) string {
 	envVarValue, ok := os.LookupEnv(envVar)
 	if !ok {
@@ -202,6 +755,15 @@ func lookupIntEnv(envVar string, defaultValue int) int {
 	return intVal
 }
 
+func lookupFloatEnv(envVar string, defaultValue float64) float64 {
+	envVarValue, ok := os.LookupEnv(envVar)
+	if !ok {
+		return defaultValue
+	}
+	floatVal, _ := strconv.ParseFloat(envVarValue, 64)
+	return floatVal
+}
+
 func lookupBoolEnv(envVar string, defaultValue bool) bool {
 	envVarValue, ok := os.LookupEnv(envVar)
 	if !ok {
@@ -211,6 +773,25 @@ func lookupBoolEnv(envVar string, defaultValue bool) bool {
 	return boolVal
 }
 
+func bootstrapServers(bootstrapServersConfig string) []string {
+	if len(bootstrapServersConfig) == 0 {
+		return nil
+	}
+	return strings.Split(bootstrapServersConfig, ",")
+}
+
+// checkNames returns the comma separated check names, nil if none
+func checkNames(checkNamesConfig string) []string {
+	if len(checkNamesConfig) == 0 {
+		return nil
+	}
+	names := strings.Split(checkNamesConfig, ",")
+	for i := range names {
+		names[i] = strings.TrimSpace(names[i])
+	}
+	return names
+}
+
 func latencyBuckets(bucketsConfig string) []float64 {
 	sBuckets := strings.Split(bucketsConfig, ",")
 	fBuckets := make([]float64, len(sBuckets))
@@ -246,6 +827,56 @@ func topicConfig(topicConfig string) map[string]string {
 	return mapTopicConfig
 }
 
+// connectionCheckListeners parses the additional listeners configuration, as a list of
+// <name>=<bootstrap servers>[@<interval ms>] separated by ";"
+func connectionCheckListeners(listenersConfig string) []ConnectionCheckListener {
+	if len(listenersConfig) == 0 {
+		return nil
+	}
+
+	listeners := make([]ConnectionCheckListener, 0)
+	for _, listenerConfig := range strings.Split(listenersConfig, ";") {
+		// allows trailing or consecutive ";"
+		if len(listenerConfig) == 0 {
+			continue
+		}
+		kv := strings.Split(listenerConfig, "=")
+		if len(kv) != 2 || len(kv[0]) == 0 || len(kv[1]) == 0 {
+			panic(fmt.Errorf("error parsing connection check listeners [%s]: [%s] is not a valid listener", listenersConfig, listenerConfig))
+		}
+		listener := ConnectionCheckListener{Name: kv[0]}
+		servers := kv[1]
+		if i := strings.LastIndex(servers, "@"); i >= 0 {
+			interval, err := strconv.Atoi(servers[i+1:])
+			if err != nil || interval <= 0 {
+				panic(fmt.Errorf("error parsing connection check listeners [%s]: [%s] has not a valid interval", listenersConfig, listenerConfig))
+			}
+			listener.Interval = time.Duration(interval)
+			servers = servers[:i]
+		}
+		listener.BootstrapServers = strings.Split(servers, ",")
+		listeners = append(listeners, listener)
+	}
+	return listeners
+}
+
+// successRatioWindows parses the success ratio windows configuration, as a list of windows in ms separated by ","
+func successRatioWindows(windowsConfig string) []time.Duration {
+	if len(windowsConfig) == 0 {
+		return nil
+	}
+
+	windows := make([]time.Duration, 0)
+	for _, windowConfig := range strings.Split(windowsConfig, ",") {
+		window, err := strconv.Atoi(strings.TrimSpace(windowConfig))
+		if err != nil || window <= 0 {
+			panic(fmt.Errorf("error parsing success ratio windows [%s]: [%s] is not a valid window", windowsConfig, windowConfig))
+		}
+		windows = append(windows, time.Duration(window))
+	}
+	return windows
+}
+
 func (c CanaryConfig) String() string {
 
 	// just using placeholders for certs/keys (content or paths)
@@ -275,16 +906,133 @@ func (c CanaryConfig) String() string {
 		}
 	}
 
+	// proxy credentials could be in the URL as well
+	ProxyURL := c.ProxyURL
+	if u, err := url.Parse(c.ProxyURL); err == nil && u.User != nil {
+		ProxyURL = u.Redacted()
+	}
+	ProxyUsername := ""
+	if c.ProxyUsername != "" {
+		ProxyUsername = "[Proxy user]"
+	}
+	ProxyPassword := ""
+	if c.ProxyPassword != "" {
+		ProxyPassword = "[Proxy password]"
+	}
+
+	HTTPAuthToken := ""
+	if c.HTTPAuthToken != "" {
+		HTTPAuthToken = "[HTTP auth token]"
+	}
+	HTTPTLSCert := ""
+	if c.HTTPTLSCert != "" {
+		HTTPTLSCert = "[HTTP server cert]"
+	}
+	HTTPTLSKey := ""
+	if c.HTTPTLSKey != "" {
+		HTTPTLSKey = "[HTTP server key]"
+	}
+	HTTPTLSClientCA := ""
+	if c.HTTPTLSClientCA != "" {
+		HTTPTLSClientCA = "[HTTP client CA cert]"
+	}
+
+	// the producer and consumer specific identities
+	ProducerSASLUser := ""
+	if c.ProducerSASLUser != "" {
+		ProducerSASLUser = "[Producer SASL user]"
+	}
+	ProducerSASLPassword := ""
+	if c.ProducerSASLPassword != "" {
+		ProducerSASLPassword = "[Producer SASL password]"
+	}
+	ProducerTLSClientCert := ""
+	if c.ProducerTLSClientCert != "" {
+		ProducerTLSClientCert = "[Producer client cert]"
+	}
+	ProducerTLSClientKey := ""
+	if c.ProducerTLSClientKey != "" {
+		ProducerTLSClientKey = "[Producer client key]"
+	}
+	ConsumerSASLUser := ""
+	if c.ConsumerSASLUser != "" {
+		ConsumerSASLUser = "[Consumer SASL user]"
+	}
+	ConsumerSASLPassword := ""
+	if c.ConsumerSASLPassword != "" {
+		ConsumerSASLPassword = "[Consumer SASL password]"
+	}
+	ConsumerTLSClientCert := ""
+	if c.ConsumerTLSClientCert != "" {
+		ConsumerTLSClientCert = "[Consumer client cert]"
+	}
+	ConsumerTLSClientKey := ""
+	if c.ConsumerTLSClientKey != "" {
+		ConsumerTLSClientKey = "[Consumer client key]"
+	}
+
 	return fmt.Sprintf("{BootstrapServers:%s, BootstrapBackoffMaxAttempts:%d, BootstrapBackoffScale:%d, Topic:%s, TopicConfig:%v, ReconcileInterval:%d ms, "+
 		"ClientID:%s, ConsumerGroupID:%s, ProducerLatencyBuckets:%v, EndToEndLatencyBuckets:%v, ExpectedClusterSize:%d, KafkaVersion:%s,"+
 		"TLSEnabled:%t, TLSCACert:%s, TLSClientCert:%s, TLSClientKey:%s, TLSInsecureSkipVerify:%t,"+
 		"SASLMechanism:%s, SASLUser:%s, SASLPassword:%s, ConnectionCheckInterval:%d ms, ConnectionCheckLatencyBuckets:%v, StatusCheckInterval:%d ms, StatusTimeWindow:%d ms,"+
-		"DynamicConfigFile: %s, DynamicCanaryConfig: %s, DynamicConfigWatcherInterval: %d ms}",
+		"DynamicConfigFile: %s, DynamicCanaryConfig: %s, DynamicConfigWatcherInterval: %d ms,"+
+		"TargetBootstrapServers:%s, SourceClusterAlias:%s, TargetTopic:%s, ReplicationLatencyBuckets:%v, "+
+		"GrpcServerEnabled:%t, GrpcServerPort:%d, OnDemandCheckTimeout:%d ms, "+
+		"KafkaClientBackend:%s, ShutdownDrainTimeout:%d ms, DeleteTopicOnShutdown:%t, "+
+		"BootstrapBackoffMaxWait:%d ms, CircuitBreakerThreshold:%d, AdminLatencyBuckets:%v, NativeHistogramsEnabled:%t, MetricsPartitionsLimit:%d, "+
+		"SLOWindow:%d ms, SLOAvailabilityTarget:%.3f%%, SLOLatencyTarget:%.3f%%, SLOLatencyThreshold:%d ms, EventsBufferSize:%d, "+
+		"SoakDuration:%d ms, SoakMessageCount:%d, SoakMinConsumedPercentage:%.2f%%, SoakMaxLatency:%d ms, ChaosLeaderElectionInterval:%d ms, "+
+		"ReconcileJitterPercentage:%.2f%%, ReconcileMaxInterval:%d ms, "+
+		"ProducerRequestTimeout:%d ms, AdminTimeout:%d ms, DialTimeout:%d ms, MetadataRefreshTimeout:%d ms, DNSReResolutionThreshold:%d, "+
+		"ProxyURL:%s, ProxyUsername:%s, ProxyPassword:%s, TLSServerName:%s, TLSSkipHostnameVerify:%t, "+
+		"ConsumerRackID:%s, PerBrokerCheckEnabled:%t, StatusHistoryFile:%s, "+
+		"HTTPAuthToken:%s, HTTPTLSCert:%s, HTTPTLSKey:%s, HTTPTLSClientCA:%s, "+
+		"ProducerLatencyLabels:%s, SaramaLogLevel:%s, "+
+		"ConsumerGroupCheckInterval:%d ms, ConsumerGroupExpectedMembers:%d, ConsumerGroupExpectedStrategy:%s, "+
+		"ProducerLinger:%d ms, ProducerBatchMessages:%d, ProducerMaxMessageBytes:%d, "+
+		"ConsumerFetchMinBytes:%d, ConsumerFetchDefaultBytes:%d, ConsumerFetchMaxBytes:%d, ConsumerFetchMaxWait:%d ms, "+
+		"ProducerSASLUser:%s, ProducerSASLPassword:%s, ProducerTLSClientCert:%s, ProducerTLSClientKey:%s, "+
+		"ConsumerSASLUser:%s, ConsumerSASLPassword:%s, ConsumerTLSClientCert:%s, ConsumerTLSClientKey:%s, "+
+		"DelayedConsumeDelay:%d ms, DelayedConsumeInterval:%d ms, "+
+		"TieredStorageCheckInterval:%d ms, TieredStorageCheckAge:%d ms, TieredStorageLatencyBuckets:%v, "+
+		"EventsTopic:%s, RebalanceStormThreshold:%d, RebalanceStormWindow:%d ms, "+
+		"ConnectionCheckListeners:%v, AdvertisedListenerCheckInterval:%d ms, "+
+		"LatencyWarningThreshold:%d ms, LatencyCriticalThreshold:%d ms, "+
+		"ProducerAcks:%s, ProducerAcksComparisonEnabled:%t, "+
+		"OrphanCleanupPrefix:%s, OrphanCleanupDeleteEnabled:%t, OrphanCleanupIdleTime:%d ms, "+
+		"RateLimit:%d ops/s, BrokerRollWindow:%d ms, SuccessRatioWindows:%d ms, SafeToRollWindow:%d ms, "+
+		"MessagePayloadTemplate:%s, ConsumerProcessingDelay:%d ms, ChecksDisabled:%v}",
 		c.BootstrapServers, c.BootstrapBackoffMaxAttempts, c.BootstrapBackoffScale, c.Topic, c.TopicConfig, c.ReconcileInterval, c.ClientID, c.ConsumerGroupID,
 		c.ProducerLatencyBuckets, c.EndToEndLatencyBuckets, c.ExpectedClusterSize, c.KafkaVersion,
 		c.TLSEnabled, TLSCACert, TLSClientCert, TLSClientKey, c.TLSInsecureSkipVerify, c.SASLMechanism, SASLUser, SASLPassword,
 		c.ConnectionCheckInterval, c.ConnectionCheckLatencyBuckets, c.StatusCheckInterval, c.StatusTimeWindow,
-		c.DynamicConfigFile, c.DynamicCanaryConfig, c.DynamicConfigWatcherInterval)
+		c.DynamicConfigFile, c.DynamicCanaryConfig, c.DynamicConfigWatcherInterval,
+		c.TargetBootstrapServers, c.SourceClusterAlias, c.TargetTopic, c.ReplicationLatencyBuckets,
+		c.GrpcServerEnabled, c.GrpcServerPort, c.OnDemandCheckTimeout,
+		c.KafkaClientBackend, c.ShutdownDrainTimeout, c.DeleteTopicOnShutdown,
+		c.BootstrapBackoffMaxWait, c.CircuitBreakerThreshold, c.AdminLatencyBuckets, c.NativeHistogramsEnabled, c.MetricsPartitionsLimit,
+		c.SLOWindow, c.SLOAvailabilityTarget, c.SLOLatencyTarget, c.SLOLatencyThreshold, c.EventsBufferSize,
+		c.SoakDuration, c.SoakMessageCount, c.SoakMinConsumedPercentage, c.SoakMaxLatency, c.ChaosLeaderElectionInterval,
+		c.ReconcileJitterPercentage, c.ReconcileMaxInterval,
+		c.ProducerRequestTimeout, c.AdminTimeout, c.DialTimeout, c.MetadataRefreshTimeout, c.DNSReResolutionThreshold,
+		ProxyURL, ProxyUsername, ProxyPassword, c.TLSServerName, c.TLSSkipHostnameVerify,
+		c.ConsumerRackID, c.PerBrokerCheckEnabled, c.StatusHistoryFile,
+		HTTPAuthToken, HTTPTLSCert, HTTPTLSKey, HTTPTLSClientCA,
+		c.ProducerLatencyLabels, c.SaramaLogLevel,
+		c.ConsumerGroupCheckInterval, c.ConsumerGroupExpectedMembers, c.ConsumerGroupExpectedStrategy,
+		c.ProducerLinger, c.ProducerBatchMessages, c.ProducerMaxMessageBytes,
+		c.ConsumerFetchMinBytes, c.ConsumerFetchDefaultBytes, c.ConsumerFetchMaxBytes, c.ConsumerFetchMaxWait,
+		ProducerSASLUser, ProducerSASLPassword, ProducerTLSClientCert, ProducerTLSClientKey,
+		ConsumerSASLUser, ConsumerSASLPassword, ConsumerTLSClientCert, ConsumerTLSClientKey,
+		c.DelayedConsumeDelay, c.DelayedConsumeInterval,
+		c.TieredStorageCheckInterval, c.TieredStorageCheckAge, c.TieredStorageLatencyBuckets,
+		c.EventsTopic, c.RebalanceStormThreshold, c.RebalanceStormWindow,
+		c.ConnectionCheckListeners, c.AdvertisedListenerCheckInterval,
+		c.LatencyWarningThreshold, c.LatencyCriticalThreshold,
+		c.ProducerAcks, c.ProducerAcksComparisonEnabled,
+		c.OrphanCleanupPrefix, c.OrphanCleanupDeleteEnabled, c.OrphanCleanupIdleTime,
+		c.RateLimit, c.BrokerRollWindow, c.SuccessRatioWindows, c.SafeToRollWindow,
+		c.MessagePayloadTemplate, c.ConsumerProcessingDelay, c.ChecksDisabled)
 }
 func exporterTypeTracing() string {
 	exporterType := lookupStringEnv(ExporterTypeTracing, ExporterTypeTracingDefault)
@@ -10,6 +10,7 @@ package config
 
 import (
 	"os"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -48,6 +49,97 @@ func TestConfigDefault(t *testing.T) {
 	assertBucketsConfigParameter(c.ConnectionCheckLatencyBuckets, connectionCheckLatencyBucketsDefault, t)
 	assertDurationConfigParameter(c.StatusCheckInterval, StatusCheckIntervalDefault, t)
 	assertDurationConfigParameter(c.StatusTimeWindow, StatusTimeWindowDefault, t)
+	assertStringSlicesConfigParameter(c.TargetBootstrapServers, nil, t)
+	assertStringConfigParameter(c.SourceClusterAlias, SourceClusterAliasDefault, t)
+	assertStringConfigParameter(c.TargetTopic, SourceClusterAliasDefault+"."+TopicDefault, t)
+	replicationLatencyBucketsDefault := latencyBuckets(ReplicationLatencyBucketsDefault)
+	assertBucketsConfigParameter(c.ReplicationLatencyBuckets, replicationLatencyBucketsDefault, t)
+	assertBoolConfigParameter(c.GrpcServerEnabled, GrpcServerEnabledDefault, t)
+	assertIntConfigParameter(c.GrpcServerPort, GrpcServerPortDefault, t)
+	assertDurationConfigParameter(c.OnDemandCheckTimeout, OnDemandCheckTimeoutDefault, t)
+	assertStringConfigParameter(c.KafkaClientBackend, KafkaClientBackendDefault, t)
+	assertDurationConfigParameter(c.ShutdownDrainTimeout, ShutdownDrainTimeoutDefault, t)
+	assertBoolConfigParameter(c.DeleteTopicOnShutdown, DeleteTopicOnShutdownDefault, t)
+	assertDurationConfigParameter(c.BootstrapBackoffMaxWait, BootstrapBackoffMaxWaitDefault, t)
+	assertIntConfigParameter(c.CircuitBreakerThreshold, CircuitBreakerThresholdDefault, t)
+	adminLatencyBucketsDefault := latencyBuckets(AdminLatencyBucketsDefault)
+	assertBucketsConfigParameter(c.AdminLatencyBuckets, adminLatencyBucketsDefault, t)
+	assertBoolConfigParameter(c.NativeHistogramsEnabled, NativeHistogramsEnabledDefault, t)
+	assertIntConfigParameter(c.MetricsPartitionsLimit, MetricsPartitionsLimitDefault, t)
+	assertDurationConfigParameter(c.SLOWindow, SLOWindowDefault, t)
+	assertFloatConfigParameter(c.SLOAvailabilityTarget, SLOAvailabilityTargetDefault, t)
+	assertFloatConfigParameter(c.SLOLatencyTarget, SLOLatencyTargetDefault, t)
+	assertDurationConfigParameter(c.SLOLatencyThreshold, SLOLatencyThresholdDefault, t)
+	assertIntConfigParameter(c.EventsBufferSize, EventsBufferSizeDefault, t)
+	assertDurationConfigParameter(c.SoakDuration, SoakDurationDefault, t)
+	assertIntConfigParameter(c.SoakMessageCount, SoakMessageCountDefault, t)
+	assertFloatConfigParameter(c.SoakMinConsumedPercentage, SoakMinConsumedPercentageDefault, t)
+	assertDurationConfigParameter(c.SoakMaxLatency, SoakMaxLatencyDefault, t)
+	assertDurationConfigParameter(c.ChaosLeaderElectionInterval, ChaosLeaderElectionIntervalDefault, t)
+	assertFloatConfigParameter(c.ReconcileJitterPercentage, ReconcileJitterPercentageDefault, t)
+	assertDurationConfigParameter(c.ReconcileMaxInterval, ReconcileMaxIntervalDefault, t)
+	assertDurationConfigParameter(c.ProducerRequestTimeout, ProducerRequestTimeoutDefault, t)
+	assertDurationConfigParameter(c.AdminTimeout, AdminTimeoutDefault, t)
+	assertDurationConfigParameter(c.DialTimeout, DialTimeoutDefault, t)
+	assertDurationConfigParameter(c.MetadataRefreshTimeout, MetadataRefreshTimeoutDefault, t)
+	assertIntConfigParameter(c.DNSReResolutionThreshold, DNSReResolutionThresholdDefault, t)
+	assertStringConfigParameter(c.ProxyURL, ProxyURLDefault, t)
+	assertStringConfigParameter(c.ProxyUsername, ProxyUsernameDefault, t)
+	assertStringConfigParameter(c.ProxyPassword, ProxyPasswordDefault, t)
+	assertStringConfigParameter(c.TLSServerName, TLSServerNameDefault, t)
+	assertBoolConfigParameter(c.TLSSkipHostnameVerify, TLSSkipHostnameVerifyDefault, t)
+	assertStringConfigParameter(c.ConsumerRackID, ConsumerRackIDDefault, t)
+	assertBoolConfigParameter(c.PerBrokerCheckEnabled, PerBrokerCheckEnabledDefault, t)
+	assertStringConfigParameter(c.StatusHistoryFile, StatusHistoryFileDefault, t)
+	assertStringConfigParameter(c.HTTPAuthToken, HTTPAuthTokenDefault, t)
+	assertStringConfigParameter(c.HTTPTLSCert, HTTPTLSCertDefault, t)
+	assertStringConfigParameter(c.HTTPTLSKey, HTTPTLSKeyDefault, t)
+	assertStringConfigParameter(c.HTTPTLSClientCA, HTTPTLSClientCADefault, t)
+	assertStringConfigParameter(c.ProducerLatencyLabels, ProducerLatencyLabelsDefault, t)
+	assertStringConfigParameter(c.SaramaLogLevel, SaramaLogLevelDefault, t)
+	assertDurationConfigParameter(c.ConsumerGroupCheckInterval, ConsumerGroupCheckIntervalDefault, t)
+	assertIntConfigParameter(c.ConsumerGroupExpectedMembers, ConsumerGroupExpectedMembersDefault, t)
+	assertStringConfigParameter(c.ConsumerGroupExpectedStrategy, ConsumerGroupExpectedStrategyDefault, t)
+	assertDurationConfigParameter(c.ProducerLinger, ProducerLingerDefault, t)
+	assertIntConfigParameter(c.ProducerBatchMessages, ProducerBatchMessagesDefault, t)
+	assertIntConfigParameter(c.ProducerMaxMessageBytes, ProducerMaxMessageBytesDefault, t)
+	assertIntConfigParameter(c.ConsumerFetchMinBytes, ConsumerFetchMinBytesDefault, t)
+	assertIntConfigParameter(c.ConsumerFetchDefaultBytes, ConsumerFetchDefaultBytesDefault, t)
+	assertIntConfigParameter(c.ConsumerFetchMaxBytes, ConsumerFetchMaxBytesDefault, t)
+	assertDurationConfigParameter(c.ConsumerFetchMaxWait, ConsumerFetchMaxWaitDefault, t)
+	assertStringConfigParameter(c.ProducerSASLUser, ProducerSASLUserDefault, t)
+	assertStringConfigParameter(c.ProducerSASLPassword, ProducerSASLPasswordDefault, t)
+	assertStringConfigParameter(c.ProducerTLSClientCert, ProducerTLSClientCertDefault, t)
+	assertStringConfigParameter(c.ProducerTLSClientKey, ProducerTLSClientKeyDefault, t)
+	assertStringConfigParameter(c.ConsumerSASLUser, ConsumerSASLUserDefault, t)
+	assertStringConfigParameter(c.ConsumerSASLPassword, ConsumerSASLPasswordDefault, t)
+	assertStringConfigParameter(c.ConsumerTLSClientCert, ConsumerTLSClientCertDefault, t)
+	assertStringConfigParameter(c.ConsumerTLSClientKey, ConsumerTLSClientKeyDefault, t)
+	assertDurationConfigParameter(c.DelayedConsumeDelay, DelayedConsumeDelayDefault, t)
+	assertDurationConfigParameter(c.DelayedConsumeInterval, DelayedConsumeIntervalDefault, t)
+	assertDurationConfigParameter(c.TieredStorageCheckInterval, TieredStorageCheckIntervalDefault, t)
+	assertDurationConfigParameter(c.TieredStorageCheckAge, TieredStorageCheckAgeDefault, t)
+	tieredStorageLatencyBucketsDefault := latencyBuckets(TieredStorageLatencyBucketsDefault)
+	assertBucketsConfigParameter(c.TieredStorageLatencyBuckets, tieredStorageLatencyBucketsDefault, t)
+	assertStringConfigParameter(c.EventsTopic, EventsTopicDefault, t)
+	assertIntConfigParameter(c.RebalanceStormThreshold, RebalanceStormThresholdDefault, t)
+	assertDurationConfigParameter(c.RebalanceStormWindow, RebalanceStormWindowDefault, t)
+	assertDurationConfigParameter(c.AdvertisedListenerCheckInterval, AdvertisedListenerCheckIntervalDefault, t)
+	assertDurationConfigParameter(c.LatencyWarningThreshold, LatencyWarningThresholdDefault, t)
+	assertDurationConfigParameter(c.LatencyCriticalThreshold, LatencyCriticalThresholdDefault, t)
+	assertIntConfigParameter(*c.DynamicCanaryConfig.LatencyWarningThreshold, LatencyWarningThresholdDefault, t)
+	assertIntConfigParameter(*c.DynamicCanaryConfig.LatencyCriticalThreshold, LatencyCriticalThresholdDefault, t)
+	assertStringConfigParameter(c.ProducerAcks, ProducerAcksDefault, t)
+	assertBoolConfigParameter(c.ProducerAcksComparisonEnabled, ProducerAcksComparisonEnabledDefault, t)
+	assertStringConfigParameter(c.OrphanCleanupPrefix, OrphanCleanupPrefixDefault, t)
+	assertIntConfigParameter(c.RateLimit, RateLimitDefault, t)
+	assertDurationConfigParameter(c.BrokerRollWindow, BrokerRollWindowDefault, t)
+	assertDurationConfigParameter(c.SafeToRollWindow, SafeToRollWindowDefault, t)
+	assertStringConfigParameter(c.MessagePayloadTemplate, MessagePayloadTemplateDefault, t)
+	assertDurationConfigParameter(c.ConsumerProcessingDelay, ConsumerProcessingDelayDefault, t)
+	assertStringSlicesConfigParameter(c.ChecksDisabled, nil, t)
+	assertBoolConfigParameter(c.OrphanCleanupDeleteEnabled, OrphanCleanupDeleteEnabledDefault, t)
+	assertDurationConfigParameter(c.OrphanCleanupIdleTime, OrphanCleanupIdleTimeDefault, t)
 }
 
 func TestConfigCustom(t *testing.T) {
@@ -77,6 +169,91 @@ func TestConfigCustom(t *testing.T) {
 	os.Setenv(ConnectionCheckLatencyBucketsEnvVar, "200,400,800")
 	os.Setenv(StatusCheckIntervalEnvVar, "30000")
 	os.Setenv(StatusTimeWindowEnvVar, "200000")
+	os.Setenv(TargetBootstrapServersEnvVar, "kafka-target-broker-1:9092,kafka-target-broker-2:9092")
+	os.Setenv(SourceClusterAliasEnvVar, "my-source")
+	os.Setenv(ReplicationLatencyBucketsEnvVar, "1000,2000,4000")
+	os.Setenv(GrpcServerEnabledEnvVar, "true")
+	os.Setenv(GrpcServerPortEnvVar, "9091")
+	os.Setenv(OnDemandCheckTimeoutEnvVar, "5000")
+	os.Setenv(KafkaClientBackendEnvVar, "franz-go")
+	os.Setenv(ShutdownDrainTimeoutEnvVar, "5000")
+	os.Setenv(DeleteTopicOnShutdownEnvVar, "true")
+	os.Setenv(BootstrapBackoffMaxWaitEnvVar, "60000")
+	os.Setenv(CircuitBreakerThresholdEnvVar, "5")
+	os.Setenv(AdminLatencyBucketsEnvVar, "100,1000,10000")
+	os.Setenv(NativeHistogramsEnabledEnvVar, "true")
+	os.Setenv(MetricsPartitionsLimitEnvVar, "10")
+	os.Setenv(SLOWindowEnvVar, "86400000")
+	os.Setenv(SLOAvailabilityTargetEnvVar, "99.5")
+	os.Setenv(SLOLatencyTargetEnvVar, "95")
+	os.Setenv(SLOLatencyThresholdEnvVar, "200")
+	os.Setenv(EventsBufferSizeEnvVar, "1000")
+	os.Setenv(SoakDurationEnvVar, "3600000")
+	os.Setenv(SoakMessageCountEnvVar, "1000")
+	os.Setenv(SoakMinConsumedPercentageEnvVar, "99.5")
+	os.Setenv(SoakMaxLatencyEnvVar, "1000")
+	os.Setenv(ChaosLeaderElectionIntervalEnvVar, "600000")
+	os.Setenv(ReconcileJitterPercentageEnvVar, "10")
+	os.Setenv(ReconcileMaxIntervalEnvVar, "300000")
+	os.Setenv(ProducerRequestTimeoutEnvVar, "5000")
+	os.Setenv(AdminTimeoutEnvVar, "5000")
+	os.Setenv(DialTimeoutEnvVar, "3000")
+	os.Setenv(MetadataRefreshTimeoutEnvVar, "10000")
+	os.Setenv(DNSReResolutionThresholdEnvVar, "3")
+	os.Setenv(ProxyURLEnvVar, "socks5://proxy:1080")
+	os.Setenv(ProxyUsernameEnvVar, "my-user")
+	os.Setenv(ProxyPasswordEnvVar, "my-password")
+	os.Setenv(TLSServerNameEnvVar, "my-cluster-kafka-bootstrap")
+	os.Setenv(TLSSkipHostnameVerifyEnvVar, "true")
+	os.Setenv(ConsumerRackIDEnvVar, "zone-a")
+	os.Setenv(PerBrokerCheckEnabledEnvVar, "true")
+	os.Setenv(StatusHistoryFileEnvVar, "/var/lib/canary/status.json")
+	os.Setenv(HTTPAuthTokenEnvVar, "my-token")
+	os.Setenv(HTTPTLSCertEnvVar, "my-cert")
+	os.Setenv(HTTPTLSKeyEnvVar, "my-key")
+	os.Setenv(HTTPTLSClientCAEnvVar, "my-ca")
+	os.Setenv(ProducerLatencyLabelsEnvVar, "partition,broker")
+	os.Setenv(SaramaLogLevelEnvVar, "warning")
+	os.Setenv(ConsumerGroupCheckIntervalEnvVar, "60000")
+	os.Setenv(ConsumerGroupExpectedMembersEnvVar, "2")
+	os.Setenv(ConsumerGroupExpectedStrategyEnvVar, "roundrobin")
+	os.Setenv(ProducerLingerEnvVar, "5")
+	os.Setenv(ProducerBatchMessagesEnvVar, "10")
+	os.Setenv(ProducerMaxMessageBytesEnvVar, "2000000")
+	os.Setenv(ConsumerFetchMinBytesEnvVar, "1024")
+	os.Setenv(ConsumerFetchDefaultBytesEnvVar, "524288")
+	os.Setenv(ConsumerFetchMaxBytesEnvVar, "10485760")
+	os.Setenv(ConsumerFetchMaxWaitEnvVar, "100")
+	os.Setenv(ProducerSASLUserEnvVar, "producer-user")
+	os.Setenv(ProducerSASLPasswordEnvVar, "producer-password")
+	os.Setenv(ProducerTLSClientCertEnvVar, "producer-cert")
+	os.Setenv(ProducerTLSClientKeyEnvVar, "producer-key")
+	os.Setenv(ConsumerSASLUserEnvVar, "consumer-user")
+	os.Setenv(ConsumerSASLPasswordEnvVar, "consumer-password")
+	os.Setenv(ConsumerTLSClientCertEnvVar, "consumer-cert")
+	os.Setenv(ConsumerTLSClientKeyEnvVar, "consumer-key")
+	os.Setenv(DelayedConsumeDelayEnvVar, "3600000")
+	os.Setenv(DelayedConsumeIntervalEnvVar, "30000")
+	os.Setenv(TieredStorageCheckIntervalEnvVar, "60000")
+	os.Setenv(TieredStorageCheckAgeEnvVar, "7200000")
+	os.Setenv(TieredStorageLatencyBucketsEnvVar, "100,1000,10000")
+	os.Setenv(EventsTopicEnvVar, "canary-events")
+	os.Setenv(RebalanceStormThresholdEnvVar, "5")
+	os.Setenv(RebalanceStormWindowEnvVar, "60000")
+	os.Setenv(AdvertisedListenerCheckIntervalEnvVar, "300000")
+	os.Setenv(LatencyWarningThresholdEnvVar, "500")
+	os.Setenv(LatencyCriticalThresholdEnvVar, "2000")
+	os.Setenv(ProducerAcksEnvVar, "1")
+	os.Setenv(ProducerAcksComparisonEnabledEnvVar, "true")
+	os.Setenv(OrphanCleanupPrefixEnvVar, "__strimzi_canary-")
+	os.Setenv(RateLimitEnvVar, "50")
+	os.Setenv(BrokerRollWindowEnvVar, "600000")
+	os.Setenv(SafeToRollWindowEnvVar, "120000")
+	os.Setenv(MessagePayloadTemplateEnvVar, "{{.ProducerID}}-{{.MessageID}}")
+	os.Setenv(ConsumerProcessingDelayEnvVar, "500")
+	os.Setenv(ChecksDisabledEnvVar, "chaos,connection_check")
+	os.Setenv(OrphanCleanupDeleteEnabledEnvVar, "true")
+	os.Setenv(OrphanCleanupIdleTimeEnvVar, "600000")
 	c := NewCanaryConfig()
 	bootstrapServers := strings.Split("kafka-broker-1:9092,kafka-broker-2:9092", ",")
 	assertStringSlicesConfigParameter(c.BootstrapServers, bootstrapServers, t)
@@ -109,6 +286,181 @@ func TestConfigCustom(t *testing.T) {
 	assertBucketsConfigParameter(c.ConnectionCheckLatencyBuckets, connectionCheckLatencyBuckets, t)
 	assertDurationConfigParameter(c.StatusCheckInterval, 30000, t)
 	assertDurationConfigParameter(c.StatusTimeWindow, 200000, t)
+	targetBootstrapServers := strings.Split("kafka-target-broker-1:9092,kafka-target-broker-2:9092", ",")
+	assertStringSlicesConfigParameter(c.TargetBootstrapServers, targetBootstrapServers, t)
+	assertStringConfigParameter(c.SourceClusterAlias, "my-source", t)
+	assertStringConfigParameter(c.TargetTopic, "my-source.my-strimzi-canary-topic", t)
+	replicationLatencyBuckets := latencyBuckets("1000,2000,4000")
+	assertBucketsConfigParameter(c.ReplicationLatencyBuckets, replicationLatencyBuckets, t)
+	assertBoolConfigParameter(c.GrpcServerEnabled, true, t)
+	assertIntConfigParameter(c.GrpcServerPort, 9091, t)
+	assertDurationConfigParameter(c.OnDemandCheckTimeout, 5000, t)
+	assertStringConfigParameter(c.KafkaClientBackend, "franz-go", t)
+	assertDurationConfigParameter(c.ShutdownDrainTimeout, 5000, t)
+	assertBoolConfigParameter(c.DeleteTopicOnShutdown, true, t)
+	assertDurationConfigParameter(c.BootstrapBackoffMaxWait, 60000, t)
+	assertIntConfigParameter(c.CircuitBreakerThreshold, 5, t)
+	adminLatencyBuckets := latencyBuckets("100,1000,10000")
+	assertBucketsConfigParameter(c.AdminLatencyBuckets, adminLatencyBuckets, t)
+	assertBoolConfigParameter(c.NativeHistogramsEnabled, true, t)
+	assertIntConfigParameter(c.MetricsPartitionsLimit, 10, t)
+	assertDurationConfigParameter(c.SLOWindow, 86400000, t)
+	assertFloatConfigParameter(c.SLOAvailabilityTarget, 99.5, t)
+	assertFloatConfigParameter(c.SLOLatencyTarget, 95, t)
+	assertDurationConfigParameter(c.SLOLatencyThreshold, 200, t)
+	assertIntConfigParameter(c.EventsBufferSize, 1000, t)
+	assertDurationConfigParameter(c.SoakDuration, 3600000, t)
+	assertIntConfigParameter(c.SoakMessageCount, 1000, t)
+	assertFloatConfigParameter(c.SoakMinConsumedPercentage, 99.5, t)
+	assertDurationConfigParameter(c.SoakMaxLatency, 1000, t)
+	assertDurationConfigParameter(c.ChaosLeaderElectionInterval, 600000, t)
+	assertFloatConfigParameter(c.ReconcileJitterPercentage, 10.0, t)
+	assertDurationConfigParameter(c.ReconcileMaxInterval, 300000, t)
+	assertDurationConfigParameter(c.ProducerRequestTimeout, 5000, t)
+	assertDurationConfigParameter(c.AdminTimeout, 5000, t)
+	assertDurationConfigParameter(c.DialTimeout, 3000, t)
+	assertDurationConfigParameter(c.MetadataRefreshTimeout, 10000, t)
+	assertIntConfigParameter(c.DNSReResolutionThreshold, 3, t)
+	assertStringConfigParameter(c.ProxyURL, "socks5://proxy:1080", t)
+	assertStringConfigParameter(c.ProxyUsername, "my-user", t)
+	assertStringConfigParameter(c.ProxyPassword, "my-password", t)
+	assertStringConfigParameter(c.TLSServerName, "my-cluster-kafka-bootstrap", t)
+	assertBoolConfigParameter(c.TLSSkipHostnameVerify, true, t)
+	assertStringConfigParameter(c.ConsumerRackID, "zone-a", t)
+	assertBoolConfigParameter(c.PerBrokerCheckEnabled, true, t)
+	assertStringConfigParameter(c.StatusHistoryFile, "/var/lib/canary/status.json", t)
+	assertStringConfigParameter(c.HTTPAuthToken, "my-token", t)
+	assertStringConfigParameter(c.HTTPTLSCert, "my-cert", t)
+	assertStringConfigParameter(c.HTTPTLSKey, "my-key", t)
+	assertStringConfigParameter(c.HTTPTLSClientCA, "my-ca", t)
+	assertStringConfigParameter(c.ProducerLatencyLabels, "partition,broker", t)
+	assertStringConfigParameter(c.SaramaLogLevel, "warning", t)
+	assertDurationConfigParameter(c.ConsumerGroupCheckInterval, 60000, t)
+	assertIntConfigParameter(c.ConsumerGroupExpectedMembers, 2, t)
+	assertStringConfigParameter(c.ConsumerGroupExpectedStrategy, "roundrobin", t)
+	assertDurationConfigParameter(c.ProducerLinger, 5, t)
+	assertIntConfigParameter(c.ProducerBatchMessages, 10, t)
+	assertIntConfigParameter(c.ProducerMaxMessageBytes, 2000000, t)
+	assertIntConfigParameter(c.ConsumerFetchMinBytes, 1024, t)
+	assertIntConfigParameter(c.ConsumerFetchDefaultBytes, 524288, t)
+	assertIntConfigParameter(c.ConsumerFetchMaxBytes, 10485760, t)
+	assertDurationConfigParameter(c.ConsumerFetchMaxWait, 100, t)
+	assertStringConfigParameter(c.ProducerSASLUser, "producer-user", t)
+	assertStringConfigParameter(c.ProducerSASLPassword, "producer-password", t)
+	assertStringConfigParameter(c.ProducerTLSClientCert, "producer-cert", t)
+	assertStringConfigParameter(c.ProducerTLSClientKey, "producer-key", t)
+	assertStringConfigParameter(c.ConsumerSASLUser, "consumer-user", t)
+	assertStringConfigParameter(c.ConsumerSASLPassword, "consumer-password", t)
+	assertStringConfigParameter(c.ConsumerTLSClientCert, "consumer-cert", t)
+	assertStringConfigParameter(c.ConsumerTLSClientKey, "consumer-key", t)
+	assertDurationConfigParameter(c.DelayedConsumeDelay, 3600000, t)
+	assertDurationConfigParameter(c.DelayedConsumeInterval, 30000, t)
+	assertDurationConfigParameter(c.TieredStorageCheckInterval, 60000, t)
+	assertDurationConfigParameter(c.TieredStorageCheckAge, 7200000, t)
+	tieredStorageLatencyBuckets := latencyBuckets("100,1000,10000")
+	assertBucketsConfigParameter(c.TieredStorageLatencyBuckets, tieredStorageLatencyBuckets, t)
+	assertStringConfigParameter(c.EventsTopic, "canary-events", t)
+	assertIntConfigParameter(c.RebalanceStormThreshold, 5, t)
+	assertDurationConfigParameter(c.RebalanceStormWindow, 60000, t)
+	assertDurationConfigParameter(c.AdvertisedListenerCheckInterval, 300000, t)
+	assertDurationConfigParameter(c.LatencyWarningThreshold, 500, t)
+	assertDurationConfigParameter(c.LatencyCriticalThreshold, 2000, t)
+	assertIntConfigParameter(*c.DynamicCanaryConfig.LatencyWarningThreshold, 500, t)
+	assertIntConfigParameter(*c.DynamicCanaryConfig.LatencyCriticalThreshold, 2000, t)
+	assertStringConfigParameter(c.ProducerAcks, "1", t)
+	assertBoolConfigParameter(c.ProducerAcksComparisonEnabled, true, t)
+	assertStringConfigParameter(c.OrphanCleanupPrefix, "__strimzi_canary-", t)
+	assertIntConfigParameter(c.RateLimit, 50, t)
+	assertDurationConfigParameter(c.BrokerRollWindow, 600000, t)
+	assertDurationConfigParameter(c.SafeToRollWindow, 120000, t)
+	assertStringConfigParameter(c.MessagePayloadTemplate, "{{.ProducerID}}-{{.MessageID}}", t)
+	assertDurationConfigParameter(c.ConsumerProcessingDelay, 500, t)
+	assertStringSlicesConfigParameter(c.ChecksDisabled, []string{"chaos", "connection_check"}, t)
+	assertBoolConfigParameter(c.OrphanCleanupDeleteEnabled, true, t)
+	assertDurationConfigParameter(c.OrphanCleanupIdleTime, 600000, t)
+}
+
+func TestClientIdentity(t *testing.T) {
+	c := CanaryConfig{
+		SASLUser:              "user",
+		SASLPassword:          "password",
+		TLSClientCert:         "cert",
+		TLSClientKey:          "key",
+		ProducerSASLUser:      "producer-user",
+		ProducerSASLPassword:  "producer-password",
+		ConsumerTLSClientCert: "consumer-cert",
+		ConsumerTLSClientKey:  "consumer-key",
+	}
+
+	producer := c.ProducerIdentity()
+	assertStringConfigParameter(producer.SASLUser, "producer-user", t)
+	assertStringConfigParameter(producer.SASLPassword, "producer-password", t)
+	assertStringConfigParameter(producer.TLSClientCert, "cert", t)
+	assertStringConfigParameter(producer.TLSClientKey, "key", t)
+
+	consumer := c.ConsumerIdentity()
+	assertStringConfigParameter(consumer.SASLUser, "user", t)
+	assertStringConfigParameter(consumer.SASLPassword, "password", t)
+	assertStringConfigParameter(consumer.TLSClientCert, "consumer-cert", t)
+	assertStringConfigParameter(consumer.TLSClientKey, "consumer-key", t)
+
+	// the shared configuration is not changed
+	assertStringConfigParameter(c.SASLUser, "user", t)
+	assertStringConfigParameter(c.TLSClientCert, "cert", t)
+}
+
+func TestConnectionCheckListeners(t *testing.T) {
+	listeners := connectionCheckListeners("external=my-cluster-kafka-external-bootstrap:443@60000;route=broker-0.example.com:443,broker-1.example.com:443;")
+	if len(listeners) != 2 {
+		t.Fatalf("Listeners = %d, expected = 2", len(listeners))
+	}
+	assertStringConfigParameter(listeners[0].Name, "external", t)
+	assertStringSlicesConfigParameter(listeners[0].BootstrapServers, []string{"my-cluster-kafka-external-bootstrap:443"}, t)
+	assertDurationConfigParameter(listeners[0].Interval, 60000, t)
+	assertStringConfigParameter(listeners[1].Name, "route", t)
+	assertStringSlicesConfigParameter(listeners[1].BootstrapServers, []string{"broker-0.example.com:443", "broker-1.example.com:443"}, t)
+	// the connection check interval is used
+	assertDurationConfigParameter(listeners[1].Interval, 0, t)
+
+	if listeners := connectionCheckListeners(ConnectionCheckListenersDefault); listeners != nil {
+		t.Errorf("Listeners = %v, expected none", listeners)
+	}
+}
+
+func TestConnectionCheckListenersInvalid(t *testing.T) {
+	for _, listenersConfig := range []string{"my-cluster-kafka-external-bootstrap:443", "=broker:443", "external=broker:443@never"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Should have been panicked on [%s]!", listenersConfig)
+				}
+			}()
+			connectionCheckListeners(listenersConfig)
+		}()
+	}
+}
+
+func TestSuccessRatioWindows(t *testing.T) {
+	windows := successRatioWindows(SuccessRatioWindowsDefault)
+	if !reflect.DeepEqual(windows, []time.Duration{300000, 3600000}) {
+		t.Errorf("Windows = %v, expected = [300000 3600000]", windows)
+	}
+	if windows := successRatioWindows(""); windows != nil {
+		t.Errorf("Windows = %v, expected none", windows)
+	}
+}
+
+func TestSuccessRatioWindowsInvalid(t *testing.T) {
+	for _, windowsConfig := range []string{"5m", "300000,0", "300000,"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Should have been panicked on [%s]!", windowsConfig)
+				}
+			}()
+			successRatioWindows(windowsConfig)
+		}()
+	}
 }
 
 func TestTopicConfigurationNoKey(t *testing.T) {
@@ -169,6 +521,12 @@ func assertBucketsConfigParameter(value []float64, defaultValue []float64, t *te
 	}
 }
 
+func assertFloatConfigParameter(value float64, defaultValue float64, t *testing.T) {
+	if value != defaultValue {
+		t.Errorf("got = %f, want = %f", value, defaultValue)
+	}
+}
+
 func assertBoolConfigParameter(value bool, defaultValue bool, t *testing.T) {
 	if value != defaultValue {
 		t.Errorf("got = %t, want = %t", value, defaultValue)
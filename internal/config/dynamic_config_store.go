@@ -0,0 +1,78 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package config defining the canary configuration parameters
+package config
+
+import (
+	"sync"
+
+	"github.com/golang/glog"
+)
+
+// DynamicConfigStore holds the dynamic configuration currently applied, updated by the configuration file watcher
+// and patched through the HTTP API, so that the updates are applied one at a time.
+// Both the sources patch the current configuration, so the latest change of a value wins, whichever its source
+type DynamicConfigStore struct {
+	mutex   sync.Mutex
+	current DynamicCanaryConfig
+	// the configuration last read from the file, for patching only the values changed in the file
+	file      DynamicCanaryConfig
+	applyFunc func(config *DynamicCanaryConfig)
+}
+
+// NewDynamicConfigStore returns an instance of DynamicConfigStore applying the initial configuration
+func NewDynamicConfigStore(initial *DynamicCanaryConfig, applyFunc func(config *DynamicCanaryConfig)) *DynamicConfigStore {
+	s := &DynamicConfigStore{
+		current:   *initial,
+		applyFunc: applyFunc,
+	}
+	s.applyFunc(initial)
+	return s
+}
+
+// Apply patches the current dynamic configuration with the values changed in the configuration file, if valid.
+// The values not changed in the file, i.e. the ones patched through the HTTP API in the meantime, are kept while the
+// ones removed from it are unset, restoring the ones from the environment variables
+func (s *DynamicConfigStore) Apply(config *DynamicCanaryConfig) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := config.Validate(); err != nil {
+		glog.Warningf("Invalid dynamic config %s not applied: %v", config, err)
+		return
+	}
+	changed := config.Changed(&s.file)
+	removed := config.Removed(&s.file)
+	s.file = *config
+	merged, _ := s.current.Merge(changed).Unset(removed)
+	s.current = *merged
+	s.applyFunc(merged)
+}
+
+// Patch validates and applies the values set in the patch over the current dynamic configuration, unsetting the named
+// ones so that the ones from the environment variables are restored, and returns the resulting configuration.
+// Nothing is applied if any of the values is not valid
+func (s *DynamicConfigStore) Patch(patch *DynamicCanaryConfig, unset []string) (*DynamicCanaryConfig, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := patch.Validate(); err != nil {
+		return nil, err
+	}
+	merged, err := s.current.Merge(patch).Unset(unset)
+	if err != nil {
+		return nil, err
+	}
+	s.current = *merged
+	s.applyFunc(merged)
+	return merged, nil
+}
+
+// Current returns the current dynamic configuration
+func (s *DynamicConfigStore) Current() *DynamicCanaryConfig {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	current := s.current
+	return &current
+}
@@ -0,0 +1,77 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// +build unit_test
+
+// Package config defining the canary configuration parameters
+package config
+
+import (
+	"testing"
+)
+
+func TestDynamicConfigStoreFileAndPatch(t *testing.T) {
+	verbosity, interval, threshold := 0, 30000, 0
+	store := NewDynamicConfigStore(&DynamicCanaryConfig{VerbosityLogLevel: &verbosity, ReconcileInterval: &interval, CircuitBreakerThreshold: &threshold},
+		func(config *DynamicCanaryConfig) {})
+
+	fileVerbosity, fileInterval := 1, 20000
+	store.Apply(&DynamicCanaryConfig{VerbosityLogLevel: &fileVerbosity, ReconcileInterval: &fileInterval})
+
+	patchInterval := 10000
+	if _, err := store.Patch(&DynamicCanaryConfig{ReconcileInterval: &patchInterval}, nil); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// the file changes the verbosity only, the patched interval is kept and so the threshold missing from the file
+	fileVerbosity2 := 2
+	store.Apply(&DynamicCanaryConfig{VerbosityLogLevel: &fileVerbosity2, ReconcileInterval: &fileInterval})
+	current := store.Current()
+	if current.VerbosityLogLevel == nil || *current.VerbosityLogLevel != 2 {
+		t.Errorf("VerbosityLogLevel = %v, expected = 2", current.VerbosityLogLevel)
+	}
+	if current.ReconcileInterval == nil || *current.ReconcileInterval != 10000 {
+		t.Errorf("ReconcileInterval = %v, expected the patched 10000", current.ReconcileInterval)
+	}
+	if current.CircuitBreakerThreshold == nil || *current.CircuitBreakerThreshold != 0 {
+		t.Errorf("CircuitBreakerThreshold = %v, expected the initial 0", current.CircuitBreakerThreshold)
+	}
+
+	// the file changing the interval again wins over the patch
+	fileInterval2 := 40000
+	store.Apply(&DynamicCanaryConfig{VerbosityLogLevel: &fileVerbosity2, ReconcileInterval: &fileInterval2})
+	if current := store.Current(); *current.ReconcileInterval != 40000 {
+		t.Errorf("ReconcileInterval = %d, expected the file 40000", *current.ReconcileInterval)
+	}
+}
+
+func TestDynamicConfigStoreUnset(t *testing.T) {
+	interval, threshold := 30000, 3
+	var applied *DynamicCanaryConfig
+	store := NewDynamicConfigStore(&DynamicCanaryConfig{ReconcileInterval: &interval, CircuitBreakerThreshold: &threshold},
+		func(config *DynamicCanaryConfig) { applied = config })
+
+	// the value removed from the file is unset
+	fileInterval, fileThreshold := 20000, 5
+	store.Apply(&DynamicCanaryConfig{ReconcileInterval: &fileInterval, CircuitBreakerThreshold: &fileThreshold})
+	store.Apply(&DynamicCanaryConfig{ReconcileInterval: &fileInterval})
+	if applied.CircuitBreakerThreshold != nil {
+		t.Errorf("CircuitBreakerThreshold = %d, expected unset", *applied.CircuitBreakerThreshold)
+	}
+	if applied.ReconcileInterval == nil || *applied.ReconcileInterval != 20000 {
+		t.Errorf("ReconcileInterval = %v, expected the file 20000", applied.ReconcileInterval)
+	}
+
+	// the value unset through the patch
+	if _, err := store.Patch(&DynamicCanaryConfig{}, []string{"reconcileIntervalMs"}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if applied.ReconcileInterval != nil {
+		t.Errorf("ReconcileInterval = %d, expected unset", *applied.ReconcileInterval)
+	}
+	if _, err := store.Patch(&DynamicCanaryConfig{}, []string{"topic"}); err == nil {
+		t.Errorf("expected error unsetting a not dynamic configuration field")
+	}
+}
@@ -12,6 +12,9 @@ import (
 
 	"github.com/Shopify/sarama"
 	"github.com/strimzi/strimzi-canary/internal/config"
+	"github.com/twmb/franz-go/pkg/sasl"
+	"github.com/twmb/franz-go/pkg/sasl/plain"
+	"github.com/twmb/franz-go/pkg/sasl/scram"
 )
 
 func SetAuthConfig(canaryConfig *config.CanaryConfig, saramaConfig *sarama.Config) error {
@@ -42,3 +45,29 @@ func SetAuthConfig(canaryConfig *config.CanaryConfig, saramaConfig *sarama.Confi
 	}
 	return fmt.Errorf("SASL mechanism %s is not supported", canaryConfig.SASLMechanism)
 }
+
+// NewSASLMechanism returns the franz-go SASL mechanism for the configured SASL authentication
+func NewSASLMechanism(canaryConfig *config.CanaryConfig) (sasl.Mechanism, error) {
+
+	if canaryConfig.SASLMechanism == sarama.SASLTypePlaintext ||
+		canaryConfig.SASLMechanism == sarama.SASLTypeSCRAMSHA256 ||
+		canaryConfig.SASLMechanism == sarama.SASLTypeSCRAMSHA512 {
+
+		if canaryConfig.SASLUser == "" {
+			return nil, errors.New("SASL user must be specified")
+		}
+		if canaryConfig.SASLPassword == "" {
+			return nil, errors.New("SASL password must be specified")
+		}
+
+		switch canaryConfig.SASLMechanism {
+		case sarama.SASLTypeSCRAMSHA256:
+			return scram.Auth{User: canaryConfig.SASLUser, Pass: canaryConfig.SASLPassword}.AsSha256Mechanism(), nil
+		case sarama.SASLTypeSCRAMSHA512:
+			return scram.Auth{User: canaryConfig.SASLUser, Pass: canaryConfig.SASLPassword}.AsSha512Mechanism(), nil
+		default:
+			return plain.Auth{User: canaryConfig.SASLUser, Pass: canaryConfig.SASLPassword}.AsMechanism(), nil
+		}
+	}
+	return nil, fmt.Errorf("SASL mechanism %s is not supported", canaryConfig.SASLMechanism)
+}
@@ -49,3 +49,14 @@ func TestSASLPlainAuth(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestSASLScramMechanism(t *testing.T) {
+	os.Setenv(config.SASLMechanismEnvVar, "SCRAM-SHA-512")
+	os.Setenv(config.SASLUserEnvVar, "user")
+	os.Setenv(config.SASLPasswordEnvVar, "password")
+	canaryConfig := config.NewCanaryConfig()
+	mechanism, e := NewSASLMechanism(canaryConfig)
+	if e != nil || mechanism.Name() != "SCRAM-SHA-512" {
+		t.Fail()
+	}
+}
@@ -9,6 +9,7 @@ package security
 import (
 	"crypto/tls"
 	"crypto/x509"
+	"errors"
 	"io/ioutil"
 	"os"
 
@@ -56,9 +57,81 @@ func NewTLSConfig(canaryConfig *config.CanaryConfig) (*tls.Config, error) {
 	}
 	tlsConfig.InsecureSkipVerify = canaryConfig.TLSInsecureSkipVerify
 
+	// overriding the server name, sent as SNI and verified against the broker certificate, instead of the broker host
+	// i.e. when the cluster is exposed through a load balancer whose DNS name doesn't match the broker certificates
+	tlsConfig.ServerName = canaryConfig.TLSServerName
+
+	if canaryConfig.TLSSkipHostnameVerify && !canaryConfig.TLSInsecureSkipVerify {
+		glog.Warningf("TLS host name verification disabled, only the broker certificate chain is verified")
+		// the default verification is disabled for replacing it with the certificate chain one only
+		tlsConfig.InsecureSkipVerify = true
+		tlsConfig.VerifyConnection = verifyCertificateChain(tlsConfig.RootCAs)
+	}
+
 	return tlsConfig, nil
 }
 
+// NewHTTPServerTLSConfig returns the TLS configuration for the HTTP server, nil if the HTTP server certificate is not provided.
+// With a client CA certificate, the client certificates are verified when provided, leaving to the handlers
+// requiring it to check the client was actually authenticated (so that probes without certificates still work)
+func NewHTTPServerTLSConfig(canaryConfig *config.CanaryConfig) (*tls.Config, error) {
+	if canaryConfig.HTTPTLSCert == "" || canaryConfig.HTTPTLSKey == "" {
+		if canaryConfig.HTTPTLSClientCA != "" {
+			return nil, errors.New("HTTP client certificate authentication requires the HTTP server certificate and key")
+		}
+		return nil, nil
+	}
+
+	var serverCert, serverKey []byte
+	var err error
+	var cert tls.Certificate
+
+	if serverCert, err = loadCertKey(config.HTTPTLSCertEnvVar, canaryConfig.HTTPTLSCert); err != nil {
+		return nil, err
+	}
+	if serverKey, err = loadCertKey(config.HTTPTLSKeyEnvVar, canaryConfig.HTTPTLSKey); err != nil {
+		return nil, err
+	}
+	if cert, err = tls.X509KeyPair(serverCert, serverKey); err != nil {
+		return nil, err
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+	}
+
+	if canaryConfig.HTTPTLSClientCA != "" {
+		clientCA, err := loadCertKey(config.HTTPTLSClientCAEnvVar, canaryConfig.HTTPTLSClientCA)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.ClientCAs = x509.NewCertPool()
+		if !tlsConfig.ClientCAs.AppendCertsFromPEM(clientCA) {
+			return nil, errors.New("no valid HTTP client CA certificates provided")
+		}
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return tlsConfig, nil
+}
+
+// verifyCertificateChain returns a function verifying the peer certificate chain against the provided root CAs,
+// without verifying the host name
+func verifyCertificateChain(rootCAs *x509.CertPool) func(cs tls.ConnectionState) error {
+	return func(cs tls.ConnectionState) error {
+		if len(cs.PeerCertificates) == 0 {
+			return errors.New("no peer certificates provided")
+		}
+		opts := x509.VerifyOptions{
+			Roots:         rootCAs,
+			Intermediates: x509.NewCertPool(),
+		}
+		for _, cert := range cs.PeerCertificates[1:] {
+			opts.Intermediates.AddCert(cert)
+		}
+		_, err := cs.PeerCertificates[0].Verify(opts)
+		return err
+	}
+}
+
 func loadCertKey(config string, value string) ([]byte, error) {
 	var bytes []byte
 	// first check if the config is providing a file path to the certificate/key
@@ -9,9 +9,17 @@
 package security
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
 	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/strimzi/strimzi-canary/internal/config"
 )
@@ -28,3 +36,119 @@ func TestSystemCertsPool(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestTLSServerName(t *testing.T) {
+	canaryConfig := &config.CanaryConfig{TLSEnabled: true, TLSServerName: "my-cluster-kafka-bootstrap"}
+	tlsConfig, e := NewTLSConfig(canaryConfig)
+	if e != nil {
+		t.Fatalf("unexpected error %v", e)
+	}
+	if tlsConfig.ServerName != "my-cluster-kafka-bootstrap" {
+		t.Errorf("ServerName: got = %s, want = my-cluster-kafka-bootstrap", tlsConfig.ServerName)
+	}
+	if tlsConfig.InsecureSkipVerify || tlsConfig.VerifyConnection != nil {
+		t.Errorf("Unexpected verification disabled")
+	}
+}
+
+func TestTLSSkipHostnameVerification(t *testing.T) {
+	canaryConfig := &config.CanaryConfig{TLSEnabled: true, TLSSkipHostnameVerify: true}
+	tlsConfig, e := NewTLSConfig(canaryConfig)
+	if e != nil {
+		t.Fatalf("unexpected error %v", e)
+	}
+	if !tlsConfig.InsecureSkipVerify || tlsConfig.VerifyConnection == nil {
+		t.Errorf("Certificate chain only verification not configured")
+	}
+}
+
+func TestVerifyCertificateChain(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "my-cluster-kafka-0"},
+		DNSNames:              []string{"my-cluster-kafka-0.my-cluster-kafka-brokers"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	// the certificate doesn't match the load balancer host name but its chain is trusted
+	trusted := x509.NewCertPool()
+	trusted.AddCert(cert)
+	state := tls.ConnectionState{ServerName: "kafka.example.com", PeerCertificates: []*x509.Certificate{cert}}
+	if err := verifyCertificateChain(trusted)(state); err != nil {
+		t.Errorf("unexpected error verifying a trusted chain %v", err)
+	}
+	if err := verifyCertificateChain(x509.NewCertPool())(state); err == nil {
+		t.Errorf("expected error verifying an untrusted chain")
+	}
+}
+
+func TestHTTPServerTLSConfig(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "strimzi-canary"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	certPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	keyPEM := string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}))
+
+	tests := []struct {
+		name         string
+		canaryConfig *config.CanaryConfig
+		wantTLS      bool
+		wantAuth     tls.ClientAuthType
+		wantErr      bool
+	}{
+		{"plain HTTP", &config.CanaryConfig{}, false, tls.NoClientCert, false},
+		{"server TLS", &config.CanaryConfig{HTTPTLSCert: certPEM, HTTPTLSKey: keyPEM}, true, tls.NoClientCert, false},
+		{"mutual TLS", &config.CanaryConfig{HTTPTLSCert: certPEM, HTTPTLSKey: keyPEM, HTTPTLSClientCA: certPEM}, true, tls.VerifyClientCertIfGiven, false},
+		{"client CA without server TLS", &config.CanaryConfig{HTTPTLSClientCA: certPEM}, false, tls.NoClientCert, true},
+		{"invalid client CA", &config.CanaryConfig{HTTPTLSCert: certPEM, HTTPTLSKey: keyPEM, HTTPTLSClientCA: "not a cert"}, false, tls.NoClientCert, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tlsConfig, err := NewHTTPServerTLSConfig(tt.canaryConfig)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Error: got = %v, wantErr = %t", err, tt.wantErr)
+			}
+			if (tlsConfig != nil) != tt.wantTLS {
+				t.Fatalf("TLS config: got = %v, wantTLS = %t", tlsConfig, tt.wantTLS)
+			}
+			if tlsConfig != nil && tlsConfig.ClientAuth != tt.wantAuth {
+				t.Errorf("ClientAuth: got = %v, want = %v", tlsConfig.ClientAuth, tt.wantAuth)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package servers contains some servers implementations
+package servers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/golang/glog"
+	"github.com/strimzi/strimzi-canary/internal/config"
+)
+
+// max size of a dynamic configuration patch
+const maxConfigPatchBytes = 64 * 1024
+
+// DynamicConfigHandler returns the handler providing the current dynamic configuration on GET requests and patching
+// it on PUT requests, with a JSON object setting only the values to change, or to null for restoring the ones from
+// the environment variables. The patch is validated and applied as a whole, then logged for auditing
+func DynamicConfigHandler(store *config.DynamicConfigStore) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			writeDynamicConfig(rw, store.Current())
+		case http.MethodPut:
+			patch, unset, err := decodeConfigPatch(r)
+			if err != nil {
+				http.Error(rw, err.Error(), http.StatusBadRequest)
+				return
+			}
+			current, err := store.Patch(patch, unset)
+			if err != nil {
+				glog.Warningf("Dynamic config patch %s from %s rejected: %v", patch, r.RemoteAddr, err)
+				http.Error(rw, err.Error(), http.StatusUnprocessableEntity)
+				return
+			}
+			glog.Warningf("Dynamic config patched through the HTTP API from %s: patch %s, unset %v, applied %s", r.RemoteAddr, patch, unset, current)
+			writeDynamicConfig(rw, current)
+		default:
+			rw.Header().Add("Allow", http.MethodGet+", "+http.MethodPut)
+			rw.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+// decodeConfigPatch decodes the dynamic configuration patch from the request body, rejecting the unknown
+// or not reloadable settings, along with the names of the settings set to null
+func decodeConfigPatch(r *http.Request) (*config.DynamicCanaryConfig, []string, error) {
+	body, err := ioutil.ReadAll(http.MaxBytesReader(nil, r.Body, maxConfigPatchBytes))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid dynamic config patch: %v", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	var patch config.DynamicCanaryConfig
+	if err := decoder.Decode(&patch); err != nil {
+		return nil, nil, fmt.Errorf("invalid dynamic config patch: %v", err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, nil, fmt.Errorf("invalid dynamic config patch: %v", err)
+	}
+	unset := []string{}
+	for name, value := range fields {
+		if string(value) == "null" {
+			unset = append(unset, name)
+		}
+	}
+	return &patch, unset, nil
+}
+
+func writeDynamicConfig(rw http.ResponseWriter, current *config.DynamicCanaryConfig) {
+	json, _ := json.Marshal(current)
+	rw.Header().Add("Content-Type", "application/json")
+	rw.Write(json)
+}
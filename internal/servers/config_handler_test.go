@@ -0,0 +1,60 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// +build unit_test
+
+// Package servers contains some servers implementations
+package servers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/strimzi/strimzi-canary/internal/config"
+)
+
+func TestDynamicConfigHandler(t *testing.T) {
+	verbosity, interval := 0, 30000
+	applied := 0
+	store := config.NewDynamicConfigStore(&config.DynamicCanaryConfig{VerbosityLogLevel: &verbosity, ReconcileInterval: &interval},
+		func(config *config.DynamicCanaryConfig) { applied++ })
+	handler := DynamicConfigHandler(store)
+
+	tests := []struct {
+		name    string
+		method  string
+		body    string
+		want    int
+		applied int
+	}{
+		{"get", http.MethodGet, "", http.StatusOK, 1},
+		{"valid patch", http.MethodPut, `{"reconcileIntervalMs": 10000, "verbosityLogLevel": 1}`, http.StatusOK, 2},
+		{"invalid value", http.MethodPut, `{"reconcileIntervalMs": 0, "verbosityLogLevel": 2}`, http.StatusUnprocessableEntity, 2},
+		{"unknown setting", http.MethodPut, `{"topic": "my-topic"}`, http.StatusBadRequest, 2},
+		{"not JSON", http.MethodPut, `reconcileIntervalMs=10000`, http.StatusBadRequest, 2},
+		{"not allowed method", http.MethodPost, `{}`, http.StatusMethodNotAllowed, 2},
+		{"unset", http.MethodPut, `{"verbosityLogLevel": null}`, http.StatusOK, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			handler.ServeHTTP(rw, httptest.NewRequest(tt.method, "/config", strings.NewReader(tt.body)))
+			if rw.Code != tt.want {
+				t.Errorf("Status = %d, expected = %d", rw.Code, tt.want)
+			}
+			if applied != tt.applied {
+				t.Errorf("Applied = %d, expected = %d", applied, tt.applied)
+			}
+		})
+	}
+
+	// the invalid patch is not applied partially and the null value is unset
+	current := store.Current()
+	if *current.ReconcileInterval != 10000 || current.VerbosityLogLevel != nil {
+		t.Errorf("Current = %s, expected = {ReconcileInterval:10000 ms}", current)
+	}
+}
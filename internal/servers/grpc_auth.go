@@ -0,0 +1,56 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package servers contains some servers implementations
+package servers
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// authorizedGrpc returns if the gRPC call is authenticated by one of the enabled authentications,
+// the same ones of the HTTP server, with the bearer token in the "authorization" metadata
+func (ha *httpAuth) authorizedGrpc(ctx context.Context) bool {
+	if !ha.enabled() {
+		return true
+	}
+	if ha.clientCert {
+		if p, ok := peer.FromContext(ctx); ok {
+			if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(tlsInfo.State.VerifiedChains) > 0 {
+				return true
+			}
+		}
+	}
+	if ha.token != "" {
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			for _, header := range md.Get("authorization") {
+				if strings.HasPrefix(header, bearerPrefix) {
+					token := strings.TrimPrefix(header, bearerPrefix)
+					if subtle.ConstantTimeCompare([]byte(token), []byte(ha.token)) == 1 {
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
+// unaryInterceptor rejects the gRPC calls not authenticated with the Unauthenticated code
+func (ha *httpAuth) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if !ha.authorizedGrpc(ctx) {
+		return nil, status.Error(codes.Unauthenticated, "missing or invalid credentials")
+	}
+	return handler(ctx, req)
+}
@@ -0,0 +1,58 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// +build unit_test
+
+// Package servers contains some servers implementations
+package servers
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestGrpcAuth(t *testing.T) {
+	verified := tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{&x509.Certificate{}}}}
+	tests := []struct {
+		name   string
+		auth   *httpAuth
+		header string
+		tls    *tls.ConnectionState
+		want   codes.Code
+	}{
+		{"no authentication", &httpAuth{}, "", nil, codes.OK},
+		{"valid token", &httpAuth{token: "my-token"}, "Bearer my-token", nil, codes.OK},
+		{"invalid token", &httpAuth{token: "my-token"}, "Bearer other-token", nil, codes.Unauthenticated},
+		{"missing token", &httpAuth{token: "my-token"}, "", nil, codes.Unauthenticated},
+		{"verified client cert", &httpAuth{clientCert: true}, "", &verified, codes.OK},
+		{"missing client cert", &httpAuth{clientCert: true}, "", &tls.ConnectionState{}, codes.Unauthenticated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.header != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", tt.header))
+			}
+			if tt.tls != nil {
+				ctx = peer.NewContext(ctx, &peer.Peer{AuthInfo: credentials.TLSInfo{State: *tt.tls}})
+			}
+			_, err := tt.auth.unaryInterceptor(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+				return nil, nil
+			})
+			if code := status.Code(err); code != tt.want {
+				t.Errorf("Status code: got = %v, want = %v", code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package servers contains some servers implementations
+package servers
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/golang/glog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/strimzi/strimzi-canary/api"
+	"github.com/strimzi/strimzi-canary/internal/config"
+	"github.com/strimzi/strimzi-canary/internal/security"
+	"github.com/strimzi/strimzi-canary/internal/services"
+)
+
+// GrpcServer exposes the canary status over gRPC
+type GrpcServer struct {
+	api.UnimplementedCanaryStatusServer
+	canaryConfig  *config.CanaryConfig
+	statusService *services.StatusService
+	grpcServer    *grpc.Server
+}
+
+// NewGrpcServer returns an instance of the GrpcServer, protected by the same TLS and authentication of the HTTP server
+func NewGrpcServer(canaryConfig *config.CanaryConfig, statusService *services.StatusService) *GrpcServer {
+	auth := newHttpAuth(canaryConfig)
+	opts := []grpc.ServerOption{grpc.UnaryInterceptor(auth.unaryInterceptor)}
+	tlsConfig, err := security.NewHTTPServerTLSConfig(canaryConfig)
+	if err != nil {
+		glog.Fatalf("Error creating the gRPC server TLS configuration: %v", err)
+	}
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	gs := GrpcServer{
+		canaryConfig:  canaryConfig,
+		statusService: statusService,
+		grpcServer:    grpc.NewServer(opts...),
+	}
+	api.RegisterCanaryStatusServer(gs.grpcServer, &gs)
+	return &gs
+}
+
+// Start runs the gRPC server in its own go routine
+func (gs *GrpcServer) Start() {
+	glog.Infof("Starting gRPC server on port %d", gs.canaryConfig.GrpcServerPort)
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", gs.canaryConfig.GrpcServerPort))
+	if err != nil {
+		glog.Fatalf("Error listening on gRPC server port %d: %v", gs.canaryConfig.GrpcServerPort, err)
+	}
+	go func() {
+		gs.grpcServer.Serve(listener)
+	}()
+}
+
+// Stop stops the gRPC server exiting the go routine
+func (gs *GrpcServer) Stop() {
+	glog.Infof("Stopping gRPC server")
+	gs.grpcServer.GracefulStop()
+	glog.Infof("gRPC server closed")
+}
+
+// GetStatus returns the current canary health, per partition latency statistics and last error details
+func (gs *GrpcServer) GetStatus(ctx context.Context, req *api.GetStatusRequest) (*api.GetStatusResponse, error) {
+	status := gs.statusService.Status()
+
+	state := api.Health_UNKNOWN
+	if status.Consuming.Percentage >= 100 {
+		state = api.Health_HEALTHY
+	} else if status.Consuming.Percentage >= 0 {
+		state = api.Health_DEGRADED
+	}
+
+	resp := &api.GetStatusResponse{
+		Health: &api.Health{
+			State:              state,
+			TimeWindowMs:       int64(status.Consuming.TimeWindow),
+			ConsumedPercentage: status.Consuming.Percentage,
+		},
+	}
+	for _, stats := range gs.statusService.PartitionsLatencyStats() {
+		resp.Partitions = append(resp.Partitions, &api.PartitionLatency{
+			Partition: stats.Partition,
+			Produced:  toLatencyStats(stats.Produced),
+			EndToEnd:  toLatencyStats(stats.EndToEnd),
+		})
+	}
+	if lastError := gs.statusService.LastError(); lastError != nil {
+		resp.LastError = &api.ErrorDetails{
+			Timestamp: lastError.Timestamp,
+			Source:    lastError.Source,
+			Message:   lastError.Message,
+		}
+	}
+	return resp, nil
+}
+
+func toLatencyStats(stats services.LatencyStats) *api.LatencyStats {
+	return &api.LatencyStats{
+		Count: stats.Count,
+		Min:   stats.Min,
+		Max:   stats.Max,
+		Avg:   stats.Avg,
+		Last:  stats.Last,
+	}
+}
@@ -0,0 +1,73 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package servers contains some servers implementations
+package servers
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/strimzi/strimzi-canary/internal/config"
+)
+
+const bearerPrefix = "Bearer "
+
+// httpAuth protects HTTP handlers with a bearer token and/or a client certificate authentication,
+// a request is authorized when at least one of the enabled authentications succeeds
+type httpAuth struct {
+	token string
+	// client certificates verified by the TLS layer
+	clientCert bool
+}
+
+// newHttpAuth returns the authentication configured for the HTTP and gRPC servers
+func newHttpAuth(canaryConfig *config.CanaryConfig) *httpAuth {
+	return &httpAuth{
+		token:      canaryConfig.HTTPAuthToken,
+		clientCert: canaryConfig.HTTPTLSClientCA != "",
+	}
+}
+
+// enabled returns if any authentication is enabled
+func (ha *httpAuth) enabled() bool {
+	return ha.token != "" || ha.clientCert
+}
+
+// authorized returns if the request is authenticated by one of the enabled authentications
+func (ha *httpAuth) authorized(r *http.Request) bool {
+	if !ha.enabled() {
+		return true
+	}
+	if ha.clientCert && r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		return true
+	}
+	if ha.token != "" {
+		header := r.Header.Get("Authorization")
+		if strings.HasPrefix(header, bearerPrefix) {
+			token := strings.TrimPrefix(header, bearerPrefix)
+			return subtle.ConstantTimeCompare([]byte(token), []byte(ha.token)) == 1
+		}
+	}
+	return false
+}
+
+// handler returns the provided handler replying 401 Unauthorized to requests not authenticated
+func (ha *httpAuth) handler(handler http.Handler) http.Handler {
+	if !ha.enabled() {
+		return handler
+	}
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if !ha.authorized(r) {
+			if ha.token != "" {
+				rw.Header().Set("WWW-Authenticate", `Bearer realm="strimzi-canary"`)
+			}
+			http.Error(rw, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(rw, r)
+	})
+}
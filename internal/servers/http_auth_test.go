@@ -0,0 +1,54 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// +build unit_test
+
+// Package servers contains some servers implementations
+package servers
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHttpAuth(t *testing.T) {
+	verified := &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{&x509.Certificate{}}}}
+	tests := []struct {
+		name   string
+		auth   *httpAuth
+		header string
+		tls    *tls.ConnectionState
+		want   int
+	}{
+		{"no authentication", &httpAuth{}, "", nil, http.StatusOK},
+		{"valid token", &httpAuth{token: "my-token"}, "Bearer my-token", nil, http.StatusOK},
+		{"invalid token", &httpAuth{token: "my-token"}, "Bearer other-token", nil, http.StatusUnauthorized},
+		{"missing token", &httpAuth{token: "my-token"}, "", nil, http.StatusUnauthorized},
+		{"not bearer", &httpAuth{token: "my-token"}, "Basic my-token", nil, http.StatusUnauthorized},
+		{"verified client cert", &httpAuth{clientCert: true}, "", verified, http.StatusOK},
+		{"missing client cert", &httpAuth{clientCert: true}, "", &tls.ConnectionState{}, http.StatusUnauthorized},
+		{"client cert or token", &httpAuth{token: "my-token", clientCert: true}, "Bearer my-token", &tls.ConnectionState{}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := tt.auth.handler(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+				rw.WriteHeader(http.StatusOK)
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/status", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			req.TLS = tt.tls
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("Status code: got = %d, want = %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
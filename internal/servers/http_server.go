@@ -13,31 +13,61 @@ import (
 
 	"github.com/golang/glog"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/strimzi/strimzi-canary/internal/config"
+	"github.com/strimzi/strimzi-canary/internal/security"
 	"github.com/strimzi/strimzi-canary/internal/services"
 )
 
 // HttpServer exposes some services over HTTP (i.e. Prometheus metrics, healthchecks)
 type HttpServer struct {
-	httpServer *http.Server
+	canaryConfig *config.CanaryConfig
+	httpServer   *http.Server
+	mux          *http.ServeMux
+	auth         *httpAuth
 }
 
 // NewHttpServer returns an instance of the HttpServer
-func NewHttpServer(statusService *services.StatusService) *HttpServer {
-	mux := http.NewServeMux()
-	mux.Handle("/metrics", promhttp.Handler())
-	mux.Handle("/liveness", services.LivenessHandler())
-	mux.Handle("/readiness", services.ReadinessHandler())
-	mux.Handle("/status", statusService.StatusHandler())
-	ms := HttpServer{}
+func NewHttpServer(canaryConfig *config.CanaryConfig, statusService *services.StatusService) *HttpServer {
+	ms := HttpServer{
+		canaryConfig: canaryConfig,
+		mux:          http.NewServeMux(),
+		auth:         newHttpAuth(canaryConfig),
+	}
+	// liveness and readiness are never protected, for not breaking the probes
+	ms.mux.Handle("/liveness", services.LivenessHandler())
+	ms.mux.Handle("/readiness", services.ReadinessHandler())
+	ms.Handle("/metrics", promhttp.Handler())
+	ms.Handle("/status", statusService.StatusHandler())
+	ms.Handle("/report", statusService.ReportHandler())
+	ms.Handle("/safe-to-roll", statusService.SafeToRollHandler())
 	ms.httpServer = &http.Server{
 		Addr:    ":8080",
-		Handler: mux,
+		Handler: ms.mux,
 	}
 	return &ms
 }
 
+// Handle registers an additional handler, for services available only after the HTTP server is started,
+// protected by the authentication when enabled
+func (ms *HttpServer) Handle(pattern string, handler http.Handler) {
+	ms.mux.Handle(pattern, ms.auth.handler(handler))
+}
+
 // Start runs the HTTP server in its own go routine
 func (ms *HttpServer) Start() {
+	tlsConfig, err := security.NewHTTPServerTLSConfig(ms.canaryConfig)
+	if err != nil {
+		glog.Fatalf("Error creating the HTTP server TLS configuration: %v", err)
+	}
+	if tlsConfig != nil {
+		glog.Infof("Starting HTTPS server")
+		ms.httpServer.TLSConfig = tlsConfig
+		go func() {
+			// certificate and key are already in the TLS configuration
+			ms.httpServer.ListenAndServeTLS("", "")
+		}()
+		return
+	}
 	glog.Infof("Starting HTTP server")
 	go func() {
 		ms.httpServer.ListenAndServe()
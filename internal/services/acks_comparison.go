@@ -0,0 +1,122 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"github.com/golang/glog"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/strimzi/strimzi-canary/internal/clients"
+	"github.com/strimzi/strimzi-canary/internal/config"
+	"github.com/strimzi/strimzi-canary/internal/util"
+)
+
+var (
+	// it's defined when the comparison is enabled because buckets are configurable
+	producerAcksLatency *prometheus.HistogramVec
+
+	producerAcksLatencyDelta = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name:      "producer_acks_latency_delta_ms",
+		Namespace: "strimzi_canary",
+		Help:      "Difference in milliseconds between the last records produced with acks=all and with fewer acks, the replication overhead",
+	}, []string{"clientid", "partition"})
+)
+
+// acksComparison sends, along with each canary record, another one waiting for different acks, so that the produce
+// latency with acks=all is compared with the one waiting for the leader only, quantifying the replication overhead
+// and surfacing the in-sync replicas problems
+type acksComparison struct {
+	canaryConfig  *config.CanaryConfig
+	clientFactory clients.Factory
+	// the acks the canary records are produced with and the ones they are compared with
+	acks           string
+	comparisonAcks string
+	// lazily created and recreated on unrecoverable errors, used by the send cycle only
+	producer clients.Producer
+}
+
+func newAcksComparison(canaryConfig *config.CanaryConfig, clientFactory clients.Factory) *acksComparison {
+	producerAcksLatency = promauto.NewHistogramVec(latencyHistogramOpts(canaryConfig, prometheus.HistogramOpts{
+		Name:      "producer_acks_latency",
+		Namespace: "strimzi_canary",
+		Help:      "Records produced latency in milliseconds, by the acks the producer waits for",
+		Buckets:   canaryConfig.ProducerLatencyBuckets,
+	}), []string{"clientid", "acks"})
+	partitionMetrics.Register(producerAcksLatencyDelta)
+
+	acks := canaryConfig.ProducerAcks
+	if acks == "" || acks == "-1" {
+		acks = clients.AcksAll
+	}
+	return &acksComparison{
+		canaryConfig:   canaryConfig,
+		clientFactory:  clientFactory,
+		acks:           acks,
+		comparisonAcks: comparisonAcks(acks),
+	}
+}
+
+// compare sends the canary message with the comparison acks to the partition and reports the latency delta with the
+// canary record just produced in the provided latency (in ms)
+func (ac *acksComparison) compare(cm CanaryMessage, partition int32, latency int64) {
+	if ac.producer == nil {
+		producer, err := ac.clientFactory.NewProducerWithAcks(ac.canaryConfig.BootstrapServers, ac.comparisonAcks)
+		if err != nil {
+			glog.Errorf("Error creating the Kafka producer with acks=%s: %v", ac.comparisonAcks, err)
+			return
+		}
+		ac.producer = producer
+	}
+
+	if _, _, err := ac.producer.Send(ac.canaryConfig.Topic, partition, []byte(cm.Json())); err != nil {
+		glog.Warningf("Error sending message with acks=%s: %v", ac.comparisonAcks, err)
+		if clients.IsFatal(err) {
+			ac.producer.Close()
+			ac.producer = nil
+			recordClientRecreation(ProducerBootstrapClient)
+		}
+		return
+	}
+	comparisonLatency := util.NowInMilliseconds() - cm.Timestamp
+	glog.V(1).Infof("Message sent with acks=%s: partition=%d, duration=%d ms", ac.comparisonAcks, partition, comparisonLatency)
+
+	producerAcksLatency.With(prometheus.Labels{"clientid": ac.canaryConfig.ClientID, "acks": ac.acks}).Observe(float64(latency))
+	producerAcksLatency.With(prometheus.Labels{"clientid": ac.canaryConfig.ClientID, "acks": ac.comparisonAcks}).Observe(float64(comparisonLatency))
+	labels := prometheus.Labels{
+		"clientid":  ac.canaryConfig.ClientID,
+		"partition": partitionLabel(ac.canaryConfig, partition),
+	}
+	producerAcksLatencyDelta.With(labels).Set(float64(acksLatencyDelta(ac.acks, latency, comparisonLatency)))
+}
+
+// close closes the underneath Kafka producer
+func (ac *acksComparison) close() {
+	if ac.producer != nil {
+		if err := ac.producer.Close(); err != nil {
+			glog.Errorf("Error closing the Kafka producer with acks=%s: %v", ac.comparisonAcks, err)
+		}
+		ac.producer = nil
+	}
+}
+
+// comparisonAcks returns the acks the canary records produced with the provided ones are compared with,
+// the leader ones for acks=all, otherwise acks=all
+func comparisonAcks(acks string) string {
+	if acks == clients.AcksAll {
+		return clients.AcksLeader
+	}
+	return clients.AcksAll
+}
+
+// acksLatencyDelta returns the latency with acks=all minus the one with fewer acks
+func acksLatencyDelta(acks string, latency int64, comparisonLatency int64) int64 {
+	if acks == clients.AcksAll {
+		return latency - comparisonLatency
+	}
+	return comparisonLatency - latency
+}
@@ -0,0 +1,40 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// +build unit_test
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"testing"
+
+	"github.com/strimzi/strimzi-canary/internal/clients"
+)
+
+func TestAcksComparison(t *testing.T) {
+	tests := []struct {
+		name              string
+		acks              string
+		comparisonAcks    string
+		latency           int64
+		comparisonLatency int64
+		delta             int64
+	}{
+		{"all compared with leader", clients.AcksAll, clients.AcksLeader, 30, 10, 20},
+		{"leader compared with all", clients.AcksLeader, clients.AcksAll, 10, 30, 20},
+		{"none compared with all", clients.AcksNone, clients.AcksAll, 5, 30, 25},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if acks := comparisonAcks(tt.acks); acks != tt.comparisonAcks {
+				t.Errorf("Comparison acks = %s, expected = %s", acks, tt.comparisonAcks)
+			}
+			if delta := acksLatencyDelta(tt.acks, tt.latency, tt.comparisonLatency); delta != tt.delta {
+				t.Errorf("Delta = %d, expected = %d", delta, tt.delta)
+			}
+		})
+	}
+}
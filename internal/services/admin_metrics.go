@@ -0,0 +1,158 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/strimzi/strimzi-canary/internal/clients"
+	"github.com/strimzi/strimzi-canary/internal/util"
+)
+
+const (
+	// admin operations
+	DescribeClusterOperation             = "describe_cluster"
+	DescribeTopicOperation               = "describe_topic"
+	CreateTopicOperation                 = "create_topic"
+	AlterConfigsOperation                = "alter_configs"
+	CreatePartitionsOperation            = "create_partitions"
+	AlterPartitionReassignmentsOperation = "alter_partition_reassignments"
+	ListPartitionReassignmentsOperation  = "list_partition_reassignments"
+	DeleteTopicOperation                 = "delete_topic"
+	ElectLeadersOperation                = "elect_leaders"
+	DescribeConsumerGroupOperation       = "describe_consumer_group"
+	ListTopicsOperation                  = "list_topics"
+	ListConsumerGroupsOperation          = "list_consumer_groups"
+	DeleteConsumerGroupOperation         = "delete_consumer_group"
+)
+
+var (
+	// it's defined when the service is created because buckets are configurable
+	adminOperationLatency *prometheus.HistogramVec
+
+	adminOperationError = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name:      "admin_operation_error_total",
+		Namespace: "strimzi_canary",
+		Help:      "Total number of errors on admin operations",
+	}, []string{"operation"})
+)
+
+// instrumentedAdmin decorates a Kafka admin reporting the operations latency and errors as metrics
+type instrumentedAdmin struct {
+	admin clients.Admin
+}
+
+func (a *instrumentedAdmin) DescribeCluster() (brokers []clients.Broker, err error) {
+	observe(DescribeClusterOperation, func() error {
+		brokers, err = a.admin.DescribeCluster()
+		return err
+	})
+	return brokers, err
+}
+
+func (a *instrumentedAdmin) DescribeTopic(topic string) (metadata *clients.TopicMetadata, err error) {
+	observe(DescribeTopicOperation, func() error {
+		metadata, err = a.admin.DescribeTopic(topic)
+		return err
+	})
+	return metadata, err
+}
+
+func (a *instrumentedAdmin) CreateTopic(topic string, assignments map[int32][]int32, config map[string]*string) error {
+	return observe(CreateTopicOperation, func() error {
+		return a.admin.CreateTopic(topic, assignments, config)
+	})
+}
+
+func (a *instrumentedAdmin) AlterTopicConfig(topic string, config map[string]*string) error {
+	return observe(AlterConfigsOperation, func() error {
+		return a.admin.AlterTopicConfig(topic, config)
+	})
+}
+
+func (a *instrumentedAdmin) CreatePartitions(topic string, count int32, assignments [][]int32) error {
+	return observe(CreatePartitionsOperation, func() error {
+		return a.admin.CreatePartitions(topic, count, assignments)
+	})
+}
+
+func (a *instrumentedAdmin) AlterPartitionReassignments(topic string, assignments [][]int32) error {
+	return observe(AlterPartitionReassignmentsOperation, func() error {
+		return a.admin.AlterPartitionReassignments(topic, assignments)
+	})
+}
+
+func (a *instrumentedAdmin) ListPartitionReassignments(topic string, partitions []int32) (reassignments map[int32]*clients.PartitionReassignment, err error) {
+	observe(ListPartitionReassignmentsOperation, func() error {
+		reassignments, err = a.admin.ListPartitionReassignments(topic, partitions)
+		return err
+	})
+	return reassignments, err
+}
+
+func (a *instrumentedAdmin) DeleteTopic(topic string) error {
+	return observe(DeleteTopicOperation, func() error {
+		return a.admin.DeleteTopic(topic)
+	})
+}
+
+func (a *instrumentedAdmin) ElectPreferredLeaders(topic string, partitions []int32) error {
+	return observe(ElectLeadersOperation, func() error {
+		return a.admin.ElectPreferredLeaders(topic, partitions)
+	})
+}
+
+func (a *instrumentedAdmin) DescribeConsumerGroup(group string) (description *clients.ConsumerGroupDescription, err error) {
+	observe(DescribeConsumerGroupOperation, func() error {
+		description, err = a.admin.DescribeConsumerGroup(group)
+		return err
+	})
+	return description, err
+}
+
+func (a *instrumentedAdmin) ListTopics() (topics []string, err error) {
+	observe(ListTopicsOperation, func() error {
+		topics, err = a.admin.ListTopics()
+		return err
+	})
+	return topics, err
+}
+
+func (a *instrumentedAdmin) ListConsumerGroups() (groups []string, err error) {
+	observe(ListConsumerGroupsOperation, func() error {
+		groups, err = a.admin.ListConsumerGroups()
+		return err
+	})
+	return groups, err
+}
+
+func (a *instrumentedAdmin) DeleteConsumerGroup(group string) error {
+	return observe(DeleteConsumerGroupOperation, func() error {
+		return a.admin.DeleteConsumerGroup(group)
+	})
+}
+
+func (a *instrumentedAdmin) Close() error {
+	return a.admin.Close()
+}
+
+// observe runs the admin operation, reporting its latency and error if any
+func observe(operation string, fn func() error) error {
+	limitRate(operation)
+	start := util.NowInMilliseconds() // timestamp in milliseconds
+	err := fn()
+	duration := util.NowInMilliseconds() - start
+	labels := prometheus.Labels{
+		"operation": operation,
+	}
+	adminOperationLatency.With(labels).Observe(float64(duration))
+	if err != nil {
+		adminOperationError.With(labels).Inc()
+	}
+	return err
+}
@@ -0,0 +1,210 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package services defines some canary related services
+package services
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/proxy"
+
+	"github.com/strimzi/strimzi-canary/internal/clients"
+	"github.com/strimzi/strimzi-canary/internal/config"
+)
+
+const (
+	// reasons of an advertised address not reachable
+	InvalidAddressUnreachable = "invalid_address"
+	DNSUnreachable            = "dns"
+	DialUnreachable           = "dial"
+
+	// timeout for resolving and dialing a single advertised address
+	advertisedAddressTimeout = 10 * time.Second
+)
+
+var (
+	advertisedListenerReachable = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name:      "advertised_listener_reachable",
+		Namespace: "strimzi_canary",
+		Help:      "If the address advertised by the broker on the listener is reachable (1) or not (0) from the canary",
+	}, []string{"brokerid", "listener"})
+
+	advertisedListenerUnreachable = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name:      "advertised_listener_unreachable_total",
+		Namespace: "strimzi_canary",
+		Help:      "Total number of checks finding the address advertised by the broker on the listener not reachable, by reason",
+	}, []string{"brokerid", "listener", "reason"})
+
+	advertisedListenerCheckError = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name:      "advertised_listener_check_error_total",
+		Namespace: "strimzi_canary",
+		Help:      "Total number of errors while getting the brokers addresses advertised on the listener",
+	}, []string{"listener"})
+)
+
+// AdvertisedListenerCheckService defines the service periodically walking from the bootstrap servers of each listener
+// to the brokers addresses advertised in the metadata, and verifying each one is resolvable and reachable at the
+// network level from the canary, so that brokers advertising wrong addresses (i.e. after ingress or route changes)
+// are reported apart from the TLS or authentication issues surfacing on the connection check
+type AdvertisedListenerCheckService struct {
+	canaryConfig  *config.CanaryConfig
+	clientFactory clients.Factory
+	// the default listener first, then the additional ones
+	listeners []*listenerCheck
+}
+
+func init() {
+	RegisterCheck(AdvertisedListenerCheckLoop, newAdvertisedListenerCheck)
+}
+
+// newAdvertisedListenerCheck returns the advertised listener check, nil when it's not enabled
+func newAdvertisedListenerCheck(canaryConfig *config.CanaryConfig, clientFactory clients.Factory) Check {
+	if canaryConfig.AdvertisedListenerCheckInterval == 0 {
+		return nil
+	}
+	return NewAdvertisedListenerCheckService(canaryConfig, clientFactory)
+}
+
+// NewAdvertisedListenerCheckService returns an instance of AdvertisedListenerCheckService
+//
+// It checks the same listeners as the connection check
+func NewAdvertisedListenerCheckService(canaryConfig *config.CanaryConfig, clientFactory clients.Factory) *AdvertisedListenerCheckService {
+	return &AdvertisedListenerCheckService{
+		canaryConfig:  canaryConfig,
+		clientFactory: clientFactory,
+		listeners:     newListenerChecks(canaryConfig),
+	}
+}
+
+// Name returns the advertised listener check name
+func (alcs *AdvertisedListenerCheckService) Name() string {
+	return AdvertisedListenerCheckLoop
+}
+
+// Interval returns the interval between the advertised listener checks
+func (alcs *AdvertisedListenerCheckService) Interval() time.Duration {
+	return alcs.canaryConfig.AdvertisedListenerCheckInterval * time.Millisecond
+}
+
+// Run runs the advertised listener check
+func (alcs *AdvertisedListenerCheckService) Run(ctx context.Context) {
+	alcs.advertisedListenerCheck(ctx)
+}
+
+// Metrics returns the advertised listener check metrics
+func (alcs *AdvertisedListenerCheckService) Metrics() []prometheus.Collector {
+	return []prometheus.Collector{advertisedListenerReachable, advertisedListenerUnreachable, advertisedListenerCheckError}
+}
+
+// Close closes the underneath Kafka admin instances
+func (alcs *AdvertisedListenerCheckService) Close() {
+	glog.Infof("Closing advertised listener check service")
+
+	for _, lc := range alcs.listeners {
+		if lc.admin != nil {
+			if err := lc.admin.Close(); err != nil {
+				glog.Errorf("Error closing the Kafka admin: %v", err)
+			}
+			lc.admin = nil
+		}
+	}
+	glog.Infof("Advertised listener check service closed")
+}
+
+// advertisedListenerCheck checks the addresses advertised by the brokers on each listener
+func (alcs *AdvertisedListenerCheckService) advertisedListenerCheck(ctx context.Context) {
+	dialer, err := clients.NewDialer(alcs.canaryConfig)
+	if err != nil {
+		glog.Errorf("Error creating the dialer for the advertised listener check: %v", err)
+		return
+	}
+	// with a proxy, the advertised hosts are resolved by the proxy itself
+	resolve := alcs.canaryConfig.ProxyURL == ""
+
+	for _, lc := range alcs.listeners {
+		brokers, err := alcs.describeCluster(lc)
+		if err != nil {
+			advertisedListenerCheckError.With(prometheus.Labels{"listener": lc.name}).Inc()
+			glog.Errorf("Error describing cluster on listener %s: %v", lc.name, err)
+			if clients.IsFatal(err) && lc.admin != nil {
+				lc.admin.Close()
+				lc.admin = nil
+				recordClientRecreation(AdminBootstrapClient)
+			}
+			continue
+		}
+
+		for _, b := range brokers {
+			checkCtx, cancel := context.WithTimeout(ctx, advertisedAddressTimeout)
+			reason, err := checkAdvertisedAddress(checkCtx, dialer, net.DefaultResolver.LookupHost, b.Addr, resolve)
+			cancel()
+
+			brokerID := strconv.Itoa(int(b.ID))
+			if reason == "" {
+				glog.V(1).Infof("Broker %d address %s advertised on listener %s is reachable", b.ID, b.Addr, lc.name)
+				advertisedListenerReachable.With(prometheus.Labels{"brokerid": brokerID, "listener": lc.name}).Set(1)
+				continue
+			}
+			glog.Warningf("Broker %d address %s advertised on listener %s is not reachable (%s): %v", b.ID, b.Addr, lc.name, reason, err)
+			advertisedListenerReachable.With(prometheus.Labels{"brokerid": brokerID, "listener": lc.name}).Set(0)
+			advertisedListenerUnreachable.With(prometheus.Labels{"brokerid": brokerID, "listener": lc.name, "reason": reason}).Inc()
+		}
+		alcs.expireBrokers(lc, brokers)
+	}
+}
+
+// describeCluster returns the brokers with the addresses advertised on the listener
+func (alcs *AdvertisedListenerCheckService) describeCluster(lc *listenerCheck) ([]clients.Broker, error) {
+	if lc.admin == nil {
+		admin, err := alcs.clientFactory.NewAdmin(lc.bootstrapServers)
+		if err != nil {
+			return nil, err
+		}
+		lc.admin = &instrumentedAdmin{admin: admin}
+	}
+	return lc.admin.DescribeCluster()
+}
+
+// expireBrokers deletes the reachability metric of the brokers no longer in the cluster, i.e. after a scale down
+func (alcs *AdvertisedListenerCheckService) expireBrokers(lc *listenerCheck, brokers []clients.Broker) {
+	current := make(map[int32]bool, len(brokers))
+	for _, b := range brokers {
+		current[b.ID] = true
+	}
+	for _, b := range lc.brokers {
+		if !current[b.ID] {
+			advertisedListenerReachable.Delete(prometheus.Labels{"brokerid": strconv.Itoa(int(b.ID)), "listener": lc.name})
+		}
+	}
+	lc.brokers = brokers
+}
+
+// checkAdvertisedAddress resolves, if needed, and dials the advertised address, returning the reason if it's not
+// reachable or an empty one otherwise
+func checkAdvertisedAddress(ctx context.Context, dialer proxy.ContextDialer,
+	lookupHost func(context.Context, string) ([]string, error), addr string, resolve bool) (string, error) {
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil || host == "" {
+		return InvalidAddressUnreachable, err
+	}
+	if resolve && net.ParseIP(host) == nil {
+		if _, err := lookupHost(ctx, host); err != nil {
+			return DNSUnreachable, err
+		}
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return DialUnreachable, err
+	}
+	conn.Close()
+	return "", nil
+}
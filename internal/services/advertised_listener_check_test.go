@@ -0,0 +1,68 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// +build unit_test
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+)
+
+// fakeDialer connects only to the reachable addresses
+type fakeDialer struct {
+	reachable map[string]bool
+}
+
+func (d *fakeDialer) DialContext(ctx context.Context, network string, addr string) (net.Conn, error) {
+	if !d.reachable[addr] {
+		return nil, errors.New("connection refused")
+	}
+	client, server := net.Pipe()
+	server.Close()
+	return client, nil
+}
+
+func TestCheckAdvertisedAddress(t *testing.T) {
+	dialer := &fakeDialer{reachable: map[string]bool{
+		"broker-0.example.com:443": true,
+		"10.0.0.1:9092":            true,
+	}}
+	lookupHost := func(ctx context.Context, host string) ([]string, error) {
+		if host == "broker-0.example.com" || host == "broker-1.example.com" {
+			return []string{"10.0.0.2"}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+
+	tests := []struct {
+		name    string
+		addr    string
+		resolve bool
+		reason  string
+	}{
+		{"reachable", "broker-0.example.com:443", true, ""},
+		{"reachable IP", "10.0.0.1:9092", true, ""},
+		{"invalid address", "broker-0.example.com", true, InvalidAddressUnreachable},
+		{"not resolvable", "broker-2.example.com:443", true, DNSUnreachable},
+		{"not reachable", "broker-1.example.com:443", true, DialUnreachable},
+		{"resolved by the proxy", "broker-2.example.com:443", false, DialUnreachable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, err := checkAdvertisedAddress(context.Background(), dialer, lookupHost, tt.addr, tt.resolve)
+			if reason != tt.reason {
+				t.Errorf("Reason = %q (%v), expected = %q", reason, err, tt.reason)
+			}
+			if (reason == "") != (err == nil) {
+				t.Errorf("Error = %v, expected only when not reachable", err)
+			}
+		})
+	}
+}
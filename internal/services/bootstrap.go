@@ -0,0 +1,74 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/strimzi/strimzi-canary/internal/config"
+)
+
+const (
+	// Kafka clients created on bootstrap
+	VersionBootstrapClient  = "version"
+	ProducerBootstrapClient = "producer"
+	ConsumerBootstrapClient = "consumer"
+	AdminBootstrapClient    = "admin"
+)
+
+var (
+	clientCreationFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name:      "client_creation_error_total",
+		Namespace: "strimzi_canary",
+		Help:      "Total number of errors while creating Kafka client",
+	}, nil)
+
+	bootstrapFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name:      "bootstrap_failures_total",
+		Namespace: "strimzi_canary",
+		Help:      "Total number of failed attempts to bootstrap the Kafka clients on start up",
+	}, []string{"client"})
+)
+
+// NewBootstrapBackoff returns the backoff for retrying the bootstrap of the Kafka clients as configured
+func NewBootstrapBackoff(canaryConfig *config.CanaryConfig) *Backoff {
+	return NewBackoff(canaryConfig.BootstrapBackoffMaxAttempts, canaryConfig.BootstrapBackoffScale*time.Millisecond, canaryConfig.BootstrapBackoffMaxWait*time.Millisecond)
+}
+
+// Bootstrap runs the provided Kafka client creation, retrying with exponential backoff on failure
+//
+// Returns an error if the creation still fails after the configured max attempts
+func Bootstrap(canaryConfig *config.CanaryConfig, client string, create func() error) error {
+	backoff := NewBootstrapBackoff(canaryConfig)
+	for {
+		clientErr := create()
+		if clientErr == nil {
+			return nil
+		}
+		RecordBootstrapFailure(client)
+		delay, backoffErr := backoff.Delay()
+		if backoffErr != nil {
+			glog.Errorf("Error connecting to the Kafka cluster after %d retries: %v", canaryConfig.BootstrapBackoffMaxAttempts, backoffErr)
+			return backoffErr
+		}
+		clientCreationFailed.With(nil).Inc()
+		glog.Warningf("Error creating new Kafka %s client, retrying in %d ms: %v", client, delay.Milliseconds(), clientErr)
+		time.Sleep(delay)
+	}
+}
+
+// RecordBootstrapFailure records a failed attempt to bootstrap the provided Kafka client
+func RecordBootstrapFailure(client string) {
+	labels := prometheus.Labels{
+		"client": client,
+	}
+	bootstrapFailures.With(labels).Inc()
+}
@@ -0,0 +1,51 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// +build unit_test
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/strimzi/strimzi-canary/internal/config"
+)
+
+func TestBootstrapRetry(t *testing.T) {
+	cfg := &config.CanaryConfig{
+		BootstrapBackoffMaxAttempts: 3,
+		BootstrapBackoffScale:       1,
+		BootstrapBackoffMaxWait:     10,
+	}
+
+	creationErrors := testutil.ToFloat64(clientCreationFailed.With(nil))
+	attempts := 0
+	err := Bootstrap(cfg, ProducerBootstrapClient, func() error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("not ready")
+		}
+		return nil
+	})
+	if err != nil || attempts != 3 {
+		t.Errorf("got = %d attempts with error %v, want = 3 attempts without error", attempts, err)
+	}
+	// the failed attempts retried are still counted by the client_creation_error_total metric
+	if value := testutil.ToFloat64(clientCreationFailed.With(nil)) - creationErrors; value != 2 {
+		t.Errorf("client_creation_error_total got = %v, want = 2", value)
+	}
+
+	attempts = 0
+	err = Bootstrap(cfg, ProducerBootstrapClient, func() error {
+		attempts++
+		return errors.New("not ready")
+	})
+	if _, ok := err.(*MaxAttemptsExceeded); !ok || attempts != 4 {
+		t.Errorf("got = %d attempts with error %v, want = 4 attempts with max attempts exceeded", attempts, err)
+	}
+}
@@ -0,0 +1,124 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/strimzi/strimzi-canary/internal/clients"
+	"github.com/strimzi/strimzi-canary/internal/config"
+)
+
+var (
+	brokerRecordsProduced = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name:      "broker_records_produced_total",
+		Namespace: "strimzi_canary",
+		Help:      "The total number of records produced to partitions led by the broker",
+	}, []string{"clientid", "brokerid"})
+
+	brokerRecordsProducedFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name:      "broker_records_produced_failed_total",
+		Namespace: "strimzi_canary",
+		Help:      "The total number of records failed to produce to partitions led by the broker",
+	}, []string{"clientid", "brokerid"})
+
+	brokerRecordsConsumed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name:      "broker_records_consumed_total",
+		Namespace: "strimzi_canary",
+		Help:      "The total number of records consumed from partitions led by the broker",
+	}, []string{"clientid", "brokerid"})
+
+	brokerLeadershipElections = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name:      "broker_leadership_elections_total",
+		Namespace: "strimzi_canary",
+		Help:      "Total number of preferred leader elections triggered for having every broker leading at least one canary topic partition",
+	}, []string{"topic"})
+)
+
+// observeBrokerProduce reports the result of a send to the partition, by its leader, when the per broker check is enabled
+func observeBrokerProduce(canaryConfig *config.CanaryConfig, partition int32, err error) {
+	if !canaryConfig.PerBrokerCheckEnabled {
+		return
+	}
+	leader, ok := partitionLeaders.Leader(partition)
+	if !ok {
+		return
+	}
+	labels := prometheus.Labels{
+		"clientid": canaryConfig.ClientID,
+		"brokerid": strconv.Itoa(int(leader)),
+	}
+	brokerRecordsProduced.With(labels).Inc()
+	if err != nil {
+		brokerRecordsProducedFailed.With(labels).Inc()
+	}
+}
+
+// observeBrokerConsume reports a record consumed from the partition, by its leader, when the per broker check is enabled
+//
+// The partition leaders are known for the canary topic only, so nothing is reported with the replication check
+func observeBrokerConsume(canaryConfig *config.CanaryConfig, partition int32) {
+	if !canaryConfig.PerBrokerCheckEnabled || canaryConfig.IsReplicationCheckEnabled() {
+		return
+	}
+	leader, ok := partitionLeaders.Leader(partition)
+	if !ok {
+		return
+	}
+	labels := prometheus.Labels{
+		"clientid": canaryConfig.ClientID,
+		"brokerid": strconv.Itoa(int(leader)),
+	}
+	brokerRecordsConsumed.With(labels).Inc()
+}
+
+// brokersWithoutLeadership returns the brokers not leading any canary topic partition, sorted by ID
+func brokersWithoutLeadership(brokers []clients.Broker, metadata *clients.TopicMetadata) []int32 {
+	leading := make(map[int32]bool, len(metadata.Partitions))
+	for _, p := range metadata.Partitions {
+		leading[p.Leader] = true
+	}
+	return brokersNotIn(brokers, leading)
+}
+
+// brokersNotPreferred returns the brokers which are not the preferred leader (first replica) of any partition, sorted by ID
+func brokersNotPreferred(brokers []clients.Broker, assignments map[int32][]int32) []int32 {
+	preferred := make(map[int32]bool, len(assignments))
+	for _, replicas := range assignments {
+		if len(replicas) > 0 {
+			preferred[replicas[0]] = true
+		}
+	}
+	return brokersNotIn(brokers, preferred)
+}
+
+func brokersNotIn(brokers []clients.Broker, set map[int32]bool) []int32 {
+	missing := make([]int32, 0)
+	for _, b := range brokers {
+		if !set[b.ID] {
+			missing = append(missing, b.ID)
+		}
+	}
+	sort.Slice(missing, func(i, j int) bool { return missing[i] < missing[j] })
+	return missing
+}
+
+// partitionsNotLedByPreferred returns the partitions whose current leader is not the preferred one, sorted by ID
+func partitionsNotLedByPreferred(assignments map[int32][]int32, metadata *clients.TopicMetadata) []int32 {
+	partitions := make([]int32, 0)
+	for _, p := range metadata.Partitions {
+		if replicas, ok := assignments[p.ID]; ok && len(replicas) > 0 && replicas[0] != p.Leader {
+			partitions = append(partitions, p.ID)
+		}
+	}
+	sort.Slice(partitions, func(i, j int) bool { return partitions[i] < partitions[j] })
+	return partitions
+}
@@ -0,0 +1,48 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// +build unit_test
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/strimzi/strimzi-canary/internal/clients"
+)
+
+func TestBrokersLeadership(t *testing.T) {
+	brokers := []clients.Broker{{ID: 2}, {ID: 0}, {ID: 1}}
+	// broker 1 restarted, so its partition is led by broker 2
+	metadata := &clients.TopicMetadata{
+		Partitions: []*clients.PartitionMetadata{
+			{ID: 0, Leader: 0, Replicas: []int32{0, 1, 2}},
+			{ID: 1, Leader: 2, Replicas: []int32{1, 2, 0}},
+			{ID: 2, Leader: 2, Replicas: []int32{2, 0, 1}},
+		},
+	}
+	assignments := map[int32][]int32{0: {0, 1, 2}, 1: {1, 2, 0}, 2: {2, 0, 1}}
+
+	if missing := brokersWithoutLeadership(brokers, metadata); !reflect.DeepEqual(missing, []int32{1}) {
+		t.Errorf("Brokers without leadership: got = %v, want = [1]", missing)
+	}
+	if notPreferred := brokersNotPreferred(brokers, assignments); len(notPreferred) != 0 {
+		t.Errorf("Brokers not preferred: got = %v, want = []", notPreferred)
+	}
+	if partitions := partitionsNotLedByPreferred(assignments, metadata); !reflect.DeepEqual(partitions, []int32{1}) {
+		t.Errorf("Partitions not led by preferred: got = %v, want = [1]", partitions)
+	}
+}
+
+func TestBrokersNotPreferred(t *testing.T) {
+	// broker 3 added without reassigning the partitions
+	brokers := []clients.Broker{{ID: 0}, {ID: 1}, {ID: 2}, {ID: 3}}
+	assignments := map[int32][]int32{0: {0, 1, 2}, 1: {1, 2, 0}, 2: {2, 0, 1}}
+	if notPreferred := brokersNotPreferred(brokers, assignments); !reflect.DeepEqual(notPreferred, []int32{3}) {
+		t.Errorf("Brokers not preferred: got = %v, want = [3]", notPreferred)
+	}
+}
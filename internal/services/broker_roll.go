@@ -0,0 +1,159 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/strimzi/strimzi-canary/internal/clients"
+	"github.com/strimzi/strimzi-canary/internal/util"
+)
+
+var (
+	brokerRestarts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name:      "broker_restarts_total",
+		Namespace: "strimzi_canary",
+		Help:      "Total number of broker restarts detected, the broker disappearing from the metadata or refusing connections and then coming back",
+	}, []string{"brokerid"})
+)
+
+// BrokerRollStatus defines the brokers rolling restart related status information
+type BrokerRollStatus struct {
+	// if any broker is down or restarted within the roll window, so latency spikes are expected
+	UpgradeInProgress bool
+	// brokers missing from the metadata or refusing connections
+	BrokersDown []int32
+	// brokers restarted within the roll window
+	BrokersRestarted []int32
+	// timestamp (in ms) of the last broker restart
+	LastRestart int64 `json:",omitempty"`
+}
+
+// brokerRollTracker tracks the brokers going down, because missing from the metadata or refusing connections,
+// and coming back, counting a restart. While any broker is down, or until the roll window is elapsed since the
+// last restart, a rolling restart (i.e. an upgrade) is considered in progress
+type brokerRollTracker struct {
+	mutex sync.Mutex
+	// roll window in ms
+	window int64
+	// brokers seen in the metadata
+	known map[int32]bool
+	// brokers down, with the timestamp (in ms) they went down
+	down map[int32]int64
+	// brokers restarted, with the timestamp (in ms) of the last restart
+	restarted map[int32]int64
+}
+
+func newBrokerRollTracker() *brokerRollTracker {
+	return &brokerRollTracker{
+		known:     make(map[int32]bool),
+		down:      make(map[int32]int64),
+		restarted: make(map[int32]int64),
+	}
+}
+
+func (t *brokerRollTracker) setWindow(window time.Duration) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.window = window.Milliseconds()
+}
+
+// Metadata updates the brokers from the cluster metadata: the known brokers missing are down, the down ones
+// present again are restarted. Returns the restarted brokers
+func (t *brokerRollTracker) Metadata(brokers []clients.Broker, now int64) []int32 {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	present := make(map[int32]bool, len(brokers))
+	restarted := make([]int32, 0)
+	for _, b := range brokers {
+		present[b.ID] = true
+		if t.up(b.ID, now) {
+			restarted = append(restarted, b.ID)
+		}
+		t.known[b.ID] = true
+	}
+	for id := range t.known {
+		if !present[id] {
+			t.goDown(id, now)
+		}
+	}
+	return restarted
+}
+
+// Connection updates the broker from a connection check result: the connection refused or reset means the broker
+// is down, the connection established means it's up. Returns true if the broker is restarted
+func (t *brokerRollTracker) Connection(brokerID int32, err error, now int64) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if err == nil {
+		return t.up(brokerID, now)
+	}
+	if isBrokerDown(err) {
+		t.goDown(brokerID, now)
+	}
+	return false
+}
+
+func (t *brokerRollTracker) goDown(brokerID int32, now int64) {
+	if _, ok := t.down[brokerID]; !ok {
+		glog.Infof("Broker %d is down", brokerID)
+		t.down[brokerID] = now
+	}
+}
+
+func (t *brokerRollTracker) up(brokerID int32, now int64) bool {
+	if _, ok := t.down[brokerID]; !ok {
+		return false
+	}
+	delete(t.down, brokerID)
+	t.restarted[brokerID] = now
+	glog.Infof("Broker %d restarted", brokerID)
+	brokerRestarts.With(prometheus.Labels{"brokerid": strconv.Itoa(int(brokerID))}).Inc()
+	return true
+}
+
+// Status returns the brokers rolling restart status, expiring the restarts older than the roll window
+func (t *brokerRollTracker) Status(now int64) BrokerRollStatus {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	status := BrokerRollStatus{
+		BrokersDown:      make([]int32, 0, len(t.down)),
+		BrokersRestarted: make([]int32, 0, len(t.restarted)),
+	}
+	for id := range t.down {
+		status.BrokersDown = append(status.BrokersDown, id)
+	}
+	for id, restart := range t.restarted {
+		if now-restart > t.window {
+			delete(t.restarted, id)
+			continue
+		}
+		status.BrokersRestarted = append(status.BrokersRestarted, id)
+		if restart > status.LastRestart {
+			status.LastRestart = restart
+		}
+	}
+	sort.Slice(status.BrokersDown, func(i, j int) bool { return status.BrokersDown[i] < status.BrokersDown[j] })
+	sort.Slice(status.BrokersRestarted, func(i, j int) bool { return status.BrokersRestarted[i] < status.BrokersRestarted[j] })
+	status.UpgradeInProgress = len(status.BrokersDown) > 0 || len(status.BrokersRestarted) > 0
+	return status
+}
+
+// isBrokerDown returns true if the connection error means the broker is not running, the connection is refused
+// or reset, rather than a TLS or authentication failure
+func isBrokerDown(err error) bool {
+	return util.IsDisconnection(err) || errors.Is(err, syscall.ECONNREFUSED)
+}
@@ -8,28 +8,92 @@ package services
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"hash/crc32"
 )
 
+const (
+	// CanaryMessageVersion is the latest schema version of the canary messages, the messages are produced with the
+	// first version having their fields, so that they are decoded by the canaries not supporting the latest one yet
+	CanaryMessageVersion = 4
+	// first schema version, the messages without the version field
+	legacyCanaryMessageVersion = 1
+	// first schema version with the payload checksum
+	checksumCanaryMessageVersion = 3
+	// first schema version with the custom payload
+	payloadCanaryMessageVersion = 4
+)
+
+// ErrChecksumMismatch defines the error returned when decoding a canary message whose payload doesn't match its checksum
+var ErrChecksumMismatch = errors.New("canary message checksum mismatch")
+
+// ErrUnknownMessageVersion defines the error returned when decoding a canary message with a schema version
+// not supported, i.e. produced by a newer canary on the same topic during a rolling upgrade
+type ErrUnknownMessageVersion struct {
+	Version int
+}
+
+func (e *ErrUnknownMessageVersion) Error() string {
+	return fmt.Sprintf("unknown canary message version %d, supported versions %d to %d", e.Version, legacyCanaryMessageVersion, CanaryMessageVersion)
+}
+
 // CanaryMessage defines the payload of a canary message
 type CanaryMessage struct {
 	ProducerID string `json:"producerId"`
 	MessageID  int    `json:"messageId"`
 	Timestamp  int64  `json:"timestamp"`
+	// schema version, missing in the legacy messages
+	Version int `json:"version,omitempty"`
+	// CRC32 of the message payload without the checksum, since version 3
+	Checksum uint32 `json:"checksum,omitempty"`
+	// custom payload rendered from the configured template, since version 4
+	Payload string `json:"payload,omitempty"`
 }
 
 func NewCanaryMessage(bytes []byte) CanaryMessage {
+	cm, _ := DecodeCanaryMessage(bytes)
+	return cm
+}
+
+// DecodeCanaryMessage decodes a canary message accepting the current and the previous schema versions,
+// the fields unknown to the current version are ignored. The checksum is verified for the versions having it,
+// returning ErrChecksumMismatch if the payload was corrupted
+func DecodeCanaryMessage(bytes []byte) (CanaryMessage, error) {
 	var cm CanaryMessage
-	json.Unmarshal(bytes, &cm)
+	if err := json.Unmarshal(bytes, &cm); err != nil {
+		return cm, err
+	}
+	if cm.Version == 0 {
+		cm.Version = legacyCanaryMessageVersion
+	}
+	if cm.Version < legacyCanaryMessageVersion || cm.Version > CanaryMessageVersion {
+		return cm, &ErrUnknownMessageVersion{Version: cm.Version}
+	}
+	if cm.Version >= checksumCanaryMessageVersion && cm.Checksum != cm.checksum() {
+		return cm, ErrChecksumMismatch
+	}
+	return cm, nil
+}
+
+// WithChecksum returns the canary message with the checksum of its payload
+func (cm CanaryMessage) WithChecksum() CanaryMessage {
+	cm.Checksum = cm.checksum()
 	return cm
 }
 
+// checksum returns the CRC32 of the message JSON payload without the checksum
+func (cm CanaryMessage) checksum() uint32 {
+	cm.Checksum = 0
+	return crc32.ChecksumIEEE([]byte(cm.Json()))
+}
+
 func (cm CanaryMessage) Json() string {
 	json, _ := json.Marshal(cm)
 	return string(json)
 }
 
 func (cm CanaryMessage) String() string {
-	return fmt.Sprintf("{ProducerID:%s, MessageID:%d, Timestamp:%d}",
-		cm.ProducerID, cm.MessageID, cm.Timestamp)
+	return fmt.Sprintf("{ProducerID:%s, MessageID:%d, Timestamp:%d, Version:%d, Checksum:%d, Payload:%s}",
+		cm.ProducerID, cm.MessageID, cm.Timestamp, cm.Version, cm.Checksum, cm.Payload)
 }
@@ -9,6 +9,7 @@
 package services
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/Shopify/sarama"
@@ -31,6 +32,7 @@ func TestCanaryMessageEncode(t *testing.T) {
 		ProducerID: "producer-id",
 		MessageID:  0,
 		Timestamp:  12345,
+		Version:    CanaryMessageVersion,
 	}
 	encoder := sarama.StringEncoder(cm.Json())
 	bytes, _ := encoder.Encode()
@@ -52,3 +54,55 @@ func TestCanaryMessageEncode(t *testing.T) {
 		t.Errorf("got %v should be different from %v", decodedCm, cm)
 	}
 }
+
+func TestDecodeCanaryMessage(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected CanaryMessage
+		wantErr  bool
+	}{
+		{"legacy", `{"producerId":"producer-id","messageId":1,"timestamp":12345}`,
+			CanaryMessage{ProducerID: "producer-id", MessageID: 1, Timestamp: 12345, Version: 1}, false},
+		{"previous", `{"producerId":"producer-id","messageId":1,"timestamp":12345,"version":2}`,
+			CanaryMessage{ProducerID: "producer-id", MessageID: 1, Timestamp: 12345, Version: 2}, false},
+		{"unknown fields", `{"producerId":"producer-id","messageId":1,"timestamp":12345,"version":2,"other":"value"}`,
+			CanaryMessage{ProducerID: "producer-id", MessageID: 1, Timestamp: 12345, Version: 2}, false},
+		{"newer version", `{"producerId":"producer-id","messageId":1,"timestamp":12345,"version":5}`,
+			CanaryMessage{ProducerID: "producer-id", MessageID: 1, Timestamp: 12345, Version: 5}, true},
+		{"not JSON", `not a canary message`, CanaryMessage{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cm, err := DecodeCanaryMessage([]byte(tt.value))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error: got = %v, wantErr = %t", err, tt.wantErr)
+			}
+			if cm != tt.expected {
+				t.Errorf("got = %v, want = %v", cm, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCanaryMessageChecksum(t *testing.T) {
+	cm := CanaryMessage{
+		ProducerID: "producer-id",
+		MessageID:  1,
+		Timestamp:  12345,
+		Version:    CanaryMessageVersion,
+	}.WithChecksum()
+	decoded, err := DecodeCanaryMessage([]byte(cm.Json()))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if decoded != cm {
+		t.Errorf("got = %v, want = %v", decoded, cm)
+	}
+
+	// a flipped digit in the timestamp
+	corrupted := strings.Replace(cm.Json(), "12345", "12346", 1)
+	if _, err := DecodeCanaryMessage([]byte(corrupted)); err != ErrChecksumMismatch {
+		t.Errorf("got = %v, want = %v", err, ErrChecksumMismatch)
+	}
+}
@@ -0,0 +1,183 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package services defines some canary related services
+package services
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/strimzi/strimzi-canary/internal/clients"
+	"github.com/strimzi/strimzi-canary/internal/config"
+)
+
+var (
+	chaosLeaderElections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name:      "chaos_leader_elections_total",
+		Namespace: "strimzi_canary",
+		Help:      "Total number of leader elections triggered on the canary topic by the chaos mode",
+	}, nil)
+
+	chaosLeaderElectionError = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name:      "chaos_leader_election_error_total",
+		Namespace: "strimzi_canary",
+		Help:      "Total number of errors while triggering leader elections on the canary topic by the chaos mode",
+	}, nil)
+
+	chaosLeaderElectionImpact = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name:      "chaos_leader_election_produce_failures",
+		Namespace: "strimzi_canary",
+		Help:      "Number of records failed to be produced between the last chaos leader election and the following one",
+	}, nil)
+)
+
+// ChaosService defines the service periodically moving the canary topic partitions leadership, through preferred
+// replica leader elections, in order to validate that the failover is transparent to the clients
+type ChaosService struct {
+	canaryConfig  *config.CanaryConfig
+	clientFactory clients.Factory
+	admin         clients.Admin
+	// records failed to be produced when the last leader election was triggered
+	failedAtElection uint64
+	elected          bool
+}
+
+func init() {
+	RegisterCheck(ChaosLoop, newChaosCheck)
+}
+
+// newChaosCheck returns the chaos leader elections check, strictly opt-in
+func newChaosCheck(canaryConfig *config.CanaryConfig, clientFactory clients.Factory) Check {
+	if canaryConfig.ChaosLeaderElectionInterval == 0 {
+		return nil
+	}
+	return NewChaosService(canaryConfig, clientFactory)
+}
+
+// NewChaosService returns an instance of ChaosService
+func NewChaosService(canaryConfig *config.CanaryConfig, clientFactory clients.Factory) *ChaosService {
+	cs := ChaosService{
+		canaryConfig:  canaryConfig,
+		clientFactory: clientFactory,
+	}
+	return &cs
+}
+
+// Name returns the chaos check name
+func (cs *ChaosService) Name() string {
+	return ChaosLoop
+}
+
+// Interval returns the interval between the chaos leader elections
+func (cs *ChaosService) Interval() time.Duration {
+	return cs.canaryConfig.ChaosLeaderElectionInterval * time.Millisecond
+}
+
+// Run triggers a chaos leader election
+func (cs *ChaosService) Run(ctx context.Context) {
+	cs.leaderElection()
+}
+
+// Metrics returns the chaos leader elections metrics
+func (cs *ChaosService) Metrics() []prometheus.Collector {
+	return []prometheus.Collector{chaosLeaderElections, chaosLeaderElectionError, chaosLeaderElectionImpact}
+}
+
+// Close closes the underneath Kafka admin instance
+func (cs *ChaosService) Close() {
+	glog.Infof("Closing chaos service")
+
+	if cs.admin != nil {
+		if err := cs.admin.Close(); err != nil {
+			glog.Errorf("Error closing the Kafka admin: %v", err)
+		}
+		cs.admin = nil
+	}
+	glog.Infof("Chaos service closed")
+}
+
+// leaderElection moves the leadership of the canary topic partitions
+//
+// The partitions led by the preferred replica get the replicas order rotated, so that a new preferred replica is
+// elected as leader. The partitions not led by the preferred replica (i.e. because the topic reconcile restored the
+// original replicas order) get the leadership back to the preferred replica. In both cases, the leadership moves.
+//
+// The impact on the clients is measured as the number of records failed to be produced until the next leader election.
+func (cs *ChaosService) leaderElection() {
+	failed := atomic.LoadUint64(&recordsProducedFailedCounter)
+	if cs.elected {
+		impact := failed - cs.failedAtElection
+		chaosLeaderElectionImpact.With(nil).Set(float64(impact))
+		glog.Infof("Chaos leader election impact: %d records failed to be produced", impact)
+	}
+
+	if err := cs.electLeaders(); err != nil {
+		chaosLeaderElectionError.With(nil).Inc()
+		glog.Errorf("Error triggering chaos leader election: %v", err)
+		if clients.IsFatal(err) && cs.admin != nil {
+			cs.admin.Close()
+			cs.admin = nil
+			recordClientRecreation(AdminBootstrapClient)
+		}
+		cs.elected = false
+		return
+	}
+	chaosLeaderElections.With(nil).Inc()
+	cs.failedAtElection = failed
+	cs.elected = true
+}
+
+func (cs *ChaosService) electLeaders() error {
+	if cs.admin == nil {
+		admin, err := cs.clientFactory.NewAdmin(cs.canaryConfig.BootstrapServers)
+		if err != nil {
+			return err
+		}
+		cs.admin = &instrumentedAdmin{admin: admin}
+	}
+
+	metadata, err := cs.admin.DescribeTopic(cs.canaryConfig.Topic)
+	if err != nil {
+		return err
+	}
+	if metadata.Err != nil {
+		return metadata.Err
+	}
+
+	assignments, rotated := rotatedAssignments(metadata)
+	if rotated {
+		if err := cs.admin.AlterPartitionReassignments(cs.canaryConfig.Topic, assignments); err != nil {
+			return err
+		}
+	}
+
+	partitions := make([]int32, 0, len(metadata.Partitions))
+	for _, p := range metadata.Partitions {
+		partitions = append(partitions, p.ID)
+	}
+	glog.Infof("Triggering chaos leader election on topic %s partitions %v", cs.canaryConfig.Topic, partitions)
+	return cs.admin.ElectPreferredLeaders(cs.canaryConfig.Topic, partitions)
+}
+
+// rotatedAssignments returns the replicas assignments for all the topic partitions, indexed by partition, with the
+// replicas order rotated for the partitions led by the preferred replica, and if any partition was rotated
+func rotatedAssignments(metadata *clients.TopicMetadata) ([][]int32, bool) {
+	assignments := make([][]int32, len(metadata.Partitions))
+	rotated := false
+	for _, p := range metadata.Partitions {
+		replicas := p.Replicas
+		if len(replicas) > 1 && p.Leader == replicas[0] {
+			replicas = append(append([]int32{}, replicas[1:]...), replicas[0])
+			rotated = true
+		}
+		assignments[p.ID] = replicas
+	}
+	return assignments, rotated
+}
@@ -0,0 +1,35 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// +build unit_test
+
+// Package services defines some canary related services
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/strimzi/strimzi-canary/internal/clients"
+)
+
+func TestRotatedAssignments(t *testing.T) {
+	metadata := &clients.TopicMetadata{
+		Partitions: []*clients.PartitionMetadata{
+			{ID: 1, Leader: 2, Replicas: []int32{2, 0, 1}},
+			{ID: 0, Leader: 1, Replicas: []int32{0, 1, 2}},
+			{ID: 2, Leader: 0, Replicas: []int32{0}},
+		},
+	}
+	assignments, rotated := rotatedAssignments(metadata)
+	expected := [][]int32{{0, 1, 2}, {0, 1, 2}, {0}}
+	if !rotated || !reflect.DeepEqual(assignments, expected) {
+		t.Errorf("got = %v (rotated = %t), want = %v (rotated = true)", assignments, rotated, expected)
+	}
+	// original metadata not modified
+	if !reflect.DeepEqual(metadata.Partitions[0].Replicas, []int32{2, 0, 1}) {
+		t.Errorf("metadata replicas modified %v", metadata.Partitions[0].Replicas)
+	}
+}
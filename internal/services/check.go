@@ -0,0 +1,153 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/strimzi/strimzi-canary/internal/clients"
+	"github.com/strimzi/strimzi-canary/internal/config"
+)
+
+// Check defines a check run periodically by the checks scheduler, alongside the core canary loop
+type Check interface {
+	// Name returns the check name, used as the loop label of the self-health metrics and for disabling the check
+	Name() string
+	// Interval returns how often the check runs
+	Interval() time.Duration
+	// Run runs the check once, the context is done when the scheduler is stopping
+	Run(ctx context.Context)
+	// Metrics returns the metrics reported by the check, registered only when the check is enabled
+	Metrics() []prometheus.Collector
+	// Close releases the Kafka clients used by the check
+	Close()
+}
+
+// StartupCheck defines a check not depending on the canary topic, i.e. the built-in connection and status checks,
+// started along with the canary instead of after the first successful topic reconcile
+type StartupCheck interface {
+	Check
+	// RunOnStartup returns true if the check has to run as soon as it's started, without waiting for the first tick
+	RunOnStartup() bool
+}
+
+// CheckFactory returns the check created from the canary configuration, nil when the check is not configured
+type CheckFactory func(canaryConfig *config.CanaryConfig, clientFactory clients.Factory) Check
+
+// registeredCheck is the factory of a pluggable check, along with the name the check is disabled by
+type registeredCheck struct {
+	name    string
+	factory CheckFactory
+}
+
+// pluggable checks, in registration order
+var registeredChecks []registeredCheck
+
+// RegisterCheck registers the factory of a pluggable check, a new check needs just to be registered on init
+func RegisterCheck(name string, factory CheckFactory) {
+	registeredChecks = append(registeredChecks, registeredCheck{name: name, factory: factory})
+}
+
+// NewChecks returns the registered checks configured, and not disabled, in the canary configuration
+func NewChecks(canaryConfig *config.CanaryConfig, clientFactory clients.Factory) []Check {
+	checks := make([]Check, 0, len(registeredChecks))
+	for _, rc := range registeredChecks {
+		if canaryConfig.IsCheckDisabled(rc.name) {
+			continue
+		}
+		if check := rc.factory(canaryConfig, clientFactory); check != nil {
+			checks = append(checks, check)
+		}
+	}
+	return checks
+}
+
+// CheckScheduler runs the registered checks, each one on its own interval
+type CheckScheduler struct {
+	canaryConfig *config.CanaryConfig
+	checks       []Check
+	ctx          context.Context
+	cancel       context.CancelFunc
+	syncStop     sync.WaitGroup
+}
+
+// NewCheckScheduler returns an instance of CheckScheduler
+func NewCheckScheduler(canaryConfig *config.CanaryConfig) *CheckScheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &CheckScheduler{
+		canaryConfig: canaryConfig,
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+}
+
+// Register adds the check to the scheduler, registering its metrics, unless the check is disabled
+func (cs *CheckScheduler) Register(check Check) {
+	if cs.canaryConfig.IsCheckDisabled(check.Name()) {
+		glog.Infof("Check %s disabled", check.Name())
+		return
+	}
+	glog.Infof("Registering %s check, running every %d ms", check.Name(), check.Interval().Milliseconds())
+	prometheus.MustRegister(check.Metrics()...)
+	cs.checks = append(cs.checks, check)
+}
+
+// StartStartupChecks starts the loops running the registered startup checks
+func (cs *CheckScheduler) StartStartupChecks() {
+	for _, check := range cs.checks {
+		if sc, ok := check.(StartupCheck); ok {
+			if sc.RunOnStartup() {
+				sc.Run(cs.ctx)
+			}
+			cs.syncStop.Add(1)
+			go cs.loop(check)
+		}
+	}
+}
+
+// Start starts the loops running the other registered checks
+func (cs *CheckScheduler) Start() {
+	for _, check := range cs.checks {
+		if _, ok := check.(StartupCheck); !ok {
+			cs.syncStop.Add(1)
+			go cs.loop(check)
+		}
+	}
+}
+
+func (cs *CheckScheduler) loop(check Check) {
+	defer cs.syncStop.Done()
+	ticker := time.NewTicker(check.Interval())
+	defer ticker.Stop()
+	for {
+		select {
+		case tick := <-ticker.C:
+			run := StartLoopRun(check.Name(), tick)
+			check.Run(cs.ctx)
+			run.Done()
+		case <-cs.ctx.Done():
+			glog.Infof("Stopping %s loop", check.Name())
+			return
+		}
+	}
+}
+
+// Stop stops the loops, waiting for the in progress checks, and closes the checks
+func (cs *CheckScheduler) Stop() {
+	glog.Infof("Stopping checks scheduler")
+	cs.cancel()
+	cs.syncStop.Wait()
+	for _, check := range cs.checks {
+		check.Close()
+	}
+	glog.Infof("Checks scheduler stopped")
+}
@@ -0,0 +1,130 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// +build unit_test
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/strimzi/strimzi-canary/internal/config"
+)
+
+// fakeCheck counts the runs and records if it was closed
+type fakeCheck struct {
+	runs   int32
+	closed bool
+}
+
+func (c *fakeCheck) Name() string {
+	return "fake"
+}
+
+func (c *fakeCheck) Interval() time.Duration {
+	return time.Millisecond
+}
+
+func (c *fakeCheck) Run(ctx context.Context) {
+	atomic.AddInt32(&c.runs, 1)
+}
+
+func (c *fakeCheck) Metrics() []prometheus.Collector {
+	return []prometheus.Collector{prometheus.NewCounter(prometheus.CounterOpts{Name: "fake_check_total"})}
+}
+
+func (c *fakeCheck) Close() {
+	c.closed = true
+}
+
+func TestCheckScheduler(t *testing.T) {
+	check := &fakeCheck{}
+	scheduler := NewCheckScheduler(&config.CanaryConfig{})
+	scheduler.Register(check)
+	scheduler.Start()
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt32(&check.runs) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	scheduler.Stop()
+	if atomic.LoadInt32(&check.runs) == 0 {
+		t.Errorf("Check never run")
+	}
+	if !check.closed {
+		t.Errorf("Check not closed on scheduler stop")
+	}
+}
+
+// fakeStartupCheck is a fake check started along with the canary, running right away
+type fakeStartupCheck struct {
+	fakeCheck
+}
+
+func (c *fakeStartupCheck) Name() string {
+	return "fake_startup"
+}
+
+func (c *fakeStartupCheck) Interval() time.Duration {
+	return time.Hour
+}
+
+func (c *fakeStartupCheck) Metrics() []prometheus.Collector {
+	return nil
+}
+
+func (c *fakeStartupCheck) RunOnStartup() bool {
+	return true
+}
+
+func TestCheckSchedulerStartupChecks(t *testing.T) {
+	check := &fakeCheck{}
+	startupCheck := &fakeStartupCheck{}
+	scheduler := NewCheckScheduler(&config.CanaryConfig{})
+	scheduler.checks = []Check{check, startupCheck}
+	scheduler.StartStartupChecks()
+	// the startup check runs right away, the other one waits for the scheduler start
+	if runs := atomic.LoadInt32(&startupCheck.runs); runs != 1 {
+		t.Errorf("Startup check runs = %d, expected = 1", runs)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if runs := atomic.LoadInt32(&check.runs); runs != 0 {
+		t.Errorf("Check runs = %d before the scheduler start, expected none", runs)
+	}
+	scheduler.Stop()
+	if !check.closed || !startupCheck.closed {
+		t.Errorf("Checks not closed on scheduler stop")
+	}
+}
+
+func TestCheckSchedulerDisabled(t *testing.T) {
+	scheduler := NewCheckScheduler(&config.CanaryConfig{ChecksDisabled: []string{"fake_startup"}})
+	scheduler.Register(&fakeStartupCheck{})
+	if len(scheduler.checks) != 0 {
+		t.Errorf("Checks = %d, expected the disabled check not registered", len(scheduler.checks))
+	}
+}
+
+func TestNewChecks(t *testing.T) {
+	if checks := NewChecks(&config.CanaryConfig{}, nil); len(checks) != 0 {
+		t.Errorf("Checks = %d, expected none enabled", len(checks))
+	}
+	checks := NewChecks(&config.CanaryConfig{ChaosLeaderElectionInterval: 60000}, nil)
+	if len(checks) != 1 || checks[0].Name() != ChaosLoop {
+		t.Fatalf("Checks = %v, expected the chaos check only", checks)
+	}
+	if checks[0].Interval() != time.Minute {
+		t.Errorf("Interval = %v, expected = %v", checks[0].Interval(), time.Minute)
+	}
+	disabled := NewChecks(&config.CanaryConfig{ChaosLeaderElectionInterval: 60000, ChecksDisabled: []string{ChaosLoop}}, nil)
+	if len(disabled) != 0 {
+		t.Errorf("Checks = %v, expected the disabled chaos check not created", disabled)
+	}
+}
@@ -0,0 +1,85 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	circuitBreakerOpen = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name:      "producer_circuit_breaker_open",
+		Namespace: "strimzi_canary",
+		Help:      "If the producer circuit breaker is open (1) pausing the sends because the cluster is unavailable, or closed (0)",
+	}, []string{"clientid"})
+)
+
+// circuitBreaker opens after a number of consecutive failed cycles and stays open until it's explicitly closed
+type circuitBreaker struct {
+	// consecutive failed cycles for opening the circuit, 0 means the circuit never opens
+	threshold int
+	// threshold set through the dynamic configuration, if any, overriding the configured one
+	dynamicConfig *dynamicConfig
+	failures      int
+	open          bool
+	mutex         sync.Mutex
+}
+
+// Failure records a failed cycle, returns true if the circuit has just been opened
+func (cb *circuitBreaker) Failure() bool {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+	cb.failures++
+	threshold := cb.Threshold()
+	if threshold > 0 && !cb.open && cb.failures >= threshold {
+		cb.open = true
+		return true
+	}
+	return false
+}
+
+// Threshold returns the threshold set through the dynamic configuration, if any, or the configured one
+func (cb *circuitBreaker) Threshold() int {
+	if cb.dynamicConfig != nil {
+		if threshold := cb.dynamicConfig.CircuitBreakerThreshold(); threshold >= 0 {
+			return int(threshold)
+		}
+	}
+	return cb.threshold
+}
+
+// Success records a successful cycle, resetting the consecutive failures
+func (cb *circuitBreaker) Success() {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+	cb.failures = 0
+}
+
+// Close closes the circuit
+func (cb *circuitBreaker) Close() {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+	cb.failures = 0
+	cb.open = false
+}
+
+// Failures returns the current consecutive failed cycles
+func (cb *circuitBreaker) Failures() int {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+	return cb.failures
+}
+
+// IsOpen returns true if the circuit is open
+func (cb *circuitBreaker) IsOpen() bool {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+	return cb.open
+}
@@ -0,0 +1,45 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// +build unit_test
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"testing"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	cb := &circuitBreaker{threshold: 3}
+
+	cb.Failure()
+	cb.Failure()
+	// a successful cycle resets the consecutive failures
+	cb.Success()
+	if cb.Failure() || cb.Failure() || cb.IsOpen() {
+		t.Errorf("circuit opened before the threshold")
+	}
+	if !cb.Failure() || !cb.IsOpen() {
+		t.Errorf("circuit not opened on the threshold")
+	}
+	// already open
+	if cb.Failure() {
+		t.Errorf("circuit opened twice")
+	}
+	cb.Close()
+	if cb.IsOpen() {
+		t.Errorf("circuit not closed")
+	}
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	cb := &circuitBreaker{threshold: 0}
+	for i := 0; i < 10; i++ {
+		if cb.Failure() {
+			t.Errorf("disabled circuit opened")
+		}
+	}
+}
@@ -0,0 +1,37 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"github.com/golang/glog"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/strimzi/strimzi-canary/internal/clients"
+)
+
+var (
+	clientAuthFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name:      "client_auth_failures_total",
+		Namespace: "strimzi_canary",
+		Help:      "Total number of authentication and authorization failures, by Kafka client role",
+	}, []string{"clientid", "role"})
+)
+
+// recordAuthFailure records the err, if it's an authentication or authorization failure, for the provided
+// Kafka client role, so that a missing ACL for the producer or the consumer identity is reported separately
+func recordAuthFailure(clientID string, role string, err error) {
+	if !clients.IsAuthError(err) {
+		return
+	}
+	labels := prometheus.Labels{
+		"clientid": clientID,
+		"role":     role,
+	}
+	clientAuthFailures.With(labels).Inc()
+	glog.Warningf("Kafka %s authentication or authorization failure: %v", role, err)
+}
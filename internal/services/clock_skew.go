@@ -0,0 +1,70 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	brokerClockSkew = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name:      "broker_clock_skew_ms",
+		Namespace: "strimzi_canary",
+		Help:      "Estimated clock skew in milliseconds of the broker, as partition leader, compared to the canary clock",
+	}, []string{"clientid", "brokerid"})
+
+	// tracks the current leader of each canary topic partition, as got by the topic reconcile
+	partitionLeaders = &partitionLeadersTracker{}
+)
+
+// partitionLeadersTracker tracks the current leader of each canary topic partition
+type partitionLeadersTracker struct {
+	mutex   sync.Mutex
+	leaders map[int32]int32
+}
+
+// Update replaces the current leaders with the provided ones
+func (t *partitionLeadersTracker) Update(leaders map[int32]int32) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.leaders = leaders
+}
+
+// Leader returns the current leader of the partition, if known
+func (t *partitionLeadersTracker) Leader(partition int32) (int32, bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	leader, ok := t.leaders[partition]
+	return leader, ok
+}
+
+// estimateClockSkew returns the estimated clock skew (in ms) between the broker, assigning the log append time, and the
+// canary, using the middle point between sending the record and getting the response as the canary local time
+func estimateClockSkew(sendStart int64, sendEnd int64, logAppendTime time.Time) int64 {
+	return logAppendTime.UnixNano()/int64(time.Millisecond) - (sendStart+sendEnd)/2
+}
+
+// observeClockSkew reports the clock skew of the partition leader, estimated from the log append time of a produced record
+func observeClockSkew(clientID string, partition int32, sendStart int64, sendEnd int64, logAppendTime time.Time) {
+	leader, ok := partitionLeaders.Leader(partition)
+	if !ok {
+		return
+	}
+	skew := estimateClockSkew(sendStart, sendEnd, logAppendTime)
+	glog.V(1).Infof("Clock skew of broker %d, leader of partition %d, is %d ms", leader, partition, skew)
+	labels := prometheus.Labels{
+		"clientid": clientID,
+		"brokerid": strconv.Itoa(int(leader)),
+	}
+	brokerClockSkew.With(labels).Set(float64(skew))
+}
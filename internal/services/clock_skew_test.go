@@ -0,0 +1,27 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// +build unit_test
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEstimateClockSkew(t *testing.T) {
+	// broker clock 500 ms ahead, with a 100 ms round trip
+	logAppendTime := time.Unix(0, (1050+500)*int64(time.Millisecond))
+	if skew := estimateClockSkew(1000, 1100, logAppendTime); skew != 500 {
+		t.Errorf("got = %d, want = %d", skew, 500)
+	}
+	// broker clock 200 ms behind
+	logAppendTime = time.Unix(0, (1050-200)*int64(time.Millisecond))
+	if skew := estimateClockSkew(1000, 1100, logAppendTime); skew != -200 {
+		t.Errorf("got = %d, want = %d", skew, -200)
+	}
+}
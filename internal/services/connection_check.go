@@ -7,97 +7,160 @@
 package services
 
 import (
+	"context"
+	"fmt"
 	"strconv"
-	"sync"
 	"time"
 
-	"github.com/Shopify/sarama"
 	"github.com/golang/glog"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/strimzi/strimzi-canary/internal/clients"
 	"github.com/strimzi/strimzi-canary/internal/config"
 	"github.com/strimzi/strimzi-canary/internal/util"
 )
 
+const (
+	// DefaultListenerName is the name of the listener the canary bootstrap servers belong to
+	DefaultListenerName = "default"
+)
+
 var (
 	connectionError = promauto.NewCounterVec(prometheus.CounterOpts{
 		Name:      "connection_error_total",
 		Namespace: "strimzi_canary",
 		Help:      "Total number of errors while checking the connection to Kafka brokers",
-	}, []string{"brokerid", "connected"})
+	}, []string{"brokerid", "connected", "listener"})
 
 	// it's defined when the service is created because buckets are configurable
 	connectionLatency *prometheus.HistogramVec
 )
 
+// BrokerConnectionResult defines the result of the connection check to a broker
+type BrokerConnectionResult struct {
+	BrokerID  int32
+	Listener  string
+	Connected bool
+	// time (in ms) needed to establish the connection or to fail
+	Latency int64
+	Error   string `json:",omitempty"`
+}
+
+// listenerCheck defines the connection check state of a listener, each one has its own admin client
+// for getting the brokers addresses on the listener
+type listenerCheck struct {
+	name             string
+	bootstrapServers []string
+	interval         time.Duration
+	admin            clients.Admin
+	brokers          []clients.Broker
+}
+
 type ConnectionService struct {
-	canaryConfig *config.CanaryConfig
-	saramaConfig *sarama.Config
-	admin        sarama.ClusterAdmin
-	brokers      []*sarama.Broker
-	stop         chan struct{}
-	syncStop     sync.WaitGroup
+	canaryConfig  *config.CanaryConfig
+	clientFactory clients.Factory
+	// the default listener first, then the additional ones
+	listeners []*listenerCheck
+	trackers  *Trackers
 }
 
 // NewConnectionService returns an instance of ConnectionService
-func NewConnectionService(canaryConfig *config.CanaryConfig, saramaConfig *sarama.Config) *ConnectionService {
+func NewConnectionService(canaryConfig *config.CanaryConfig, clientFactory clients.Factory, trackers *Trackers) *ConnectionService {
 	connectionLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
 		Name:      "connection_latency",
 		Namespace: "strimzi_canary",
 		Help:      "Latency in milliseconds for established or failed connections",
 		Buckets:   canaryConfig.ConnectionCheckLatencyBuckets,
-	}, []string{"brokerid", "connected"})
+	}, []string{"brokerid", "connected", "listener"})
 
-	// lazy creation of the Sarama cluster admin client when connections are checked for the first time or it's closed
+	// lazy creation of the Kafka admin clients when connections are checked for the first time or they are closed
 	cs := ConnectionService{
-		canaryConfig: canaryConfig,
-		saramaConfig: saramaConfig,
-		admin:        nil,
+		canaryConfig:  canaryConfig,
+		clientFactory: clientFactory,
+		listeners:     newListenerChecks(canaryConfig),
+		trackers:      trackers,
 	}
 	return &cs
 }
 
-// Open starts the connection check loop
-func (cs *ConnectionService) Open() {
-	cs.stop = make(chan struct{})
-	cs.syncStop.Add(1)
-
-	cs.connectionCheck()
-
-	ticker := time.NewTicker(cs.canaryConfig.ConnectionCheckInterval * time.Millisecond)
-	go func() {
-		for {
-			select {
-			case <-ticker.C:
-				cs.connectionCheck()
-			case <-cs.stop:
-				ticker.Stop()
-				defer cs.syncStop.Done()
-				glog.Infof("Stopping connection check loop")
-				return
-			}
+// newListenerChecks returns the listeners to check, the default one and the additional configured ones
+func newListenerChecks(canaryConfig *config.CanaryConfig) []*listenerCheck {
+	listeners := []*listenerCheck{{
+		name:             DefaultListenerName,
+		bootstrapServers: canaryConfig.BootstrapServers,
+		interval:         canaryConfig.ConnectionCheckInterval,
+	}}
+	for _, l := range canaryConfig.ConnectionCheckListeners {
+		interval := l.Interval
+		if interval == 0 {
+			interval = canaryConfig.ConnectionCheckInterval
 		}
-	}()
+		listeners = append(listeners, &listenerCheck{name: l.Name, bootstrapServers: l.BootstrapServers, interval: interval})
+	}
+	return listeners
+}
+
+// listenerConnectionCheck runs the connection check of a listener as a check run by the checks scheduler
+type listenerConnectionCheck struct {
+	cs *ConnectionService
+	lc *listenerCheck
+}
+
+// Name returns the connection check name for the default listener, suffixed with the listener name for the others
+func (c *listenerConnectionCheck) Name() string {
+	if c.lc.name == DefaultListenerName {
+		return ConnectionCheckLoop
+	}
+	return ConnectionCheckLoop + "_" + c.lc.name
+}
+
+func (c *listenerConnectionCheck) Interval() time.Duration {
+	return c.lc.interval * time.Millisecond
+}
+
+func (c *listenerConnectionCheck) Run(ctx context.Context) {
+	c.cs.connectionCheck(c.lc)
+}
+
+func (c *listenerConnectionCheck) Metrics() []prometheus.Collector {
+	return nil
 }
 
-// Close stops the connection check loop and closes the underneath Sarama admin instance
+// Close does nothing, the admin client of the listener is closed along with the service
+func (c *listenerConnectionCheck) Close() {}
+
+func (c *listenerConnectionCheck) RunOnStartup() bool {
+	return true
+}
+
+// Checks returns a connection check for each listener, running on its own interval
+func (cs *ConnectionService) Checks() []Check {
+	checks := make([]Check, 0, len(cs.listeners))
+	for _, lc := range cs.listeners {
+		checks = append(checks, &listenerConnectionCheck{cs: cs, lc: lc})
+	}
+	return checks
+}
+
+// Close closes the underneath Kafka admin instances, the connection checks have to be already stopped
 func (cs *ConnectionService) Close() {
 	glog.Infof("Closing connection check service")
 
-	// ask to stop the ticker reconcile loop and wait
-	close(cs.stop)
-	cs.syncStop.Wait()
-
-	if err := cs.admin.Close(); err != nil {
-		glog.Fatalf("Error closing the Sarama cluster admin: %v", err)
+	for _, lc := range cs.listeners {
+		if lc.admin != nil {
+			if err := lc.admin.Close(); err != nil {
+				glog.Errorf("Error closing the Kafka admin: %v", err)
+			}
+			lc.admin = nil
+		}
 	}
-	cs.admin = nil
 	glog.Infof("Connection check service closed")
 }
 
-// connectionCheck does a connection check to the Kafka brokers
+// connectionCheck does a connection check to the Kafka brokers on the listener
 //
-// It uses the Sarama admin client to get brokers metadata and then the low level Broker "object" in order to:
+// It uses the Kafka admin client, bootstrapping through the listener, to get brokers metadata, so the brokers addresses
+// advertised on the listener, and then a new short-lived connection to each broker in order to:
 //
 // 1. open a connection
 // 2. check if the connection was ok
@@ -109,58 +172,90 @@ func (cs *ConnectionService) Close() {
 // metadata on each check so it doesn't try to connect to not running brokers (the user could have scaled down the cluster).
 //
 // It also reports the time needed to open a connection successfully or connection errors as metrics.
-func (cs *ConnectionService) connectionCheck() {
+func (cs *ConnectionService) connectionCheck(lc *listenerCheck) ([]BrokerConnectionResult, error) {
 	var err error
 
-	if cs.admin == nil {
-		glog.Infof("Creating Sarama cluster admin")
-		admin, err := sarama.NewClusterAdmin(cs.canaryConfig.BootstrapServers, cs.saramaConfig)
+	if lc.admin == nil {
+		glog.Infof("Creating Kafka admin on listener %s", lc.name)
+		admin, err := cs.clientFactory.NewAdmin(lc.bootstrapServers)
 		if err != nil {
-			glog.Errorf("Error creating the Sarama cluster admin: %v", err)
-			return
+			glog.Errorf("Error creating the Kafka admin on listener %s: %v", lc.name, err)
+			return nil, err
 		}
-		cs.admin = admin
+		lc.admin = admin
 	}
 
-	if cs.isDynamicScalingEnabled() || cs.canaryConfig.ExpectedClusterSize != len(cs.brokers) {
-		cs.brokers, _, err = cs.admin.DescribeCluster()
+	if cs.isDynamicScalingEnabled() || cs.canaryConfig.ExpectedClusterSize != len(lc.brokers) {
+		lc.brokers, err = lc.admin.DescribeCluster()
 		if err != nil {
-			if util.IsDisconnection(err) {
+			if clients.IsFatal(err) {
 				// Kafka brokers close connection to the admin client not able to recover
 				// Sarama issues: https://github.com/Shopify/sarama/issues/2042, https://github.com/Shopify/sarama/issues/1796
 				// Workaround closing the admin client and the reopen on next connection check
-				if err := cs.admin.Close(); err != nil {
-					glog.Fatalf("Error closing the Sarama cluster admin: %v", err)
+				if err := lc.admin.Close(); err != nil {
+					glog.Errorf("Error closing the Kafka admin: %v", err)
 				}
-				cs.admin = nil
+				lc.admin = nil
+				recordClientRecreation(AdminBootstrapClient)
 			}
-			glog.Errorf("Error describing cluster: %v", err)
-			return
+			glog.Errorf("Error describing cluster on listener %s: %v", lc.name, err)
+			return nil, err
 		}
 	}
 
-	for _, b := range cs.brokers {
+	results := make([]BrokerConnectionResult, 0, len(lc.brokers))
+	for _, b := range lc.brokers {
 
 		start := util.NowInMilliseconds() // timestamp in milliseconds
-		// ignore error because it will be reported by Connected() call if "not connected"
-		b.Open(cs.saramaConfig)
-		connected, err := b.Connected()
+		err := cs.clientFactory.CheckConnection(b)
+		connected := err == nil
+		// the brokers health is tracked through the default listener only, the additional ones failing (i.e. a route
+		// not reachable) doesn't mean the brokers are down or not safe to roll
+		if lc.name == DefaultListenerName {
+			cs.trackers.brokerRoll.Connection(b.ID, err, start)
+			cs.trackers.safeToRoll.Observe(b.ID, err, start)
+		}
 		duration := util.NowInMilliseconds() - start
 
 		labels := prometheus.Labels{
-			"brokerid":  strconv.Itoa(int(b.ID())),
+			"brokerid":  strconv.Itoa(int(b.ID)),
 			"connected": strconv.FormatBool(connected),
+			"listener":  lc.name,
 		}
 
 		if connected {
-			b.Close()
-			glog.V(1).Infof("Connected to broker %d in %d ms", b.ID(), duration)
+			glog.V(1).Infof("Connected to broker %d on listener %s in %d ms", b.ID, lc.name, duration)
 		} else {
 			connectionError.With(labels).Inc()
-			glog.Errorf("Error connecting to broker %d in %d ms (error [%v])", b.ID(), duration, err)
+			lastError.Record(ConnectionErrorSource, fmt.Errorf("error connecting to broker %d on listener %s: %v", b.ID, lc.name, err))
+			canaryEvents.Record(Event{Type: ConnectionFailedEvent, Partition: noPartition, BrokerID: b.ID, Error: err.Error()})
+			glog.Errorf("Error connecting to broker %d on listener %s in %d ms (error [%v])", b.ID, lc.name, duration, err)
 		}
 		connectionLatency.With(labels).Observe(float64(duration))
+
+		result := BrokerConnectionResult{BrokerID: b.ID, Listener: lc.name, Connected: connected, Latency: duration}
+		if err != nil {
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// Check runs an immediate connection check to the Kafka brokers on all the listeners, it has to be used when the
+// connection check loops are not running. The error is the first one describing the cluster on a listener, the
+// results on the other listeners are returned anyway
+func (cs *ConnectionService) Check() ([]BrokerConnectionResult, error) {
+	var checkErr error
+	results := make([]BrokerConnectionResult, 0)
+	for _, lc := range cs.listeners {
+		listenerResults, err := cs.connectionCheck(lc)
+		if err != nil && checkErr == nil {
+			checkErr = fmt.Errorf("listener %s: %w", lc.name, err)
+		}
+		results = append(results, listenerResults...)
 	}
+	return results, checkErr
 }
 
 // If the "dynamic" scaling is enabled
@@ -0,0 +1,135 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// +build unit_test
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/strimzi/strimzi-canary/internal/clients"
+	"github.com/strimzi/strimzi-canary/internal/config"
+	"github.com/strimzi/strimzi-canary/internal/util"
+)
+
+// fakeConnectionAdmin is a Kafka admin returning the provided brokers
+type fakeConnectionAdmin struct {
+	clients.Admin
+	brokers []clients.Broker
+}
+
+func (a *fakeConnectionAdmin) DescribeCluster() ([]clients.Broker, error) {
+	return a.brokers, nil
+}
+
+// fakeConnectionFactory is a Kafka clients factory whose connections to the brokers fail with the provided error
+type fakeConnectionFactory struct {
+	clients.Factory
+	admin         *fakeConnectionAdmin
+	connectionErr error
+}
+
+func (f *fakeConnectionFactory) NewAdmin(bootstrapServers []string) (clients.Admin, error) {
+	return f.admin, nil
+}
+
+func (f *fakeConnectionFactory) CheckConnection(broker clients.Broker) error {
+	return f.connectionErr
+}
+
+func TestListenerChecks(t *testing.T) {
+	canaryConfig := &config.CanaryConfig{
+		BootstrapServers:        []string{"my-cluster-kafka-bootstrap:9092"},
+		ConnectionCheckInterval: 120000,
+		ConnectionCheckListeners: []config.ConnectionCheckListener{
+			{Name: "external", BootstrapServers: []string{"my-cluster-kafka-external-bootstrap:443"}, Interval: 60000},
+			{Name: "route", BootstrapServers: []string{"broker-0.example.com:443"}},
+		},
+	}
+
+	listeners := newListenerChecks(canaryConfig)
+	expected := []struct {
+		name     string
+		server   string
+		interval time.Duration
+	}{
+		{DefaultListenerName, "my-cluster-kafka-bootstrap:9092", 120000},
+		{"external", "my-cluster-kafka-external-bootstrap:443", 60000},
+		// the connection check interval is used when not set
+		{"route", "broker-0.example.com:443", 120000},
+	}
+	if len(listeners) != len(expected) {
+		t.Fatalf("Listeners = %d, expected = %d", len(listeners), len(expected))
+	}
+	for i, e := range expected {
+		lc := listeners[i]
+		if lc.name != e.name || lc.bootstrapServers[0] != e.server || lc.interval != e.interval {
+			t.Errorf("Listener = {%s %v %d}, expected = {%s %s %d}", lc.name, lc.bootstrapServers, lc.interval, e.name, e.server, e.interval)
+		}
+	}
+}
+
+func TestConnectionCheckTrackersDefaultListenerOnly(t *testing.T) {
+	canaryConfig := &config.CanaryConfig{
+		BootstrapServers:         []string{"my-cluster-kafka-bootstrap:9092"},
+		ConnectionCheckListeners: []config.ConnectionCheckListener{{Name: "route", BootstrapServers: []string{"broker-0.example.com:443"}}},
+		ExpectedClusterSize:      config.ExpectedClusterSizeDefault,
+		SafeToRollWindow:         60000,
+	}
+	factory := &fakeConnectionFactory{admin: &fakeConnectionAdmin{brokers: []clients.Broker{{ID: 0}}}, connectionErr: syscall.ECONNREFUSED}
+	trackers := NewTrackers(canaryConfig)
+	cs := NewConnectionService(canaryConfig, factory, trackers)
+
+	// the route not reachable doesn't mean the broker is down
+	if _, err := cs.connectionCheck(cs.listeners[1]); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	roll := trackers.brokerRoll.Status(util.NowInMilliseconds())
+	if len(roll.BrokersDown) != 0 {
+		t.Errorf("BrokersDown = %v, expected none", roll.BrokersDown)
+	}
+	if statuses := trackers.safeToRoll.Status(roll, "client", util.NowInMilliseconds()); len(statuses) != 0 {
+		t.Errorf("Safe to roll = %+v, expected no brokers observed", statuses)
+	}
+
+	if _, err := cs.connectionCheck(cs.listeners[0]); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	roll = trackers.brokerRoll.Status(util.NowInMilliseconds())
+	if len(roll.BrokersDown) != 1 || roll.BrokersDown[0] != 0 {
+		t.Errorf("BrokersDown = %v, expected = [0]", roll.BrokersDown)
+	}
+	if statuses := trackers.safeToRoll.Status(roll, "client", util.NowInMilliseconds()); len(statuses) != 1 || statuses[0].SafeToRoll {
+		t.Errorf("Safe to roll = %+v, expected broker 0 not safe to roll", statuses)
+	}
+}
+
+func TestListenerConnectionCheckNames(t *testing.T) {
+	canaryConfig := &config.CanaryConfig{
+		ConnectionCheckListeners: []config.ConnectionCheckListener{{Name: "external"}},
+		ChecksDisabled:           []string{"connection_check_external"},
+	}
+	cs := &ConnectionService{canaryConfig: canaryConfig, listeners: newListenerChecks(canaryConfig)}
+	checks := cs.Checks()
+	if name := checks[0].Name(); name != ConnectionCheckLoop {
+		t.Errorf("Default listener check name = %s, expected = %s", name, ConnectionCheckLoop)
+	}
+	if name := checks[1].Name(); name != "connection_check_external" {
+		t.Errorf("Additional listener check name = %s, expected = connection_check_external", name)
+	}
+
+	// only the disabled listener check is not registered
+	scheduler := NewCheckScheduler(canaryConfig)
+	for _, check := range checks {
+		scheduler.Register(check)
+	}
+	if len(scheduler.checks) != 1 || scheduler.checks[0].Name() != ConnectionCheckLoop {
+		t.Errorf("Registered checks = %v, expected the default listener one only", scheduler.checks)
+	}
+}
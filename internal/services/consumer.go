@@ -8,19 +8,22 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"go.opentelemetry.io/contrib/instrumentation/github.com/Shopify/sarama/otelsarama"
 	"go.opentelemetry.io/otel"
 	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
 	"go.opentelemetry.io/otel/trace"
 
-	"github.com/Shopify/sarama"
 	"github.com/golang/glog"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 
+	"github.com/strimzi/strimzi-canary/internal/clients"
 	"github.com/strimzi/strimzi-canary/internal/config"
 	"github.com/strimzi/strimzi-canary/internal/util"
 )
@@ -36,6 +39,8 @@ const (
 
 var (
 	RecordsConsumedCounter uint64 = 0
+	// records consumed with an end-to-end latency within the SLO latency threshold
+	RecordsConsumedWithinLatencyCounter uint64 = 0
 
 	recordsConsumed = promauto.NewCounterVec(prometheus.CounterOpts{
 		Name:      "records_consumed_total",
@@ -52,6 +57,18 @@ var (
 	// it's defined when the service is created because buckets are configurable
 	recordsEndToEndLatency *prometheus.HistogramVec
 
+	recordsConsumedUnknownVersion = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name:      "records_consumed_unknown_version_total",
+		Namespace: "strimzi_canary",
+		Help:      "The total number of consumed records skipped for having an unknown canary message schema version",
+	}, []string{"clientid", "version"})
+
+	recordsConsumedCorrupted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name:      "records_consumed_corrupted_total",
+		Namespace: "strimzi_canary",
+		Help:      "The total number of consumed records with a corrupted payload, not decodable or not matching the checksum",
+	}, []string{"clientid", "partition"})
+
 	timeoutJoinGroup = promauto.NewCounterVec(prometheus.CounterOpts{
 		Name:      "consumer_timeout_join_group_total",
 		Namespace: "strimzi_canary",
@@ -59,174 +76,448 @@ var (
 	}, []string{"clientid"})
 )
 
+// ErrJoinGroupTimeout defines the error returned when the consumer doesn't join the group in time
+var ErrJoinGroupTimeout = errors.New("consumer joining group timed out")
+
 // ConsumerService defines the service for consuming messages
 type ConsumerService struct {
 	canaryConfig  *config.CanaryConfig
-	client        sarama.Client
-	consumerGroup sarama.ConsumerGroup
-	// reference to the function for cancelling the Sarama consumer group context
-	// in order to ending the session and allowing a rejoin with rebalancing
-	cancel context.CancelFunc
-	ready  chan bool
+	clientFactory clients.Factory
+	// the consumer group is recreated on unrecoverable errors
+	consumerGroup      clients.ConsumerGroup
+	consumerGroupMutex sync.Mutex
+	// topic to consume from, the canary one or the mirrored one on the target cluster when replication check is enabled
+	topic string
+	// bootstrap servers of the cluster to consume from
+	bootstrapServers []string
+	// closed, so the consumer group is not recreated anymore
+	closed bool
+	// channels to notify when awaited canary messages are consumed, by message ID
+	waiters      map[int]chan ConsumedRecord
+	waitersMutex sync.Mutex
+	// handler of the current consume session, whose context is cancelled for ending the session
+	// and allowing a rejoin with rebalancing
+	session      *consumerGroupHandler
+	sessionMutex sync.Mutex
+	// re-resolving the bootstrap servers and rebuilding the consumer group on repeated errors
+	dnsReResolver *dnsReResolver
+	// detecting the consumer group rebalancing too often
+	rebalanceStorm *rebalanceStormDetector
+	trackers       *Trackers
 }
 
 // NewConsumerService returns an instance of ConsumerService
-func NewConsumerService(canaryConfig *config.CanaryConfig, client sarama.Client) *ConsumerService {
-	recordsEndToEndLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+func NewConsumerService(canaryConfig *config.CanaryConfig, clientFactory clients.Factory, consumerGroup clients.ConsumerGroup, trackers *Trackers) *ConsumerService {
+	recordsEndToEndLatency = promauto.NewHistogramVec(latencyHistogramOpts(canaryConfig, prometheus.HistogramOpts{
 		Name:      "records_consumed_latency",
 		Namespace: "strimzi_canary",
 		Help:      "Records end-to-end latency in milliseconds",
 		Buckets:   canaryConfig.EndToEndLatencyBuckets,
-	}, []string{"clientid", "partition"})
-	consumerGroup, err := sarama.NewConsumerGroupFromClient(canaryConfig.ConsumerGroupID, client)
-	if err != nil {
-		glog.Fatalf("Error creating the Sarama consumer: %v", err)
+	}), []string{"clientid", "partition"})
+	topic := canaryConfig.Topic
+	bootstrapServers := canaryConfig.BootstrapServers
+	if canaryConfig.IsReplicationCheckEnabled() {
+		recordsReplicationLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+			Name:      "records_replication_latency",
+			Namespace: "strimzi_canary",
+			Help:      "Records latency in milliseconds between producing on the source cluster and appending to the mirrored topic on the target cluster",
+			Buckets:   canaryConfig.ReplicationLatencyBuckets,
+		}, []string{"clientid", "partition"})
+		topic = canaryConfig.TargetTopic
+		bootstrapServers = canaryConfig.TargetBootstrapServers
+	}
+	// with the replication check, the consumer metrics are related to the target topic partitions instead
+	if !canaryConfig.IsReplicationCheckEnabled() {
+		partitionMetrics.Register(recordsConsumed, recordsEndToEndLatency, logTruncationsDetected, recordsConsumedFetchSource, recordsConsumedCorrupted)
 	}
 	cs := ConsumerService{
-		canaryConfig:  canaryConfig,
-		client:        client,
-		consumerGroup: consumerGroup,
-		ready:         make(chan bool),
+		canaryConfig:     canaryConfig,
+		clientFactory:    clientFactory,
+		topic:            topic,
+		bootstrapServers: bootstrapServers,
+		consumerGroup:    consumerGroup,
+		waiters:          make(map[int]chan ConsumedRecord),
+		dnsReResolver:    newDNSReResolver(ConsumerBootstrapClient, canaryConfig.DNSReResolutionThreshold),
+		rebalanceStorm:   newRebalanceStormDetector(canaryConfig.RebalanceStormThreshold, int64(canaryConfig.RebalanceStormWindow)),
+		trackers:         trackers,
 	}
-	go func() {
-		labels := prometheus.Labels{
-			"clientid": canaryConfig.ClientID,
+	go cs.handleErrors(consumerGroup)
+	return &cs
+}
+
+// handleErrors reports the errors from the consumer group, until it's closed
+func (cs *ConsumerService) handleErrors(consumerGroup clients.ConsumerGroup) {
+	labels := prometheus.Labels{
+		"clientid": cs.canaryConfig.ClientID,
+	}
+
+	for err := range consumerGroup.Errors() {
+		glog.Errorf("Error received whilst consuming from topic: %v", err)
+		recordsConsumerFailed.With(labels).Inc()
+		lastError.Record(ConsumerErrorSource, err)
+		canaryEvents.Record(Event{Type: ConsumeFailedEvent, Partition: noPartition, BrokerID: noBroker, Error: err.Error()})
+		recordAuthFailure(cs.canaryConfig.ClientID, ConsumerBootstrapClient, err)
+		if clients.IsFatal(err) || cs.dnsReResolver.Failure(cs.bootstrapServers) {
+			go cs.recreate(consumerGroup)
 		}
+	}
+}
 
-		for err := range consumerGroup.Errors() {
-			glog.Errorf("Error received whilst consuming from topic: %v", err)
-			recordsConsumerFailed.With(labels).Inc()
+// recreate replaces the failed Kafka consumer group, hitting an unrecoverable error, with a new one and starts consuming again
+//
+// The recreation is retried with a backoff until the new consumer group joins, it was already recreated on a previous
+// error or the service is closed
+func (cs *ConsumerService) recreate(failed clients.ConsumerGroup) {
+	backoff := NewBackoff(maxConsumeAttempts, 5000*time.Millisecond, MaxDefault)
+	for {
+		consumerGroup, err := cs.replace(failed)
+		if err == nil {
+			return
 		}
-	}()
-	return &cs
+		glog.Errorf("Error recreating the Kafka consumer group: %v", err)
+		// the new consumer group not joining is the one to replace on the next attempt
+		if consumerGroup != nil {
+			failed = consumerGroup
+		}
+		delay, backoffErr := backoff.Delay()
+		if backoffErr != nil {
+			// retrying at the max delay, without a consumer group the canary doesn't work
+			delay = MaxDefault
+		}
+		time.Sleep(delay)
+	}
 }
 
-// Consume starts a Sarama consumer group instance consuming messages
+// replace replaces the failed Kafka consumer group with a new one and joins the group, returning the new consumer
+// group, nil if it wasn't created, and the error if it didn't join
+func (cs *ConsumerService) replace(failed clients.ConsumerGroup) (clients.ConsumerGroup, error) {
+	cs.consumerGroupMutex.Lock()
+	defer cs.consumerGroupMutex.Unlock()
+	// already recreated on a previous error or closed
+	if cs.closed || cs.consumerGroup != failed {
+		return nil, nil
+	}
+	glog.Warningf("Recreating the Kafka consumer group after an unrecoverable error")
+	consumerGroup, err := cs.clientFactory.NewConsumerGroup(cs.bootstrapServers, cs.canaryConfig.ConsumerGroupID)
+	if err != nil {
+		// keeping the failed one, the recreation is tried again
+		recordAuthFailure(cs.canaryConfig.ClientID, ConsumerBootstrapClient, err)
+		return nil, err
+	}
+	cs.cancelSession()
+	if err := failed.Close(); err != nil {
+		glog.Warningf("Error closing the failed Kafka consumer group: %v", err)
+	}
+	cs.consumerGroup = consumerGroup
+	recordClientRecreation(ConsumerBootstrapClient)
+	go cs.handleErrors(consumerGroup)
+	return consumerGroup, cs.join(consumerGroup)
+}
+
+// Refresh makes the consumer rejoin the group, so that partitions added to the canary topic are assigned as well
+//
+// If the consumer doesn't rejoin the group, the consumer group is recreated
+func (cs *ConsumerService) Refresh() {
+	cs.consumerGroupMutex.Lock()
+	glog.Infof("Refreshing the consumer for rejoining the group")
+	cs.cancelSession()
+	consumerGroup := cs.consumerGroup
+	err := cs.consume(consumerGroup)
+	cs.consumerGroupMutex.Unlock()
+	if err != nil {
+		glog.Errorf("Error refreshing the consumer: %v", err)
+		go cs.recreate(consumerGroup)
+	}
+}
+
+// Consume starts a Kafka consumer group instance consuming messages
 //
-// This function starts a goroutine calling in an endless loop the consume on the Sarama consumer group
-// It can be exited cancelling the corresponding context through the cancel function provided by the ConsumerService instance
-// Before returning, it waits for the consumer to join the group for all the topic partitions
-func (cs *ConsumerService) Consume() {
+// This function starts a goroutine calling in an endless loop the consume on the Kafka consumer group
+// It can be exited cancelling the current session context
+// Before returning, it waits for the consumer to join the group for all the topic partitions, returning an error
+// if it didn't join after the max attempts
+func (cs *ConsumerService) Consume() error {
+	cs.consumerGroupMutex.Lock()
+	defer cs.consumerGroupMutex.Unlock()
+	return cs.consume(cs.consumerGroup)
+}
+
+// consume joins the group with the consumer group, retrying with a backoff
+func (cs *ConsumerService) consume(consumerGroup clients.ConsumerGroup) error {
 	backoff := NewBackoff(maxConsumeAttempts, 5000*time.Millisecond, MaxDefault)
 	for {
-		cgh := &consumerGroupHandler{
-			consumerService: cs,
+		err := cs.join(consumerGroup)
+		if err == nil {
+			return nil
 		}
-		h := otelsarama.WrapConsumerGroupHandler(cgh)
-
-		// creating new context with cancellation, for exiting Consume when metadata refresh is needed
-		ctx, cancel := context.WithCancel(context.Background())
-		cs.cancel = cancel
-		go func() {
-			// the Consume has to be in a loop, because each time a metadata refresh happens, this method exits
-			// and needs to be called again for a new session and rejoining group
-			for {
-
-				glog.Infof("Consumer group consume starting...")
-				// this method calls the methods handler on each stage: setup, consume and cleanup
-				if err := cs.consumerGroup.Consume(ctx, []string{cs.canaryConfig.Topic}, h); err != nil {
-					glog.Errorf("Error consuming topic: %s", err.Error())
-					time.Sleep(consumeDelay)
-					continue
-				}
+		delay, backoffErr := backoff.Delay()
+		if backoffErr != nil {
+			return fmt.Errorf("error joining the consumer group: %v", backoffErr)
+		}
+		time.Sleep(delay)
+	}
+}
 
-				// check if context was cancelled, because of forcing a refresh metadata or exiting the consumer
-				if ctx.Err() != nil {
-					glog.Infof("Consumer group context cancelled")
+// join starts a new consume session, calling the consume on the Kafka consumer group in its own goroutine,
+// and waits for the consumer to join the group
+func (cs *ConsumerService) join(consumerGroup clients.ConsumerGroup) error {
+	cgh := cs.newSession()
+	go func() {
+		// the Consume has to be in a loop, because each time a metadata refresh happens, this method exits
+		// and needs to be called again for a new session and rejoining group
+		for {
+
+			glog.Infof("Consumer group consume starting...")
+			// this method calls the methods handler on each stage: setup, consume and cleanup
+			if err := consumerGroup.Consume(cgh.ctx, []string{cs.topic}, cgh); err != nil {
+				glog.Errorf("Error consuming topic: %s", err.Error())
+				recordAuthFailure(cs.canaryConfig.ClientID, ConsumerBootstrapClient, err)
+				if clients.IsFatal(err) {
+					go cs.recreate(consumerGroup)
 					return
 				}
-				cs.ready = make(chan bool)
-			}
-		}()
-
-		glog.Infof("Waiting consumer group to be up and running")
-		// wait that the consumer is now subscribed to all partitions
-		if isTimeout := cs.wait(waitConsumeTimeout); isTimeout {
-			cs.cancel()
-			labels := prometheus.Labels{
-				"clientid": cs.canaryConfig.ClientID,
+				time.Sleep(consumeDelay)
+				continue
 			}
-			timeoutJoinGroup.With(labels).Inc()
-			glog.Warningf("Consumer joining group timed out!")
-			delay, err := backoff.Delay()
-			if err != nil {
-				glog.Fatalf("Error joining the consumer group: %v", err)
+
+			// check if context was cancelled, because of forcing a refresh metadata or exiting the consumer
+			if cgh.ctx.Err() != nil {
+				glog.Infof("Consumer group context cancelled")
+				return
 			}
-			time.Sleep(delay)
-		} else {
-			glog.Infof("Sarama consumer group up and running")
-			break
 		}
+	}()
+
+	glog.Infof("Waiting consumer group to be up and running")
+	// wait that the consumer is now subscribed to all partitions
+	if isTimeout := cgh.wait(waitConsumeTimeout); isTimeout {
+		cgh.cancel()
+		labels := prometheus.Labels{
+			"clientid": cs.canaryConfig.ClientID,
+		}
+		timeoutJoinGroup.With(labels).Inc()
+		glog.Warningf("Consumer joining group timed out!")
+		return ErrJoinGroupTimeout
 	}
+	glog.Infof("Consumer group up and running")
+	return nil
 }
 
-// Waits on the wait group about the consumer joining the group and starting
-//
-// It is possible to specify a timeout on waiting
-// Returns true if waiting timed out, otherwise false
-func (cs *ConsumerService) wait(timeout time.Duration) bool {
-	c := make(chan struct{})
-	go func() {
-		defer close(c)
-		<-cs.ready
-	}()
-	select {
-	case <-c:
-		return false
-	case <-time.After(timeout):
-		return true
+// newSession returns the handler of a new consume session, as the current one
+func (cs *ConsumerService) newSession() *consumerGroupHandler {
+	ctx, cancel := context.WithCancel(context.Background())
+	cgh := &consumerGroupHandler{
+		consumerService: cs,
+		ctx:             ctx,
+		cancel:          cancel,
+		ready:           make(chan bool),
+	}
+	cs.sessionMutex.Lock()
+	defer cs.sessionMutex.Unlock()
+	cs.session = cgh
+	return cgh
+}
+
+// cancelSession cancels the current consume session, if any
+func (cs *ConsumerService) cancelSession() {
+	cs.sessionMutex.Lock()
+	defer cs.sessionMutex.Unlock()
+	if cs.session != nil {
+		cs.session.cancel()
 	}
 }
 
-// Close closes the underneath Sarama consumer group instance
-func (cs *ConsumerService) Close() {
+// Close stops consuming and closes the underneath Kafka consumer group instance, committing the offsets, until the context is done
+func (cs *ConsumerService) Close(ctx context.Context) {
 	glog.Infof("Closing consumer")
-	cs.cancel()
-	err := cs.consumerGroup.Close()
+	cs.cancelSession()
+	var err error
+	cs.consumerGroupMutex.Lock()
+	cs.closed = true
+	consumerGroup := cs.consumerGroup
+	cs.consumerGroupMutex.Unlock()
+	if ctxErr := util.WaitWithContext(ctx, func() { err = consumerGroup.Close() }); ctxErr != nil {
+		glog.Warningf("Consumer not closed in time: %v", ctxErr)
+		return
+	}
 	if err != nil {
-		glog.Fatalf("Error closing the Sarama consumer: %v", err)
+		glog.Errorf("Error closing the Kafka consumer: %v", err)
+		return
 	}
 	glog.Infof("Consumer closed")
 }
 
-// consumerGroupHandler defines the handler for the consuming Sarama functions
+// ConsumedRecord defines information about a consumed canary message
+type ConsumedRecord struct {
+	Partition int32
+	Offset    int64
+	// end-to-end latency in ms
+	Latency int64
+}
+
+// Await returns a channel on which the consumer notifies when the canary message with the provided ID is consumed
+//
+// The caller has to call StopAwaiting when the notification is not needed anymore
+func (cs *ConsumerService) Await(messageID int) <-chan ConsumedRecord {
+	cs.waitersMutex.Lock()
+	defer cs.waitersMutex.Unlock()
+	// buffered so that the consumer never blocks on notifying
+	waiter := make(chan ConsumedRecord, 1)
+	cs.waiters[messageID] = waiter
+	return waiter
+}
+
+// StopAwaiting stops waiting for the canary message with the provided ID to be consumed
+func (cs *ConsumerService) StopAwaiting(messageID int) {
+	cs.waitersMutex.Lock()
+	defer cs.waitersMutex.Unlock()
+	delete(cs.waiters, messageID)
+}
+
+func (cs *ConsumerService) notify(cm CanaryMessage, record ConsumedRecord) {
+	if cm.ProducerID != cs.canaryConfig.ClientID {
+		return
+	}
+	cs.waitersMutex.Lock()
+	defer cs.waitersMutex.Unlock()
+	if waiter, ok := cs.waiters[cm.MessageID]; ok {
+		waiter <- record
+		delete(cs.waiters, cm.MessageID)
+	}
+}
+
+// consumerGroupHandler defines the handler for the consumer group lifecycle and the consumed records
 type consumerGroupHandler struct {
 	consumerService *ConsumerService
+	// context of the consume session, cancelled on refresh or closing
+	ctx    context.Context
+	cancel context.CancelFunc
+	// closed when the consumer joins the group the first time in the session
+	ready     chan bool
+	readyOnce sync.Once
 }
 
-func (cgh *consumerGroupHandler) Setup(sarama.ConsumerGroupSession) error {
-	glog.Infof("Consumer group setup")
-	// signaling the consumer group is ready
-	close(cgh.consumerService.ready)
-	return nil
+// wait waits for the consumer to join the group, returning true if waiting timed out
+func (cgh *consumerGroupHandler) wait(timeout time.Duration) bool {
+	select {
+	case <-cgh.ready:
+		return false
+	case <-time.After(timeout):
+		return true
+	}
 }
 
-func (cgh *consumerGroupHandler) Cleanup(sarama.ConsumerGroupSession) error {
-	glog.Infof("Consumer group cleanup")
-	return nil
+func (cgh *consumerGroupHandler) Setup() {
+	glog.Infof("Consumer group setup")
+	canaryEvents.Record(Event{Type: RebalanceEvent, Partition: noPartition, BrokerID: noBroker})
+	if count := cgh.consumerService.rebalanceStorm.Rebalanced(util.NowInMilliseconds()); count > 0 {
+		glog.Warningf("Consumer group rebalance storm detected: %d rebalances within %d ms", count, cgh.consumerService.canaryConfig.RebalanceStormWindow)
+		canaryEvents.Record(Event{Type: RebalanceStormEvent, Partition: noPartition, BrokerID: noBroker,
+			Error: fmt.Sprintf("%d rebalances within %d ms", count, cgh.consumerService.canaryConfig.RebalanceStormWindow)})
+	}
+	cgh.consumerService.trackers.logTruncation.ResetConsumed()
+	// signaling the consumer group is ready, the setup happens again on each rebalance
+	cgh.readyOnce.Do(func() { close(cgh.ready) })
 }
 
-func (cgh *consumerGroupHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
-	glog.Infof("Consumer group consumeclaim on %s [%d]", claim.Topic(), claim.Partition())
+func (cgh *consumerGroupHandler) Handle(record *clients.Record) {
 	tr := otel.Tracer("consumer")
-	for message := range claim.Messages() {
-		ctx := otel.GetTextMapPropagator().Extract(context.Background(), otelsarama.NewConsumerMessageCarrier(message))
-		_, span := tr.Start(ctx, "consume message", trace.WithAttributes(
-			semconv.MessagingOperationProcess,
-		))
-		timestamp := util.NowInMilliseconds() // timestamp in milliseconds
-		cm := NewCanaryMessage(message.Value)
-		duration := timestamp - cm.Timestamp
-		glog.V(1).Infof("Message received: value=%+v, partition=%d, offset=%d, duration=%d ms", cm, message.Partition, message.Offset, duration)
+	_, span := tr.Start(record.Context, "consume message", trace.WithAttributes(
+		semconv.MessagingOperationProcess,
+	))
+	timestamp := util.NowInMilliseconds() // timestamp in milliseconds
+	labels := prometheus.Labels{
+		"clientid":  cgh.consumerService.canaryConfig.ClientID,
+		"partition": partitionLabel(cgh.consumerService.canaryConfig, record.Partition),
+	}
+	cm, err := DecodeCanaryMessage(record.Value)
+	if err != nil {
 		span.End()
-		session.MarkMessage(message, "")
-		labels := prometheus.Labels{
-			"clientid":  cgh.consumerService.canaryConfig.ClientID,
-			"partition": strconv.Itoa(int(message.Partition)),
+		cgh.skip(record, err, labels["partition"])
+		cgh.trackOffset(record, labels["partition"])
+		return
+	}
+	duration := timestamp - cm.Timestamp
+	glog.V(1).Infof("Message received: value=%+v, partition=%d, offset=%d, duration=%d ms", cm, record.Partition, record.Offset, duration)
+	span.End()
+	recordsEndToEndLatency.With(labels).Observe(float64(duration))
+	observeLatencyThresholds(cgh.consumerService.canaryConfig, cgh.consumerService.trackers.dynamicConfig, EndToEndLatency, labels["partition"], duration)
+	partitionsLatencyStats.ObserveEndToEnd(record.Partition, float64(duration))
+	canaryEvents.Record(Event{Type: ConsumedEvent, Partition: record.Partition, BrokerID: noBroker, Latency: float64(duration)})
+	recordsConsumed.With(labels).Inc()
+	atomic.AddUint64(&RecordsConsumedCounter, 1)
+	observeBrokerConsume(cgh.consumerService.canaryConfig, record.Partition)
+	cgh.trackOffset(record, labels["partition"])
+	atomic.StoreInt64(&lastSuccessfulConsume, timestamp)
+	cgh.consumerService.dnsReResolver.Success()
+	// the partition leaders are known for the canary topic only, not for the mirrored one
+	if !cgh.consumerService.canaryConfig.IsReplicationCheckEnabled() {
+		if source := fetchSource(record.Partition, record.FetchBrokerID); source != "" {
+			recordsConsumedFetchSource.With(prometheus.Labels{
+				"clientid":  cgh.consumerService.canaryConfig.ClientID,
+				"partition": labels["partition"],
+				"source":    source,
+			}).Inc()
 		}
-		recordsEndToEndLatency.With(labels).Observe(float64(duration))
-		recordsConsumed.With(labels).Inc()
-		RecordsConsumedCounter++
 	}
-	return nil
+	if duration <= int64(cgh.consumerService.canaryConfig.SLOLatencyThreshold) {
+		atomic.AddUint64(&RecordsConsumedWithinLatencyCounter, 1)
+	}
+	cgh.consumerService.notify(cm, ConsumedRecord{Partition: record.Partition, Offset: record.Offset, Latency: duration})
+	if cgh.consumerService.canaryConfig.IsReplicationCheckEnabled() {
+		if latency, ok := replicationLatency(record, cm); ok {
+			recordsReplicationLatency.With(labels).Observe(float64(latency))
+		}
+		updateReplicationLag(cgh.consumerService.canaryConfig.ClientID, record.Partition, cgh.consumerService.trackers.replication.Replicated(record.Partition, cm.Timestamp))
+	}
+	cgh.process()
+}
+
+// process simulates a slow consumer, delaying the next records by the configured processing delay, so that the lag
+// alerting and the rebalances under slow consumers can be validated through the canary metrics
+func (cgh *consumerGroupHandler) process() {
+	delay := cgh.consumerService.canaryConfig.ConsumerProcessingDelay
+	if delay <= 0 {
+		return
+	}
+	timer := time.NewTimer(delay * time.Millisecond)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-cgh.ctx.Done():
+	}
+}
+
+// skip reports a consumed record which is not a canary message that can be measured, i.e. produced by a newer canary
+// with an unknown schema version during a rolling upgrade or with a corrupted payload
+func (cgh *consumerGroupHandler) skip(record *clients.Record, err error, partition string) {
+	if e, ok := err.(*ErrUnknownMessageVersion); ok {
+		recordsConsumedUnknownVersion.With(prometheus.Labels{
+			"clientid": cgh.consumerService.canaryConfig.ClientID,
+			"version":  strconv.Itoa(e.Version),
+		}).Inc()
+		glog.Warningf("Skipping message on partition %d at offset %d: %v", record.Partition, record.Offset, err)
+		return
+	}
+	// not decodable or not matching the checksum
+	recordsConsumedCorrupted.With(prometheus.Labels{
+		"clientid":  cgh.consumerService.canaryConfig.ClientID,
+		"partition": partition,
+	}).Inc()
+	lastError.Record(ConsumerErrorSource, fmt.Errorf("corrupted message on partition %d at offset %d: %v", record.Partition, record.Offset, err))
+	glog.Errorf("Corrupted message on partition %d at offset %d: %v", record.Partition, record.Offset, err)
+}
+
+// trackOffset tracks the offset of the consumed record for detecting log truncations
+func (cgh *consumerGroupHandler) trackOffset(record *clients.Record, partition string) {
+	if reason := cgh.consumerService.trackers.logTruncation.Consumed(record.Partition, record.Offset); reason != "" {
+		glog.Warningf("Log truncation detected on partition %d at offset %d: %s", record.Partition, record.Offset, reason)
+		canaryEvents.Record(Event{Type: LogTruncationEvent, Partition: record.Partition, BrokerID: noBroker,
+			Error: fmt.Sprintf("%s at offset %d", reason, record.Offset)})
+		logTruncationsDetected.With(prometheus.Labels{
+			"clientid":  cgh.consumerService.canaryConfig.ClientID,
+			"partition": partition,
+			"reason":    reason,
+		}).Inc()
+	}
 }
@@ -0,0 +1,234 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package services defines some canary related services
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/strimzi/strimzi-canary/internal/clients"
+	"github.com/strimzi/strimzi-canary/internal/config"
+)
+
+const (
+	// consumer group anomalies
+	unexpectedMembersAnomaly    = "unexpected_members"
+	unexpectedStrategyAnomaly   = "unexpected_strategy"
+	unassignedPartitionsAnomaly = "unassigned_partitions"
+	notStableAnomaly            = "not_stable"
+
+	consumerGroupStableState = "Stable"
+)
+
+var (
+	consumerGroupMembers = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name:      "consumer_group_members",
+		Namespace: "strimzi_canary",
+		Help:      "Number of members of the canary consumer group",
+	}, []string{"group"})
+
+	consumerGroupUnassignedPartitions = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name:      "consumer_group_unassigned_partitions",
+		Namespace: "strimzi_canary",
+		Help:      "Number of canary topic partitions not assigned to any member of the canary consumer group",
+	}, []string{"group"})
+
+	consumerGroupAnomalies = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name:      "consumer_group_anomalies_total",
+		Namespace: "strimzi_canary",
+		Help:      "Total number of anomalies detected by the canary consumer group check",
+	}, []string{"group", "anomaly"})
+
+	consumerGroupCheckError = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name:      "consumer_group_check_error_total",
+		Namespace: "strimzi_canary",
+		Help:      "Total number of errors while describing the canary consumer group",
+	}, []string{"group"})
+)
+
+// ConsumerGroupCheckService defines the service periodically describing the canary consumer group for verifying
+// the members count, the partitions assignment strategy and that all the canary topic partitions are assigned,
+// so that a group stuck rebalancing is reported before it shows up as missing consumption only
+type ConsumerGroupCheckService struct {
+	canaryConfig  *config.CanaryConfig
+	clientFactory clients.Factory
+	admin         clients.Admin
+	// the consumed topic and cluster, the target ones with the replication check
+	topic            string
+	bootstrapServers []string
+	expectedStrategy string
+}
+
+func init() {
+	RegisterCheck(ConsumerGroupCheckLoop, newConsumerGroupCheck)
+}
+
+// newConsumerGroupCheck returns the consumer group check, nil when it's not enabled
+func newConsumerGroupCheck(canaryConfig *config.CanaryConfig, clientFactory clients.Factory) Check {
+	if canaryConfig.ConsumerGroupCheckInterval == 0 {
+		return nil
+	}
+	return NewConsumerGroupCheckService(canaryConfig, clientFactory)
+}
+
+// NewConsumerGroupCheckService returns an instance of ConsumerGroupCheckService
+func NewConsumerGroupCheckService(canaryConfig *config.CanaryConfig, clientFactory clients.Factory) *ConsumerGroupCheckService {
+	cgcs := ConsumerGroupCheckService{
+		canaryConfig:     canaryConfig,
+		clientFactory:    clientFactory,
+		topic:            canaryConfig.Topic,
+		bootstrapServers: canaryConfig.BootstrapServers,
+		expectedStrategy: canaryConfig.ConsumerGroupExpectedStrategy,
+	}
+	if canaryConfig.IsReplicationCheckEnabled() {
+		cgcs.topic = canaryConfig.TargetTopic
+		cgcs.bootstrapServers = canaryConfig.TargetBootstrapServers
+	}
+	if cgcs.expectedStrategy == "" {
+		cgcs.expectedStrategy = clients.AssignmentStrategy(canaryConfig.KafkaClientBackend)
+	}
+	return &cgcs
+}
+
+// Name returns the consumer group check name
+func (cgcs *ConsumerGroupCheckService) Name() string {
+	return ConsumerGroupCheckLoop
+}
+
+// Interval returns the interval between the consumer group checks
+func (cgcs *ConsumerGroupCheckService) Interval() time.Duration {
+	return cgcs.canaryConfig.ConsumerGroupCheckInterval * time.Millisecond
+}
+
+// Run runs the consumer group check
+func (cgcs *ConsumerGroupCheckService) Run(ctx context.Context) {
+	cgcs.consumerGroupCheck()
+}
+
+// Metrics returns the consumer group check metrics
+func (cgcs *ConsumerGroupCheckService) Metrics() []prometheus.Collector {
+	return []prometheus.Collector{consumerGroupMembers, consumerGroupUnassignedPartitions, consumerGroupAnomalies, consumerGroupCheckError}
+}
+
+// Close closes the underneath Kafka admin instance
+func (cgcs *ConsumerGroupCheckService) Close() {
+	glog.Infof("Closing consumer group check service")
+
+	if cgcs.admin != nil {
+		if err := cgcs.admin.Close(); err != nil {
+			glog.Errorf("Error closing the Kafka admin: %v", err)
+		}
+		cgcs.admin = nil
+	}
+	glog.Infof("Consumer group check service closed")
+}
+
+// consumerGroupCheck describes the canary consumer group and reports the anomalies found
+func (cgcs *ConsumerGroupCheckService) consumerGroupCheck() {
+	group := cgcs.canaryConfig.ConsumerGroupID
+	labels := prometheus.Labels{
+		"group": group,
+	}
+	description, partitions, err := cgcs.describe()
+	if err != nil {
+		consumerGroupCheckError.With(labels).Inc()
+		glog.Errorf("Error describing consumer group %s: %v", group, err)
+		if clients.IsFatal(err) && cgcs.admin != nil {
+			cgcs.admin.Close()
+			cgcs.admin = nil
+			recordClientRecreation(AdminBootstrapClient)
+		}
+		return
+	}
+
+	unassigned := unassignedPartitions(description, cgcs.topic, partitions)
+	consumerGroupMembers.With(labels).Set(float64(len(description.Members)))
+	consumerGroupUnassignedPartitions.With(labels).Set(float64(len(unassigned)))
+
+	anomalies := checkConsumerGroup(description, unassigned, cgcs.canaryConfig.ConsumerGroupExpectedMembers, cgcs.expectedStrategy)
+	for anomaly, detail := range anomalies {
+		consumerGroupAnomalies.With(prometheus.Labels{"group": group, "anomaly": anomaly}).Inc()
+		glog.Warningf("Consumer group %s anomaly: %s", group, detail)
+	}
+	if len(anomalies) == 0 {
+		glog.V(1).Infof("Consumer group %s verified: state=%s, members=%d, strategy=%s",
+			group, description.State, len(description.Members), description.Protocol)
+	}
+}
+
+// describe returns the canary consumer group description and the consumed topic partitions
+func (cgcs *ConsumerGroupCheckService) describe() (*clients.ConsumerGroupDescription, []int32, error) {
+	if cgcs.admin == nil {
+		admin, err := cgcs.clientFactory.NewAdmin(cgcs.bootstrapServers)
+		if err != nil {
+			return nil, nil, err
+		}
+		cgcs.admin = &instrumentedAdmin{admin: admin}
+	}
+
+	description, err := cgcs.admin.DescribeConsumerGroup(cgcs.canaryConfig.ConsumerGroupID)
+	if err != nil {
+		return nil, nil, err
+	}
+	metadata, err := cgcs.admin.DescribeTopic(cgcs.topic)
+	if err != nil {
+		return nil, nil, err
+	}
+	if metadata.Err != nil {
+		return nil, nil, metadata.Err
+	}
+	partitions := make([]int32, 0, len(metadata.Partitions))
+	for _, p := range metadata.Partitions {
+		partitions = append(partitions, p.ID)
+	}
+	return description, partitions, nil
+}
+
+// unassignedPartitions returns the topic partitions not assigned to any member of the consumer group, sorted by ID
+func unassignedPartitions(description *clients.ConsumerGroupDescription, topic string, partitions []int32) []int32 {
+	assigned := make(map[int32]bool, len(partitions))
+	for _, m := range description.Members {
+		for _, p := range m.Assignment[topic] {
+			assigned[p] = true
+		}
+	}
+	unassigned := make([]int32, 0)
+	for _, p := range partitions {
+		if !assigned[p] {
+			unassigned = append(unassigned, p)
+		}
+	}
+	sort.Slice(unassigned, func(i, j int) bool {
+		return unassigned[i] < unassigned[j]
+	})
+	return unassigned
+}
+
+// checkConsumerGroup returns the anomalies of the consumer group, with their details, compared to the expected
+// members count and assignment strategy, an empty expected strategy is not verified
+func checkConsumerGroup(description *clients.ConsumerGroupDescription, unassigned []int32, expectedMembers int, expectedStrategy string) map[string]string {
+	anomalies := make(map[string]string)
+	if description.State != consumerGroupStableState {
+		anomalies[notStableAnomaly] = fmt.Sprintf("state %s, expected %s", description.State, consumerGroupStableState)
+	}
+	if len(description.Members) != expectedMembers {
+		anomalies[unexpectedMembersAnomaly] = fmt.Sprintf("%d members, expected %d", len(description.Members), expectedMembers)
+	}
+	// the strategy is chosen on rebalance, so it's not available while rebalancing or without members
+	if expectedStrategy != "" && description.Protocol != "" && description.Protocol != expectedStrategy {
+		anomalies[unexpectedStrategyAnomaly] = fmt.Sprintf("assignment strategy %s, expected %s", description.Protocol, expectedStrategy)
+	}
+	if len(unassigned) > 0 {
+		anomalies[unassignedPartitionsAnomaly] = fmt.Sprintf("partitions %v not assigned", unassigned)
+	}
+	return anomalies
+}
@@ -0,0 +1,58 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// +build unit_test
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/strimzi/strimzi-canary/internal/clients"
+)
+
+func TestUnassignedPartitions(t *testing.T) {
+	description := &clients.ConsumerGroupDescription{
+		Members: []clients.ConsumerGroupMember{
+			{MemberID: "member-1", Assignment: map[string][]int32{"__strimzi_canary": {0, 2}, "other": {1}}},
+			{MemberID: "member-2"},
+		},
+	}
+	unassigned := unassignedPartitions(description, "__strimzi_canary", []int32{3, 2, 1, 0})
+	if !reflect.DeepEqual(unassigned, []int32{1, 3}) {
+		t.Errorf("got = %v, want = [1 3]", unassigned)
+	}
+}
+
+func TestCheckConsumerGroup(t *testing.T) {
+	member := clients.ConsumerGroupMember{MemberID: "member-1"}
+	tests := []struct {
+		name        string
+		description *clients.ConsumerGroupDescription
+		unassigned  []int32
+		expected    []string
+	}{
+		{"healthy", &clients.ConsumerGroupDescription{State: "Stable", Protocol: "range", Members: []clients.ConsumerGroupMember{member}}, nil, nil},
+		{"rebalancing", &clients.ConsumerGroupDescription{State: "PreparingRebalance", Members: []clients.ConsumerGroupMember{member}}, []int32{0, 1}, []string{notStableAnomaly, unassignedPartitionsAnomaly}},
+		{"empty", &clients.ConsumerGroupDescription{State: "Empty"}, []int32{0}, []string{notStableAnomaly, unexpectedMembersAnomaly, unassignedPartitionsAnomaly}},
+		{"other strategy", &clients.ConsumerGroupDescription{State: "Stable", Protocol: "roundrobin", Members: []clients.ConsumerGroupMember{member}}, nil, []string{unexpectedStrategyAnomaly}},
+		{"additional member", &clients.ConsumerGroupDescription{State: "Stable", Protocol: "range", Members: []clients.ConsumerGroupMember{member, member}}, nil, []string{unexpectedMembersAnomaly}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			anomalies := checkConsumerGroup(tt.description, tt.unassigned, 1, "range")
+			if len(anomalies) != len(tt.expected) {
+				t.Errorf("got = %v, want = %v", anomalies, tt.expected)
+			}
+			for _, anomaly := range tt.expected {
+				if _, ok := anomalies[anomaly]; !ok {
+					t.Errorf("anomaly %s not detected, got = %v", anomaly, anomalies)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// +build unit_test
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/strimzi/strimzi-canary/internal/config"
+)
+
+func TestAwaitConsumedMessage(t *testing.T) {
+	cs := &ConsumerService{
+		canaryConfig: &config.CanaryConfig{ClientID: "my-client"},
+		waiters:      make(map[int]chan ConsumedRecord),
+	}
+
+	waiter := cs.Await(5)
+	// messages from other producers or not awaited are ignored
+	cs.notify(CanaryMessage{ProducerID: "other-client", MessageID: 5}, ConsumedRecord{Partition: 1, Offset: 10})
+	cs.notify(CanaryMessage{ProducerID: "my-client", MessageID: 4}, ConsumedRecord{Partition: 1, Offset: 11})
+	select {
+	case record := <-waiter:
+		t.Fatalf("unexpected notification %+v", record)
+	default:
+	}
+
+	cs.notify(CanaryMessage{ProducerID: "my-client", MessageID: 5}, ConsumedRecord{Partition: 1, Offset: 12, Latency: 20})
+	select {
+	case record := <-waiter:
+		if record.Offset != 12 || record.Latency != 20 {
+			t.Errorf("got = %+v, want = {Partition:1 Offset:12 Latency:20}", record)
+		}
+	default:
+		t.Errorf("expected notification not received")
+	}
+	if len(cs.waiters) != 0 {
+		t.Errorf("got = %d waiters, want = %d", len(cs.waiters), 0)
+	}
+}
+
+func TestConsumerProcessingDelay(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cgh := &consumerGroupHandler{
+		consumerService: &ConsumerService{canaryConfig: &config.CanaryConfig{ConsumerProcessingDelay: 50}},
+		ctx:             ctx,
+	}
+	start := time.Now()
+	cgh.process()
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("processing took %v, want at least 50ms", elapsed)
+	}
+
+	// the delay is interrupted when the consume loop is cancelled
+	cgh.consumerService.canaryConfig.ConsumerProcessingDelay = 60000
+	cancel()
+	start = time.Now()
+	cgh.process()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("processing took %v, want interrupted", elapsed)
+	}
+}
+
+func TestConsumerSessionReady(t *testing.T) {
+	cs := &ConsumerService{
+		canaryConfig:   &config.CanaryConfig{},
+		rebalanceStorm: newRebalanceStormDetector(0, 0),
+		trackers:       NewTrackers(&config.CanaryConfig{}),
+	}
+	first := cs.newSession()
+	if !first.wait(10 * time.Millisecond) {
+		t.Fatalf("ready before joining the group")
+	}
+	// set up again on a rebalance, without closing the ready channel twice
+	first.Setup()
+	first.Setup()
+	if first.wait(10 * time.Millisecond) {
+		t.Fatalf("not ready after joining the group")
+	}
+
+	// a new session is not ready until it joins, and cancelling it doesn't affect the previous one
+	second := cs.newSession()
+	if !second.wait(10 * time.Millisecond) {
+		t.Fatalf("new session ready before joining the group")
+	}
+	cs.cancelSession()
+	if second.ctx.Err() == nil || first.ctx.Err() != nil {
+		t.Errorf("only the current session has to be cancelled")
+	}
+}
@@ -0,0 +1,246 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package services defines some canary related services
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/strimzi/strimzi-canary/internal/clients"
+	"github.com/strimzi/strimzi-canary/internal/config"
+	"github.com/strimzi/strimzi-canary/internal/util"
+)
+
+const (
+	// delayed consume results
+	DelayedRecordRetrieved = "retrieved"
+	DelayedRecordMissing   = "missing"
+	DelayedRecordMismatch  = "mismatch"
+	DelayedRecordError     = "error"
+
+	// timeout for fetching a single delayed record
+	delayedConsumeFetchTimeout = 10 * time.Second
+)
+
+var (
+	delayedConsumeRecords = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name:      "delayed_consume_records_total",
+		Namespace: "strimzi_canary",
+		Help:      "Total number of canary records fetched again after the configured delay, by result",
+	}, []string{"clientid", "result"})
+
+	delayedConsumePending = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name:      "delayed_consume_pending_records",
+		Namespace: "strimzi_canary",
+		Help:      "Number of sampled canary records waiting for the configured delay before being fetched again",
+	}, []string{"clientid"})
+
+	// tracks the produced records sampled for being fetched again after the delay
+	delayedRecords = newDelayedRecordsTracker()
+)
+
+// delayedRecord defines a produced record to be fetched again after the delay
+type delayedRecord struct {
+	partition int32
+	offset    int64
+	messageID int
+	// timestamp in milliseconds the record was produced at
+	produced int64
+}
+
+// delayedRecordsTracker samples the produced records, one per partition on each sampling interval, so that the records
+// waiting for the delay are bounded by the partitions count and the delay, whatever the produce rate is
+type delayedRecordsTracker struct {
+	mutex sync.Mutex
+	// sampling interval in milliseconds, 0 means the tracking is disabled
+	sampleInterval int64
+	lastSampled    map[int32]int64
+	// ordered by produced timestamp
+	pending []delayedRecord
+}
+
+func newDelayedRecordsTracker() *delayedRecordsTracker {
+	return &delayedRecordsTracker{
+		lastSampled: make(map[int32]int64),
+	}
+}
+
+// enable starts sampling the produced records on the provided interval
+func (t *delayedRecordsTracker) enable(sampleInterval time.Duration) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.sampleInterval = sampleInterval.Milliseconds()
+}
+
+// Produced records a record successfully produced to the partition, if it's time for sampling the partition
+func (t *delayedRecordsTracker) Produced(partition int32, offset int64, messageID int, timestamp int64) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.sampleInterval == 0 {
+		return
+	}
+	if last, ok := t.lastSampled[partition]; ok && timestamp-last < t.sampleInterval {
+		return
+	}
+	t.lastSampled[partition] = timestamp
+	t.pending = append(t.pending, delayedRecord{partition: partition, offset: offset, messageID: messageID, produced: timestamp})
+}
+
+// Due removes and returns the records produced at least delay milliseconds before now
+func (t *delayedRecordsTracker) Due(now int64, delay int64) []delayedRecord {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	i := 0
+	for i < len(t.pending) && now-t.pending[i].produced >= delay {
+		i++
+	}
+	due := make([]delayedRecord, i)
+	copy(due, t.pending[:i])
+	t.pending = t.pending[i:]
+	return due
+}
+
+// Pending returns the number of records waiting for the delay
+func (t *delayedRecordsTracker) Pending() int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return len(t.pending)
+}
+
+// DelayedConsumeService defines the service fetching again the canary records after a configurable delay and verifying
+// they are still retrievable, so that a misconfigured retention, failing reads from tiered storage or an aggressive
+// cleanup are detected before the records are expected to expire
+type DelayedConsumeService struct {
+	canaryConfig  *config.CanaryConfig
+	clientFactory clients.Factory
+	fetcher       clients.Fetcher
+}
+
+func init() {
+	RegisterCheck(DelayedConsumeLoop, newDelayedConsumeCheck)
+}
+
+// newDelayedConsumeCheck returns the delayed consume check, nil when it's not enabled
+func newDelayedConsumeCheck(canaryConfig *config.CanaryConfig, clientFactory clients.Factory) Check {
+	if canaryConfig.DelayedConsumeDelay == 0 {
+		return nil
+	}
+	return NewDelayedConsumeService(canaryConfig, clientFactory)
+}
+
+// NewDelayedConsumeService returns an instance of DelayedConsumeService and starts sampling the produced records
+//
+// The records are fetched from the produced canary topic, also with the replication check enabled
+func NewDelayedConsumeService(canaryConfig *config.CanaryConfig, clientFactory clients.Factory) *DelayedConsumeService {
+	delayedRecords.enable(canaryConfig.DelayedConsumeInterval * time.Millisecond)
+	return &DelayedConsumeService{
+		canaryConfig:  canaryConfig,
+		clientFactory: clientFactory,
+	}
+}
+
+// Name returns the delayed consume check name
+func (dcs *DelayedConsumeService) Name() string {
+	return DelayedConsumeLoop
+}
+
+// Interval returns the interval between the delayed consume checks
+func (dcs *DelayedConsumeService) Interval() time.Duration {
+	return dcs.canaryConfig.DelayedConsumeInterval * time.Millisecond
+}
+
+// Run runs the delayed consume check
+func (dcs *DelayedConsumeService) Run(ctx context.Context) {
+	dcs.delayedConsume(ctx)
+}
+
+// Metrics returns the delayed consume check metrics
+func (dcs *DelayedConsumeService) Metrics() []prometheus.Collector {
+	return []prometheus.Collector{delayedConsumeRecords, delayedConsumePending}
+}
+
+// Close closes the underneath Kafka fetcher instance
+func (dcs *DelayedConsumeService) Close() {
+	glog.Infof("Closing delayed consume service")
+
+	if dcs.fetcher != nil {
+		if err := dcs.fetcher.Close(); err != nil {
+			glog.Errorf("Error closing the Kafka fetcher: %v", err)
+		}
+		dcs.fetcher = nil
+	}
+	glog.Infof("Delayed consume service closed")
+}
+
+// delayedConsume fetches again the records produced before the delay and reports the results
+func (dcs *DelayedConsumeService) delayedConsume(ctx context.Context) {
+	due := delayedRecords.Due(util.NowInMilliseconds(), int64(dcs.canaryConfig.DelayedConsumeDelay))
+	for _, dr := range due {
+		result := dcs.verify(ctx, dr)
+		labels := prometheus.Labels{
+			"clientid": dcs.canaryConfig.ClientID,
+			"result":   result,
+		}
+		delayedConsumeRecords.With(labels).Inc()
+	}
+	delayedConsumePending.With(prometheus.Labels{"clientid": dcs.canaryConfig.ClientID}).Set(float64(delayedRecords.Pending()))
+}
+
+// verify fetches the delayed record and returns the result of the verification
+func (dcs *DelayedConsumeService) verify(ctx context.Context, dr delayedRecord) string {
+	if dcs.fetcher == nil {
+		fetcher, err := dcs.clientFactory.NewFetcher(dcs.canaryConfig.BootstrapServers)
+		if err != nil {
+			glog.Errorf("Error creating the Kafka fetcher: %v", err)
+			recordAuthFailure(dcs.canaryConfig.ClientID, ConsumerBootstrapClient, err)
+			return DelayedRecordError
+		}
+		dcs.fetcher = fetcher
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, delayedConsumeFetchTimeout)
+	defer cancel()
+	record, err := dcs.fetcher.Fetch(ctx, dcs.canaryConfig.Topic, dr.partition, dr.offset)
+	result := delayedRecordResult(dcs.canaryConfig.ClientID, dr, record, err)
+	age := time.Duration(util.NowInMilliseconds()-dr.produced) * time.Millisecond
+	switch result {
+	case DelayedRecordRetrieved:
+		glog.V(1).Infof("Delayed record retrieved: partition=%d, offset=%d, age=%v", dr.partition, dr.offset, age)
+	case DelayedRecordError:
+		glog.Errorf("Error fetching delayed record on partition %d at offset %d: %v", dr.partition, dr.offset, err)
+		recordAuthFailure(dcs.canaryConfig.ClientID, ConsumerBootstrapClient, err)
+		if clients.IsFatal(err) {
+			dcs.fetcher.Close()
+			dcs.fetcher = nil
+			recordClientRecreation(ConsumerBootstrapClient)
+		}
+	default:
+		glog.Warningf("Delayed record on partition %d at offset %d, produced %v ago, is %s", dr.partition, dr.offset, age, result)
+	}
+	return result
+}
+
+// delayedRecordResult returns the result of fetching the delayed record, which is missing if not in the partition
+// log anymore or a mismatch if the record at its offset is not the produced canary message
+func delayedRecordResult(clientID string, dr delayedRecord, record *clients.Record, err error) string {
+	if errors.Is(err, clients.ErrRecordNotAvailable) {
+		return DelayedRecordMissing
+	}
+	if err != nil {
+		return DelayedRecordError
+	}
+	cm, err := DecodeCanaryMessage(record.Value)
+	if err != nil || cm.ProducerID != clientID || cm.MessageID != dr.messageID {
+		return DelayedRecordMismatch
+	}
+	return DelayedRecordRetrieved
+}
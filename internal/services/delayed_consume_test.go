@@ -0,0 +1,79 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// +build unit_test
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/strimzi/strimzi-canary/internal/clients"
+)
+
+func TestDelayedRecordsSampling(t *testing.T) {
+	tracker := newDelayedRecordsTracker()
+	// not sampling until enabled
+	tracker.Produced(0, 10, 1, 1000)
+	if pending := tracker.Pending(); pending != 0 {
+		t.Errorf("Pending with tracking disabled got = %d, want = 0", pending)
+	}
+
+	tracker.enable(60 * time.Second)
+	tracker.Produced(0, 11, 2, 1000)
+	tracker.Produced(1, 20, 3, 1000)
+	// within the sampling interval of partition 0
+	tracker.Produced(0, 12, 4, 30000)
+	tracker.Produced(0, 13, 5, 61000)
+	if pending := tracker.Pending(); pending != 3 {
+		t.Errorf("Pending got = %d, want = 3", pending)
+	}
+
+	due := tracker.Due(3600000, 3600000)
+	if len(due) != 0 {
+		t.Errorf("Due before the delay got = %v, want none", due)
+	}
+	due = tracker.Due(3601000, 3600000)
+	if len(due) != 2 || due[0].offset != 11 || due[1].offset != 20 {
+		t.Errorf("Due got = %v, want offsets 11 and 20", due)
+	}
+	due = tracker.Due(3661000, 3600000)
+	if len(due) != 1 || due[0].offset != 13 || due[0].messageID != 5 {
+		t.Errorf("Due got = %v, want offset 13", due)
+	}
+	if pending := tracker.Pending(); pending != 0 {
+		t.Errorf("Pending got = %d, want = 0", pending)
+	}
+}
+
+func TestDelayedRecordResult(t *testing.T) {
+	dr := delayedRecord{partition: 0, offset: 10, messageID: 5}
+	value := func(cm CanaryMessage) []byte {
+		return []byte(cm.WithChecksum().Json())
+	}
+	tests := []struct {
+		name   string
+		record *clients.Record
+		err    error
+		want   string
+	}{
+		{"retrieved", &clients.Record{Value: value(CanaryMessage{ProducerID: "my-client", MessageID: 5, Version: CanaryMessageVersion})}, nil, DelayedRecordRetrieved},
+		{"not available", nil, clients.ErrRecordNotAvailable, DelayedRecordMissing},
+		{"fetch error", nil, errors.New("timeout"), DelayedRecordError},
+		{"other message", &clients.Record{Value: value(CanaryMessage{ProducerID: "my-client", MessageID: 6, Version: CanaryMessageVersion})}, nil, DelayedRecordMismatch},
+		{"other producer", &clients.Record{Value: value(CanaryMessage{ProducerID: "other-client", MessageID: 5, Version: CanaryMessageVersion})}, nil, DelayedRecordMismatch},
+		{"not decodable", &clients.Record{Value: []byte("foo")}, nil, DelayedRecordMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := delayedRecordResult("my-client", dr, tt.record, tt.err); got != tt.want {
+				t.Errorf("delayedRecordResult got = %s, want = %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"net"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/golang/glog"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	dnsReResolutions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name:      "bootstrap_dns_reresolutions_total",
+		Namespace: "strimzi_canary",
+		Help:      "Total number of DNS re-resolutions of the bootstrap servers, followed by the Kafka client rebuild, after repeated connection failures",
+	}, []string{"client"})
+)
+
+// dnsReResolver tracks the consecutive connection failures of a Kafka client and, once a threshold is reached,
+// re-resolves the bootstrap servers addresses so that the client can be rebuilt, instead of keeping dead addresses
+// (i.e. on headless service churn or load balancer changes)
+type dnsReResolver struct {
+	client string
+	// consecutive connection failures for re-resolving, 0 means never re-resolving
+	threshold int
+	failures  int
+	// last resolved addresses for each bootstrap server host
+	addrs      map[string][]string
+	lookupHost func(host string) ([]string, error)
+	mutex      sync.Mutex
+}
+
+func newDNSReResolver(client string, threshold int) *dnsReResolver {
+	return &dnsReResolver{
+		client:     client,
+		threshold:  threshold,
+		addrs:      make(map[string][]string),
+		lookupHost: net.LookupHost,
+	}
+}
+
+// Failure records a connection failure, returns true if the bootstrap servers were just re-resolved
+// and the client has to be rebuilt
+func (r *dnsReResolver) Failure(bootstrapServers []string) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.failures++
+	if r.threshold <= 0 || r.failures < r.threshold {
+		return false
+	}
+	glog.Warningf("%d consecutive connection failures for the Kafka %s client, re-resolving the bootstrap servers", r.failures, r.client)
+	r.failures = 0
+	r.resolve(bootstrapServers)
+	labels := prometheus.Labels{
+		"client": r.client,
+	}
+	dnsReResolutions.With(labels).Inc()
+	return true
+}
+
+// Success records a successful connection, resetting the consecutive failures
+func (r *dnsReResolver) Success() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.failures = 0
+}
+
+// resolve looks up the bootstrap servers host names, logging the ones whose addresses changed
+func (r *dnsReResolver) resolve(bootstrapServers []string) {
+	for _, server := range bootstrapServers {
+		host, _, err := net.SplitHostPort(server)
+		if err != nil {
+			host = server
+		}
+		// nothing to resolve for an IP address
+		if net.ParseIP(host) != nil {
+			continue
+		}
+		addrs, err := r.lookupHost(host)
+		if err != nil {
+			glog.Warningf("Error resolving the bootstrap server %s: %v", host, err)
+			continue
+		}
+		sort.Strings(addrs)
+		if previous, ok := r.addrs[host]; ok && !reflect.DeepEqual(previous, addrs) {
+			glog.Infof("Bootstrap server %s addresses changed from %v to %v", host, previous, addrs)
+		}
+		r.addrs[host] = addrs
+	}
+}
@@ -0,0 +1,66 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// +build unit_test
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDNSReResolverThreshold(t *testing.T) {
+	lookups := 0
+	r := newDNSReResolver(ProducerBootstrapClient, 3)
+	r.lookupHost = func(host string) ([]string, error) {
+		lookups++
+		return []string{"10.0.0.1"}, nil
+	}
+	bootstrapServers := []string{"my-cluster-kafka-bootstrap:9092", "10.0.0.2:9092"}
+
+	steps := []struct {
+		failure bool
+		want    bool
+	}{
+		{true, false},
+		{true, false},
+		// failures reset by a success
+		{false, false},
+		{true, false},
+		{true, false},
+		{true, true},
+		// counting again after re-resolving
+		{true, false},
+	}
+	for i, s := range steps {
+		got := false
+		if s.failure {
+			got = r.Failure(bootstrapServers)
+		} else {
+			r.Success()
+		}
+		if got != s.want {
+			t.Errorf("Step %d: got = %t, want = %t", i, got, s.want)
+		}
+	}
+	// only the host name is resolved, not the IP address
+	if lookups != 1 {
+		t.Errorf("Lookups: got = %d, want = 1", lookups)
+	}
+	if !reflect.DeepEqual(r.addrs, map[string][]string{"my-cluster-kafka-bootstrap": {"10.0.0.1"}}) {
+		t.Errorf("Resolved addresses: got = %v", r.addrs)
+	}
+}
+
+func TestDNSReResolverDisabled(t *testing.T) {
+	r := newDNSReResolver(AdminBootstrapClient, 0)
+	for i := 0; i < 10; i++ {
+		if r.Failure([]string{"my-cluster-kafka-bootstrap:9092"}) {
+			t.Fatalf("Re-resolution with threshold disabled")
+		}
+	}
+}
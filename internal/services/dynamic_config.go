@@ -0,0 +1,70 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"sync/atomic"
+
+	"github.com/strimzi/strimzi-canary/internal/config"
+)
+
+// dynamicConfig holds the reloadable settings set through the dynamic configuration
+type dynamicConfig struct {
+	// reconcile interval (in ms), 0 means the configured one is used
+	reconcileInterval int64
+	// circuit breaker threshold, -1 means the configured one is used
+	circuitBreakerThreshold int64
+	// latency warning threshold (in ms), -1 means the configured one is used
+	latencyWarningThreshold int64
+	// latency critical threshold (in ms), -1 means the configured one is used
+	latencyCriticalThreshold int64
+}
+
+func newDynamicConfig() *dynamicConfig {
+	return &dynamicConfig{circuitBreakerThreshold: -1, latencyWarningThreshold: -1, latencyCriticalThreshold: -1}
+}
+
+// ReconcileInterval returns the reconcile interval (in ms), 0 if not set
+func (dc *dynamicConfig) ReconcileInterval() int64 {
+	return atomic.LoadInt64(&dc.reconcileInterval)
+}
+
+// CircuitBreakerThreshold returns the circuit breaker threshold, -1 if not set
+func (dc *dynamicConfig) CircuitBreakerThreshold() int64 {
+	return atomic.LoadInt64(&dc.circuitBreakerThreshold)
+}
+
+// LatencyThresholds returns the latency warning and critical thresholds (in ms), the configured ones if not set
+func (dc *dynamicConfig) LatencyThresholds(canaryConfig *config.CanaryConfig) (int64, int64) {
+	warning, critical := atomic.LoadInt64(&dc.latencyWarningThreshold), atomic.LoadInt64(&dc.latencyCriticalThreshold)
+	if warning < 0 {
+		warning = int64(canaryConfig.LatencyWarningThreshold)
+	}
+	if critical < 0 {
+		critical = int64(canaryConfig.LatencyCriticalThreshold)
+	}
+	return warning, critical
+}
+
+// ApplyDynamicConfig applies the reloadable settings of the dynamic configuration to the running services,
+// taking effect on the next reconcile, on the next failed send cycle and on the next records.
+// The settings not set restore the configured ones
+func (t *Trackers) ApplyDynamicConfig(dynamicCanaryConfig *config.DynamicCanaryConfig) {
+	atomic.StoreInt64(&t.dynamicConfig.reconcileInterval, dynamicValue(dynamicCanaryConfig.ReconcileInterval, 0))
+	atomic.StoreInt64(&t.dynamicConfig.circuitBreakerThreshold, dynamicValue(dynamicCanaryConfig.CircuitBreakerThreshold, -1))
+	atomic.StoreInt64(&t.dynamicConfig.latencyWarningThreshold, dynamicValue(dynamicCanaryConfig.LatencyWarningThreshold, -1))
+	atomic.StoreInt64(&t.dynamicConfig.latencyCriticalThreshold, dynamicValue(dynamicCanaryConfig.LatencyCriticalThreshold, -1))
+}
+
+// dynamicValue returns the value set through the dynamic configuration, or the given one meaning the configured
+// value is used if not set
+func dynamicValue(value *int, notSet int64) int64 {
+	if value == nil {
+		return notSet
+	}
+	return int64(*value)
+}
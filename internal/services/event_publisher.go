@@ -0,0 +1,160 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package services defines some canary related services
+package services
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/golang/glog"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/strimzi/strimzi-canary/internal/clients"
+	"github.com/strimzi/strimzi-canary/internal/config"
+)
+
+const (
+	// failure events waiting to be published, the newer ones are dropped when full
+	eventPublisherBufferSize = 1000
+	// the events are published to a single partition, for keeping them ordered
+	eventsPartition int32 = 0
+)
+
+var (
+	eventsPublished = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name:      "events_published_total",
+		Namespace: "strimzi_canary",
+		Help:      "Total number of failure events published to the events topic, by event type",
+	}, []string{"clientid", "type"})
+
+	eventsPublishFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name:      "events_publish_failed_total",
+		Namespace: "strimzi_canary",
+		Help:      "Total number of failure events not published to the events topic, because of a sending error or a full buffer",
+	}, []string{"clientid"})
+)
+
+// PublishedEvent defines the payload of a failure event published to the events topic
+type PublishedEvent struct {
+	// timestamp (in ms) of the event
+	Timestamp int64  `json:"timestamp"`
+	Type      string `json:"type"`
+	ClientID  string `json:"clientId"`
+	Topic     string `json:"topic"`
+	// -1 when the event is not related to a partition or a broker
+	Partition int32  `json:"partition"`
+	BrokerID  int32  `json:"brokerId"`
+	Error     string `json:"error,omitempty"`
+}
+
+// EventPublisher defines the service publishing the canary failure events (i.e. a produce failure, a log truncation,
+// a rebalance storm) to the events topic, so that automation can react to them through Kafka itself
+//
+// The events are buffered and published asynchronously, without slowing down the canary, and they are dropped
+// when the Kafka cluster is not reachable
+type EventPublisher struct {
+	canaryConfig  *config.CanaryConfig
+	clientFactory clients.Factory
+	producer      clients.Producer
+	events        chan Event
+	syncStop      sync.WaitGroup
+}
+
+// NewEventPublisher returns an instance of EventPublisher
+func NewEventPublisher(canaryConfig *config.CanaryConfig, clientFactory clients.Factory) *EventPublisher {
+	return &EventPublisher{
+		canaryConfig:  canaryConfig,
+		clientFactory: clientFactory,
+	}
+}
+
+// Open starts publishing the failure events recorded by the canary
+func (ep *EventPublisher) Open() {
+	ep.events = make(chan Event, eventPublisherBufferSize)
+	ep.syncStop.Add(1)
+	go func() {
+		defer ep.syncStop.Done()
+		for event := range ep.events {
+			ep.publish(event)
+		}
+		glog.Infof("Stopping events publishing loop")
+	}()
+	canaryEvents.SetPublisher(ep)
+}
+
+// Close stops publishing the failure events, after the buffered ones, and closes the underneath Kafka producer instance
+func (ep *EventPublisher) Close() {
+	glog.Infof("Closing event publisher")
+
+	// no more events are published after unsetting the publisher, so the channel can be closed
+	canaryEvents.SetPublisher(nil)
+	close(ep.events)
+	ep.syncStop.Wait()
+
+	if ep.producer != nil {
+		if err := ep.producer.Close(); err != nil {
+			glog.Errorf("Error closing the Kafka events producer: %v", err)
+		}
+		ep.producer = nil
+	}
+	glog.Infof("Event publisher closed")
+}
+
+// Publish adds the event to the ones to be published, dropping it if the buffer is full
+func (ep *EventPublisher) Publish(event Event) {
+	select {
+	case ep.events <- event:
+	default:
+		eventsPublishFailed.With(prometheus.Labels{"clientid": ep.canaryConfig.ClientID}).Inc()
+		glog.Warningf("Events buffer full, dropping the %s event", event.Type)
+	}
+}
+
+// publish sends the event to the events topic
+func (ep *EventPublisher) publish(event Event) {
+	if ep.producer == nil {
+		producer, err := ep.clientFactory.NewProducer(ep.canaryConfig.BootstrapServers)
+		if err != nil {
+			eventsPublishFailed.With(prometheus.Labels{"clientid": ep.canaryConfig.ClientID}).Inc()
+			glog.Errorf("Error creating the Kafka events producer: %v", err)
+			return
+		}
+		ep.producer = producer
+	}
+
+	value, err := json.Marshal(newPublishedEvent(ep.canaryConfig, event))
+	if err != nil {
+		glog.Errorf("Error encoding the %s event: %v", event.Type, err)
+		return
+	}
+	// the sending errors are not recorded as canary events, they would be published again
+	limitRate(ProduceOperation)
+	if _, _, err := ep.producer.Send(ep.canaryConfig.EventsTopic, eventsPartition, value); err != nil {
+		eventsPublishFailed.With(prometheus.Labels{"clientid": ep.canaryConfig.ClientID}).Inc()
+		glog.Warningf("Error publishing the %s event: %v", event.Type, err)
+		if clients.IsFatal(err) {
+			ep.producer.Close()
+			ep.producer = nil
+		}
+		return
+	}
+	eventsPublished.With(prometheus.Labels{"clientid": ep.canaryConfig.ClientID, "type": event.Type}).Inc()
+}
+
+// newPublishedEvent returns the payload for publishing the event
+func newPublishedEvent(canaryConfig *config.CanaryConfig, event Event) PublishedEvent {
+	return PublishedEvent{
+		Timestamp: event.Timestamp,
+		Type:      event.Type,
+		ClientID:  canaryConfig.ClientID,
+		Topic:     canaryConfig.Topic,
+		Partition: event.Partition,
+		BrokerID:  event.BrokerID,
+		Error:     event.Error,
+	}
+}
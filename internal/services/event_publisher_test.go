@@ -0,0 +1,89 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// +build unit_test
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/strimzi/strimzi-canary/internal/config"
+)
+
+func TestEventRingPublishing(t *testing.T) {
+	ep := &EventPublisher{
+		canaryConfig: &config.CanaryConfig{ClientID: "my-client"},
+		events:       make(chan Event, 2),
+	}
+	// the events buffer for the report is disabled, the failure events are published anyway
+	er := &eventRing{}
+	er.SetPublisher(ep)
+	er.Record(Event{Type: ProducedEvent, Partition: 0, BrokerID: noBroker, Latency: 10})
+	er.Record(Event{Type: ProduceFailedEvent, Partition: 0, BrokerID: noBroker, Error: "timeout"})
+	er.Record(Event{Type: LogTruncationEvent, Partition: 1, BrokerID: noBroker, Error: "offset_gap at offset 10"})
+	// dropped, the buffer is full
+	er.Record(Event{Type: ConsumeFailedEvent, Partition: noPartition, BrokerID: noBroker})
+
+	if len(ep.events) != 2 {
+		t.Fatalf("Published events got = %d, want = 2", len(ep.events))
+	}
+	if e := <-ep.events; e.Type != ProduceFailedEvent || e.Timestamp == 0 {
+		t.Errorf("Published event got = %+v, want a timestamped %s", e, ProduceFailedEvent)
+	}
+	if e := <-ep.events; e.Type != LogTruncationEvent {
+		t.Errorf("Published event got = %+v, want %s", e, LogTruncationEvent)
+	}
+
+	er.SetPublisher(nil)
+	er.Record(Event{Type: ProduceFailedEvent, Partition: 0, BrokerID: noBroker})
+	if len(ep.events) != 0 {
+		t.Errorf("Published events after unsetting the publisher got = %d, want = 0", len(ep.events))
+	}
+}
+
+func TestPublishedEvent(t *testing.T) {
+	canaryConfig := &config.CanaryConfig{ClientID: "my-client", Topic: "__strimzi_canary"}
+	e := newPublishedEvent(canaryConfig, Event{Timestamp: 1000, Type: ConnectionFailedEvent, Partition: noPartition, BrokerID: 2, Error: "refused"})
+	value, err := json.Marshal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"timestamp":1000,"type":"connection_failed","clientId":"my-client","topic":"__strimzi_canary","partition":-1,"brokerId":2,"error":"refused"}`
+	if string(value) != want {
+		t.Errorf("Published event got = %s, want = %s", value, want)
+	}
+}
+
+func TestRebalanceStormDetector(t *testing.T) {
+	d := newRebalanceStormDetector(3, 60000)
+	for i, tt := range []struct {
+		timestamp int64
+		want      int
+	}{
+		{0, 0},
+		{30000, 0},
+		// the first rebalance is out of the window
+		{70000, 0},
+		{80000, 3},
+		// rebalances dropped after reporting the storm
+		{90000, 0},
+		{100000, 0},
+		{110000, 3},
+	} {
+		if got := d.Rebalanced(tt.timestamp); got != tt.want {
+			t.Errorf("Rebalanced #%d at %d got = %d, want = %d", i, tt.timestamp, got, tt.want)
+		}
+	}
+
+	disabled := newRebalanceStormDetector(0, 60000)
+	for i := 0; i < 5; i++ {
+		if got := disabled.Rebalanced(int64(i)); got != 0 {
+			t.Errorf("Rebalanced with detection disabled got = %d, want = 0", got)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package services defines some canary related services
+package services
+
+import (
+	"sync"
+
+	"github.com/strimzi/strimzi-canary/internal/util"
+)
+
+const (
+	// canary event types
+	ProducedEvent         = "produced"
+	ProduceFailedEvent    = "produce_failed"
+	ConsumedEvent         = "consumed"
+	ConsumeFailedEvent    = "consume_failed"
+	RebalanceEvent        = "rebalance"
+	LeaderChangeEvent     = "leader_change"
+	ConnectionFailedEvent = "connection_failed"
+	LogTruncationEvent    = "log_truncation"
+	RebalanceStormEvent   = "rebalance_storm"
+
+	// used in the events not related to a partition or a broker
+	noPartition int32 = -1
+	noBroker    int32 = -1
+)
+
+var (
+	// tracks the latest canary events, used for building the health report
+	canaryEvents = &eventRing{}
+
+	// event types reporting a canary failure, which are published to the events topic
+	failureEvents = map[string]bool{
+		ProduceFailedEvent:    true,
+		ConsumeFailedEvent:    true,
+		ConnectionFailedEvent: true,
+		LogTruncationEvent:    true,
+		RebalanceStormEvent:   true,
+	}
+)
+
+// Event defines something happened in the canary (i.e. a record produced, a rebalance, a leader change, ...)
+type Event struct {
+	// timestamp (in ms) of the event
+	Timestamp int64
+	Type      string
+	Partition int32
+	BrokerID  int32
+	// latency (in ms) for produced and consumed records
+	Latency float64
+	Error   string
+}
+
+// eventRing is a fixed size ring buffer of the latest canary events, overwriting the oldest ones when full
+type eventRing struct {
+	mutex  sync.Mutex
+	buffer []Event
+	next   int
+	count  int
+	// publishing the failure events, when enabled
+	publisher *EventPublisher
+}
+
+// Init allocates the buffer for the provided number of events, dropping the ones already recorded
+func (er *eventRing) Init(size int) {
+	er.mutex.Lock()
+	defer er.mutex.Unlock()
+	er.buffer = make([]Event, size)
+	er.next = 0
+	er.count = 0
+}
+
+// SetPublisher sets the publisher of the failure events, nil for not publishing them anymore
+func (er *eventRing) SetPublisher(publisher *EventPublisher) {
+	er.mutex.Lock()
+	defer er.mutex.Unlock()
+	er.publisher = publisher
+}
+
+// Record adds an event, with the current timestamp, to the ring buffer and publishes it if it's a failure event
+func (er *eventRing) Record(event Event) {
+	er.mutex.Lock()
+	defer er.mutex.Unlock()
+	if event.Timestamp == 0 {
+		event.Timestamp = util.NowInMilliseconds()
+	}
+	if er.publisher != nil && failureEvents[event.Type] {
+		er.publisher.Publish(event)
+	}
+	// not initialized or disabled
+	if len(er.buffer) == 0 {
+		return
+	}
+	er.buffer[er.next] = event
+	er.next = (er.next + 1) % len(er.buffer)
+	if er.count < len(er.buffer) {
+		er.count++
+	}
+}
+
+// Since returns the events, from the oldest to the newest, happened since the provided timestamp (in ms)
+func (er *eventRing) Since(timestamp int64) []Event {
+	er.mutex.Lock()
+	defer er.mutex.Unlock()
+	events := make([]Event, 0)
+	for i := 0; i < er.count; i++ {
+		event := er.buffer[(er.next-er.count+i+len(er.buffer))%len(er.buffer)]
+		if event.Timestamp >= timestamp {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// rebalanceStormDetector detects a rebalance storm, as a number of consumer group rebalances within a time window
+type rebalanceStormDetector struct {
+	mutex sync.Mutex
+	// rebalances count and window (in ms), a threshold 0 means the detection is disabled
+	threshold  int
+	window     int64
+	rebalances []int64
+}
+
+func newRebalanceStormDetector(threshold int, window int64) *rebalanceStormDetector {
+	return &rebalanceStormDetector{
+		threshold: threshold,
+		window:    window,
+	}
+}
+
+// Rebalanced records a rebalance at the provided timestamp (in ms) and returns the number of rebalances within the
+// window if they reached the threshold, otherwise 0. The rebalances are then dropped, so a storm is reported once
+// every threshold rebalances while it lasts
+func (d *rebalanceStormDetector) Rebalanced(timestamp int64) int {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.threshold <= 0 {
+		return 0
+	}
+	rebalances := d.rebalances[:0]
+	for _, r := range d.rebalances {
+		if timestamp-r < d.window {
+			rebalances = append(rebalances, r)
+		}
+	}
+	d.rebalances = append(rebalances, timestamp)
+	if count := len(d.rebalances); count >= d.threshold {
+		d.rebalances = d.rebalances[:0]
+		return count
+	}
+	return 0
+}
@@ -0,0 +1,44 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/strimzi/strimzi-canary/internal/clients"
+)
+
+const (
+	// brokers a record can be fetched from
+	leaderFetchSource   = "leader"
+	followerFetchSource = "follower"
+)
+
+var (
+	recordsConsumedFetchSource = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name:      "records_consumed_fetch_source_total",
+		Namespace: "strimzi_canary",
+		Help:      "The total number of records consumed, by the replica they were fetched from (leader or follower)",
+	}, []string{"clientid", "partition", "source"})
+)
+
+// fetchSource returns if the record was fetched from the partition leader or a follower,
+// or an empty string if the broker the record was fetched from or the leader are not known
+func fetchSource(partition int32, fetchBrokerID int32) string {
+	if fetchBrokerID == clients.NoBrokerID {
+		return ""
+	}
+	leader, ok := partitionLeaders.Leader(partition)
+	if !ok {
+		return ""
+	}
+	if leader == fetchBrokerID {
+		return leaderFetchSource
+	}
+	return followerFetchSource
+}
@@ -0,0 +1,40 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// +build unit_test
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"testing"
+
+	"github.com/strimzi/strimzi-canary/internal/clients"
+)
+
+func TestFetchSource(t *testing.T) {
+	partitionLeaders.Update(map[int32]int32{0: 0, 1: 1})
+	defer partitionLeaders.Update(nil)
+
+	tests := []struct {
+		name          string
+		partition     int32
+		fetchBrokerID int32
+		expected      string
+	}{
+		{"fetched from leader", 0, 0, leaderFetchSource},
+		{"fetched from follower", 1, 2, followerFetchSource},
+		{"fetch broker not known", 1, clients.NoBrokerID, ""},
+		{"leader not known", 2, 2, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if source := fetchSource(tt.partition, tt.fetchBrokerID); source != tt.expected {
+				t.Errorf("got = %q, want = %q", source, tt.expected)
+			}
+		})
+	}
+}
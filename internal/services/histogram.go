@@ -0,0 +1,36 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/strimzi/strimzi-canary/internal/config"
+)
+
+const (
+	// growth factor between consecutive native histogram buckets, giving a relative error of ~5%
+	nativeHistogramBucketFactor = 1.1
+	// maximum number of native histogram buckets before resetting them
+	nativeHistogramMaxBucketNumber = 160
+	// minimum time between native histogram buckets resets
+	nativeHistogramMinResetDuration = time.Hour
+)
+
+// latencyHistogramOpts returns the histogram options for a latency metric, using sparse native
+// histogram buckets instead of the configured fixed ones when enabled
+func latencyHistogramOpts(canaryConfig *config.CanaryConfig, opts prometheus.HistogramOpts) prometheus.HistogramOpts {
+	if canaryConfig.NativeHistogramsEnabled {
+		opts.Buckets = nil
+		opts.NativeHistogramBucketFactor = nativeHistogramBucketFactor
+		opts.NativeHistogramMaxBucketNumber = nativeHistogramMaxBucketNumber
+		opts.NativeHistogramMinResetDuration = nativeHistogramMinResetDuration
+	}
+	return opts
+}
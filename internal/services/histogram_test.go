@@ -0,0 +1,30 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// +build unit_test
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/strimzi/strimzi-canary/internal/config"
+)
+
+func TestLatencyHistogramOpts(t *testing.T) {
+	buckets := []float64{2, 5, 10}
+	opts := latencyHistogramOpts(&config.CanaryConfig{}, prometheus.HistogramOpts{Buckets: buckets})
+	if len(opts.Buckets) != len(buckets) || opts.NativeHistogramBucketFactor != 0 {
+		t.Errorf("unexpected fixed buckets histogram options %+v", opts)
+	}
+
+	opts = latencyHistogramOpts(&config.CanaryConfig{NativeHistogramsEnabled: true}, prometheus.HistogramOpts{Buckets: buckets})
+	if opts.Buckets != nil || opts.NativeHistogramBucketFactor != nativeHistogramBucketFactor {
+		t.Errorf("unexpected native histogram options %+v", opts)
+	}
+}
@@ -0,0 +1,79 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"runtime/debug"
+	"strconv"
+
+	"github.com/golang/glog"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/strimzi/strimzi-canary/internal/clients"
+	"github.com/strimzi/strimzi-canary/internal/config"
+)
+
+const (
+	saramaModule   = "github.com/Shopify/sarama"
+	unknownVersion = "unknown"
+)
+
+var (
+	canaryInfo = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name:      "info",
+		Namespace: "strimzi_canary",
+		Help:      "Canary build and runtime information, the value is always 1",
+	}, []string{"version", "commit", "sarama_version", "kafka_version", "topic",
+		"producer_linger_ms", "producer_batch_messages", "producer_max_message_bytes",
+		"consumer_fetch_min_bytes", "consumer_fetch_default_bytes", "consumer_fetch_max_bytes", "consumer_fetch_max_wait_ms"})
+)
+
+// RecordInfo exports the canary build and runtime information, for inventorying the deployed canaries,
+// together with the Kafka clients configuration in effect
+//
+// It has to be called once the Kafka version is negotiated with the Kafka cluster
+func RecordInfo(canaryConfig *config.CanaryConfig, version string, commit string) {
+	batching := clients.EffectiveProducerBatching(canaryConfig)
+	glog.Infof("Producer batching in effect: linger=%d ms, messages=%d, max message bytes=%d",
+		batching.Linger.Milliseconds(), batching.Messages, batching.MaxMessageBytes)
+	fetch := clients.EffectiveConsumerFetch(canaryConfig)
+	glog.Infof("Consumer fetch in effect: min bytes=%d, default bytes=%d, max bytes=%d, max wait=%d ms",
+		fetch.MinBytes, fetch.DefaultBytes, fetch.MaxBytes, fetch.MaxWait.Milliseconds())
+	labels := prometheus.Labels{
+		"version":                      version,
+		"commit":                       commit,
+		"sarama_version":               moduleVersion(saramaModule),
+		"kafka_version":                canaryConfig.KafkaVersion,
+		"topic":                        canaryConfig.Topic,
+		"producer_linger_ms":           strconv.FormatInt(batching.Linger.Milliseconds(), 10),
+		"producer_batch_messages":      strconv.Itoa(batching.Messages),
+		"producer_max_message_bytes":   strconv.Itoa(batching.MaxMessageBytes),
+		"consumer_fetch_min_bytes":     strconv.Itoa(fetch.MinBytes),
+		"consumer_fetch_default_bytes": strconv.Itoa(fetch.DefaultBytes),
+		"consumer_fetch_max_bytes":     strconv.Itoa(fetch.MaxBytes),
+		"consumer_fetch_max_wait_ms":   strconv.FormatInt(fetch.MaxWait.Milliseconds(), 10),
+	}
+	canaryInfo.With(labels).Set(1)
+}
+
+// moduleVersion returns the version of a module dependency the canary binary was built with
+func moduleVersion(path string) string {
+	buildInfo, ok := debug.ReadBuildInfo()
+	if !ok {
+		return unknownVersion
+	}
+	for _, dep := range buildInfo.Deps {
+		if dep.Path == path {
+			// the dependency could be replaced by another module
+			if dep.Replace != nil {
+				return dep.Replace.Version
+			}
+			return dep.Version
+		}
+	}
+	return unknownVersion
+}
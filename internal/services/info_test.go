@@ -0,0 +1,22 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// +build unit_test
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"testing"
+)
+
+func TestModuleVersion(t *testing.T) {
+	if v := moduleVersion(saramaModule); v == unknownVersion || v == "" {
+		t.Errorf("Sarama version: got = %s, want a known version", v)
+	}
+	if v := moduleVersion("github.com/strimzi/not-a-module"); v != unknownVersion {
+		t.Errorf("Unknown module version: got = %s, want = %s", v, unknownVersion)
+	}
+}
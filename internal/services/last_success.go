@@ -0,0 +1,43 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/strimzi/strimzi-canary/internal/util"
+)
+
+var (
+	// timestamps (in ms) of the last successful produce and consume, starting from the canary start up
+	lastSuccessfulProduce = util.NowInMilliseconds()
+	lastSuccessfulConsume = util.NowInMilliseconds()
+
+	_ = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name:      "seconds_since_last_successful_produce",
+		Namespace: "strimzi_canary",
+		Help:      "Seconds since the last record successfully produced, or since the canary start up if none",
+	}, func() float64 {
+		return secondsSince(atomic.LoadInt64(&lastSuccessfulProduce))
+	})
+
+	_ = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name:      "seconds_since_last_successful_consume",
+		Namespace: "strimzi_canary",
+		Help:      "Seconds since the last record successfully consumed, or since the canary start up if none",
+	}, func() float64 {
+		return secondsSince(atomic.LoadInt64(&lastSuccessfulConsume))
+	})
+)
+
+// secondsSince returns the seconds elapsed since the provided timestamp (in ms)
+func secondsSince(timestamp int64) float64 {
+	return float64(util.NowInMilliseconds()-timestamp) / 1000
+}
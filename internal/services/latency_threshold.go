@@ -0,0 +1,76 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/strimzi/strimzi-canary/internal/config"
+)
+
+const (
+	// latencies checked against the thresholds
+	ProduceLatency  = "produce"
+	EndToEndLatency = "end_to_end"
+
+	// severities of the latency threshold breaches
+	WarningSeverity  = "warning"
+	CriticalSeverity = "critical"
+)
+
+var (
+	latencyThresholdBreaches = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name:      "latency_threshold_breaches_total",
+		Namespace: "strimzi_canary",
+		Help:      "Total number of records whose latency breached the warning or critical threshold, by latency and severity",
+	}, []string{"clientid", "partition", "latency", "severity"})
+
+	latencyThresholdBreached = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name:      "latency_threshold_breached",
+		Namespace: "strimzi_canary",
+		Help:      "Threshold breached by the latency of the last record, none (0), warning (1) or critical (2)",
+	}, []string{"clientid", "partition", "latency"})
+)
+
+// observeLatencyThresholds checks the record latency (in ms) against the thresholds, the configured ones or the ones
+// set through the dynamic configuration, reporting the breaches
+func observeLatencyThresholds(canaryConfig *config.CanaryConfig, dynamicConfig *dynamicConfig, latencyType string, partition string, latency int64) {
+	warning, critical := dynamicConfig.LatencyThresholds(canaryConfig)
+	if warning == 0 && critical == 0 {
+		return
+	}
+	labels := prometheus.Labels{
+		"clientid":  canaryConfig.ClientID,
+		"partition": partition,
+		"latency":   latencyType,
+	}
+	severity := latencySeverity(latency, warning, critical)
+	switch severity {
+	case CriticalSeverity:
+		latencyThresholdBreached.With(labels).Set(2)
+	case WarningSeverity:
+		latencyThresholdBreached.With(labels).Set(1)
+	default:
+		latencyThresholdBreached.With(labels).Set(0)
+		return
+	}
+	labels["severity"] = severity
+	latencyThresholdBreaches.With(labels).Inc()
+}
+
+// latencySeverity returns the severity of the threshold breached by the latency, empty if none,
+// a threshold of 0 is disabled
+func latencySeverity(latency int64, warning int64, critical int64) string {
+	if critical > 0 && latency >= critical {
+		return CriticalSeverity
+	}
+	if warning > 0 && latency >= warning {
+		return WarningSeverity
+	}
+	return ""
+}
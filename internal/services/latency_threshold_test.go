@@ -0,0 +1,59 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// +build unit_test
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"testing"
+
+	"github.com/strimzi/strimzi-canary/internal/config"
+)
+
+func TestLatencySeverity(t *testing.T) {
+	tests := []struct {
+		name     string
+		latency  int64
+		warning  int64
+		critical int64
+		want     string
+	}{
+		{"below the thresholds", 100, 500, 2000, ""},
+		{"warning", 500, 500, 2000, WarningSeverity},
+		{"critical", 2500, 500, 2000, CriticalSeverity},
+		{"warning only", 2500, 500, 0, WarningSeverity},
+		{"critical only", 1000, 0, 2000, ""},
+		{"disabled", 10000, 0, 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if severity := latencySeverity(tt.latency, tt.warning, tt.critical); severity != tt.want {
+				t.Errorf("Severity = %q, expected = %q", severity, tt.want)
+			}
+		})
+	}
+}
+
+func TestLatencyThresholdsDynamicConfig(t *testing.T) {
+	canaryConfig := &config.CanaryConfig{LatencyWarningThreshold: 500, LatencyCriticalThreshold: 2000}
+	trackers := NewTrackers(canaryConfig)
+	if warning, critical := trackers.dynamicConfig.LatencyThresholds(canaryConfig); warning != 500 || critical != 2000 {
+		t.Errorf("Thresholds = %d, %d, expected the configured 500, 2000", warning, critical)
+	}
+
+	warning, critical := 0, 1000
+	trackers.ApplyDynamicConfig(&config.DynamicCanaryConfig{LatencyWarningThreshold: &warning, LatencyCriticalThreshold: &critical})
+	if warning, critical := trackers.dynamicConfig.LatencyThresholds(canaryConfig); warning != 0 || critical != 1000 {
+		t.Errorf("Thresholds = %d, %d, expected the dynamic 0, 1000", warning, critical)
+	}
+
+	// the thresholds not set anymore restore the configured ones
+	trackers.ApplyDynamicConfig(&config.DynamicCanaryConfig{})
+	if warning, critical := trackers.dynamicConfig.LatencyThresholds(canaryConfig); warning != 500 || critical != 2000 {
+		t.Errorf("Thresholds = %d, %d, expected the configured 500, 2000", warning, critical)
+	}
+}
@@ -0,0 +1,80 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	// log truncation detection reasons
+	OffsetBackwardsTruncation = "offset_backwards"
+	OffsetGapTruncation       = "offset_gap"
+)
+
+var (
+	logTruncationsDetected = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name:      "log_truncations_detected_total",
+		Namespace: "strimzi_canary",
+		Help:      "Total number of log truncations, or offset resets, detected on the canary topic partitions",
+	}, []string{"clientid", "partition", "reason"})
+)
+
+// logTruncationTracker tracks the last produced and consumed offset for each partition, in order to detect when the
+// consumed offsets go backwards or skip records which were produced, i.e. due to an unclean leader election
+type logTruncationTracker struct {
+	mutex    sync.Mutex
+	produced map[int32]int64
+	consumed map[int32]int64
+}
+
+func newLogTruncationTracker() *logTruncationTracker {
+	return &logTruncationTracker{
+		produced: make(map[int32]int64),
+		consumed: make(map[int32]int64),
+	}
+}
+
+// Produced records the offset of a record successfully produced to the partition
+func (t *logTruncationTracker) Produced(partition int32, offset int64) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if last, ok := t.produced[partition]; !ok || offset > last {
+		t.produced[partition] = offset
+	}
+}
+
+// Consumed records the offset of a record consumed from the partition and returns the log truncation reason
+// if one was detected, otherwise an empty string
+func (t *logTruncationTracker) Consumed(partition int32, offset int64) string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	last, ok := t.consumed[partition]
+	t.consumed[partition] = offset
+	if !ok {
+		return ""
+	}
+	if offset <= last {
+		return OffsetBackwardsTruncation
+	}
+	// skipped offsets which were produced but never consumed
+	if produced, ok := t.produced[partition]; ok && offset > last+1 && produced > last {
+		return OffsetGapTruncation
+	}
+	return ""
+}
+
+// ResetConsumed drops the consumed offsets, i.e. on a new consumer group session the records not committed
+// yet are consumed again, so it doesn't mean a log truncation
+func (t *logTruncationTracker) ResetConsumed() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.consumed = make(map[int32]int64)
+}
@@ -0,0 +1,40 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// +build unit_test
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"testing"
+)
+
+func TestLogTruncationDetection(t *testing.T) {
+	tracker := newLogTruncationTracker()
+	for offset := int64(10); offset <= 13; offset++ {
+		tracker.Produced(0, offset)
+	}
+
+	if reason := tracker.Consumed(0, 10); reason != "" {
+		t.Errorf("unexpected truncation %s on first consumed offset", reason)
+	}
+	if reason := tracker.Consumed(0, 11); reason != "" {
+		t.Errorf("unexpected truncation %s on next offset", reason)
+	}
+	// offset 12 produced but skipped
+	if reason := tracker.Consumed(0, 13); reason != OffsetGapTruncation {
+		t.Errorf("got = %q, want = %q", reason, OffsetGapTruncation)
+	}
+	if reason := tracker.Consumed(0, 12); reason != OffsetBackwardsTruncation {
+		t.Errorf("got = %q, want = %q", reason, OffsetBackwardsTruncation)
+	}
+
+	// records consumed again on a new consumer group session
+	tracker.ResetConsumed()
+	if reason := tracker.Consumed(0, 11); reason != "" {
+		t.Errorf("unexpected truncation %s after reset", reason)
+	}
+}
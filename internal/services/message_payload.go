@@ -0,0 +1,39 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"os"
+	"strings"
+	"text/template"
+)
+
+// payloadTemplateFuncs are the functions available to the payload template, other than the built-in ones
+var payloadTemplateFuncs = template.FuncMap{
+	// environment variables, i.e. the pod name and namespace or any label injected through the Downward API
+	"env": os.Getenv,
+}
+
+// newPayloadTemplate returns the template for the canary messages payload, nil if not configured
+//
+// The template is executed on the canary message, so its ProducerID, MessageID and Timestamp fields are available,
+// along with the env function returning an environment variable (i.e. {{ env "POD_NAME" }})
+func newPayloadTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+	return template.New("payload").Funcs(payloadTemplateFuncs).Parse(text)
+}
+
+// renderPayload returns the payload rendered from the template for the canary message
+func renderPayload(payloadTemplate *template.Template, cm CanaryMessage) (string, error) {
+	var payload strings.Builder
+	if err := payloadTemplate.Execute(&payload, cm); err != nil {
+		return "", err
+	}
+	return payload.String(), nil
+}
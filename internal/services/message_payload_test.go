@@ -0,0 +1,74 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// +build unit_test
+
+// Package services defines some canary related services
+package services
+
+import (
+	"os"
+	"testing"
+)
+
+func TestRenderPayload(t *testing.T) {
+	os.Setenv("CANARY_TEST_POD_NAME", "canary-pod")
+	defer os.Unsetenv("CANARY_TEST_POD_NAME")
+
+	cm := CanaryMessage{ProducerID: "producer-id", MessageID: 7, Timestamp: 12345}
+	tests := []struct {
+		name     string
+		template string
+		expected string
+		wantErr  bool
+	}{
+		{"static", "audit-canary", "audit-canary", false},
+		{"message fields", "{{.ProducerID}}/{{.MessageID}}/{{.Timestamp}}", "producer-id/7/12345", false},
+		{"env", `{{ env "CANARY_TEST_POD_NAME" }}-{{.MessageID}}`, "canary-pod-7", false},
+		{"unknown field", "{{.Unknown}}", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payloadTemplate, err := newPayloadTemplate(tt.template)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			payload, err := renderPayload(payloadTemplate, cm)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error: got = %v, wantErr = %t", err, tt.wantErr)
+			}
+			if payload != tt.expected {
+				t.Errorf("got = %s, want = %s", payload, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPayloadTemplateInvalid(t *testing.T) {
+	if payloadTemplate, err := newPayloadTemplate(""); payloadTemplate != nil || err != nil {
+		t.Errorf("got = (%v, %v), want no template", payloadTemplate, err)
+	}
+	if _, err := newPayloadTemplate("{{.ProducerID"); err == nil {
+		t.Errorf("expected error parsing an invalid template")
+	}
+}
+
+func TestCanaryMessagePayloadChecksum(t *testing.T) {
+	cm := CanaryMessage{
+		ProducerID: "producer-id",
+		MessageID:  1,
+		Timestamp:  12345,
+		Version:    payloadCanaryMessageVersion,
+		Payload:    `audit "canary"`,
+	}.WithChecksum()
+	decoded, err := DecodeCanaryMessage([]byte(cm.Json()))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if decoded != cm {
+		t.Errorf("got = %v, want = %v", decoded, cm)
+	}
+}
@@ -0,0 +1,74 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/strimzi/strimzi-canary/internal/util"
+)
+
+const (
+	// consecutive metadata refresh failures for considering the producer metadata stale
+	metadataRefreshStaleThreshold = 3
+)
+
+var (
+	metadataRefreshFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name:      "metadata_refresh_failures_total",
+		Namespace: "strimzi_canary",
+		Help:      "Total number of failures while refreshing producer metadata",
+	}, []string{"clientid"})
+
+	// tracker of the producer metadata refresh failures
+	metadataRefresh = &metadataRefreshTracker{}
+)
+
+// MetadataRefreshStatus defines producer metadata refresh related status information
+type MetadataRefreshStatus struct {
+	ConsecutiveFailures int
+	// timestamp (in ms) of the last failure
+	LastFailure int64 `json:",omitempty"`
+	// if the refresh is failing persistently, so the producer metadata could be stale
+	Stale bool
+}
+
+// metadataRefreshTracker tracks the consecutive failures on refreshing the producer metadata
+type metadataRefreshTracker struct {
+	consecutiveFailures int
+	lastFailure         int64
+	mutex               sync.Mutex
+}
+
+// Failure records a metadata refresh failure
+func (t *metadataRefreshTracker) Failure() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.consecutiveFailures++
+	t.lastFailure = util.NowInMilliseconds()
+}
+
+// Success records a successful metadata refresh, resetting the consecutive failures
+func (t *metadataRefreshTracker) Success() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.consecutiveFailures = 0
+}
+
+// Status returns the current metadata refresh status
+func (t *metadataRefreshTracker) Status() MetadataRefreshStatus {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return MetadataRefreshStatus{
+		ConsecutiveFailures: t.consecutiveFailures,
+		LastFailure:         t.lastFailure,
+		Stale:               t.consecutiveFailures >= metadataRefreshStaleThreshold,
+	}
+}
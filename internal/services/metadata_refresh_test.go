@@ -0,0 +1,31 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// +build unit_test
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"testing"
+)
+
+func TestMetadataRefreshStale(t *testing.T) {
+	tracker := &metadataRefreshTracker{}
+	for i := 0; i < metadataRefreshStaleThreshold-1; i++ {
+		tracker.Failure()
+	}
+	if status := tracker.Status(); status.Stale || status.ConsecutiveFailures != metadataRefreshStaleThreshold-1 || status.LastFailure == 0 {
+		t.Errorf("unexpected status %+v", status)
+	}
+	tracker.Failure()
+	if status := tracker.Status(); !status.Stale {
+		t.Errorf("metadata not stale after %d consecutive failures", metadataRefreshStaleThreshold)
+	}
+	tracker.Success()
+	if status := tracker.Status(); status.Stale || status.ConsecutiveFailures != 0 {
+		t.Errorf("unexpected status %+v after success", status)
+	}
+}
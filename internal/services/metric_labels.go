@@ -0,0 +1,140 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/golang/glog"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/strimzi/strimzi-canary/internal/config"
+)
+
+var (
+	// registry of the producer and consumer metrics having the partition label
+	partitionMetrics = &partitionMetricsRegistry{vecs: make(map[partitionMetricVec]struct{})}
+)
+
+// partitionLabel returns the value of the partition label for the producer and consumer metrics
+//
+// Partitions beyond the configured limit get an empty value, so that Prometheus aggregates them
+// in a single series without the partition label (with a limit of 0 metrics are at topic level only)
+func partitionLabel(canaryConfig *config.CanaryConfig, partition int32) string {
+	if canaryConfig.MetricsPartitionsLimit >= 0 && int(partition) >= canaryConfig.MetricsPartitionsLimit {
+		return ""
+	}
+	return strconv.Itoa(int(partition))
+}
+
+// producerLatencyLabelsBy returns which labels, between partition and leader broker ID, the produce latency histogram
+// is labelled by, falling back to the partition one if the configuration is not valid
+func producerLatencyLabelsBy(canaryConfig *config.CanaryConfig) (byPartition bool, byBroker bool, valid bool) {
+	for _, label := range strings.Split(canaryConfig.ProducerLatencyLabels, ",") {
+		switch strings.TrimSpace(label) {
+		case "partition":
+			byPartition = true
+		case "broker":
+			byBroker = true
+		}
+	}
+	if !byPartition && !byBroker {
+		return true, false, false
+	}
+	return byPartition, byBroker, true
+}
+
+// producerLatencyLabelNames returns the label names of the produce latency histogram
+func producerLatencyLabelNames(canaryConfig *config.CanaryConfig) []string {
+	names := []string{"clientid"}
+	byPartition, byBroker, valid := producerLatencyLabelsBy(canaryConfig)
+	if !valid {
+		glog.Warningf("Invalid produce latency labels %q, using the partition one", canaryConfig.ProducerLatencyLabels)
+	}
+	if byPartition {
+		names = append(names, "partition")
+	}
+	if byBroker {
+		names = append(names, "brokerid")
+	}
+	return names
+}
+
+// producerLatencyLabels returns the labels of the produce latency histogram for the partition,
+// the broker one is the current partition leader, empty if not known yet
+func producerLatencyLabels(canaryConfig *config.CanaryConfig, partition int32) prometheus.Labels {
+	labels := prometheus.Labels{
+		"clientid": canaryConfig.ClientID,
+	}
+	byPartition, byBroker, _ := producerLatencyLabelsBy(canaryConfig)
+	if byPartition {
+		labels["partition"] = partitionLabel(canaryConfig, partition)
+	}
+	if byBroker {
+		labels["brokerid"] = ""
+		if leader, ok := partitionLeaders.Leader(partition); ok {
+			labels["brokerid"] = strconv.Itoa(int(leader))
+		}
+	}
+	return labels
+}
+
+// partitionMetricVec defines a metric vector, with the partition label, whose series can be deleted
+type partitionMetricVec interface {
+	DeletePartialMatch(labels prometheus.Labels) int
+}
+
+// partitionMetricsRegistry tracks the metric vectors with the partition label in order to delete
+// the series of the partitions no longer present in the canary topic
+type partitionMetricsRegistry struct {
+	vecs map[partitionMetricVec]struct{}
+	// partition label values of the partitions present on the last expiry
+	partitions map[string]struct{}
+	mutex      sync.Mutex
+}
+
+// Register adds metric vectors to the ones whose stale partition series are deleted on expiry
+func (r *partitionMetricsRegistry) Register(vecs ...partitionMetricVec) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, vec := range vecs {
+		r.vecs[vec] = struct{}{}
+	}
+}
+
+// Expire deletes the series of the partitions which were present on the previous expiry but not anymore
+func (r *partitionMetricsRegistry) Expire(canaryConfig *config.CanaryConfig, partitions []int32) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	current := make(map[string]struct{}, len(partitions))
+	for _, p := range partitions {
+		current[partitionLabel(canaryConfig, p)] = struct{}{}
+	}
+	for label := range r.partitions {
+		if _, ok := current[label]; ok {
+			continue
+		}
+		deleted := 0
+		for vec := range r.vecs {
+			deleted += vec.DeletePartialMatch(prometheus.Labels{"partition": label})
+		}
+		glog.Infof("Deleted %d stale metrics series for partition %q", deleted, label)
+	}
+	r.partitions = current
+}
+
+// ExpirePartitionMetrics deletes the producer and consumer metrics series related to partitions
+// no longer present in the canary topic (i.e. topic re-created with fewer partitions)
+func ExpirePartitionMetrics(canaryConfig *config.CanaryConfig, assignments map[int32][]int32) {
+	partitions := make([]int32, 0, len(assignments))
+	for p := range assignments {
+		partitions = append(partitions, p)
+	}
+	partitionMetrics.Expire(canaryConfig, partitions)
+}
@@ -0,0 +1,86 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// +build unit_test
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/strimzi/strimzi-canary/internal/config"
+)
+
+func TestPartitionLabel(t *testing.T) {
+	tests := []struct {
+		name      string
+		limit     int
+		partition int32
+		expected  string
+	}{
+		{"no limit", -1, 100, "100"},
+		{"topic level", 0, 0, ""},
+		{"within limit", 3, 2, "2"},
+		{"beyond limit", 3, 3, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			label := partitionLabel(&config.CanaryConfig{MetricsPartitionsLimit: tt.limit}, tt.partition)
+			if label != tt.expected {
+				t.Errorf("got = %q, want = %q", label, tt.expected)
+			}
+		})
+	}
+}
+
+func TestExpirePartitionMetrics(t *testing.T) {
+	vec := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_total"}, []string{"clientid", "partition"})
+	registry := &partitionMetricsRegistry{vecs: make(map[partitionMetricVec]struct{})}
+	registry.Register(vec)
+	canaryConfig := &config.CanaryConfig{MetricsPartitionsLimit: -1}
+
+	registry.Expire(canaryConfig, []int32{0, 1, 2})
+	for _, p := range []string{"0", "1", "2"} {
+		vec.With(prometheus.Labels{"clientid": "my-client", "partition": p}).Inc()
+	}
+	// topic shrunk to 2 partitions
+	registry.Expire(canaryConfig, []int32{0, 1})
+	if count := testutil.CollectAndCount(vec); count != 2 {
+		t.Errorf("got = %d series, want = %d", count, 2)
+	}
+}
+
+func TestProducerLatencyLabels(t *testing.T) {
+	partitionLeaders.Update(map[int32]int32{0: 2})
+	tests := []struct {
+		name     string
+		labels   string
+		expected prometheus.Labels
+	}{
+		{"partition", "partition", prometheus.Labels{"clientid": "my-client", "partition": "0"}},
+		{"broker", "broker", prometheus.Labels{"clientid": "my-client", "brokerid": "2"}},
+		{"partition and broker", "partition, broker", prometheus.Labels{"clientid": "my-client", "partition": "0", "brokerid": "2"}},
+		{"invalid", "leader", prometheus.Labels{"clientid": "my-client", "partition": "0"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			canaryConfig := &config.CanaryConfig{ClientID: "my-client", MetricsPartitionsLimit: -1, ProducerLatencyLabels: tt.labels}
+			labels := producerLatencyLabels(canaryConfig, 0)
+			if !reflect.DeepEqual(labels, tt.expected) {
+				t.Errorf("got = %v, want = %v", labels, tt.expected)
+			}
+			if names := producerLatencyLabelNames(canaryConfig); len(names) != len(tt.expected) {
+				t.Errorf("got = %v label names, want = %d", names, len(tt.expected))
+			}
+		})
+	}
+}
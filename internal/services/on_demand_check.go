@@ -0,0 +1,156 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package services defines some canary related services
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/strimzi/strimzi-canary/internal/config"
+)
+
+// CheckResult defines the result of an on-demand produce/consume round trip
+type CheckResult struct {
+	Success    bool
+	Partitions []PartitionCheckResult
+	Error      string `json:",omitempty"`
+}
+
+// PartitionCheckResult defines the result of an on-demand produce/consume round trip on a partition
+type PartitionCheckResult struct {
+	Partition      int32
+	ProducedOffset int64
+	// time (in ms) needed to produce the message
+	ProducedLatency int64
+	ConsumedOffset  int64
+	// end-to-end latency (in ms) between producing and consuming the message
+	EndToEndLatency int64
+	Error           string `json:",omitempty"`
+}
+
+// CheckService defines the service for running on-demand produce/consume round trips
+type CheckService struct {
+	canaryConfig    *config.CanaryConfig
+	producerService *ProducerService
+	consumerService *ConsumerService
+	// only one on-demand check at time
+	mutex sync.Mutex
+}
+
+// NewCheckService returns an instance of CheckService
+func NewCheckService(canaryConfig *config.CanaryConfig, producerService *ProducerService, consumerService *ConsumerService) *CheckService {
+	cs := CheckService{
+		canaryConfig:    canaryConfig,
+		producerService: producerService,
+		consumerService: consumerService,
+	}
+	return &cs
+}
+
+// Check runs an immediate produce/consume round trip on all the canary topic partitions
+//
+// It sends one message to each partition and waits for them to be consumed up to the configured timeout
+func (cs *CheckService) Check() CheckResult {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+
+	glog.Infof("Running on-demand check")
+	partitions, err := cs.producerService.Partitions()
+	if err != nil {
+		glog.Errorf("Error getting partitions for on-demand check: %v", err)
+		return CheckResult{Success: false, Error: err.Error()}
+	}
+
+	result := CheckResult{Success: true}
+	waiters := make(map[int32]<-chan ConsumedRecord, len(partitions))
+	messages := make(map[int32]CanaryMessage, len(partitions))
+	for _, partition := range partitions {
+		cm := cs.producerService.NewCanaryMessage()
+		// waiting has to start before sending, the message could be consumed before Send returns
+		waiter := cs.consumerService.Await(cm.MessageID)
+		offset, latency, err := cs.producerService.SendMessage(cm, partition)
+		if err != nil {
+			cs.consumerService.StopAwaiting(cm.MessageID)
+			result.Success = false
+			result.Partitions = append(result.Partitions, PartitionCheckResult{
+				Partition:      partition,
+				ProducedOffset: -1,
+				ConsumedOffset: -1,
+				Error:          err.Error(),
+			})
+			continue
+		}
+		result.Partitions = append(result.Partitions, PartitionCheckResult{
+			Partition:       partition,
+			ProducedOffset:  offset,
+			ProducedLatency: latency,
+			ConsumedOffset:  -1,
+		})
+		waiters[partition] = waiter
+		messages[partition] = cm
+	}
+
+	timeout := time.After(cs.canaryConfig.OnDemandCheckTimeout * time.Millisecond)
+	timedOut := false
+	for i := range result.Partitions {
+		pr := &result.Partitions[i]
+		waiter, ok := waiters[pr.Partition]
+		if !ok {
+			continue
+		}
+		var record ConsumedRecord
+		consumed := false
+		if !timedOut {
+			select {
+			case record = <-waiter:
+				consumed = true
+			case <-timeout:
+				timedOut = true
+			}
+		}
+		// after the timeout, just getting the messages already consumed
+		if timedOut && !consumed {
+			select {
+			case record = <-waiter:
+				consumed = true
+			default:
+			}
+		}
+		if consumed {
+			pr.ConsumedOffset = record.Offset
+			pr.EndToEndLatency = record.Latency
+		} else {
+			cs.consumerService.StopAwaiting(messages[pr.Partition].MessageID)
+			result.Success = false
+			pr.Error = "timed out waiting for the message to be consumed"
+		}
+	}
+
+	glog.Infof("On-demand check done: success = %t", result.Success)
+	return result
+}
+
+// CheckHandler returns the HTTP handler running an on-demand check on POST requests
+func (cs *CheckService) CheckHandler() http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			rw.Header().Add("Allow", http.MethodPost)
+			rw.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		result := cs.Check()
+		json, _ := json.Marshal(result)
+		rw.Header().Add("Content-Type", "application/json")
+		if !result.Success {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+		}
+		rw.Write(json)
+	})
+}
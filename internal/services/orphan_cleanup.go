@@ -0,0 +1,221 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/strimzi/strimzi-canary/internal/clients"
+	"github.com/strimzi/strimzi-canary/internal/util"
+)
+
+const (
+	// types of the orphaned resources
+	TopicOrphan         = "topic"
+	ConsumerGroupOrphan = "consumer_group"
+
+	// timeout for looking up the recent records on each partition of a topic
+	orphanLookupTimeout = 10 * time.Second
+)
+
+// states of a consumer group without members, not rebalancing
+var idleConsumerGroupStates = map[string]bool{"Empty": true, "Dead": true}
+
+var (
+	orphansDeleted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name:      "orphans_deleted_total",
+		Namespace: "strimzi_canary",
+		Help:      "Total number of orphaned canary topics and consumer groups deleted, by type",
+	}, []string{"type"})
+
+	orphansFound = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name:      "orphans_found_total",
+		Namespace: "strimzi_canary",
+		Help:      "Total number of orphaned canary topics and consumer groups found, deleted or not, by type",
+	}, []string{"type"})
+)
+
+// CleanupOrphans deletes the canary topics and consumer groups left by previous canary instances, the ones whose
+// names start with the configured orphan cleanup prefix, other than the ones in use by this canary instance.
+// A consumer group is orphaned when it's empty and its paired topic, if any, had no records written within the
+// orphan cleanup idle time, a topic when it's paired with an orphaned consumer group.
+// Unless the deletion is enabled, the orphans are just logged
+func (ts *TopicService) CleanupOrphans() {
+	prefix := ts.canaryConfig.OrphanCleanupPrefix
+	if prefix == "" || ts.admin == nil {
+		return
+	}
+	glog.Infof("Looking for orphaned canary topics and consumer groups with prefix %s", prefix)
+
+	groupIDs, err := ts.admin.ListConsumerGroups()
+	if err != nil {
+		glog.Errorf("Error listing the consumer groups: %v", err)
+		return
+	}
+	groups := make([]*clients.ConsumerGroupDescription, 0)
+	for _, groupID := range groupIDs {
+		if !strings.HasPrefix(groupID, prefix) || groupID == ts.canaryConfig.ConsumerGroupID {
+			continue
+		}
+		description, err := ts.admin.DescribeConsumerGroup(groupID)
+		if err != nil {
+			glog.Errorf("Error describing the consumer group %s: %v", groupID, err)
+			return
+		}
+		groups = append(groups, description)
+	}
+	topics, err := ts.admin.ListTopics()
+	if err != nil {
+		glog.Errorf("Error listing the topics: %v", err)
+		return
+	}
+	inUse := []string{ts.canaryConfig.Topic, ts.canaryConfig.TargetTopic, ts.canaryConfig.EventsTopic}
+	written, err := ts.recentlyWrittenTopics(prefix, inUse, topics)
+	if err != nil {
+		glog.Errorf("Error looking up the records recently written on the topics: %v", err)
+		return
+	}
+	orphanedTopics, orphanedGroups := orphans(prefix, inUse, topics, groups, written)
+
+	for _, groupID := range orphanedGroups {
+		orphansFound.With(prometheus.Labels{"type": ConsumerGroupOrphan}).Inc()
+		if !ts.canaryConfig.OrphanCleanupDeleteEnabled {
+			glog.Infof("Found the orphaned canary consumer group %s, not deleted", groupID)
+			continue
+		}
+		glog.Infof("Deleting the orphaned canary consumer group %s", groupID)
+		if err := ts.admin.DeleteConsumerGroup(groupID); err != nil {
+			glog.Errorf("Error deleting the orphaned canary consumer group %s: %v", groupID, err)
+			continue
+		}
+		orphansDeleted.With(prometheus.Labels{"type": ConsumerGroupOrphan}).Inc()
+	}
+	for _, topic := range orphanedTopics {
+		orphansFound.With(prometheus.Labels{"type": TopicOrphan}).Inc()
+		if !ts.canaryConfig.OrphanCleanupDeleteEnabled {
+			glog.Infof("Found the orphaned canary topic %s, not deleted", topic)
+			continue
+		}
+		glog.Infof("Deleting the orphaned canary topic %s", topic)
+		if err := ts.admin.DeleteTopic(topic); err != nil {
+			glog.Errorf("Error deleting the orphaned canary topic %s: %v", topic, err)
+			continue
+		}
+		orphansDeleted.With(prometheus.Labels{"type": TopicOrphan}).Inc()
+	}
+}
+
+// DeleteConsumerGroup deletes the canary consumer group, the consumer has to be closed already
+func (ts *TopicService) DeleteConsumerGroup() error {
+	if ts.admin == nil {
+		admin, err := ts.clientFactory.NewAdmin(ts.canaryConfig.BootstrapServers)
+		if err != nil {
+			return err
+		}
+		ts.admin = &instrumentedAdmin{admin: admin}
+	}
+	glog.Infof("Deleting the canary consumer group %s", ts.canaryConfig.ConsumerGroupID)
+	return ts.admin.DeleteConsumerGroup(ts.canaryConfig.ConsumerGroupID)
+}
+
+// recentlyWrittenTopics returns the topics, with the prefix and not in use, having any record written within the
+// orphan cleanup idle time, i.e. the ones of a running canary instance whose consumer is between two sessions
+func (ts *TopicService) recentlyWrittenTopics(prefix string, inUse []string, topics []string) (map[string]bool, error) {
+	fetcher, err := ts.clientFactory.NewFetcher(ts.canaryConfig.BootstrapServers)
+	if err != nil {
+		return nil, err
+	}
+	defer fetcher.Close()
+
+	used := make(map[string]bool)
+	for _, topic := range inUse {
+		used[topic] = true
+	}
+	cutoff := util.NowInMilliseconds() - int64(ts.canaryConfig.OrphanCleanupIdleTime)
+	written := make(map[string]bool)
+	for _, topic := range topics {
+		if !strings.HasPrefix(topic, prefix) || used[topic] {
+			continue
+		}
+		metadata, err := ts.admin.DescribeTopic(topic)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range metadata.Partitions {
+			ctx, cancel := context.WithTimeout(context.Background(), orphanLookupTimeout)
+			offset, err := fetcher.OffsetForTimestamp(ctx, topic, p.ID, cutoff)
+			cancel()
+			if err != nil {
+				return nil, err
+			}
+			if offset >= 0 {
+				written[topic] = true
+				break
+			}
+		}
+	}
+	return written, nil
+}
+
+// orphans returns the topics and the consumer groups, with the prefix, which are orphaned.
+// The consumer groups without members and not rebalancing (i.e. empty or dead) are orphaned and so the topics named
+// as them, the pair a canary instance creates, unless in use or assigned to the other consumer groups. A consumer
+// group whose paired topic was recently written is not orphaned, because its canary instance is still producing
+// while its consumer is between two sessions. Any other topic with the prefix (i.e. the events topic of another
+// canary instance or a mirrored topic) is never orphaned.
+// No topic is orphaned while any of the consumer groups is rebalancing, because its assignment is not known
+func orphans(prefix string, inUse []string, topics []string, groups []*clients.ConsumerGroupDescription, written map[string]bool) ([]string, []string) {
+	orphanedGroups := make([]string, 0)
+	// the suffixes, after the prefix, of the orphaned consumer groups
+	orphanedSuffixes := make(map[string]bool)
+	assigned := make(map[string]bool)
+	rebalancing := false
+	for _, g := range groups {
+		if len(g.Members) == 0 {
+			if !idleConsumerGroupStates[g.State] {
+				rebalancing = true
+				continue
+			}
+			// the paired topic is named as the consumer group
+			if written[g.GroupID] {
+				continue
+			}
+			orphanedGroups = append(orphanedGroups, g.GroupID)
+			orphanedSuffixes[strings.TrimPrefix(g.GroupID, prefix)] = true
+			continue
+		}
+		for _, m := range g.Members {
+			if len(m.Assignment) == 0 {
+				rebalancing = true
+			}
+			for topic := range m.Assignment {
+				assigned[topic] = true
+			}
+		}
+	}
+	for _, topic := range inUse {
+		assigned[topic] = true
+	}
+
+	orphanedTopics := make([]string, 0)
+	if rebalancing {
+		glog.Warningf("Consumer groups with prefix %s rebalancing, orphaned canary topics not cleaned up", prefix)
+		return orphanedTopics, orphanedGroups
+	}
+	for _, topic := range topics {
+		if strings.HasPrefix(topic, prefix) && orphanedSuffixes[strings.TrimPrefix(topic, prefix)] && !assigned[topic] {
+			orphanedTopics = append(orphanedTopics, topic)
+		}
+	}
+	return orphanedTopics, orphanedGroups
+}
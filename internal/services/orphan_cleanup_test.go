@@ -0,0 +1,62 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// +build unit_test
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/strimzi/strimzi-canary/internal/clients"
+)
+
+func TestOrphans(t *testing.T) {
+	active := &clients.ConsumerGroupDescription{
+		GroupID: "canary-1",
+		State:   "Stable",
+		Members: []clients.ConsumerGroupMember{{MemberID: "m1", Assignment: map[string][]int32{"canary-1": {0, 1}}}},
+	}
+	empty := &clients.ConsumerGroupDescription{GroupID: "canary-2", State: "Empty"}
+	rebalancing := &clients.ConsumerGroupDescription{
+		GroupID: "canary-3",
+		State:   "CompletingRebalance",
+		Members: []clients.ConsumerGroupMember{{MemberID: "m1"}},
+	}
+	// the members left the group for rebalancing, so it has no members for a while
+	joining := &clients.ConsumerGroupDescription{GroupID: "canary-3", State: "PreparingRebalance"}
+	// a running canary instance whose consumer is between two sessions, still writing to its topic
+	betweenSessions := &clients.ConsumerGroupDescription{GroupID: "canary-4", State: "Empty"}
+	// canary-events has no paired consumer group, i.e. the events topic of another canary instance
+	topics := []string{"canary-0", "canary-1", "canary-2", "canary-4", "canary-events", "orders"}
+	written := map[string]bool{"canary-1": true, "canary-4": true}
+
+	tests := []struct {
+		name           string
+		groups         []*clients.ConsumerGroupDescription
+		orphanedTopics []string
+		orphanedGroups []string
+	}{
+		{"active and empty groups", []*clients.ConsumerGroupDescription{active, empty}, []string{"canary-2"}, []string{"canary-2"}},
+		{"no groups", []*clients.ConsumerGroupDescription{}, []string{}, []string{}},
+		{"in use group", []*clients.ConsumerGroupDescription{{GroupID: "canary-0", State: "Dead"}}, []string{}, []string{"canary-0"}},
+		{"rebalancing group", []*clients.ConsumerGroupDescription{active, empty, rebalancing}, []string{}, []string{"canary-2"}},
+		{"rebalancing group without members", []*clients.ConsumerGroupDescription{active, empty, joining}, []string{}, []string{"canary-2"}},
+		{"live canary between sessions", []*clients.ConsumerGroupDescription{active, betweenSessions}, []string{}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orphanedTopics, orphanedGroups := orphans("canary-", []string{"canary-0", ""}, topics, tt.groups, written)
+			if !reflect.DeepEqual(orphanedTopics, tt.orphanedTopics) {
+				t.Errorf("Orphaned topics = %v, expected = %v", orphanedTopics, tt.orphanedTopics)
+			}
+			if !reflect.DeepEqual(orphanedGroups, tt.orphanedGroups) {
+				t.Errorf("Orphaned groups = %v, expected = %v", orphanedGroups, tt.orphanedGroups)
+			}
+		})
+	}
+}
@@ -8,22 +8,24 @@ package services
 
 import (
 	"context"
-	"strconv"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"text/template"
 
-	"go.opentelemetry.io/contrib/instrumentation/github.com/Shopify/sarama/otelsarama"
-	"go.opentelemetry.io/otel"
-
-	"github.com/Shopify/sarama"
 	"github.com/golang/glog"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 
+	"github.com/strimzi/strimzi-canary/internal/clients"
 	"github.com/strimzi/strimzi-canary/internal/config"
 	"github.com/strimzi/strimzi-canary/internal/util"
 )
 
 var (
 	RecordsProducedCounter uint64 = 0
+	// records failed to be produced, used for measuring the impact of the chaos leader elections
+	recordsProducedFailedCounter uint64 = 0
 
 	recordsProduced = promauto.NewCounterVec(prometheus.CounterOpts{
 		Name:      "records_produced_total",
@@ -49,97 +51,292 @@ var (
 
 // ProducerService defines the service for producing messages
 type ProducerService struct {
-	canaryConfig *config.CanaryConfig
-	client       sarama.Client
-	producer     sarama.SyncProducer
+	canaryConfig  *config.CanaryConfig
+	clientFactory clients.Factory
+	// the producer is recreated on unrecoverable errors
+	producer      clients.Producer
+	producerMutex sync.RWMutex
 	// index of the next message to send
-	index int
+	index      int
+	indexMutex sync.Mutex
+	// sends in progress, to be drained on closing
+	inFlight sync.WaitGroup
+	// pausing the sends when the cluster is fully unavailable
+	circuitBreaker *circuitBreaker
+	// re-resolving the bootstrap servers and rebuilding the producer on repeated failures
+	dnsReResolver *dnsReResolver
+	// comparing the produce latency with different acks, nil if disabled
+	acksComparison *acksComparison
+	// template of the canary messages payload, nil if not configured
+	payloadTemplate *template.Template
+	trackers        *Trackers
 }
 
 // NewProducerService returns an instance of ProductService
-func NewProducerService(canaryConfig *config.CanaryConfig, client sarama.Client) *ProducerService {
+func NewProducerService(canaryConfig *config.CanaryConfig, clientFactory clients.Factory, producer clients.Producer, trackers *Trackers) *ProducerService {
 
-	recordsProducedLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	recordsProducedLatency = promauto.NewHistogramVec(latencyHistogramOpts(canaryConfig, prometheus.HistogramOpts{
 		Name:      "records_produced_latency",
 		Namespace: "strimzi_canary",
 		Help:      "Records produced latency in milliseconds",
 		Buckets:   canaryConfig.ProducerLatencyBuckets,
-	}, []string{"clientid", "partition"})
+	}), producerLatencyLabelNames(canaryConfig))
+	partitionMetrics.Register(recordsProduced, recordsProducedFailed, recordsProducedLatency, latencyThresholdBreaches, latencyThresholdBreached)
 
-	producer, err := sarama.NewSyncProducerFromClient(client)
-	if err != nil {
-		glog.Fatalf("Error creating the Sarama sync producer: %v", err)
-	}
-	producer = otelsarama.WrapSyncProducer(client.Config(), producer)
 	ps := ProducerService{
-		canaryConfig: canaryConfig,
-		client:       client,
-		producer:     producer,
+		canaryConfig:  canaryConfig,
+		clientFactory: clientFactory,
+		producer:      producer,
+		circuitBreaker: &circuitBreaker{
+			threshold:     canaryConfig.CircuitBreakerThreshold,
+			dynamicConfig: trackers.dynamicConfig,
+		},
+		dnsReResolver: newDNSReResolver(ProducerBootstrapClient, canaryConfig.DNSReResolutionThreshold),
+		trackers:      trackers,
 	}
+	if canaryConfig.ProducerAcksComparisonEnabled {
+		ps.acksComparison = newAcksComparison(canaryConfig, clientFactory)
+	}
+	payloadTemplate, err := newPayloadTemplate(canaryConfig.MessagePayloadTemplate)
+	if err != nil {
+		glog.Fatalf("Error parsing the message payload template: %v", err)
+	}
+	ps.payloadTemplate = payloadTemplate
 	return &ps
 }
 
 // Send sends one message to partitions assigned to brokers
+//
+// When all the sends fail for consecutive cycles, the circuit breaker opens and the sends are paused
+// until a lightweight connection probe to the cluster succeeds
 func (ps *ProducerService) Send(partitionsAssignments map[int32][]int32) {
-	numPartitions := len(partitionsAssignments)
-	msg := &sarama.ProducerMessage{
-		Topic: ps.canaryConfig.Topic,
+	labels := prometheus.Labels{
+		"clientid": ps.canaryConfig.ClientID,
+	}
+	if ps.circuitBreaker.IsOpen() {
+		if !ps.probe() {
+			glog.V(1).Infof("Producer circuit breaker open, skipping send")
+			ps.connectionFailure()
+			return
+		}
+		glog.Infof("Connection probe succeeded, closing producer circuit breaker")
+		ps.circuitBreaker.Close()
+		circuitBreakerOpen.With(labels).Set(0)
 	}
+
+	numPartitions := len(partitionsAssignments)
+	failed := 0
 	for i := 0; i < numPartitions; i++ {
 		// build the message JSON payload and send to the current partition
-		cm := ps.newCanaryMessage()
-		msg.Value = sarama.StringEncoder(cm.Json())
-		msg.Partition = int32(i)
-		otel.GetTextMapPropagator().Inject(context.Background(), otelsarama.NewProducerMessageCarrier(msg))
-		glog.V(1).Infof("Sending message: value=%s on partition=%d", msg.Value, msg.Partition)
-		partition, offset, err := ps.producer.SendMessage(msg)
-		timestamp := util.NowInMilliseconds() // timestamp in milliseconds
-		labels := prometheus.Labels{
-			"clientid":  ps.canaryConfig.ClientID,
-			"partition": strconv.Itoa(i),
-		}
-		recordsProduced.With(labels).Inc()
-		RecordsProducedCounter++
+		_, duration, err := ps.SendMessage(ps.NewCanaryMessage(), int32(i))
 		if err != nil {
-			glog.Warningf("Error sending message: %v", err)
-			recordsProducedFailed.With(labels).Inc()
-		} else {
-			duration := timestamp - cm.Timestamp
-			glog.V(1).Infof("Message sent: partition=%d, offset=%d, duration=%d ms", partition, offset, duration)
-			recordsProducedLatency.With(labels).Observe(float64(duration))
+			failed++
+		} else if ps.acksComparison != nil {
+			ps.compareAcks(int32(i), duration)
 		}
 	}
+
+	if numPartitions > 0 && failed == numPartitions {
+		if ps.circuitBreaker.Failure() {
+			glog.Warningf("All sends failed for %d consecutive cycles, opening producer circuit breaker", ps.circuitBreaker.Threshold())
+			circuitBreakerOpen.With(labels).Set(1)
+		}
+		ps.connectionFailure()
+	} else {
+		ps.circuitBreaker.Success()
+		ps.dnsReResolver.Success()
+	}
+}
+
+// compareAcks sends another canary message to the partition waiting for different acks, comparing the latency
+// with the one (in ms) of the record just sent
+func (ps *ProducerService) compareAcks(partition int32, duration int64) {
+	ps.inFlight.Add(1)
+	defer ps.inFlight.Done()
+	ps.acksComparison.compare(ps.NewCanaryMessage(), partition, duration)
 }
 
-// Refresh does a refresh metadata on the underneath Sarama client
+// connectionFailure records a cycle failing to reach the cluster, rebuilding the producer
+// once the bootstrap servers are re-resolved after repeated failures
+func (ps *ProducerService) connectionFailure() {
+	if ps.dnsReResolver.Failure(ps.canaryConfig.BootstrapServers) {
+		ps.recreate(ps.currentProducer())
+	}
+}
+
+// Degraded returns true if all the sends failed on the last cycle or the circuit breaker is open
+func (ps *ProducerService) Degraded() bool {
+	return ps.circuitBreaker.Failures() > 0 || ps.circuitBreaker.IsOpen()
+}
+
+// probe checks if at least one of the bootstrap servers is reachable
+func (ps *ProducerService) probe() bool {
+	for _, addr := range ps.canaryConfig.BootstrapServers {
+		err := ps.clientFactory.CheckConnection(clients.Broker{ID: -1, Addr: addr})
+		if err == nil {
+			return true
+		}
+		glog.V(1).Infof("Connection probe to %s failed: %v", addr, err)
+	}
+	return false
+}
+
+// SendMessage sends the provided canary message to the specified partition
+//
+// Returns the offset of the sent message and the time (in ms) needed to send it
+func (ps *ProducerService) SendMessage(cm CanaryMessage, partition int32) (int64, int64, error) {
+	ps.inFlight.Add(1)
+	defer ps.inFlight.Done()
+	value := cm.Json()
+	glog.V(1).Infof("Sending message: value=%s on partition=%d", value, partition)
+	producer := ps.currentProducer()
+	offset, logAppendTime, err := producer.Send(ps.canaryConfig.Topic, partition, []byte(value))
+	timestamp := util.NowInMilliseconds() // timestamp in milliseconds
+	labels := prometheus.Labels{
+		"clientid":  ps.canaryConfig.ClientID,
+		"partition": partitionLabel(ps.canaryConfig, partition),
+	}
+	recordsProduced.With(labels).Inc()
+	atomic.AddUint64(&RecordsProducedCounter, 1)
+	observeBrokerProduce(ps.canaryConfig, partition, err)
+	ps.trackers.safeToRoll.Produce(partition, err, timestamp)
+	if err != nil {
+		glog.Warningf("Error sending message: %v", err)
+		recordsProducedFailed.With(labels).Inc()
+		atomic.AddUint64(&recordsProducedFailedCounter, 1)
+		lastError.Record(ProducerErrorSource, err)
+		canaryEvents.Record(Event{Type: ProduceFailedEvent, Partition: partition, BrokerID: noBroker, Error: err.Error()})
+		recordAuthFailure(ps.canaryConfig.ClientID, ProducerBootstrapClient, err)
+		if clients.IsFatal(err) {
+			ps.recreate(producer)
+		}
+		return offset, 0, err
+	}
+	atomic.StoreInt64(&lastSuccessfulProduce, timestamp)
+	// with the replication check, the consumed offsets are on the target topic, so not comparable with the produced ones
+	// without acks the offset is not known
+	if offset >= 0 {
+		if !ps.canaryConfig.IsReplicationCheckEnabled() {
+			ps.trackers.logTruncation.Produced(partition, offset)
+		}
+		delayedRecords.Produced(partition, offset, cm.MessageID, timestamp)
+	}
+	duration := timestamp - cm.Timestamp
+	glog.V(1).Infof("Message sent: partition=%d, offset=%d, duration=%d ms", partition, offset, duration)
+	recordsProducedLatency.With(producerLatencyLabels(ps.canaryConfig, partition)).Observe(float64(duration))
+	observeLatencyThresholds(ps.canaryConfig, ps.trackers.dynamicConfig, ProduceLatency, labels["partition"], duration)
+	partitionsLatencyStats.ObserveProduced(partition, float64(duration))
+	if !logAppendTime.IsZero() {
+		observeClockSkew(ps.canaryConfig.ClientID, partition, cm.Timestamp, timestamp, logAppendTime)
+	}
+	canaryEvents.Record(Event{Type: ProducedEvent, Partition: partition, BrokerID: noBroker, Latency: float64(duration)})
+	if ps.canaryConfig.IsReplicationCheckEnabled() {
+		updateReplicationLag(ps.canaryConfig.ClientID, partition, ps.trackers.replication.Produced(partition))
+	}
+	return offset, duration, nil
+}
+
+// Partitions returns the canary topic partitions, sorted by ID, from the underneath Kafka producer metadata
+func (ps *ProducerService) Partitions() ([]int32, error) {
+	partitions, err := ps.currentProducer().Partitions(ps.canaryConfig.Topic)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(partitions, func(i, j int) bool {
+		return partitions[i] < partitions[j]
+	})
+	return partitions, nil
+}
+
+// Refresh does a refresh metadata on the underneath Kafka producer
 func (ps *ProducerService) Refresh() {
 	glog.Infof("Producer refreshing metadata")
-	if err := ps.client.RefreshMetadata(ps.canaryConfig.Topic); err != nil {
+	producer := ps.currentProducer()
+	if err := producer.RefreshMetadata(ps.canaryConfig.Topic); err != nil {
 		labels := prometheus.Labels{
 			"clientid": ps.canaryConfig.ClientID,
 		}
 		refreshMetadataError.With(labels).Inc()
+		metadataRefreshFailures.With(labels).Inc()
+		metadataRefresh.Failure()
 		glog.Errorf("Error refreshing metadata in producer: %v", err)
+		recordAuthFailure(ps.canaryConfig.ClientID, ProducerBootstrapClient, err)
+		if clients.IsFatal(err) {
+			ps.recreate(producer)
+		}
+		return
 	}
+	metadataRefresh.Success()
 }
 
-// Close closes the underneath Sarama producer instance
-func (ps *ProducerService) Close() {
+// Close waits for the in-flight sends to complete, until the context is done, and closes the underneath Kafka producer instance
+func (ps *ProducerService) Close(ctx context.Context) {
 	glog.Infof("Closing producer")
-	err := ps.producer.Close()
-	if err != nil {
-		glog.Fatalf("Error closing the Sarama sync producer: %v", err)
+	if err := util.WaitWithContext(ctx, ps.inFlight.Wait); err != nil {
+		glog.Warningf("Producer closing with in-flight sends: %v", err)
+	}
+	if ps.acksComparison != nil {
+		ps.acksComparison.close()
+	}
+	if err := ps.currentProducer().Close(); err != nil {
+		glog.Errorf("Error closing the Kafka producer: %v", err)
+		return
 	}
 	glog.Infof("Producer closed")
 }
 
-func (ps *ProducerService) newCanaryMessage() CanaryMessage {
+func (ps *ProducerService) currentProducer() clients.Producer {
+	ps.producerMutex.RLock()
+	defer ps.producerMutex.RUnlock()
+	return ps.producer
+}
+
+// recreate replaces the failed Kafka producer, hitting an unrecoverable error, with a new one
+func (ps *ProducerService) recreate(failed clients.Producer) {
+	ps.producerMutex.Lock()
+	defer ps.producerMutex.Unlock()
+	// already recreated by a concurrent send
+	if ps.producer != failed {
+		return
+	}
+	glog.Warningf("Recreating the Kafka producer after an unrecoverable error")
+	producer, err := ps.clientFactory.NewProducer(ps.canaryConfig.BootstrapServers)
+	if err != nil {
+		// keeping the failed one, the recreation is tried again on the next error
+		glog.Errorf("Error recreating the Kafka producer: %v", err)
+		recordAuthFailure(ps.canaryConfig.ClientID, ProducerBootstrapClient, err)
+		return
+	}
+	if err := failed.Close(); err != nil {
+		glog.Warningf("Error closing the failed Kafka producer: %v", err)
+	}
+	ps.producer = producer
+	recordClientRecreation(ProducerBootstrapClient)
+}
+
+// NewCanaryMessage returns a new canary message with the next index
+//
+// It waits for the rate limit, if any, before the message is timestamped, so that the wait is not part of the latency
+func (ps *ProducerService) NewCanaryMessage() CanaryMessage {
+	limitRate(ProduceOperation)
+	ps.indexMutex.Lock()
 	ps.index++
+	index := ps.index
+	ps.indexMutex.Unlock()
 	timestamp := util.NowInMilliseconds() // timestamp in milliseconds
 	cm := CanaryMessage{
 		ProducerID: ps.canaryConfig.ClientID,
-		MessageID:  ps.index,
+		MessageID:  index,
 		Timestamp:  timestamp,
+		Version:    checksumCanaryMessageVersion,
+	}
+	if ps.payloadTemplate != nil {
+		payload, err := renderPayload(ps.payloadTemplate, cm)
+		if err != nil {
+			glog.Warningf("Error rendering the message payload: %v", err)
+		}
+		cm.Payload = payload
+		cm.Version = payloadCanaryMessageVersion
 	}
-	return cm
+	return cm.WithChecksum()
 }
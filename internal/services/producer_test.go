@@ -0,0 +1,85 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// +build unit_test
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/strimzi/strimzi-canary/internal/clients"
+	"github.com/strimzi/strimzi-canary/internal/config"
+)
+
+// fakeProducer is a Kafka producer returning the provided partitions and send error
+type fakeProducer struct {
+	partitions []int32
+	sendErr    error
+	closed     bool
+}
+
+func (p *fakeProducer) Send(topic string, partition int32, value []byte) (int64, time.Time, error) {
+	return 0, time.Time{}, p.sendErr
+}
+
+func (p *fakeProducer) Partitions(topic string) ([]int32, error) {
+	return p.partitions, nil
+}
+
+func (p *fakeProducer) RefreshMetadata(topic string) error {
+	return nil
+}
+
+func (p *fakeProducer) Close() error {
+	p.closed = true
+	return nil
+}
+
+// fakeFactory is a Kafka clients factory returning the provided producer
+type fakeFactory struct {
+	clients.Factory
+	producer *fakeProducer
+}
+
+func (f *fakeFactory) NewProducer(bootstrapServers []string) (clients.Producer, error) {
+	return f.producer, nil
+}
+
+func TestProducerPartitionsSorted(t *testing.T) {
+	ps := &ProducerService{
+		canaryConfig: &config.CanaryConfig{Topic: "test"},
+		producer:     &fakeProducer{partitions: []int32{2, 0, 1}},
+	}
+	partitions, err := ps.Partitions()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !reflect.DeepEqual(partitions, []int32{0, 1, 2}) {
+		t.Errorf("got = %v, want = [0 1 2]", partitions)
+	}
+}
+
+func TestProducerRecreatedOnFatalError(t *testing.T) {
+	failed := &fakeProducer{sendErr: io.EOF}
+	recreated := &fakeProducer{}
+	canaryConfig := &config.CanaryConfig{Topic: "test", ClientID: "my-client"}
+	ps := &ProducerService{
+		canaryConfig:  canaryConfig,
+		clientFactory: &fakeFactory{producer: recreated},
+		producer:      failed,
+		trackers:      NewTrackers(canaryConfig),
+	}
+	if _, _, err := ps.SendMessage(ps.NewCanaryMessage(), 0); err != io.EOF {
+		t.Fatalf("got = %v, want = %v", err, io.EOF)
+	}
+	if ps.producer != recreated || !failed.closed {
+		t.Errorf("producer not recreated after an unrecoverable error")
+	}
+}
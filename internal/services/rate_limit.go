@@ -0,0 +1,60 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/strimzi/strimzi-canary/internal/util"
+)
+
+const (
+	// operations rate limited, in addition to the admin ones
+	ProduceOperation = "produce"
+)
+
+var (
+	// global limiter over the produce and admin operations, nil when not rate limited
+	operationsRateLimiter *util.TokenBucket
+
+	rateLimitedOperations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name:      "rate_limited_operations_total",
+		Namespace: "strimzi_canary",
+		Help:      "Total number of produce and admin operations delayed by the rate limit, by operation",
+	}, []string{"operation"})
+
+	rateLimitWait = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name:      "rate_limit_wait_ms_total",
+		Namespace: "strimzi_canary",
+		Help:      "Total time in milliseconds the produce and admin operations waited because of the rate limit, by operation",
+	}, []string{"operation"})
+)
+
+// SetRateLimit limits all the produce and admin operations to the provided number per second, 0 means not limited.
+// It allows bursts up to the operations of one second, so that a produce cycle on few partitions is not delayed
+func SetRateLimit(opsPerSecond int) {
+	if opsPerSecond <= 0 {
+		operationsRateLimiter = nil
+		return
+	}
+	operationsRateLimiter = util.NewTokenBucket(float64(opsPerSecond), opsPerSecond)
+}
+
+// limitRate waits until the operation is allowed by the rate limit, if any
+func limitRate(operation string) {
+	if operationsRateLimiter == nil {
+		return
+	}
+	if wait := operationsRateLimiter.Wait(); wait > 0 {
+		labels := prometheus.Labels{
+			"operation": operation,
+		}
+		rateLimitedOperations.With(labels).Inc()
+		rateLimitWait.With(labels).Add(float64(wait.Milliseconds()))
+	}
+}
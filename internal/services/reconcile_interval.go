@@ -0,0 +1,96 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/strimzi/strimzi-canary/internal/config"
+)
+
+var (
+	reconcileIntervalGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name:      "reconcile_interval_ms",
+		Namespace: "strimzi_canary",
+		Help:      "Current interval between reconciles in milliseconds, stretched while the cluster is degraded (jitter excluded)",
+	}, nil)
+)
+
+// ReconcileInterval computes the delay before the next reconcile
+//
+// A random jitter is added to the configured interval, so that many canaries don't synchronize their load
+// on the clusters, and the interval is doubled on each consecutive degraded reconcile, up to a maximum,
+// for not hammering a recovering cluster
+type ReconcileInterval struct {
+	interval time.Duration
+	// max interval when stretching, stretching is disabled if not greater than the interval
+	max time.Duration
+	// jitter as a percentage of the interval, added or subtracted
+	jitter float64
+	// consecutive degraded reconciles
+	degraded int
+	random   *rand.Rand
+	// interval set through the dynamic configuration, if any, overriding the configured one
+	dynamicConfig *dynamicConfig
+}
+
+// NewReconcileInterval returns an instance of ReconcileInterval
+func NewReconcileInterval(canaryConfig *config.CanaryConfig, trackers *Trackers) *ReconcileInterval {
+	return &ReconcileInterval{
+		interval:      canaryConfig.ReconcileInterval * time.Millisecond,
+		max:           canaryConfig.ReconcileMaxInterval * time.Millisecond,
+		jitter:        canaryConfig.ReconcileJitterPercentage,
+		random:        rand.New(rand.NewSource(time.Now().UnixNano())),
+		dynamicConfig: trackers.dynamicConfig,
+	}
+}
+
+// Next returns the delay before the next reconcile, taking into account if the last one found the cluster degraded
+func (ri *ReconcileInterval) Next(degraded bool) time.Duration {
+	if degraded {
+		ri.degraded++
+	} else {
+		ri.degraded = 0
+	}
+	interval := ri.stretched()
+	reconcileIntervalGauge.With(nil).Set(float64(interval.Milliseconds()))
+	if ri.jitter > 0 {
+		// uniformly distributed in [-jitter, +jitter) percentage of the interval
+		offset := (ri.random.Float64()*2 - 1) * ri.jitter / 100
+		interval += time.Duration(float64(interval) * offset)
+	}
+	if interval <= 0 {
+		return ri.base()
+	}
+	return interval
+}
+
+// base returns the interval set through the dynamic configuration, if any, or the configured one
+func (ri *ReconcileInterval) base() time.Duration {
+	if interval := ri.dynamicConfig.ReconcileInterval(); interval > 0 {
+		return time.Duration(interval) * time.Millisecond
+	}
+	return ri.interval
+}
+
+// stretched returns the interval doubled for each consecutive degraded reconcile, up to the max interval
+func (ri *ReconcileInterval) stretched() time.Duration {
+	if ri.max <= ri.base() {
+		return ri.base()
+	}
+	interval := ri.base()
+	for i := 0; i < ri.degraded && interval < ri.max; i++ {
+		interval *= 2
+	}
+	if interval > ri.max {
+		interval = ri.max
+	}
+	return interval
+}
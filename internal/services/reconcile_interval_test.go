@@ -0,0 +1,84 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// +build unit_test
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/strimzi/strimzi-canary/internal/config"
+)
+
+func TestReconcileIntervalStretching(t *testing.T) {
+	canaryConfig := &config.CanaryConfig{
+		ReconcileInterval:    30000,
+		ReconcileMaxInterval: 100000,
+	}
+	ri := NewReconcileInterval(canaryConfig, NewTrackers(canaryConfig))
+	steps := []struct {
+		degraded bool
+		want     time.Duration
+	}{
+		{false, 30 * time.Second},
+		{true, 60 * time.Second},
+		{true, 100 * time.Second},
+		{true, 100 * time.Second},
+		{false, 30 * time.Second},
+	}
+	for i, s := range steps {
+		if delay := ri.Next(s.degraded); delay != s.want {
+			t.Errorf("Step %d: got = %v, want = %v", i, delay, s.want)
+		}
+	}
+}
+
+func TestReconcileIntervalNoStretching(t *testing.T) {
+	canaryConfig := &config.CanaryConfig{
+		ReconcileInterval: 30000,
+	}
+	ri := NewReconcileInterval(canaryConfig, NewTrackers(canaryConfig))
+	want := 30 * time.Second
+	for i := 0; i < 3; i++ {
+		if delay := ri.Next(true); delay != want {
+			t.Errorf("Delay: got = %v, want = %v", delay, want)
+		}
+	}
+}
+
+func TestReconcileIntervalJitter(t *testing.T) {
+	canaryConfig := &config.CanaryConfig{
+		ReconcileInterval:         30000,
+		ReconcileJitterPercentage: 10,
+	}
+	ri := NewReconcileInterval(canaryConfig, NewTrackers(canaryConfig))
+	min, max := 27*time.Second, 33*time.Second
+	for i := 0; i < 100; i++ {
+		if delay := ri.Next(false); delay < min || delay > max {
+			t.Errorf("Delay: got = %v, want between %v and %v", delay, min, max)
+		}
+	}
+}
+
+func TestReconcileIntervalDynamicConfig(t *testing.T) {
+	canaryConfig := &config.CanaryConfig{
+		ReconcileInterval: 30000,
+	}
+	trackers := NewTrackers(canaryConfig)
+	ri := NewReconcileInterval(canaryConfig, trackers)
+	interval := 10000
+	trackers.ApplyDynamicConfig(&config.DynamicCanaryConfig{ReconcileInterval: &interval})
+	if delay := ri.Next(false); delay != 10*time.Second {
+		t.Errorf("Delay: got = %v, want = %v", delay, 10*time.Second)
+	}
+	// the interval not set anymore restores the configured one
+	trackers.ApplyDynamicConfig(&config.DynamicCanaryConfig{})
+	if delay := ri.Next(false); delay != 30*time.Second {
+		t.Errorf("Delay: got = %v, want = %v", delay, 30*time.Second)
+	}
+}
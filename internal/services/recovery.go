@@ -0,0 +1,28 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	clientRecreations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name:      "client_recreations_total",
+		Namespace: "strimzi_canary",
+		Help:      "Total number of Kafka clients recreated after an unrecoverable error",
+	}, []string{"client"})
+)
+
+// recordClientRecreation records the recreation of the provided Kafka client
+func recordClientRecreation(client string) {
+	labels := prometheus.Labels{
+		"client": client,
+	}
+	clientRecreations.With(labels).Inc()
+}
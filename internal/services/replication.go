@@ -0,0 +1,97 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package services defines some canary related services
+package services
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/strimzi/strimzi-canary/internal/clients"
+)
+
+var (
+	// it's defined when the consumer service is created because buckets are configurable
+	recordsReplicationLatency *prometheus.HistogramVec
+
+	replicationLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name:      "replication_lag",
+		Namespace: "strimzi_canary",
+		Help:      "The number of records produced on the source cluster and not consumed yet from the mirrored topic on the target cluster",
+	}, []string{"clientid", "partition"})
+)
+
+// replicationTracker keeps track, per partition, of records produced on the source cluster
+// and consumed from the mirrored topic on the target cluster in order to compute the replication lag
+type replicationTracker struct {
+	mutex sync.Mutex
+	// timestamp (in ms) since when the records are tracked, older records are not taken into account
+	since      int64
+	produced   map[int32]uint64
+	replicated map[int32]uint64
+}
+
+func newReplicationTracker(since int64) *replicationTracker {
+	return &replicationTracker{
+		since:      since,
+		produced:   make(map[int32]uint64),
+		replicated: make(map[int32]uint64),
+	}
+}
+
+// Produced tracks a record successfully produced on the source cluster and returns the current lag for the partition
+func (rt *replicationTracker) Produced(partition int32) uint64 {
+	rt.mutex.Lock()
+	defer rt.mutex.Unlock()
+	rt.produced[partition]++
+	return rt.lag(partition)
+}
+
+// Replicated tracks a record consumed from the target cluster and returns the current lag for the partition
+//
+// Records produced before the tracking started (i.e. by a previous canary instance) are ignored
+func (rt *replicationTracker) Replicated(partition int32, timestamp int64) uint64 {
+	rt.mutex.Lock()
+	defer rt.mutex.Unlock()
+	if timestamp >= rt.since {
+		rt.replicated[partition]++
+	}
+	return rt.lag(partition)
+}
+
+func (rt *replicationTracker) lag(partition int32) uint64 {
+	if rt.replicated[partition] >= rt.produced[partition] {
+		return 0
+	}
+	return rt.produced[partition] - rt.replicated[partition]
+}
+
+// replicationLatency returns the latency (in ms) between producing the canary message on the source cluster and
+// appending the mirrored record on the target cluster, as its timestamp, false if the record has no timestamp.
+// The mirrored topic needs the LogAppendTime timestamp type, otherwise the timestamp is the one of the source record
+func replicationLatency(record *clients.Record, cm CanaryMessage) (int64, bool) {
+	if record.Timestamp.IsZero() {
+		return 0, false
+	}
+	latency := record.Timestamp.UnixNano()/int64(time.Millisecond) - cm.Timestamp
+	if latency < 0 {
+		// clocks skew between the canary and the target cluster
+		latency = 0
+	}
+	return latency, true
+}
+
+func updateReplicationLag(clientID string, partition int32, lag uint64) {
+	labels := prometheus.Labels{
+		"clientid":  clientID,
+		"partition": strconv.Itoa(int(partition)),
+	}
+	replicationLag.With(labels).Set(float64(lag))
+}
@@ -0,0 +1,72 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// +build unit_test
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/strimzi/strimzi-canary/internal/clients"
+)
+
+func TestReplicationLag(t *testing.T) {
+	rt := newReplicationTracker(1000)
+
+	rt.Produced(0)
+	rt.Produced(0)
+	if lag := rt.Produced(0); lag != 3 {
+		t.Errorf("got = %d, want = %d", lag, 3)
+	}
+	if lag := rt.Replicated(0, 1500); lag != 2 {
+		t.Errorf("got = %d, want = %d", lag, 2)
+	}
+	// other partitions are not affected
+	if lag := rt.Produced(1); lag != 1 {
+		t.Errorf("got = %d, want = %d", lag, 1)
+	}
+}
+
+func TestReplicationLagIgnoresOldRecords(t *testing.T) {
+	rt := newReplicationTracker(1000)
+
+	rt.Produced(0)
+	// record produced by a previous canary instance
+	if lag := rt.Replicated(0, 500); lag != 1 {
+		t.Errorf("got = %d, want = %d", lag, 1)
+	}
+	if lag := rt.Replicated(0, 1500); lag != 0 {
+		t.Errorf("got = %d, want = %d", lag, 0)
+	}
+	// never negative
+	if lag := rt.Replicated(0, 1600); lag != 0 {
+		t.Errorf("got = %d, want = %d", lag, 0)
+	}
+}
+
+func TestReplicationLatency(t *testing.T) {
+	cm := CanaryMessage{Timestamp: 10000}
+	tests := []struct {
+		name      string
+		timestamp time.Time
+		latency   int64
+		ok        bool
+	}{
+		{"appended later", time.Unix(0, 10250*int64(time.Millisecond)), 250, true},
+		{"clocks skew", time.Unix(0, 9900*int64(time.Millisecond)), 0, true},
+		{"no timestamp", time.Time{}, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			latency, ok := replicationLatency(&clients.Record{Timestamp: tt.timestamp}, cm)
+			if latency != tt.latency || ok != tt.ok {
+				t.Errorf("got = (%d, %t), want = (%d, %t)", latency, ok, tt.latency, tt.ok)
+			}
+		})
+	}
+}
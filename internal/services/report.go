@@ -0,0 +1,212 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package services defines some canary related services
+package services
+
+import (
+	"encoding/json"
+	"html/template"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/golang/glog"
+
+	"github.com/strimzi/strimzi-canary/internal/util"
+)
+
+const (
+	// default number of hours covered by the report
+	defaultReportHours = 24
+)
+
+// Report defines the health report summarizing the canary events in a time window
+type Report struct {
+	// timestamps (in ms) of the time window covered by the report
+	From            int64
+	To              int64
+	FailureWindows  []FailureWindow
+	ProducedLatency LatencyPercentiles
+	EndToEndLatency LatencyPercentiles
+	// timestamps (in ms) of the consumer group rebalances
+	Rebalances    []int64
+	LeaderChanges []LeaderChange
+	Brokers       []BrokerReport
+}
+
+// FailureWindow defines a time window with produce or consume failures close to each other
+type FailureWindow struct {
+	Start    int64
+	End      int64
+	Failures int
+}
+
+// LatencyPercentiles defines latency (in ms) percentiles
+type LatencyPercentiles struct {
+	Count int
+	P50   float64
+	P90   float64
+	P99   float64
+	Max   float64
+}
+
+// LeaderChange defines a canary topic partition leader change
+type LeaderChange struct {
+	Timestamp int64
+	Partition int32
+	Leader    int32
+}
+
+// BrokerReport defines the connection issues to a broker
+type BrokerReport struct {
+	BrokerID           int32
+	ConnectionFailures int
+	LastFailure        int64
+	LastError          string
+}
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head><title>Strimzi canary health report</title></head>
+<body>
+<h1>Strimzi canary health report</h1>
+<p>From {{.From}} to {{.To}} (timestamps in ms)</p>
+<h2>Failure windows</h2>
+<table border="1">
+<tr><th>Start</th><th>End</th><th>Failures</th></tr>
+{{range .FailureWindows}}<tr><td>{{.Start}}</td><td>{{.End}}</td><td>{{.Failures}}</td></tr>
+{{end}}</table>
+<h2>Latencies (ms)</h2>
+<table border="1">
+<tr><th></th><th>Count</th><th>P50</th><th>P90</th><th>P99</th><th>Max</th></tr>
+{{with .ProducedLatency}}<tr><td>Produced</td><td>{{.Count}}</td><td>{{.P50}}</td><td>{{.P90}}</td><td>{{.P99}}</td><td>{{.Max}}</td></tr>{{end}}
+{{with .EndToEndLatency}}<tr><td>End-to-end</td><td>{{.Count}}</td><td>{{.P50}}</td><td>{{.P90}}</td><td>{{.P99}}</td><td>{{.Max}}</td></tr>{{end}}
+</table>
+<h2>Rebalances</h2>
+<ul>
+{{range .Rebalances}}<li>{{.}}</li>
+{{end}}</ul>
+<h2>Leader changes</h2>
+<table border="1">
+<tr><th>Timestamp</th><th>Partition</th><th>Leader</th></tr>
+{{range .LeaderChanges}}<tr><td>{{.Timestamp}}</td><td>{{.Partition}}</td><td>{{.Leader}}</td></tr>
+{{end}}</table>
+<h2>Brokers connection issues</h2>
+<table border="1">
+<tr><th>Broker</th><th>Failures</th><th>Last failure</th><th>Last error</th></tr>
+{{range .Brokers}}<tr><td>{{.BrokerID}}</td><td>{{.ConnectionFailures}}</td><td>{{.LastFailure}}</td><td>{{.LastError}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// ReportHandler returns the handler generating the health report for the last N hours, specified by the "hours"
+// query parameter, in JSON format or in HTML format with the "format=html" query parameter
+func (ss *StatusService) ReportHandler() http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			rw.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		hours := defaultReportHours
+		if h := r.URL.Query().Get("hours"); h != "" {
+			var err error
+			if hours, err = strconv.Atoi(h); err != nil || hours <= 0 {
+				http.Error(rw, "hours has to be a positive integer", http.StatusBadRequest)
+				return
+			}
+		}
+
+		to := util.NowInMilliseconds()
+		from := to - (time.Duration(hours) * time.Hour).Milliseconds()
+		// failures within two reconcile intervals are considered part of the same failure window
+		failureGap := int64(2 * ss.canaryConfig.ReconcileInterval)
+		report := buildReport(canaryEvents.Since(from), from, to, failureGap)
+
+		if r.URL.Query().Get("format") == "html" {
+			rw.Header().Add("Content-Type", "text/html")
+			if err := reportTemplate.Execute(rw, report); err != nil {
+				glog.Errorf("Error rendering the health report: %v", err)
+			}
+			return
+		}
+		json, _ := json.Marshal(report)
+		rw.Header().Add("Content-Type", "application/json")
+		rw.Write(json)
+	})
+}
+
+// buildReport returns the health report for the provided events, sorted by timestamp
+func buildReport(events []Event, from int64, to int64, failureGap int64) Report {
+	report := Report{
+		From:           from,
+		To:             to,
+		FailureWindows: make([]FailureWindow, 0),
+		Rebalances:     make([]int64, 0),
+		LeaderChanges:  make([]LeaderChange, 0),
+		Brokers:        make([]BrokerReport, 0),
+	}
+	produced := make([]float64, 0)
+	endToEnd := make([]float64, 0)
+	brokers := make(map[int32]*BrokerReport)
+	var window *FailureWindow
+	for _, e := range events {
+		switch e.Type {
+		case ProducedEvent:
+			produced = append(produced, e.Latency)
+		case ConsumedEvent:
+			endToEnd = append(endToEnd, e.Latency)
+		case ProduceFailedEvent, ConsumeFailedEvent:
+			if window != nil && e.Timestamp-window.End <= failureGap {
+				window.End = e.Timestamp
+				window.Failures++
+			} else {
+				report.FailureWindows = append(report.FailureWindows, FailureWindow{Start: e.Timestamp, End: e.Timestamp, Failures: 1})
+				window = &report.FailureWindows[len(report.FailureWindows)-1]
+			}
+		case RebalanceEvent:
+			report.Rebalances = append(report.Rebalances, e.Timestamp)
+		case LeaderChangeEvent:
+			report.LeaderChanges = append(report.LeaderChanges, LeaderChange{Timestamp: e.Timestamp, Partition: e.Partition, Leader: e.BrokerID})
+		case ConnectionFailedEvent:
+			b, ok := brokers[e.BrokerID]
+			if !ok {
+				b = &BrokerReport{BrokerID: e.BrokerID}
+				brokers[e.BrokerID] = b
+			}
+			b.ConnectionFailures++
+			b.LastFailure = e.Timestamp
+			b.LastError = e.Error
+		}
+	}
+	report.ProducedLatency = latencyPercentiles(produced)
+	report.EndToEndLatency = latencyPercentiles(endToEnd)
+	for _, b := range brokers {
+		report.Brokers = append(report.Brokers, *b)
+	}
+	sort.Slice(report.Brokers, func(i, j int) bool { return report.Brokers[i].BrokerID < report.Brokers[j].BrokerID })
+	return report
+}
+
+// latencyPercentiles returns the percentiles of the provided latencies, using the nearest-rank method
+func latencyPercentiles(latencies []float64) LatencyPercentiles {
+	if len(latencies) == 0 {
+		return LatencyPercentiles{}
+	}
+	sort.Float64s(latencies)
+	percentile := func(p float64) float64 {
+		return latencies[int(math.Ceil(p/100*float64(len(latencies))))-1]
+	}
+	return LatencyPercentiles{
+		Count: len(latencies),
+		P50:   percentile(50),
+		P90:   percentile(90),
+		P99:   percentile(99),
+		Max:   latencies[len(latencies)-1],
+	}
+}
@@ -0,0 +1,65 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// +build unit_test
+
+// Package services defines some canary related services
+package services
+
+import (
+	"testing"
+)
+
+func TestEventRing(t *testing.T) {
+	er := &eventRing{}
+	// not initialized, events are dropped
+	er.Record(Event{Timestamp: 1, Type: ProducedEvent})
+	if events := er.Since(0); len(events) != 0 {
+		t.Errorf("got = %d events, want = %d", len(events), 0)
+	}
+
+	er.Init(3)
+	for i := int64(1); i <= 5; i++ {
+		er.Record(Event{Timestamp: i, Type: ProducedEvent})
+	}
+	events := er.Since(0)
+	if len(events) != 3 || events[0].Timestamp != 3 || events[2].Timestamp != 5 {
+		t.Errorf("got = %+v, want = events with timestamps 3, 4, 5", events)
+	}
+	if events := er.Since(5); len(events) != 1 {
+		t.Errorf("got = %d events, want = %d", len(events), 1)
+	}
+}
+
+func TestBuildReport(t *testing.T) {
+	events := []Event{
+		{Timestamp: 1000, Type: ProducedEvent, Partition: 0, Latency: 10},
+		{Timestamp: 1000, Type: ConsumedEvent, Partition: 0, Latency: 30},
+		{Timestamp: 2000, Type: ProduceFailedEvent, Partition: 1},
+		{Timestamp: 3000, Type: ConsumeFailedEvent, Partition: noPartition},
+		{Timestamp: 3500, Type: RebalanceEvent, Partition: noPartition},
+		{Timestamp: 4000, Type: LeaderChangeEvent, Partition: 1, BrokerID: 2},
+		{Timestamp: 5000, Type: ConnectionFailedEvent, Partition: noPartition, BrokerID: 1, Error: "connection refused"},
+		{Timestamp: 9000, Type: ProduceFailedEvent, Partition: 2},
+		{Timestamp: 9000, Type: ProducedEvent, Partition: 0, Latency: 20},
+	}
+	report := buildReport(events, 0, 10000, 2000)
+
+	if len(report.FailureWindows) != 2 {
+		t.Fatalf("got = %d failure windows, want = %d", len(report.FailureWindows), 2)
+	}
+	if w := report.FailureWindows[0]; w.Start != 2000 || w.End != 3000 || w.Failures != 2 {
+		t.Errorf("got = %+v, want = {Start:2000 End:3000 Failures:2}", w)
+	}
+	if p := report.ProducedLatency; p.Count != 2 || p.P50 != 10 || p.Max != 20 {
+		t.Errorf("got = %+v, want = {Count:2 P50:10 ... Max:20}", p)
+	}
+	if len(report.Rebalances) != 1 || len(report.LeaderChanges) != 1 || report.LeaderChanges[0].Leader != 2 {
+		t.Errorf("unexpected rebalances %v or leader changes %+v", report.Rebalances, report.LeaderChanges)
+	}
+	if len(report.Brokers) != 1 || report.Brokers[0].ConnectionFailures != 1 || report.Brokers[0].LastError != "connection refused" {
+		t.Errorf("unexpected brokers %+v", report.Brokers)
+	}
+}
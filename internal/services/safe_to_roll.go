@@ -0,0 +1,196 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/strimzi/strimzi-canary/internal/util"
+)
+
+var (
+	brokerSafeToRoll = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name:      "broker_safe_to_roll",
+		Namespace: "strimzi_canary",
+		Help:      "If the broker is safe to roll (1) or not (0), based on the canary health observed within the safe to roll window",
+	}, []string{"clientid", "brokerid"})
+)
+
+// SafeToRollStatus defines if a broker is safe to roll, and the reason why not
+type SafeToRollStatus struct {
+	BrokerID   int32
+	SafeToRoll bool
+	Reason     string `json:",omitempty"`
+}
+
+// brokerHealth defines the last results observed by the canary for a broker
+type brokerHealth struct {
+	// timestamps (in ms) of the last success and failure
+	lastSuccess int64
+	lastFailure int64
+	lastError   string
+}
+
+// safeToRollTracker tracks the produce results, by the partitions leader, and the connection check results for each
+// broker. A broker is safe to roll when, within the window, it was successfully reached by the canary with no failures,
+// and no broker is down or was restarted, so that the cluster is settled from any previous broker restart
+type safeToRollTracker struct {
+	mutex sync.Mutex
+	// safe to roll window in ms
+	window  int64
+	brokers map[int32]*brokerHealth
+}
+
+func newSafeToRollTracker() *safeToRollTracker {
+	return &safeToRollTracker{
+		brokers: make(map[int32]*brokerHealth),
+	}
+}
+
+func (t *safeToRollTracker) setWindow(window time.Duration) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.window = window.Milliseconds()
+}
+
+// Produce observes the result of a send to the partition, by its current leader
+func (t *safeToRollTracker) Produce(partition int32, err error, now int64) {
+	if leader, ok := partitionLeaders.Leader(partition); ok {
+		t.Observe(leader, err, now)
+	}
+}
+
+// Observe observes the result of an operation on the broker
+func (t *safeToRollTracker) Observe(brokerID int32, err error, now int64) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	health, ok := t.brokers[brokerID]
+	if !ok {
+		health = &brokerHealth{}
+		t.brokers[brokerID] = health
+	}
+	if err != nil {
+		health.lastFailure = now
+		health.lastError = err.Error()
+		return
+	}
+	health.lastSuccess = now
+}
+
+// Status returns if each broker, observed by the canary or down, is safe to roll, given the brokers rolling restart status
+func (t *safeToRollTracker) Status(roll BrokerRollStatus, clientID string, now int64) []SafeToRollStatus {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	ids := make([]int32, 0, len(t.brokers)+len(roll.BrokersDown))
+	for id := range t.brokers {
+		ids = append(ids, id)
+	}
+	for _, id := range roll.BrokersDown {
+		if _, ok := t.brokers[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	statuses := make([]SafeToRollStatus, 0, len(ids))
+	for _, id := range ids {
+		status := SafeToRollStatus{BrokerID: id}
+		status.Reason = t.notSafeReason(id, roll, now)
+		status.SafeToRoll = status.Reason == ""
+		value := 0.0
+		if status.SafeToRoll {
+			value = 1
+		}
+		brokerSafeToRoll.With(prometheus.Labels{"clientid": clientID, "brokerid": strconv.Itoa(int(id))}).Set(value)
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// notSafeReason returns why the broker is not safe to roll, empty if it is
+func (t *safeToRollTracker) notSafeReason(brokerID int32, roll BrokerRollStatus, now int64) string {
+	if len(roll.BrokersDown) > 0 {
+		return fmt.Sprintf("brokers %v are down", roll.BrokersDown)
+	}
+	if roll.LastRestart > 0 && now-roll.LastRestart <= t.window {
+		return fmt.Sprintf("brokers %v were restarted within the window", roll.BrokersRestarted)
+	}
+	health, ok := t.brokers[brokerID]
+	if !ok || now-health.lastSuccess > t.window {
+		return "no successful operations within the window"
+	}
+	if now-health.lastFailure <= t.window {
+		return fmt.Sprintf("failed operations within the window: %s", health.lastError)
+	}
+	return ""
+}
+
+// safeToRollStatus returns if each broker is safe to roll, updating the broker_safe_to_roll metric as well.
+// It's run on each status check, so that the metric is up to date even if the endpoint is never requested
+func (ss *StatusService) safeToRollStatus(now int64) []SafeToRollStatus {
+	return ss.trackers.safeToRoll.Status(ss.trackers.brokerRoll.Status(now), ss.canaryConfig.ClientID, now)
+}
+
+// anySafeToRoll returns true if at least one of the brokers is safe to roll
+func anySafeToRoll(statuses []SafeToRollStatus) bool {
+	for _, s := range statuses {
+		if s.SafeToRoll {
+			return true
+		}
+	}
+	return false
+}
+
+// SafeToRollHandler returns the brokers safe to roll, or the provided one only (as brokerid parameter), responding
+// with 503 when it's not safe to roll, or no broker is, so that the brokers restarts can be gated on it
+func (ss *StatusService) SafeToRollHandler() http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			rw.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		statuses := ss.safeToRollStatus(util.NowInMilliseconds())
+
+		b := r.URL.Query().Get("brokerid")
+		if b == "" {
+			json, _ := json.Marshal(statuses)
+			rw.Header().Add("Content-Type", "application/json")
+			if !anySafeToRoll(statuses) {
+				rw.WriteHeader(http.StatusServiceUnavailable)
+			}
+			rw.Write(json)
+			return
+		}
+		id, err := strconv.Atoi(b)
+		if err != nil || id < 0 {
+			http.Error(rw, "brokerid has to be a non negative integer", http.StatusBadRequest)
+			return
+		}
+		// a broker never observed by the canary is not safe to roll
+		status := SafeToRollStatus{BrokerID: int32(id), Reason: "no successful operations within the window"}
+		for _, s := range statuses {
+			if s.BrokerID == int32(id) {
+				status = s
+			}
+		}
+		json, _ := json.Marshal(status)
+		rw.Header().Add("Content-Type", "application/json")
+		if !status.SafeToRoll {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+		}
+		rw.Write(json)
+	})
+}
@@ -0,0 +1,108 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// +build unit_test
+
+// Package services defines some canary related services
+package services
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/strimzi/strimzi-canary/internal/config"
+	"github.com/strimzi/strimzi-canary/internal/util"
+)
+
+func TestSafeToRoll(t *testing.T) {
+	tracker := newSafeToRollTracker()
+	tracker.window = 60000
+	tracker.Observe(0, nil, 100000)
+	tracker.Observe(1, nil, 100000)
+	tracker.Observe(1, errors.New("connection refused"), 90000)
+	tracker.Observe(2, nil, 30000)
+
+	tests := []struct {
+		name     string
+		roll     BrokerRollStatus
+		expected []bool
+	}{
+		{"no roll", BrokerRollStatus{}, []bool{true, false, false}},
+		{"broker down", BrokerRollStatus{BrokersDown: []int32{3}}, []bool{false, false, false, false}},
+		{"recent restart", BrokerRollStatus{BrokersRestarted: []int32{3}, LastRestart: 80000}, []bool{false, false, false}},
+		{"restart out of the window", BrokerRollStatus{BrokersRestarted: []int32{3}, LastRestart: 20000}, []bool{true, false, false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statuses := tracker.Status(tt.roll, "client", 100000)
+			if len(statuses) != len(tt.expected) {
+				t.Fatalf("got = %+v, want %d brokers", statuses, len(tt.expected))
+			}
+			for i, s := range statuses {
+				if s.BrokerID != int32(i) || s.SafeToRoll != tt.expected[i] || s.SafeToRoll != (s.Reason == "") {
+					t.Errorf("broker %d got = %+v, want safe to roll = %t", i, s, tt.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSafeToRollHandler(t *testing.T) {
+	canaryConfig := &config.CanaryConfig{ClientID: "client", SafeToRollWindow: 60000}
+	trackers := NewTrackers(canaryConfig)
+	trackers.safeToRoll.Observe(0, nil, util.NowInMilliseconds())
+
+	ss := &StatusService{canaryConfig: canaryConfig, trackers: trackers}
+	tests := []struct {
+		query    string
+		expected int
+	}{
+		{"", http.StatusOK},
+		{"?brokerid=0", http.StatusOK},
+		{"?brokerid=1", http.StatusServiceUnavailable},
+		{"?brokerid=foo", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		rw := httptest.NewRecorder()
+		ss.SafeToRollHandler().ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/safe-to-roll"+tt.query, nil))
+		if rw.Code != tt.expected {
+			t.Errorf("query %q got = %d, want = %d", tt.query, rw.Code, tt.expected)
+		}
+	}
+
+	// no broker safe to roll
+	trackers.safeToRoll.Observe(0, errors.New("connection refused"), util.NowInMilliseconds())
+	rw := httptest.NewRecorder()
+	ss.SafeToRollHandler().ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/safe-to-roll", nil))
+	if rw.Code != http.StatusServiceUnavailable {
+		t.Errorf("no broker safe to roll got = %d, want = %d", rw.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestStatusCheckUpdatesSafeToRoll(t *testing.T) {
+	canaryConfig := &config.CanaryConfig{ClientID: "status-check-client", StatusCheckInterval: 1000, StatusTimeWindow: 10000, SafeToRollWindow: 60000}
+	trackers := NewTrackers(canaryConfig)
+	trackers.safeToRoll.Observe(7, nil, util.NowInMilliseconds())
+
+	ss := &StatusService{
+		canaryConfig:           canaryConfig,
+		producedRecordsSamples: *util.NewTimeWindowRing(canaryConfig.StatusTimeWindow, canaryConfig.StatusCheckInterval),
+		consumedRecordsSamples: *util.NewTimeWindowRing(canaryConfig.StatusTimeWindow, canaryConfig.StatusCheckInterval),
+		trackers:               trackers,
+	}
+	// the metric is updated by the status check, without any request to the endpoint
+	ss.statusCheck()
+	gauge := brokerSafeToRoll.With(prometheus.Labels{"clientid": "status-check-client", "brokerid": "7"})
+	if value := testutil.ToFloat64(gauge); value != 1 {
+		t.Errorf("broker_safe_to_roll got = %v, want = 1", value)
+	}
+}
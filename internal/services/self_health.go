@@ -0,0 +1,89 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"runtime"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	// canary loops tracked by the self-health metrics
+	ReconcileLoop               = "reconcile"
+	ConnectionCheckLoop         = "connection_check"
+	StatusCheckLoop             = "status_check"
+	SLOSamplingLoop             = "slo_sampling"
+	ChaosLoop                   = "chaos"
+	ConsumerGroupCheckLoop      = "consumer_group_check"
+	DelayedConsumeLoop          = "delayed_consume"
+	TieredStorageCheckLoop      = "tiered_storage_check"
+	AdvertisedListenerCheckLoop = "advertised_listener_check"
+)
+
+var (
+	loopDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:      "loop_duration_ms",
+		Namespace: "strimzi_canary",
+		Help:      "Duration in milliseconds of a run of the canary loops",
+		Buckets:   []float64{10, 50, 100, 250, 500, 1000, 2500, 5000, 10000, 30000},
+	}, []string{"loop"})
+
+	tickerDrift = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:      "ticker_drift_ms",
+		Namespace: "strimzi_canary",
+		Help:      "Delay in milliseconds between the scheduled and the actual start of a run of the canary loops",
+		Buckets:   []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000, 5000},
+	}, []string{"loop"})
+
+	_ = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name:      "goroutines",
+		Namespace: "strimzi_canary",
+		Help:      "Number of goroutines of the canary process",
+	}, func() float64 {
+		return float64(runtime.NumGoroutine())
+	})
+
+	_ = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name:      "heap_alloc_bytes",
+		Namespace: "strimzi_canary",
+		Help:      "Bytes of allocated heap objects of the canary process",
+	}, func() float64 {
+		var stats runtime.MemStats
+		runtime.ReadMemStats(&stats)
+		return float64(stats.HeapAlloc)
+	})
+)
+
+// LoopRun tracks a run of a canary loop for the self-health metrics, so that a canary process starved of CPU,
+// with the runs starting late, can be told apart from a slow Kafka cluster
+type LoopRun struct {
+	loop  string
+	start time.Time
+}
+
+// StartLoopRun records the start of a run of the loop, which was scheduled at the provided time (i.e. the tick)
+func StartLoopRun(loop string, scheduled time.Time) LoopRun {
+	start := time.Now()
+	tickerDrift.With(prometheus.Labels{"loop": loop}).Observe(float64(loopDrift(scheduled, start).Milliseconds()))
+	return LoopRun{loop: loop, start: start}
+}
+
+// Done records the end of the loop run
+func (lr LoopRun) Done() {
+	loopDuration.With(prometheus.Labels{"loop": lr.loop}).Observe(float64(time.Since(lr.start).Milliseconds()))
+}
+
+// loopDrift returns how late the run started compared to the scheduled time
+func loopDrift(scheduled time.Time, start time.Time) time.Duration {
+	if drift := start.Sub(scheduled); drift > 0 {
+		return drift
+	}
+	return 0
+}
@@ -0,0 +1,29 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// +build unit_test
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoopDrift(t *testing.T) {
+	scheduled := time.Now()
+
+	if drift := loopDrift(scheduled, scheduled.Add(250*time.Millisecond)); drift != 250*time.Millisecond {
+		t.Errorf("Drift = %v, expected = %v", drift, 250*time.Millisecond)
+	}
+	if drift := loopDrift(scheduled, scheduled); drift != 0 {
+		t.Errorf("Drift = %v, expected = 0", drift)
+	}
+	// a run starting before the scheduled time has no drift
+	if drift := loopDrift(scheduled, scheduled.Add(-time.Millisecond)); drift != 0 {
+		t.Errorf("Drift = %v, expected = 0", drift)
+	}
+}
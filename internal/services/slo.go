@@ -0,0 +1,173 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package services defines some canary related services
+package services
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/strimzi/strimzi-canary/internal/config"
+	"github.com/strimzi/strimzi-canary/internal/util"
+)
+
+const (
+	// number of samples covering the SLO window, it determines the sampling interval
+	sloWindowSamples = 360
+
+	// service level indicators
+	AvailabilitySLI = "availability"
+	LatencySLI      = "latency"
+)
+
+var (
+	sloIndicator = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name:      "slo_sli_percentage",
+		Namespace: "strimzi_canary",
+		Help:      "Service level indicator, as percentage of good round trips, over the SLO window",
+	}, []string{"sli"})
+
+	sloErrorBudgetRemaining = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name:      "slo_error_budget_remaining_percentage",
+		Namespace: "strimzi_canary",
+		Help:      "Percentage of the error budget remaining over the SLO window, negative when exhausted",
+	}, []string{"sli"})
+
+	sloBurnRate = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name:      "slo_burn_rate",
+		Namespace: "strimzi_canary",
+		Help:      "Rate at which the error budget is consumed over the SLO window, 1 means exhausting it exactly at the end of the window",
+	}, []string{"sli"})
+)
+
+// SLOStatus defines the SLO related status information
+type SLOStatus struct {
+	// time window covered by the samples
+	Window       time.Duration
+	Availability SLIStatus
+	Latency      SLIStatus
+}
+
+// SLIStatus defines the status of a service level indicator against its target
+type SLIStatus struct {
+	// percentage of good round trips to achieve
+	Target float64
+	// percentage of good round trips, -1 if no round trips in the window
+	SLI                  float64
+	ErrorBudgetRemaining float64
+	BurnRate             float64
+}
+
+// sloTracker tracks the availability and latency service level indicators over the SLO window
+//
+// The availability SLI is the percentage of produced records which were consumed, while the latency SLI
+// is the percentage of produced records which were consumed within the SLO latency threshold
+type sloTracker struct {
+	canaryConfig                 *config.CanaryConfig
+	availabilityTarget           float64
+	latencyTarget                float64
+	sampling                     time.Duration
+	producedSamples              util.TimeWindowRing
+	consumedSamples              util.TimeWindowRing
+	consumedWithinLatencySamples util.TimeWindowRing
+	mutex                        sync.Mutex
+}
+
+func newSLOTracker(canaryConfig *config.CanaryConfig) *sloTracker {
+	// sampling over the SLO window, but not more often than the status check
+	window := canaryConfig.SLOWindow
+	sampling := window / sloWindowSamples
+	if sampling < canaryConfig.StatusCheckInterval {
+		sampling = canaryConfig.StatusCheckInterval
+	}
+	if window < sampling {
+		window = sampling
+	}
+	t := sloTracker{
+		canaryConfig:                 canaryConfig,
+		availabilityTarget:           sloTarget(AvailabilitySLI, canaryConfig.SLOAvailabilityTarget, config.SLOAvailabilityTargetDefault),
+		latencyTarget:                sloTarget(LatencySLI, canaryConfig.SLOLatencyTarget, config.SLOLatencyTargetDefault),
+		sampling:                     sampling,
+		producedSamples:              *util.NewTimeWindowRing(window, sampling),
+		consumedSamples:              *util.NewTimeWindowRing(window, sampling),
+		consumedWithinLatencySamples: *util.NewTimeWindowRing(window, sampling),
+	}
+	// initial samples as baseline for the SLIs
+	t.Sample()
+	return &t
+}
+
+// sloTarget returns the SLO target, falling back to the default one if not valid
+func sloTarget(sli string, target float64, defaultTarget float64) float64 {
+	if target <= 0 || target >= 100 {
+		glog.Warningf("Invalid %s SLO target %f, it has to be between 0 and 100 (exclusive); using %f", sli, target, defaultTarget)
+		return defaultTarget
+	}
+	return target
+}
+
+// Sample adds the current produced and consumed records to the SLO window and updates the SLO metrics
+func (t *sloTracker) Sample() {
+	t.mutex.Lock()
+	t.producedSamples.Put(atomic.LoadUint64(&RecordsProducedCounter))
+	t.consumedSamples.Put(atomic.LoadUint64(&RecordsConsumedCounter))
+	t.consumedWithinLatencySamples.Put(atomic.LoadUint64(&RecordsConsumedWithinLatencyCounter))
+	t.mutex.Unlock()
+
+	status := t.Status()
+	for sli, s := range map[string]SLIStatus{AvailabilitySLI: status.Availability, LatencySLI: status.Latency} {
+		labels := prometheus.Labels{"sli": sli}
+		// no round trips in the window, so nothing to report
+		if s.SLI < 0 {
+			sloIndicator.Delete(labels)
+		} else {
+			sloIndicator.With(labels).Set(s.SLI)
+		}
+		sloErrorBudgetRemaining.With(labels).Set(s.ErrorBudgetRemaining)
+		sloBurnRate.With(labels).Set(s.BurnRate)
+	}
+}
+
+// Status returns the current SLIs over the SLO window, using the current records counters
+func (t *sloTracker) Status() SLOStatus {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	produced := atomic.LoadUint64(&RecordsProducedCounter) - t.producedSamples.Tail()
+	consumed := atomic.LoadUint64(&RecordsConsumedCounter) - t.consumedSamples.Tail()
+	consumedWithinLatency := atomic.LoadUint64(&RecordsConsumedWithinLatencyCounter) - t.consumedWithinLatencySamples.Tail()
+	return SLOStatus{
+		Window:       t.sampling * time.Duration(t.producedSamples.Count()),
+		Availability: computeSLI(t.availabilityTarget, consumed, produced),
+		Latency:      computeSLI(t.latencyTarget, consumedWithinLatency, produced),
+	}
+}
+
+// computeSLI returns the SLI status given the target (percentage), the good and the total events
+func computeSLI(target float64, good uint64, total uint64) SLIStatus {
+	status := SLIStatus{Target: target}
+	if total == 0 {
+		status.SLI = -1
+		status.ErrorBudgetRemaining = 100
+		return status
+	}
+	// records produced at the beginning of the window could be consumed after it
+	if good > total {
+		good = total
+	}
+	sli := float64(good*100) / float64(total)
+	burnRate := (100 - sli) / (100 - target)
+	// rounding to three decimal digits
+	status.SLI = math.Round(sli*1000) / 1000
+	status.BurnRate = math.Round(burnRate*1000) / 1000
+	status.ErrorBudgetRemaining = math.Round((1-burnRate)*100*1000) / 1000
+	return status
+}
@@ -0,0 +1,38 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// +build unit_test
+
+// Package services defines some canary related services
+package services
+
+import (
+	"testing"
+)
+
+func TestComputeSLI(t *testing.T) {
+	tests := []struct {
+		name     string
+		target   float64
+		good     uint64
+		total    uint64
+		expected SLIStatus
+	}{
+		{"no round trips", 99.9, 0, 0, SLIStatus{Target: 99.9, SLI: -1, ErrorBudgetRemaining: 100}},
+		{"all good", 99.9, 1000, 1000, SLIStatus{Target: 99.9, SLI: 100, ErrorBudgetRemaining: 100}},
+		{"half budget", 99, 995, 1000, SLIStatus{Target: 99, SLI: 99.5, ErrorBudgetRemaining: 50, BurnRate: 0.5}},
+		{"budget exhausted", 99, 980, 1000, SLIStatus{Target: 99, SLI: 98, ErrorBudgetRemaining: -100, BurnRate: 2}},
+		{"consumed more than produced", 99, 1010, 1000, SLIStatus{Target: 99, SLI: 100, ErrorBudgetRemaining: 100}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := computeSLI(tt.target, tt.good, tt.total)
+			if status != tt.expected {
+				t.Errorf("got = %+v, want = %+v", status, tt.expected)
+			}
+		})
+	}
+}
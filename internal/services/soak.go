@@ -0,0 +1,90 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package services defines some canary related services
+package services
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/glog"
+
+	"github.com/strimzi/strimzi-canary/internal/config"
+)
+
+const (
+	// interval for checking if the soak run is completed
+	soakCheckInterval = 1 * time.Second
+)
+
+// SoakService defines the service for running the canary for a bounded duration or message count
+// and verifying the thresholds at the end, i.e. as a gating check after a Kafka cluster upgrade
+type SoakService struct {
+	canaryConfig *config.CanaryConfig
+	done         chan struct{}
+}
+
+// NewSoakService returns an instance of SoakService
+func NewSoakService(canaryConfig *config.CanaryConfig) *SoakService {
+	ss := SoakService{
+		canaryConfig: canaryConfig,
+		done:         make(chan struct{}),
+	}
+	return &ss
+}
+
+// Start starts tracking the soak run, closing the Done channel when the duration elapsed or the message count is reached
+func (ss *SoakService) Start() {
+	glog.Infof("Starting soak run [duration = %d ms, message count = %d]", ss.canaryConfig.SoakDuration, ss.canaryConfig.SoakMessageCount)
+	start := time.Now()
+	ticker := time.NewTicker(soakCheckInterval)
+	go func() {
+		defer ticker.Stop()
+		for range ticker.C {
+			elapsed := ss.canaryConfig.SoakDuration > 0 && time.Since(start) >= ss.canaryConfig.SoakDuration*time.Millisecond
+			reached := ss.canaryConfig.SoakMessageCount > 0 && atomic.LoadUint64(&RecordsProducedCounter) >= uint64(ss.canaryConfig.SoakMessageCount)
+			if elapsed || reached {
+				glog.Infof("Soak run completed in %v", time.Since(start))
+				close(ss.done)
+				return
+			}
+		}
+	}()
+}
+
+// Done returns the channel closed when the soak run is completed
+func (ss *SoakService) Done() <-chan struct{} {
+	return ss.done
+}
+
+// Verify checks the soak run results against the configured thresholds, returning an error if they were violated
+func (ss *SoakService) Verify() error {
+	produced := atomic.LoadUint64(&RecordsProducedCounter)
+	consumed := atomic.LoadUint64(&RecordsConsumedCounter)
+	var maxLatency float64
+	for _, stats := range partitionsLatencyStats.Snapshot() {
+		if stats.EndToEnd.Max > maxLatency {
+			maxLatency = stats.EndToEnd.Max
+		}
+	}
+	return verifySoak(ss.canaryConfig, produced, consumed, maxLatency)
+}
+
+func verifySoak(canaryConfig *config.CanaryConfig, produced uint64, consumed uint64, maxLatency float64) error {
+	if produced == 0 {
+		return fmt.Errorf("no records produced")
+	}
+	percentage := float64(consumed*100) / float64(produced)
+	glog.Infof("Soak run results: produced = %d, consumed = %d (%.2f%%), max end-to-end latency = %.0f ms", produced, consumed, percentage, maxLatency)
+	if percentage < canaryConfig.SoakMinConsumedPercentage {
+		return fmt.Errorf("consumed records percentage %.2f%% below the threshold %.2f%%", percentage, canaryConfig.SoakMinConsumedPercentage)
+	}
+	if canaryConfig.SoakMaxLatency > 0 && maxLatency > float64(canaryConfig.SoakMaxLatency) {
+		return fmt.Errorf("max end-to-end latency %.0f ms above the threshold %d ms", maxLatency, canaryConfig.SoakMaxLatency)
+	}
+	return nil
+}
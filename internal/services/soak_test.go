@@ -0,0 +1,40 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// +build unit_test
+
+// Package services defines some canary related services
+package services
+
+import (
+	"testing"
+
+	"github.com/strimzi/strimzi-canary/internal/config"
+)
+
+func TestVerifySoak(t *testing.T) {
+	canaryConfig := &config.CanaryConfig{SoakMinConsumedPercentage: 99, SoakMaxLatency: 500}
+	tests := []struct {
+		name       string
+		produced   uint64
+		consumed   uint64
+		maxLatency float64
+		violated   bool
+	}{
+		{"no records produced", 0, 0, 0, true},
+		{"within thresholds", 1000, 995, 200, false},
+		{"consumed below threshold", 1000, 980, 200, true},
+		{"latency above threshold", 1000, 1000, 800, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifySoak(canaryConfig, tt.produced, tt.consumed, tt.maxLatency)
+			if (err != nil) != tt.violated {
+				t.Errorf("got error = %v, want violated = %t", err, tt.violated)
+			}
+		})
+	}
+}
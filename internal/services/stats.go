@@ -0,0 +1,151 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package services defines some canary related services
+package services
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/strimzi/strimzi-canary/internal/util"
+)
+
+const (
+	// error sources used when recording the last error
+	ProducerErrorSource   = "producer"
+	ConsumerErrorSource   = "consumer"
+	TopicErrorSource      = "topic"
+	ConnectionErrorSource = "connection"
+)
+
+var (
+	// tracks per partition latencies since the canary started
+	partitionsLatencyStats = newLatencyStatsTracker()
+
+	// tracks the last error happened while producing, consuming or managing the canary topic
+	lastError = errorTracker{}
+)
+
+// LatencyStats defines statistics about latencies (in ms)
+type LatencyStats struct {
+	Count uint64
+	Min   float64
+	Max   float64
+	Avg   float64
+	Last  float64
+}
+
+func (ls *LatencyStats) observe(latency float64) {
+	if ls.Count == 0 || latency < ls.Min {
+		ls.Min = latency
+	}
+	if ls.Count == 0 || latency > ls.Max {
+		ls.Max = latency
+	}
+	ls.Avg = (ls.Avg*float64(ls.Count) + latency) / float64(ls.Count+1)
+	ls.Last = latency
+	ls.Count++
+}
+
+// PartitionLatencyStats defines produced and end-to-end latency statistics for a partition
+type PartitionLatencyStats struct {
+	Partition int32
+	Produced  LatencyStats
+	EndToEnd  LatencyStats
+}
+
+type latencyStatsTracker struct {
+	mutex      sync.Mutex
+	partitions map[int32]*PartitionLatencyStats
+}
+
+func newLatencyStatsTracker() *latencyStatsTracker {
+	return &latencyStatsTracker{
+		partitions: make(map[int32]*PartitionLatencyStats),
+	}
+}
+
+func (lst *latencyStatsTracker) partition(partition int32) *PartitionLatencyStats {
+	stats, ok := lst.partitions[partition]
+	if !ok {
+		stats = &PartitionLatencyStats{Partition: partition}
+		lst.partitions[partition] = stats
+	}
+	return stats
+}
+
+// ObserveProduced adds a produced latency sample for the partition
+func (lst *latencyStatsTracker) ObserveProduced(partition int32, latency float64) {
+	lst.mutex.Lock()
+	defer lst.mutex.Unlock()
+	lst.partition(partition).Produced.observe(latency)
+}
+
+// ObserveEndToEnd adds an end-to-end latency sample for the partition
+func (lst *latencyStatsTracker) ObserveEndToEnd(partition int32, latency float64) {
+	lst.mutex.Lock()
+	defer lst.mutex.Unlock()
+	lst.partition(partition).EndToEnd.observe(latency)
+}
+
+// Snapshot returns a copy of the current latency statistics sorted by partition
+func (lst *latencyStatsTracker) Snapshot() []PartitionLatencyStats {
+	lst.mutex.Lock()
+	defer lst.mutex.Unlock()
+	snapshot := make([]PartitionLatencyStats, 0, len(lst.partitions))
+	for _, stats := range lst.partitions {
+		snapshot = append(snapshot, *stats)
+	}
+	sort.Slice(snapshot, func(i, j int) bool {
+		return snapshot[i].Partition < snapshot[j].Partition
+	})
+	return snapshot
+}
+
+// ErrorDetails defines details about an error happened in the canary
+type ErrorDetails struct {
+	// timestamp in milliseconds
+	Timestamp int64
+	Source    string
+	Message   string
+}
+
+type errorTracker struct {
+	mutex sync.Mutex
+	last  *ErrorDetails
+}
+
+// Record saves the provided error as the last one happened
+func (et *errorTracker) Record(source string, err error) {
+	et.mutex.Lock()
+	defer et.mutex.Unlock()
+	et.last = &ErrorDetails{
+		Timestamp: util.NowInMilliseconds(),
+		Source:    source,
+		Message:   err.Error(),
+	}
+}
+
+// Restore sets the provided error details as the last one happened, if no error happened yet
+func (et *errorTracker) Restore(details *ErrorDetails) {
+	et.mutex.Lock()
+	defer et.mutex.Unlock()
+	if et.last == nil && details != nil {
+		last := *details
+		et.last = &last
+	}
+}
+
+// Last returns a copy of the last error details or nil if no error happened
+func (et *errorTracker) Last() *ErrorDetails {
+	et.mutex.Lock()
+	defer et.mutex.Unlock()
+	if et.last == nil {
+		return nil
+	}
+	last := *et.last
+	return &last
+}
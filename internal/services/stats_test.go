@@ -0,0 +1,50 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// +build unit_test
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLatencyStats(t *testing.T) {
+	lst := newLatencyStatsTracker()
+	lst.ObserveProduced(1, 20)
+	lst.ObserveProduced(1, 10)
+	lst.ObserveProduced(1, 30)
+	lst.ObserveEndToEnd(0, 50)
+
+	snapshot := lst.Snapshot()
+	if len(snapshot) != 2 {
+		t.Fatalf("got = %d, want = %d", len(snapshot), 2)
+	}
+	if snapshot[0].Partition != 0 || snapshot[1].Partition != 1 {
+		t.Errorf("partitions not sorted, got = %d, %d", snapshot[0].Partition, snapshot[1].Partition)
+	}
+	want := LatencyStats{Count: 3, Min: 10, Max: 30, Avg: 20, Last: 30}
+	if snapshot[1].Produced != want {
+		t.Errorf("got = %+v, want = %+v", snapshot[1].Produced, want)
+	}
+	if snapshot[1].EndToEnd.Count != 0 {
+		t.Errorf("got = %d, want = %d", snapshot[1].EndToEnd.Count, 0)
+	}
+}
+
+func TestLastError(t *testing.T) {
+	et := errorTracker{}
+	if et.Last() != nil {
+		t.Errorf("got = %+v, want = nil", et.Last())
+	}
+	et.Record(ProducerErrorSource, errors.New("first"))
+	et.Record(ConsumerErrorSource, errors.New("second"))
+	last := et.Last()
+	if last.Source != ConsumerErrorSource || last.Message != "second" {
+		t.Errorf("got = %+v, want = {Source:%s Message:second}", last, ConsumerErrorSource)
+	}
+}
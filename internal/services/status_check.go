@@ -7,20 +7,25 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"math"
 	"net/http"
-	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/golang/glog"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/strimzi/strimzi-canary/internal/config"
 	"github.com/strimzi/strimzi-canary/internal/util"
 )
 
 // Status defines useful status related information
 type Status struct {
-	Consuming ConsumingStatus
+	Consuming       ConsumingStatus
+	MetadataRefresh MetadataRefreshStatus
+	SLO             SLOStatus
+	BrokerRoll      BrokerRollStatus
 }
 
 // ConsumingStatus defines consuming related status information
@@ -33,83 +38,136 @@ type StatusService struct {
 	canaryConfig           *config.CanaryConfig
 	producedRecordsSamples util.TimeWindowRing
 	consumedRecordsSamples util.TimeWindowRing
-	stop                   chan struct{}
-	syncStop               sync.WaitGroup
+	slo                    *sloTracker
+	trackers               *Trackers
+	// bases for the records counters samples, restored from the persisted status history
+	producedBase uint64
+	consumedBase uint64
 }
 
 // NewStatusService returns an instance of StatusService
-func NewStatusServiceService(canaryConfig *config.CanaryConfig) *StatusService {
+func NewStatusServiceService(canaryConfig *config.CanaryConfig, trackers *Trackers) *StatusService {
 	ss := StatusService{
 		canaryConfig:           canaryConfig,
 		producedRecordsSamples: *util.NewTimeWindowRing(canaryConfig.StatusTimeWindow, canaryConfig.StatusCheckInterval),
 		consumedRecordsSamples: *util.NewTimeWindowRing(canaryConfig.StatusTimeWindow, canaryConfig.StatusCheckInterval),
+		slo:                    newSLOTracker(canaryConfig),
+		trackers:               trackers,
 	}
+	canaryEvents.Init(canaryConfig.EventsBufferSize)
 	return &ss
 }
 
-// Open starts the status check loop
+// Open restores the status history, if configured, the status checks are then run by the checks scheduler
 func (ss *StatusService) Open() {
-	ss.stop = make(chan struct{})
-	ss.syncStop.Add(1)
-
-	ticker := time.NewTicker(ss.canaryConfig.StatusCheckInterval * time.Millisecond)
-	go func() {
-		for {
-			select {
-			case <-ticker.C:
-				ss.statusCheck()
-			case <-ss.stop:
-				ticker.Stop()
-				defer ss.syncStop.Done()
-				glog.Infof("Stopping status check loop")
-				return
-			}
+	if ss.canaryConfig.StatusHistoryFile != "" {
+		if err := ss.loadHistory(); err != nil {
+			glog.Warningf("Error restoring the status history from %s: %v", ss.canaryConfig.StatusHistoryFile, err)
 		}
-	}()
+	}
+}
+
+// statusLoopCheck runs one of the status loops as a check run by the checks scheduler
+type statusLoopCheck struct {
+	name     string
+	interval time.Duration
+	run      func()
+}
+
+func (c *statusLoopCheck) Name() string {
+	return c.name
+}
+
+func (c *statusLoopCheck) Interval() time.Duration {
+	return c.interval
+}
+
+func (c *statusLoopCheck) Run(ctx context.Context) {
+	c.run()
+}
+
+func (c *statusLoopCheck) Metrics() []prometheus.Collector {
+	return nil
 }
 
-// Close stops the status check loop
-func (ss *StatusService) Close() {
-	glog.Infof("Closing status check service")
+func (c *statusLoopCheck) Close() {}
 
-	// ask to stop the ticker reconcile loop and wait
-	close(ss.stop)
-	ss.syncStop.Wait()
+// RunOnStartup returns false, the samples are taken on the interval only
+func (c *statusLoopCheck) RunOnStartup() bool {
+	return false
+}
 
-	glog.Infof("Status check service closed")
+// Checks returns the status check, filling the records samples, and the SLO sampling
+func (ss *StatusService) Checks() []Check {
+	return []Check{
+		&statusLoopCheck{name: StatusCheckLoop, interval: ss.canaryConfig.StatusCheckInterval * time.Millisecond, run: ss.statusCheck},
+		&statusLoopCheck{name: SLOSamplingLoop, interval: ss.slo.sampling * time.Millisecond, run: ss.slo.Sample},
+	}
 }
 
 // statusCheck does a check of produced and consumed records to fill the time window ring buffers
 func (ss *StatusService) statusCheck() {
-	ss.producedRecordsSamples.Put(RecordsProducedCounter)
-	ss.consumedRecordsSamples.Put(RecordsConsumedCounter)
+	ss.producedRecordsSamples.Put(ss.producedBase + atomic.LoadUint64(&RecordsProducedCounter))
+	ss.consumedRecordsSamples.Put(ss.consumedBase + atomic.LoadUint64(&RecordsConsumedCounter))
 	glog.V(1).Infof("Status check: produced [head = %d, tail = %d, count = %d], consumed [head = %d, tail = %d, count = %d]",
 		ss.producedRecordsSamples.Head(), ss.producedRecordsSamples.Tail(), ss.producedRecordsSamples.Count(),
 		ss.consumedRecordsSamples.Head(), ss.consumedRecordsSamples.Tail(), ss.consumedRecordsSamples.Count())
+	now := util.NowInMilliseconds()
+	ss.trackers.successRatio.Sample(now)
+	// updating the brokers safe to roll metrics
+	ss.safeToRollStatus(now)
+	if ss.canaryConfig.StatusHistoryFile != "" {
+		if err := ss.saveHistory(); err != nil {
+			glog.Warningf("Error persisting the status history to %s: %v", ss.canaryConfig.StatusHistoryFile, err)
+		}
+	}
 }
 
 func (ss *StatusService) StatusHandler() http.Handler {
 	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
-		status := Status{}
-
-		// update consuming related status section
-		status.Consuming = ConsumingStatus{
-			TimeWindow: ss.canaryConfig.StatusCheckInterval * time.Duration(ss.consumedRecordsSamples.Count()),
-		}
-		consumedPercentage, err := ss.consumedPercentage()
-		if e, ok := err.(*util.ErrNoDataSamples); ok {
-			status.Consuming.Percentage = -1
-			glog.Errorf("Error processing consumed records percentage: %v", e)
-		} else {
-			status.Consuming.Percentage = consumedPercentage
-		}
-
-		json, _ := json.Marshal(status)
+		json, _ := json.Marshal(ss.Status())
 		rw.Header().Add("Content-Type", "application/json")
 		rw.Write(json)
 	})
 }
 
+// Status returns the current status information
+func (ss *StatusService) Status() Status {
+	status := Status{}
+
+	// update consuming related status section
+	status.Consuming = ConsumingStatus{
+		TimeWindow: ss.canaryConfig.StatusCheckInterval * time.Duration(ss.consumedRecordsSamples.Count()),
+	}
+	consumedPercentage, err := ss.consumedPercentage()
+	if e, ok := err.(*util.ErrNoDataSamples); ok {
+		status.Consuming.Percentage = -1
+		glog.Errorf("Error processing consumed records percentage: %v", e)
+	} else {
+		status.Consuming.Percentage = consumedPercentage
+	}
+
+	// update producer metadata refresh related status section
+	status.MetadataRefresh = metadataRefresh.Status()
+
+	// update SLO related status section
+	status.SLO = ss.slo.Status()
+
+	// update brokers rolling restart related status section
+	status.BrokerRoll = ss.trackers.brokerRoll.Status(util.NowInMilliseconds())
+	return status
+}
+
+// PartitionsLatencyStats returns the produced and end-to-end latency statistics for each partition
+func (ss *StatusService) PartitionsLatencyStats() []PartitionLatencyStats {
+	return partitionsLatencyStats.Snapshot()
+}
+
+// LastError returns the details about the last error happened or nil if none
+func (ss *StatusService) LastError() *ErrorDetails {
+	return lastError.Last()
+}
+
 // consumedPercentage function processes the percentage of consumed messages in the specified time window
 func (ss *StatusService) consumedPercentage() (float64, error) {
 	// sampling for produced (and consumed records) not done yet
@@ -0,0 +1,101 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package services defines some canary related services
+package services
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/strimzi/strimzi-canary/internal/util"
+)
+
+// statusHistory defines the status rolling window persisted across restarts
+type statusHistory struct {
+	// timestamp in milliseconds of the last persist
+	Timestamp int64
+	// produced and consumed records counters sampled in the status time window, from the oldest
+	ProducedSamples []uint64
+	ConsumedSamples []uint64
+	LastError       *ErrorDetails `json:",omitempty"`
+}
+
+// saveHistory persists the status rolling window to the configured file
+//
+// The file is written through a temporary one and then renamed, so that it's never left partially written
+func (ss *StatusService) saveHistory() error {
+	history := statusHistory{
+		Timestamp:       util.NowInMilliseconds(),
+		ProducedSamples: ss.producedRecordsSamples.Values(),
+		ConsumedSamples: ss.consumedRecordsSamples.Values(),
+		LastError:       lastError.Last(),
+	}
+	data, err := json.Marshal(history)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(ss.canaryConfig.StatusHistoryFile), ".status-history-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), ss.canaryConfig.StatusHistoryFile)
+}
+
+// loadHistory restores the status rolling window from the configured file, if any
+//
+// The history is discarded when older than the status time window, because the canary was down for all of it
+func (ss *StatusService) loadHistory() error {
+	data, err := ioutil.ReadFile(ss.canaryConfig.StatusHistoryFile)
+	if os.IsNotExist(err) {
+		glog.Infof("No status history to restore from %s", ss.canaryConfig.StatusHistoryFile)
+		return nil
+	} else if err != nil {
+		return err
+	}
+	var history statusHistory
+	if err := json.Unmarshal(data, &history); err != nil {
+		return err
+	}
+	age := time.Duration(util.NowInMilliseconds()-history.Timestamp) * time.Millisecond
+	if age > ss.canaryConfig.StatusTimeWindow*time.Millisecond {
+		glog.Infof("Discarding the status history from %s, older than the status time window", ss.canaryConfig.StatusHistoryFile)
+		return nil
+	}
+	ss.restoreHistory(&history)
+	glog.Infof("Status history restored from %s with %d samples", ss.canaryConfig.StatusHistoryFile, len(history.ProducedSamples))
+	return nil
+}
+
+// restoreHistory fills the time window rings with the persisted samples
+//
+// The records counters restart from 0, so the last persisted samples are used as base for the new ones
+func (ss *StatusService) restoreHistory(history *statusHistory) {
+	for _, v := range history.ProducedSamples {
+		ss.producedRecordsSamples.Put(v)
+	}
+	for _, v := range history.ConsumedSamples {
+		ss.consumedRecordsSamples.Put(v)
+	}
+	if n := len(history.ProducedSamples); n > 0 {
+		ss.producedBase = history.ProducedSamples[n-1]
+	}
+	if n := len(history.ConsumedSamples); n > 0 {
+		ss.consumedBase = history.ConsumedSamples[n-1]
+	}
+	lastError.Restore(history.LastError)
+}
@@ -0,0 +1,94 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// +build unit_test
+
+// Package services defines some canary related services
+package services
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/strimzi/strimzi-canary/internal/config"
+	"github.com/strimzi/strimzi-canary/internal/util"
+)
+
+func newTestStatusService(canaryConfig *config.CanaryConfig) *StatusService {
+	return &StatusService{
+		canaryConfig:           canaryConfig,
+		producedRecordsSamples: *util.NewTimeWindowRing(canaryConfig.StatusTimeWindow, canaryConfig.StatusCheckInterval),
+		consumedRecordsSamples: *util.NewTimeWindowRing(canaryConfig.StatusTimeWindow, canaryConfig.StatusCheckInterval),
+	}
+}
+
+func TestStatusHistoryRestored(t *testing.T) {
+	dir, err := ioutil.TempDir("", "status-history")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	defer os.RemoveAll(dir)
+	canaryConfig := &config.CanaryConfig{
+		StatusCheckInterval: 1000,
+		StatusTimeWindow:    10000,
+		StatusHistoryFile:   filepath.Join(dir, "status.json"),
+	}
+
+	ss := newTestStatusService(canaryConfig)
+	for _, v := range []uint64{0, 10, 20} {
+		ss.producedRecordsSamples.Put(v)
+	}
+	for _, v := range []uint64{0, 8, 15} {
+		ss.consumedRecordsSamples.Put(v)
+	}
+	if err := ss.saveHistory(); err != nil {
+		t.Fatalf("unexpected error saving the status history %v", err)
+	}
+
+	restored := newTestStatusService(canaryConfig)
+	if err := restored.loadHistory(); err != nil {
+		t.Fatalf("unexpected error loading the status history %v", err)
+	}
+	if percentage, err := restored.consumedPercentage(); err != nil || percentage != 75 {
+		t.Errorf("Consumed percentage: got = %f (error %v), want = 75", percentage, err)
+	}
+	// the new samples continue from the restored ones
+	if restored.producedBase != 20 || restored.consumedBase != 15 {
+		t.Errorf("Bases: got = %d/%d, want = 20/15", restored.producedBase, restored.consumedBase)
+	}
+}
+
+func TestStatusHistoryStaleDiscarded(t *testing.T) {
+	dir, err := ioutil.TempDir("", "status-history")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	defer os.RemoveAll(dir)
+	canaryConfig := &config.CanaryConfig{
+		StatusCheckInterval: 1000,
+		StatusTimeWindow:    10000,
+		StatusHistoryFile:   filepath.Join(dir, "status.json"),
+	}
+	history := statusHistory{
+		Timestamp:       util.NowInMilliseconds() - 20000,
+		ProducedSamples: []uint64{0, 10},
+		ConsumedSamples: []uint64{0, 10},
+	}
+	data, _ := json.Marshal(history)
+	if err := ioutil.WriteFile(canaryConfig.StatusHistoryFile, data, 0644); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	ss := newTestStatusService(canaryConfig)
+	if err := ss.loadHistory(); err != nil {
+		t.Fatalf("unexpected error loading the status history %v", err)
+	}
+	if !ss.producedRecordsSamples.IsEmpty() {
+		t.Errorf("Stale status history restored")
+	}
+}
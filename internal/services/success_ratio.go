@@ -0,0 +1,147 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/strimzi/strimzi-canary/internal/config"
+)
+
+var (
+	produceSuccessRatio = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name:      "produce_success_ratio",
+		Namespace: "strimzi_canary",
+		Help:      "Ratio of the records successfully produced over the sliding window, from 0 to 1",
+	}, []string{"clientid", "window"})
+
+	roundTripSuccessRatio = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name:      "round_trip_success_ratio",
+		Namespace: "strimzi_canary",
+		Help:      "Ratio of the produced records which were consumed over the sliding window, from 0 to 1",
+	}, []string{"clientid", "window"})
+)
+
+// recordsSample is a sample of the records counters at a point in time (in ms)
+type recordsSample struct {
+	timestamp      int64
+	produced       uint64
+	producedFailed uint64
+	consumed       uint64
+}
+
+// successRatioTracker tracks the records counters samples over the configured sliding windows, exporting the
+// produce and round trip success ratios as gauges. While a window is not covered yet (i.e. the canary just started),
+// the ratios are over the time since the first sample, so that even short-lived canaries report meaningful values
+type successRatioTracker struct {
+	clientID string
+	// windows in ms
+	windows   []int64
+	maxWindow int64
+	// samples covering the longest window, from the oldest
+	samples []recordsSample
+}
+
+func newSuccessRatioTracker(canaryConfig *config.CanaryConfig, now int64) *successRatioTracker {
+	t := successRatioTracker{
+		clientID: canaryConfig.ClientID,
+		windows:  make([]int64, 0, len(canaryConfig.SuccessRatioWindows)),
+	}
+	for _, window := range canaryConfig.SuccessRatioWindows {
+		t.windows = append(t.windows, int64(window))
+		if int64(window) > t.maxWindow {
+			t.maxWindow = int64(window)
+		}
+	}
+	// initial sample as baseline for the ratios
+	t.add(currentRecordsSample(now))
+	return &t
+}
+
+func currentRecordsSample(now int64) recordsSample {
+	return recordsSample{
+		timestamp:      now,
+		produced:       atomic.LoadUint64(&RecordsProducedCounter),
+		producedFailed: atomic.LoadUint64(&recordsProducedFailedCounter),
+		consumed:       atomic.LoadUint64(&RecordsConsumedCounter),
+	}
+}
+
+// Sample adds the current records counters to the sliding windows and updates the success ratio gauges
+func (t *successRatioTracker) Sample(now int64) {
+	current := currentRecordsSample(now)
+	t.add(current)
+	for _, window := range t.windows {
+		labels := prometheus.Labels{
+			"clientid": t.clientID,
+			"window":   windowLabel(time.Duration(window) * time.Millisecond),
+		}
+		produceRatio, roundTripRatio, ok := successRatios(t.baseline(now-window), current)
+		// no records produced in the window, so nothing to report
+		if !ok {
+			produceSuccessRatio.Delete(labels)
+			roundTripSuccessRatio.Delete(labels)
+			continue
+		}
+		produceSuccessRatio.With(labels).Set(produceRatio)
+		roundTripSuccessRatio.With(labels).Set(roundTripRatio)
+	}
+}
+
+// add appends the sample, dropping the ones older than the longest window
+func (t *successRatioTracker) add(sample recordsSample) {
+	t.samples = append(t.samples, sample)
+	i := 0
+	for i < len(t.samples)-1 && t.samples[i].timestamp < sample.timestamp-t.maxWindow {
+		i++
+	}
+	t.samples = t.samples[i:]
+}
+
+// baseline returns the oldest sample taken since the provided timestamp (in ms)
+func (t *successRatioTracker) baseline(since int64) recordsSample {
+	for _, s := range t.samples {
+		if s.timestamp >= since {
+			return s
+		}
+	}
+	return t.samples[len(t.samples)-1]
+}
+
+// successRatios returns the produce and round trip success ratios between the baseline and the current sample,
+// false if no records were produced in between
+func successRatios(baseline recordsSample, current recordsSample) (float64, float64, bool) {
+	produced := current.produced - baseline.produced
+	if produced == 0 {
+		return 0, 0, false
+	}
+	failed := current.producedFailed - baseline.producedFailed
+	consumed := current.consumed - baseline.consumed
+	// records produced before the window could be consumed within it
+	if consumed > produced {
+		consumed = produced
+	}
+	return float64(produced-failed) / float64(produced), float64(consumed) / float64(produced), true
+}
+
+// windowLabel returns the window as a label value in the Prometheus duration format (i.e. 5m, 1h)
+func windowLabel(window time.Duration) string {
+	switch {
+	case window%time.Hour == 0:
+		return fmt.Sprintf("%dh", window/time.Hour)
+	case window%time.Minute == 0:
+		return fmt.Sprintf("%dm", window/time.Minute)
+	case window%time.Second == 0:
+		return fmt.Sprintf("%ds", window/time.Second)
+	}
+	return fmt.Sprintf("%dms", window/time.Millisecond)
+}
@@ -0,0 +1,80 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// +build unit_test
+
+// Package services defines some canary related services
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSuccessRatios(t *testing.T) {
+	tests := []struct {
+		name              string
+		baseline          recordsSample
+		current           recordsSample
+		expectedProduce   float64
+		expectedRoundTrip float64
+		expectedOk        bool
+	}{
+		{"no records produced", recordsSample{produced: 10, consumed: 10}, recordsSample{produced: 10, consumed: 10}, 0, 0, false},
+		{"all good", recordsSample{produced: 10, consumed: 10}, recordsSample{produced: 110, consumed: 110}, 1, 1, true},
+		{"produce failures", recordsSample{}, recordsSample{produced: 100, producedFailed: 10, consumed: 90}, 0.9, 0.9, true},
+		{"records not consumed", recordsSample{}, recordsSample{produced: 100, consumed: 75}, 1, 0.75, true},
+		{"consumed more than produced", recordsSample{produced: 10}, recordsSample{produced: 20, consumed: 20}, 1, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			produce, roundTrip, ok := successRatios(tt.baseline, tt.current)
+			if produce != tt.expectedProduce || roundTrip != tt.expectedRoundTrip || ok != tt.expectedOk {
+				t.Errorf("got = (%f, %f, %t), want = (%f, %f, %t)", produce, roundTrip, ok, tt.expectedProduce, tt.expectedRoundTrip, tt.expectedOk)
+			}
+		})
+	}
+}
+
+func TestSuccessRatioTrackerWindows(t *testing.T) {
+	tracker := successRatioTracker{windows: []int64{1000, 5000}, maxWindow: 5000}
+	for i := int64(0); i <= 10; i++ {
+		tracker.add(recordsSample{timestamp: i * 1000, produced: uint64(i * 10)})
+	}
+
+	// samples older than the longest window are dropped
+	if oldest := tracker.samples[0].timestamp; oldest != 5000 {
+		t.Errorf("oldest sample = %d, want = %d", oldest, 5000)
+	}
+	if baseline := tracker.baseline(10000 - 1000); baseline.timestamp != 9000 {
+		t.Errorf("short window baseline = %d, want = %d", baseline.timestamp, 9000)
+	}
+	if baseline := tracker.baseline(10000 - 5000); baseline.timestamp != 5000 {
+		t.Errorf("long window baseline = %d, want = %d", baseline.timestamp, 5000)
+	}
+	// window not covered yet, the ratios are since the oldest sample
+	if baseline := tracker.baseline(10000 - 60000); baseline.timestamp != 5000 {
+		t.Errorf("not covered window baseline = %d, want = %d", baseline.timestamp, 5000)
+	}
+}
+
+func TestWindowLabel(t *testing.T) {
+	tests := []struct {
+		window   time.Duration
+		expected string
+	}{
+		{time.Hour, "1h"},
+		{5 * time.Minute, "5m"},
+		{90 * time.Second, "90s"},
+		{1500 * time.Millisecond, "1500ms"},
+	}
+
+	for _, tt := range tests {
+		if label := windowLabel(tt.window); label != tt.expected {
+			t.Errorf("got = %s, want = %s", label, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package services defines some canary related services
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/strimzi/strimzi-canary/internal/clients"
+	"github.com/strimzi/strimzi-canary/internal/config"
+	"github.com/strimzi/strimzi-canary/internal/util"
+)
+
+const (
+	// tiered storage fetch results
+	TieredStorageFetchSuccess = "success"
+	TieredStorageFetchSkipped = "skipped"
+	TieredStorageFetchError   = "error"
+
+	// timeout for fetching a record from the remote tier, slower than the local one
+	tieredStorageFetchTimeout = 30 * time.Second
+	// topic configuration enabling the tiered storage (KIP-405)
+	remoteStorageEnableConfig = "remote.storage.enable"
+)
+
+var (
+	// it's defined when the service is created because buckets are configurable
+	tieredStorageFetchLatency *prometheus.HistogramVec
+
+	tieredStorageFetches = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name:      "tiered_storage_fetch_total",
+		Namespace: "strimzi_canary",
+		Help:      "Total number of fetches of canary records old enough for being offloaded to the remote tier, by result",
+	}, []string{"clientid", "result"})
+)
+
+// TieredStorageCheckService defines the service periodically fetching, from each canary topic partition, the record
+// produced the configured age ago, which is expected to be offloaded to the remote tier, and measuring the fetch latency
+type TieredStorageCheckService struct {
+	canaryConfig  *config.CanaryConfig
+	clientFactory clients.Factory
+	admin         clients.Admin
+	fetcher       clients.Fetcher
+}
+
+func init() {
+	RegisterCheck(TieredStorageCheckLoop, newTieredStorageCheck)
+}
+
+// newTieredStorageCheck returns the tiered storage check, nil when it's not enabled
+func newTieredStorageCheck(canaryConfig *config.CanaryConfig, clientFactory clients.Factory) Check {
+	if canaryConfig.TieredStorageCheckInterval == 0 {
+		return nil
+	}
+	return NewTieredStorageCheckService(canaryConfig, clientFactory)
+}
+
+// NewTieredStorageCheckService returns an instance of TieredStorageCheckService
+func NewTieredStorageCheckService(canaryConfig *config.CanaryConfig, clientFactory clients.Factory) *TieredStorageCheckService {
+	tieredStorageFetchLatency = prometheus.NewHistogramVec(latencyHistogramOpts(canaryConfig, prometheus.HistogramOpts{
+		Name:      "tiered_storage_fetch_latency",
+		Namespace: "strimzi_canary",
+		Help:      "Latency in milliseconds for fetching the canary records offloaded to the remote tier",
+		Buckets:   canaryConfig.TieredStorageLatencyBuckets,
+	}), []string{"clientid"})
+
+	if canaryConfig.TopicConfig[remoteStorageEnableConfig] != "true" {
+		glog.Warningf("Tiered storage check enabled but the canary topic configuration doesn't set %s=true, "+
+			"it has to be enabled on the topic for having the records offloaded", remoteStorageEnableConfig)
+	}
+	return &TieredStorageCheckService{
+		canaryConfig:  canaryConfig,
+		clientFactory: clientFactory,
+	}
+}
+
+// Name returns the tiered storage check name
+func (tscs *TieredStorageCheckService) Name() string {
+	return TieredStorageCheckLoop
+}
+
+// Interval returns the interval between the tiered storage checks
+func (tscs *TieredStorageCheckService) Interval() time.Duration {
+	return tscs.canaryConfig.TieredStorageCheckInterval * time.Millisecond
+}
+
+// Run runs the tiered storage check
+func (tscs *TieredStorageCheckService) Run(ctx context.Context) {
+	tscs.tieredStorageCheck(ctx)
+}
+
+// Metrics returns the tiered storage check metrics
+func (tscs *TieredStorageCheckService) Metrics() []prometheus.Collector {
+	return []prometheus.Collector{tieredStorageFetchLatency, tieredStorageFetches}
+}
+
+// Close closes the underneath Kafka admin and fetcher instances
+func (tscs *TieredStorageCheckService) Close() {
+	glog.Infof("Closing tiered storage check service")
+
+	if tscs.admin != nil {
+		if err := tscs.admin.Close(); err != nil {
+			glog.Errorf("Error closing the Kafka admin: %v", err)
+		}
+		tscs.admin = nil
+	}
+	if tscs.fetcher != nil {
+		if err := tscs.fetcher.Close(); err != nil {
+			glog.Errorf("Error closing the Kafka fetcher: %v", err)
+		}
+		tscs.fetcher = nil
+	}
+	glog.Infof("Tiered storage check service closed")
+}
+
+// tieredStorageCheck fetches the offloaded record from each canary topic partition
+func (tscs *TieredStorageCheckService) tieredStorageCheck(ctx context.Context) {
+	partitions, err := tscs.partitions()
+	if err != nil {
+		glog.Errorf("Error describing the canary topic for the tiered storage check: %v", err)
+		tieredStorageFetches.With(prometheus.Labels{"clientid": tscs.canaryConfig.ClientID, "result": TieredStorageFetchError}).Inc()
+		if clients.IsFatal(err) && tscs.admin != nil {
+			tscs.admin.Close()
+			tscs.admin = nil
+			recordClientRecreation(AdminBootstrapClient)
+		}
+		return
+	}
+	for _, partition := range partitions {
+		result := tscs.check(ctx, partition)
+		labels := prometheus.Labels{
+			"clientid": tscs.canaryConfig.ClientID,
+			"result":   result,
+		}
+		tieredStorageFetches.With(labels).Inc()
+	}
+}
+
+// partitions returns the canary topic partitions
+func (tscs *TieredStorageCheckService) partitions() ([]int32, error) {
+	if tscs.admin == nil {
+		admin, err := tscs.clientFactory.NewAdmin(tscs.canaryConfig.BootstrapServers)
+		if err != nil {
+			return nil, err
+		}
+		tscs.admin = &instrumentedAdmin{admin: admin}
+	}
+	metadata, err := tscs.admin.DescribeTopic(tscs.canaryConfig.Topic)
+	if err != nil {
+		return nil, err
+	}
+	if metadata.Err != nil {
+		return nil, metadata.Err
+	}
+	partitions := make([]int32, 0, len(metadata.Partitions))
+	for _, p := range metadata.Partitions {
+		partitions = append(partitions, p.ID)
+	}
+	return partitions, nil
+}
+
+// check fetches the record produced the configured age ago from the partition and returns the result
+func (tscs *TieredStorageCheckService) check(ctx context.Context, partition int32) string {
+	if tscs.fetcher == nil {
+		fetcher, err := tscs.clientFactory.NewFetcher(tscs.canaryConfig.BootstrapServers)
+		if err != nil {
+			glog.Errorf("Error creating the Kafka fetcher: %v", err)
+			return TieredStorageFetchError
+		}
+		tscs.fetcher = fetcher
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, tieredStorageFetchTimeout)
+	defer cancel()
+	now := util.NowInMilliseconds()
+	cutoff := now - int64(tscs.canaryConfig.TieredStorageCheckAge)
+	offset, err := tscs.fetcher.OffsetForTimestamp(ctx, tscs.canaryConfig.Topic, partition, cutoff)
+	if err != nil {
+		tscs.fetchError(partition, err)
+		return TieredStorageFetchError
+	}
+	if offset < 0 {
+		glog.Warningf("Tiered storage check skipped on partition %d, no records produced since %d", partition, cutoff)
+		return TieredStorageFetchSkipped
+	}
+
+	start := time.Now()
+	record, err := tscs.fetcher.Fetch(ctx, tscs.canaryConfig.Topic, partition, offset)
+	latency := time.Since(start).Milliseconds()
+	if err != nil {
+		tscs.fetchError(partition, err)
+		return TieredStorageFetchError
+	}
+	if !isOffloadedRecord(record.Timestamp.UnixNano()/int64(time.Millisecond), cutoff, int64(tscs.canaryConfig.TieredStorageCheckInterval)) {
+		glog.V(1).Infof("Tiered storage check skipped on partition %d, the oldest record at offset %d is not old enough", partition, offset)
+		return TieredStorageFetchSkipped
+	}
+	glog.V(1).Infof("Tiered storage record fetched: partition=%d, offset=%d, latency=%d ms", partition, offset, latency)
+	tieredStorageFetchLatency.With(prometheus.Labels{"clientid": tscs.canaryConfig.ClientID}).Observe(float64(latency))
+	return TieredStorageFetchSuccess
+}
+
+// fetchError reports the error fetching from the partition and recreates the fetcher if unrecoverable
+func (tscs *TieredStorageCheckService) fetchError(partition int32, err error) {
+	glog.Errorf("Error fetching the offloaded record on partition %d: %v", partition, err)
+	recordAuthFailure(tscs.canaryConfig.ClientID, ConsumerBootstrapClient, err)
+	if clients.IsFatal(err) {
+		tscs.fetcher.Close()
+		tscs.fetcher = nil
+		recordClientRecreation(ConsumerBootstrapClient)
+	}
+}
+
+// isOffloadedRecord returns true if the record, the first one produced after the cutoff, was produced within the
+// tolerance after it, otherwise the canary topic has no records as old as the cutoff (i.e. the topic is younger or the
+// canary wasn't producing at that time) and the record found isn't expected to be offloaded yet
+func isOffloadedRecord(timestamp int64, cutoff int64, tolerance int64) bool {
+	return timestamp-cutoff <= tolerance
+}
@@ -0,0 +1,34 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// +build unit_test
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"testing"
+)
+
+func TestIsOffloadedRecord(t *testing.T) {
+	tests := []struct {
+		name      string
+		timestamp int64
+		cutoff    int64
+		tolerance int64
+		want      bool
+	}{
+		{"produced at the cutoff", 100000, 100000, 60000, true},
+		{"produced within the tolerance", 150000, 100000, 60000, true},
+		{"produced after the tolerance", 170000, 100000, 60000, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isOffloadedRecord(tt.timestamp, tt.cutoff, tt.tolerance); got != tt.want {
+				t.Errorf("isOffloadedRecord got = %t, want = %t", got, tt.want)
+			}
+		})
+	}
+}
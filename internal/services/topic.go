@@ -7,14 +7,15 @@
 package services
 
 import (
+	"fmt"
 	"sort"
 	"strconv"
 	"time"
 
-	"github.com/Shopify/sarama"
 	"github.com/golang/glog"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/strimzi/strimzi-canary/internal/clients"
 	"github.com/strimzi/strimzi-canary/internal/config"
 	"github.com/strimzi/strimzi-canary/internal/util"
 )
@@ -25,14 +26,23 @@ type TopicReconcileResult struct {
 	Assignments map[int32][]int32
 	// if a refresh metadata is needed
 	RefreshMetadata bool
+	// if the canary topic partitions were increased externally, so the consumer has to rejoin the group
+	PartitionsChanged bool
 }
 
 // TopicService defines the service for canary topic management
 type TopicService struct {
-	canaryConfig *config.CanaryConfig
-	saramaConfig *sarama.Config
-	admin        sarama.ClusterAdmin
-	initialized  bool
+	canaryConfig  *config.CanaryConfig
+	clientFactory clients.Factory
+	admin         clients.Admin
+	initialized   bool
+	// current leader for each canary topic partition, for detecting leader changes
+	leaders map[int32]int32
+	// canary topic partitions count seen on the last successful reconcile, for detecting external changes
+	partitions int
+	// re-resolving the bootstrap servers and rebuilding the admin on repeated failures
+	dnsReResolver *dnsReResolver
+	trackers      *Trackers
 }
 
 var (
@@ -67,6 +77,12 @@ var (
 		Namespace: "strimzi_canary",
 		Help:      "Total number of errors while altering configuration for the canary topic",
 	}, []string{"topic"})
+
+	partitionsDecreaseRejected = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name:      "topic_partitions_decrease_rejected_total",
+		Namespace: "strimzi_canary",
+		Help:      "Total number of reconciles rejected because the canary topic partitions count was decreased externally",
+	}, []string{"topic"})
 )
 
 // ErrExpectedClusterSize defines the error raised when the expected cluster size is not met
@@ -76,13 +92,34 @@ func (e *ErrExpectedClusterSize) Error() string {
 	return "Current cluster size differs from the expected size"
 }
 
+// ErrPartitionsDecreased defines the error raised when the canary topic partitions count was decreased externally
+type ErrPartitionsDecreased struct {
+	Previous int
+	Current  int
+}
+
+func (e *ErrPartitionsDecreased) Error() string {
+	return fmt.Sprintf("Canary topic partitions decreased from %d to %d", e.Previous, e.Current)
+}
+
 // NewTopicService returns an instance of TopicService
-func NewTopicService(canaryConfig *config.CanaryConfig, saramaConfig *sarama.Config) *TopicService {
-	// lazy creation of the Sarama cluster admin client when reconcile for the first time or it's closed
+func NewTopicService(canaryConfig *config.CanaryConfig, clientFactory clients.Factory, trackers *Trackers) *TopicService {
+	// registering the histogram just once, even if more topic services are created
+	if adminOperationLatency == nil {
+		adminOperationLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+			Name:      "admin_operation_latency",
+			Namespace: "strimzi_canary",
+			Help:      "Admin operations latency in milliseconds",
+			Buckets:   canaryConfig.AdminLatencyBuckets,
+		}, []string{"operation"})
+	}
+	// lazy creation of the Kafka admin client when reconcile for the first time or it's closed
 	ts := TopicService{
-		canaryConfig: canaryConfig,
-		saramaConfig: saramaConfig,
-		admin:        nil,
+		canaryConfig:  canaryConfig,
+		clientFactory: clientFactory,
+		admin:         nil,
+		dnsReResolver: newDNSReResolver(AdminBootstrapClient, canaryConfig.DNSReResolutionThreshold),
+		trackers:      trackers,
 	}
 	return &ts
 }
@@ -102,38 +139,49 @@ func NewTopicService(canaryConfig *config.CanaryConfig, saramaConfig *sarama.Con
 // If a scale up, scale down, scale up happens, it forces a leader election for having preferred leaders
 func (ts *TopicService) Reconcile() (TopicReconcileResult, error) {
 	result, err := ts.reconcileTopic()
-	if err != nil && util.IsDisconnection(err) {
+	if err != nil {
+		lastError.Record(TopicErrorSource, err)
+	}
+	if err != nil && clients.IsFatal(err) && ts.admin != nil {
 		// Kafka brokers close connection to the topic service admin client not able to recover
 		// Sarama issues: https://github.com/Shopify/sarama/issues/2042, https://github.com/Shopify/sarama/issues/1796
 		// Workaround closing the topic service with its admin client and the reopen on next reconcile
 		ts.Close()
+		recordClientRecreation(AdminBootstrapClient)
 	}
 	return result, err
 }
 
 func (ts *TopicService) reconcileTopic() (TopicReconcileResult, error) {
-	result := TopicReconcileResult{nil, false}
+	result := TopicReconcileResult{}
 
 	if ts.admin == nil {
-		glog.Infof("Creating Sarama cluster admin")
-		admin, err := sarama.NewClusterAdmin(ts.canaryConfig.BootstrapServers, ts.saramaConfig)
+		glog.Infof("Creating Kafka admin")
+		admin, err := ts.clientFactory.NewAdmin(ts.canaryConfig.BootstrapServers)
 		if err != nil {
-			glog.Errorf("Error creating the Sarama cluster admin: %v", err)
+			glog.Errorf("Error creating the Kafka admin: %v", err)
 			return result, err
 		}
-		ts.admin = admin
+		ts.admin = &instrumentedAdmin{admin: admin}
 	}
 
 	// getting brokers for assigning canary topic replicas accordingly
 	// on creation or cluster scale up/down when topic already exists
-	brokers, _, err := ts.admin.DescribeCluster()
+	brokers, err := ts.admin.DescribeCluster()
 	if err != nil {
 		describeClusterError.With(nil).Inc()
 		glog.Errorf("Error describing cluster: %v", err)
+		if ts.dnsReResolver.Failure(ts.canaryConfig.BootstrapServers) {
+			// the admin is rebuilt on the next reconcile, connecting to the re-resolved addresses
+			ts.Close()
+			recordClientRecreation(AdminBootstrapClient)
+		}
 		return result, err
 	}
+	ts.dnsReResolver.Success()
+	ts.trackers.brokerRoll.Metadata(brokers, util.NowInMilliseconds())
 
-	metadata, err := ts.admin.DescribeTopics([]string{ts.canaryConfig.Topic})
+	topicMetadata, err := ts.admin.DescribeTopic(ts.canaryConfig.Topic)
 	if err != nil {
 		labels := prometheus.Labels{
 			"topic": ts.canaryConfig.Topic,
@@ -142,9 +190,8 @@ func (ts *TopicService) reconcileTopic() (TopicReconcileResult, error) {
 		glog.Errorf("Error retrieving metadata for topic %s: %v", ts.canaryConfig.Topic, err)
 		return result, err
 	}
-	topicMetadata := metadata[0]
 
-	if topicMetadata.Err == sarama.ErrUnknownTopicOrPartition {
+	if topicMetadata.Err == clients.ErrUnknownTopicOrPartition {
 
 		// canary topic doesn't exist, going to create it
 		glog.V(1).Infof("The canary topic %s doesn't exist", topicMetadata.Name)
@@ -166,10 +213,14 @@ func (ts *TopicService) reconcileTopic() (TopicReconcileResult, error) {
 			// not creating the topic and returning error to avoid starting producer/consumer
 			return result, &ErrExpectedClusterSize{}
 		}
-	} else if topicMetadata.Err == sarama.ErrNoError {
+	} else if topicMetadata.Err == nil {
 		// canary topic already exists
 		glog.V(1).Infof("The canary topic %s already exists", topicMetadata.Name)
 		logTopicMetadata(topicMetadata)
+		ts.trackLeaders(topicMetadata)
+		if err := ts.watchPartitions(topicMetadata, &result); err != nil {
+			return result, err
+		}
 
 		// topic exists so altering the configuration with the provided one (only at startup)
 		if !ts.initialized {
@@ -188,7 +239,7 @@ func (ts *TopicService) reconcileTopic() (TopicReconcileResult, error) {
 		if ts.isDynamicReassignmentEnabled() || (!ts.initialized && ts.canaryConfig.ExpectedClusterSize == len(brokers)) {
 
 			glog.Infof("Going to reassign topic partitions if needed")
-			result.RefreshMetadata = len(brokers) != len(topicMetadata.Partitions)
+			result.RefreshMetadata = result.RefreshMetadata || len(brokers) != len(topicMetadata.Partitions)
 			if result.Assignments, err = ts.alterTopicAssignments(len(topicMetadata.Partitions), brokers); err != nil {
 				labels := prometheus.Labels{
 					"topic": topicMetadata.Name,
@@ -202,6 +253,12 @@ func (ts *TopicService) reconcileTopic() (TopicReconcileResult, error) {
 		} else {
 			result.Assignments = ts.currentAssignments(topicMetadata)
 		}
+
+		if ts.canaryConfig.PerBrokerCheckEnabled {
+			if err := ts.ensureBrokersLeadership(brokers, topicMetadata, &result); err != nil {
+				return result, err
+			}
+		}
 	} else {
 		labels := prometheus.Labels{
 			"topic": ts.canaryConfig.Topic,
@@ -212,22 +269,138 @@ func (ts *TopicService) reconcileTopic() (TopicReconcileResult, error) {
 	}
 
 	ts.initialized = true
+	ts.partitions = len(result.Assignments)
 	return result, err
 }
 
-// Close closes the underneath Sarama admin instance
+// watchPartitions compares the canary topic partitions count with the one seen on the last reconcile,
+// detecting changes done externally to the canary (i.e. by an operator)
+//
+// An increase is accepted by asking for a metadata refresh and the consumer to rejoin the group,
+// while a decrease is rejected with an error until the previous partitions count is restored
+func (ts *TopicService) watchPartitions(metadata *clients.TopicMetadata, result *TopicReconcileResult) error {
+	switch partitionsChange(ts.partitions, len(metadata.Partitions)) {
+	case partitionsIncreased:
+		glog.Infof("The canary topic %s partitions increased from %d to %d", metadata.Name, ts.partitions, len(metadata.Partitions))
+		result.RefreshMetadata = true
+		result.PartitionsChanged = true
+	case partitionsDecreased:
+		labels := prometheus.Labels{
+			"topic": metadata.Name,
+		}
+		partitionsDecreaseRejected.With(labels).Inc()
+		err := &ErrPartitionsDecreased{Previous: ts.partitions, Current: len(metadata.Partitions)}
+		glog.Errorf("Rejecting the canary topic %s reconcile: %v", metadata.Name, err)
+		return err
+	}
+	return nil
+}
+
+const (
+	partitionsUnchanged = iota
+	partitionsIncreased
+	partitionsDecreased
+)
+
+// partitionsChange returns how the partitions count changed from the previous one, which is 0 if not known yet
+func partitionsChange(previous int, current int) int {
+	switch {
+	case previous == 0 || current == previous:
+		return partitionsUnchanged
+	case current > previous:
+		return partitionsIncreased
+	default:
+		return partitionsDecreased
+	}
+}
+
+// trackLeaders records an event for each canary topic partition whose leader changed since the last reconcile
+func (ts *TopicService) trackLeaders(metadata *clients.TopicMetadata) {
+	leaders := make(map[int32]int32, len(metadata.Partitions))
+	for _, p := range metadata.Partitions {
+		leaders[p.ID] = p.Leader
+		if previous, ok := ts.leaders[p.ID]; ok && previous != p.Leader {
+			glog.Infof("Leader for partition %d changed from broker %d to %d", p.ID, previous, p.Leader)
+			canaryEvents.Record(Event{Type: LeaderChangeEvent, Partition: p.ID, BrokerID: p.Leader})
+		}
+	}
+	ts.leaders = leaders
+	partitionLeaders.Update(leaders)
+}
+
+// ensureBrokersLeadership makes every broker leading at least one canary topic partition, for the per broker check
+//
+// If a broker isn't the preferred leader of any partition, the partitions are reassigned first,
+// then a preferred leader election is triggered for the partitions not led by their preferred leader
+func (ts *TopicService) ensureBrokersLeadership(brokers []clients.Broker, metadata *clients.TopicMetadata, result *TopicReconcileResult) error {
+	missing := brokersWithoutLeadership(brokers, metadata)
+	if len(missing) == 0 {
+		return nil
+	}
+	glog.Infof("Brokers %v are not leading any partition of the canary topic %s", missing, metadata.Name)
+
+	if notPreferred := brokersNotPreferred(brokers, result.Assignments); len(notPreferred) > 0 {
+		glog.Infof("Reassigning the canary topic %s partitions for having brokers %v as preferred leaders", metadata.Name, notPreferred)
+		assignments, err := ts.alterTopicAssignments(len(metadata.Partitions), brokers)
+		if err != nil {
+			labels := prometheus.Labels{
+				"topic": metadata.Name,
+			}
+			alterTopicAssignmentsError.With(labels).Inc()
+			glog.Errorf("Error reassigning partitions for topic %s: %v", metadata.Name, err)
+			return err
+		}
+		result.Assignments = assignments
+		result.RefreshMetadata = true
+		// partitions could be added when they are less than the brokers
+		result.PartitionsChanged = result.PartitionsChanged || len(assignments) > len(metadata.Partitions)
+	}
+
+	partitions := partitionsNotLedByPreferred(result.Assignments, metadata)
+	if len(partitions) == 0 {
+		return nil
+	}
+	// not failing the reconcile, the election is tried again on the next one
+	if err := ts.admin.ElectPreferredLeaders(metadata.Name, partitions); err != nil {
+		glog.Warningf("Error electing the preferred leaders for the canary topic %s partitions %v: %v", metadata.Name, partitions, err)
+		return nil
+	}
+	labels := prometheus.Labels{
+		"topic": metadata.Name,
+	}
+	brokerLeadershipElections.With(labels).Inc()
+	glog.Infof("Preferred leaders elected for the canary topic %s partitions %v", metadata.Name, partitions)
+	return nil
+}
+
+// Close closes the underneath Kafka admin instance
+//
+// A close error is just logged, because the admin is closed for being rebuilt when it's already failing as well
 func (ts *TopicService) Close() {
 	glog.Infof("Closing topic service")
 
 	if ts.admin != nil {
 		if err := ts.admin.Close(); err != nil {
-			glog.Fatalf("Error closing the Sarama cluster admin: %v", err)
+			glog.Errorf("Error closing the Kafka admin: %v", err)
 		}
 		ts.admin = nil
 	}
 	glog.Infof("Topic service closed")
 }
 
+// DeleteTopic deletes the canary topic
+func (ts *TopicService) DeleteTopic() error {
+	if ts.admin == nil {
+		admin, err := ts.clientFactory.NewAdmin(ts.canaryConfig.BootstrapServers)
+		if err != nil {
+			return err
+		}
+		ts.admin = &instrumentedAdmin{admin: admin}
+	}
+	glog.Infof("Deleting the canary topic %s", ts.canaryConfig.Topic)
+	return ts.admin.DeleteTopic(ts.canaryConfig.Topic)
+}
+
 func (ts *TopicService) alterTopicConfiguration() error {
 	topicConfig := make(map[string]*string, len(ts.canaryConfig.TopicConfig))
 	for index, param := range ts.canaryConfig.TopicConfig {
@@ -235,12 +408,12 @@ func (ts *TopicService) alterTopicConfiguration() error {
 		topicConfig[index] = &p
 	}
 	if len(topicConfig) != 0 {
-		return ts.admin.AlterConfig(sarama.TopicResource, ts.canaryConfig.Topic, topicConfig, false)
+		return ts.admin.AlterTopicConfig(ts.canaryConfig.Topic, topicConfig)
 	}
 	return nil
 }
 
-func (ts *TopicService) createTopic(brokers []*sarama.Broker) (map[int32][]int32, error) {
+func (ts *TopicService) createTopic(brokers []clients.Broker) (map[int32][]int32, error) {
 	assignments, minISR := ts.requestedAssignments(0, brokers)
 
 	v := strconv.Itoa(int(minISR))
@@ -253,17 +426,11 @@ func (ts *TopicService) createTopic(brokers []*sarama.Broker) (map[int32][]int32
 	// override cleanup policy because it needs to be "delete" (canary doesn't use keys on messages)
 	topicConfig["cleanup.policy"] = &cleanupPolicy
 
-	topicDetail := sarama.TopicDetail{
-		NumPartitions:     -1,
-		ReplicationFactor: -1,
-		ReplicaAssignment: assignments,
-		ConfigEntries:     topicConfig,
-	}
-	err := ts.admin.CreateTopic(ts.canaryConfig.Topic, &topicDetail, false)
+	err := ts.admin.CreateTopic(ts.canaryConfig.Topic, assignments, topicConfig)
 	return assignments, err
 }
 
-func (ts *TopicService) alterTopicAssignments(currentPartitions int, brokers []*sarama.Broker) (map[int32][]int32, error) {
+func (ts *TopicService) alterTopicAssignments(currentPartitions int, brokers []clients.Broker) (map[int32][]int32, error) {
 	brokersNumber := len(brokers)
 	assignmentsMap, _ := ts.requestedAssignments(currentPartitions, brokers)
 
@@ -281,7 +448,7 @@ func (ts *TopicService) alterTopicAssignments(currentPartitions int, brokers []*
 		// So first alter the assignment of current partitions with new replicas (higher replication factor)
 		if err = ts.alterAssignments(assignments[:currentPartitions]); err == nil {
 			// passing the assigments just for the partitions that needs to be created
-			err = ts.admin.CreatePartitions(ts.canaryConfig.Topic, int32(brokersNumber), assignments[currentPartitions:], false)
+			err = ts.admin.CreatePartitions(ts.canaryConfig.Topic, int32(brokersNumber), assignments[currentPartitions:])
 		}
 	} else {
 		// more or equals partitions than brokers, just need reassignment
@@ -290,7 +457,7 @@ func (ts *TopicService) alterTopicAssignments(currentPartitions int, brokers []*
 	return assignmentsMap, err
 }
 
-func (ts *TopicService) isPreferredLeaderElectionNeeded(brokersNumber int, metadata *sarama.TopicMetadata) {
+func (ts *TopicService) isPreferredLeaderElectionNeeded(brokersNumber int, metadata *clients.TopicMetadata) {
 	electLeader := false
 	if len(metadata.Partitions) == brokersNumber {
 		for _, p := range metadata.Partitions {
@@ -302,7 +469,7 @@ func (ts *TopicService) isPreferredLeaderElectionNeeded(brokersNumber int, metad
 	glog.V(2).Infof("Elect leader = %t", electLeader)
 }
 
-func (ts *TopicService) requestedAssignments(currentPartitions int, brokers []*sarama.Broker) (map[int32][]int32, int) {
+func (ts *TopicService) requestedAssignments(currentPartitions int, brokers []clients.Broker) (map[int32][]int32, int) {
 	brokersNumber := len(brokers)
 	partitions := max(currentPartitions, brokersNumber)
 	replicationFactor := min(brokersNumber, 3)
@@ -311,7 +478,7 @@ func (ts *TopicService) requestedAssignments(currentPartitions int, brokers []*s
 	// partitions assignments algorithm is simpler and works effectively if brokers are ordered by ID
 	// it could not be the case from a Metadata request, so sorting them first
 	sort.Slice(brokers, func(i, j int) bool {
-		return brokers[i].ID() < brokers[j].ID()
+		return brokers[i].ID < brokers[j].ID
 	})
 
 	// now adjust the broker ordering to produce a rack alternated list.
@@ -326,17 +493,17 @@ func (ts *TopicService) requestedAssignments(currentPartitions int, brokers []*s
 	// rack alternated broker list:
 	// 0, 3, 1, 5, 4, 2
 
-	rackMap := make(map[string][]*sarama.Broker)
+	rackMap := make(map[string][]clients.Broker)
 	var rackNames []string
 	brokersWithRack := 0
 	for _, broker := range brokers {
-		if broker.Rack() != "" {
+		if broker.Rack != "" {
 			brokersWithRack++
-			if _, ok := rackMap[broker.Rack()]; !ok {
-				rackMap[broker.Rack()] = make([]*sarama.Broker, 0)
-				rackNames = append(rackNames, broker.Rack())
+			if _, ok := rackMap[broker.Rack]; !ok {
+				rackMap[broker.Rack] = make([]clients.Broker, 0)
+				rackNames = append(rackNames, broker.Rack)
 			}
-			rackMap[broker.Rack()] = append(rackMap[broker.Rack()], broker)
+			rackMap[broker.Rack] = append(rackMap[broker.Rack], broker)
 		}
 	}
 
@@ -353,7 +520,7 @@ func (ts *TopicService) requestedAssignments(currentPartitions int, brokers []*s
 			for _, rackName := range rackNames {
 				brokerList := rackMap[rackName]
 				if len(brokerList) > 0 {
-					var head *sarama.Broker
+					var head clients.Broker
 					head, rackMap[rackName] = brokerList[0], brokerList[1:]
 					brokers[index] = head
 					index++
@@ -374,7 +541,7 @@ func (ts *TopicService) requestedAssignments(currentPartitions int, brokers []*s
 		for r := 0; r < replicationFactor; r++ {
 			// get brokers ID for assignment from the brokers list and not using
 			// just a monotonic increasing index because there could be "hole" (a broker down)
-			assignments[int32(p)][r] = brokers[int32(k%brokersNumber)].ID()
+			assignments[int32(p)][r] = brokers[int32(k%brokersNumber)].ID
 			k++
 		}
 	}
@@ -382,7 +549,7 @@ func (ts *TopicService) requestedAssignments(currentPartitions int, brokers []*s
 	return assignments, int(minISR)
 }
 
-func (ts *TopicService) currentAssignments(topicMetadata *sarama.TopicMetadata) map[int32][]int32 {
+func (ts *TopicService) currentAssignments(topicMetadata *clients.TopicMetadata) map[int32][]int32 {
 	assignments := make(map[int32][]int32, len(topicMetadata.Partitions))
 	for _, p := range topicMetadata.Partitions {
 		assignments[p.ID] = make([]int32, len(p.Replicas))
@@ -412,7 +579,7 @@ func (ts *TopicService) alterAssignments(assignments [][]int32) error {
 			return nil
 		}
 		// on each partition of the topic shouldn't be adding or removing replicas ongoing
-		for _, reassignmentStatus := range reassignments[ts.canaryConfig.Topic] {
+		for _, reassignmentStatus := range reassignments {
 			glog.V(1).Infof("List reassignments = %+v", reassignmentStatus)
 			ongoing = ongoing || (len(reassignmentStatus.AddingReplicas) != 0 || len(reassignmentStatus.RemovingReplicas) != 0)
 		}
@@ -443,7 +610,7 @@ func min(x, y int) int {
 	return x
 }
 
-func logTopicMetadata(topicMetadata *sarama.TopicMetadata) {
+func logTopicMetadata(topicMetadata *clients.TopicMetadata) {
 	// sorting partitions first, as it could not be from a Metadata request and it's better for logging
 	sort.Slice(topicMetadata.Partitions, func(i, j int) bool {
 		return topicMetadata.Partitions[i].ID < topicMetadata.Partitions[j].ID
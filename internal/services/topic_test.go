@@ -11,13 +11,13 @@ package services
 
 import (
 	"fmt"
-	"github.com/Shopify/sarama"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/strimzi/strimzi-canary/internal/clients"
 	"github.com/strimzi/strimzi-canary/internal/config"
 	"math/rand"
-	"reflect"
 	"testing"
 	"time"
-	"unsafe"
 )
 
 func TestRequestedAssignments(t *testing.T) {
@@ -43,7 +43,7 @@ func TestRequestedAssignments(t *testing.T) {
 
 			brokers, brokerMap := createBrokers(t, tt.numBrokers, tt.useRack)
 
-			ts := NewTopicService(cfg, nil)
+			ts := NewTopicService(cfg, nil, NewTrackers(cfg))
 
 			assignments, minISR := ts.requestedAssignments(tt.numPartitions, brokers)
 
@@ -102,11 +102,11 @@ func TestRequestedAssignments(t *testing.T) {
 					rackBrokerId := make(map[string][]int32)
 					for _, brokerId := range brokerIds {
 						broker := brokerMap[brokerId]
-						_, ok := rackBrokerId[broker.Rack()]
+						_, ok := rackBrokerId[broker.Rack]
 						if !ok {
-							rackBrokerId[broker.Rack()] = make([]int32, 0)
+							rackBrokerId[broker.Rack] = make([]int32, 0)
 						}
-						rackBrokerId[broker.Rack()] = append(rackBrokerId[broker.Rack()], broker.ID())
+						rackBrokerId[broker.Rack] = append(rackBrokerId[broker.Rack], broker.ID)
 					}
 
 					for rack, brokerIds := range rackBrokerId {
@@ -123,16 +123,10 @@ func TestRequestedAssignments(t *testing.T) {
 
 }
 
-func createBrokers(t *testing.T, num int, rack bool) ([]*sarama.Broker, map[int32]*sarama.Broker) {
-	brokers := make([]*sarama.Broker, 0)
-	brokerMap := make(map[int32]*sarama.Broker)
+func createBrokers(t *testing.T, num int, rack bool) ([]clients.Broker, map[int32]clients.Broker) {
+	brokers := make([]clients.Broker, 0)
 	for i := 0; i < num ; i++ {
-		broker := &sarama.Broker{}
-
-		setBrokerID(t, broker, i)
-
-		brokers = append(brokers, broker)
-		brokerMap[broker.ID()] = broker
+		brokers = append(brokers, clients.Broker{ID: int32(i)})
 	}
 
 	rand.Seed(time.Now().UnixNano())
@@ -144,33 +138,59 @@ func createBrokers(t *testing.T, num int, rack bool) ([]*sarama.Broker, map[int3
 			rackNames[i] = fmt.Sprintf("useRack%d", i)
 		}
 
-		for i, broker := range brokers {
-			rackName := rackNames[i%3]
-			setBrokerRack(t, broker, rackName)
+		for i := range brokers {
+			brokers[i].Rack = rackNames[i%3]
 		}
 	}
 
+	brokerMap := make(map[int32]clients.Broker)
+	for _, broker := range brokers {
+		brokerMap[broker.ID] = broker
+	}
 	return brokers, brokerMap
 }
 
-func setBrokerID(t *testing.T, broker *sarama.Broker, i int) {
-	idV := reflect.ValueOf(broker).Elem().FieldByName("id")
-	setUnexportedField(idV, int32(i))
-	if int32(i) != broker.ID() {
-		t.Errorf("failed to set id by reflection")
+func TestPartitionsChange(t *testing.T) {
+	tests := []struct {
+		name     string
+		previous int
+		current  int
+		expected int
+	}{
+		{"first reconcile", 0, 3, partitionsUnchanged},
+		{"same partitions", 3, 3, partitionsUnchanged},
+		{"partitions increased", 3, 5, partitionsIncreased},
+		{"partitions decreased", 5, 3, partitionsDecreased},
 	}
-}
 
-func setBrokerRack(t *testing.T, broker *sarama.Broker, rackName string) {
-	rackV := reflect.ValueOf(broker).Elem().FieldByName("rack")
-	setUnexportedField(rackV, &rackName)
-	if rackName != broker.Rack() {
-		t.Errorf("failed to set useRack by reflection")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if change := partitionsChange(tt.previous, tt.current); change != tt.expected {
+				t.Errorf("Partitions change from %d to %d = %d, expected %d", tt.previous, tt.current, change, tt.expected)
+			}
+		})
 	}
 }
 
-func setUnexportedField(field reflect.Value, value interface{}) {
-	reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).
-		Elem().
-		Set(reflect.ValueOf(value))
+func TestWatchPartitionsDecreaseRejected(t *testing.T) {
+	ts := &TopicService{partitions: 5}
+	metadata := &clients.TopicMetadata{Name: "canary-decreased", Partitions: []*clients.PartitionMetadata{{ID: 0}, {ID: 1}, {ID: 2}}}
+	for i := 1; i <= 2; i++ {
+		result := TopicReconcileResult{}
+		err := ts.watchPartitions(metadata, &result)
+		if _, ok := err.(*ErrPartitionsDecreased); !ok {
+			t.Errorf("got = %v, want = ErrPartitionsDecreased", err)
+		}
+		if result.RefreshMetadata || result.PartitionsChanged {
+			t.Errorf("got = %+v, want the producer and consumer not refreshed", result)
+		}
+		// the previous partitions count is kept, so the decrease is rejected until it's restored
+		if ts.partitions != 5 {
+			t.Errorf("partitions got = %d, want = 5", ts.partitions)
+		}
+		counter := partitionsDecreaseRejected.With(prometheus.Labels{"topic": "canary-decreased"})
+		if value := testutil.ToFloat64(counter); value != float64(i) {
+			t.Errorf("topic_partitions_decrease_rejected_total got = %v, want = %d", value, i)
+		}
+	}
 }
@@ -0,0 +1,46 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package services defines an interface for canary services and related implementations
+package services
+
+import (
+	"time"
+
+	"github.com/strimzi/strimzi-canary/internal/config"
+	"github.com/strimzi/strimzi-canary/internal/util"
+)
+
+// Trackers holds the state shared by the canary services, i.e. the records outcomes and the brokers health observed by
+// the clients and reported by the status service, passed to the services along with the canary configuration
+type Trackers struct {
+	// tracks the brokers going down and coming back, for detecting a rolling restart in progress
+	brokerRoll *brokerRollTracker
+	// tracks the recent produce and connection results by broker, for signaling the brokers safe to roll
+	safeToRoll *safeToRollTracker
+	// tracks the produced and consumed offsets for detecting log truncations
+	logTruncation *logTruncationTracker
+	// tracks records produced on the source cluster and consumed from the target cluster when the replication check is enabled
+	replication *replicationTracker
+	// tracks the records counters over the sliding windows, for the produce and round trip success ratios
+	successRatio *successRatioTracker
+	// the reloadable settings set through the dynamic configuration
+	dynamicConfig *dynamicConfig
+}
+
+// NewTrackers returns an instance of Trackers
+func NewTrackers(canaryConfig *config.CanaryConfig) *Trackers {
+	t := Trackers{
+		brokerRoll:    newBrokerRollTracker(),
+		safeToRoll:    newSafeToRollTracker(),
+		logTruncation: newLogTruncationTracker(),
+		replication:   newReplicationTracker(util.NowInMilliseconds()),
+		successRatio:  newSuccessRatioTracker(canaryConfig, util.NowInMilliseconds()),
+		dynamicConfig: newDynamicConfig(),
+	}
+	t.brokerRoll.setWindow(canaryConfig.BrokerRollWindow * time.Millisecond)
+	t.safeToRoll.setWindow(canaryConfig.SafeToRollWindow * time.Millisecond)
+	return &t
+}
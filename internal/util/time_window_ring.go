@@ -92,6 +92,15 @@ func (rb *TimeWindowRing) Put(value uint64) {
 	}
 }
 
+// Values returns the sampled values in the time window ring buffer, from the tail to the head
+func (rb *TimeWindowRing) Values() []uint64 {
+	values := make([]uint64, 0, rb.count)
+	for i := 0; i < rb.count; i++ {
+		values = append(values, rb.buffer[(rb.tail+i)%len(rb.buffer)])
+	}
+	return values
+}
+
 // Head returns the sampled value at the head position
 func (rb *TimeWindowRing) Head() uint64 {
 	return rb.buffer[rb.head]
@@ -0,0 +1,62 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// Package util contains some utility functions
+package util
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// TokenBucket limits the rate of the operations to the configured number per second, allowing bursts up to
+// the bucket size. The bucket starts full and it's refilled continuously at the configured rate
+type TokenBucket struct {
+	mutex  sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+// NewTokenBucket returns an instance of TokenBucket refilled with rate tokens per second, up to burst tokens
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	return &TokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+		now:    time.Now,
+	}
+}
+
+// Reserve takes a token from the bucket and returns how long to wait before running the operation,
+// 0 if a token was available
+func (tb *TokenBucket) Reserve() time.Duration {
+	tb.mutex.Lock()
+	defer tb.mutex.Unlock()
+	now := tb.now()
+	if elapsed := now.Sub(tb.last).Seconds(); elapsed > 0 {
+		tb.tokens = math.Min(tb.burst, tb.tokens+elapsed*tb.rate)
+	}
+	tb.last = now
+	// the tokens go negative for the operations waiting, so that the following ones wait longer
+	tb.tokens--
+	if tb.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-tb.tokens / tb.rate * float64(time.Second))
+}
+
+// Wait takes a token from the bucket, waiting for it if not available, and returns how long it waited
+func (tb *TokenBucket) Wait() time.Duration {
+	delay := tb.Reserve()
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+	return delay
+}
@@ -0,0 +1,45 @@
+//
+// Copyright Strimzi authors.
+// License: Apache License 2.0 (see the file LICENSE or http://apache.org/licenses/LICENSE-2.0.html).
+//
+
+// +build unit_test
+
+// Package util contains some utility functions
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Unix(0, 0)
+	tb := NewTokenBucket(10, 2)
+	tb.last = now
+	tb.now = func() time.Time { return now }
+
+	// the burst is available immediately
+	for i := 0; i < 2; i++ {
+		if delay := tb.Reserve(); delay != 0 {
+			t.Errorf("Operation %d delay = %v, expected = 0", i, delay)
+		}
+	}
+	// then the operations are spaced by 1/rate
+	if delay := tb.Reserve(); delay != 100*time.Millisecond {
+		t.Errorf("Delay = %v, expected = 100ms", delay)
+	}
+	if delay := tb.Reserve(); delay != 200*time.Millisecond {
+		t.Errorf("Delay = %v, expected = 200ms", delay)
+	}
+	// the bucket is refilled over time, up to the burst
+	now = now.Add(10 * time.Second)
+	for i := 0; i < 2; i++ {
+		if delay := tb.Reserve(); delay != 0 {
+			t.Errorf("Operation %d after refill delay = %v, expected = 0", i, delay)
+		}
+	}
+	if delay := tb.Reserve(); delay != 100*time.Millisecond {
+		t.Errorf("Delay after refill = %v, expected = 100ms", delay)
+	}
+}
@@ -7,6 +7,7 @@
 package util
 
 import (
+	"context"
 	"errors"
 	"io"
 	"os"
@@ -23,3 +24,20 @@ func NowInMilliseconds() int64 {
 func IsDisconnection(err error) bool {
 	return errors.Is(err, io.EOF) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) || errors.Is(err, syscall.ETIMEDOUT) || errors.Is(err, os.ErrDeadlineExceeded)
 }
+
+// WaitWithContext runs the provided wait function and returns when it completes or the context is done
+//
+// Returns the context error if it was done before the wait function completed, otherwise nil
+func WaitWithContext(ctx context.Context, wait func()) error {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		wait()
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
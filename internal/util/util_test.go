@@ -7,11 +7,13 @@
 package util
 
 import (
+	"context"
 	"errors"
 	"io"
 	"os"
 	"syscall"
 	"testing"
+	"time"
 )
 
 func TestIsDisconnection(t *testing.T) {
@@ -35,3 +37,38 @@ func TestIsDisconnection(t *testing.T) {
 		}
 	}
 }
+
+func TestWaitWithContext(t *testing.T) {
+	if err := WaitWithContext(context.Background(), func() {}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	block := make(chan struct{})
+	defer close(block)
+	if err := WaitWithContext(ctx, func() { <-block }); err != context.DeadlineExceeded {
+		t.Errorf("got = %v, want = %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestTimeWindowRingValues(t *testing.T) {
+	rb := NewTimeWindowRing(3*time.Second, time.Second)
+	if values := rb.Values(); len(values) != 0 {
+		t.Errorf("unexpected values %v for an empty ring", values)
+	}
+	for v := uint64(1); v <= 5; v++ {
+		rb.Put(v)
+	}
+	// the oldest values are kicked out, with the tail moving forward
+	expected := []uint64{3, 4, 5}
+	values := rb.Values()
+	if len(values) != len(expected) {
+		t.Fatalf("unexpected values %v (expecting %v)", values, expected)
+	}
+	for i := range expected {
+		if values[i] != expected[i] {
+			t.Errorf("unexpected values %v (expecting %v)", values, expected)
+		}
+	}
+}
@@ -7,6 +7,7 @@
 package workers
 
 import (
+	"context"
 	"sync"
 	"time"
 
@@ -15,6 +16,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/strimzi/strimzi-canary/internal/config"
 	"github.com/strimzi/strimzi-canary/internal/services"
+	"github.com/strimzi/strimzi-canary/internal/util"
 )
 
 // CanaryManager defines the manager driving the different producer, consumer and topic services
@@ -25,8 +27,11 @@ type CanaryManager struct {
 	consumerService   *services.ConsumerService
 	connectionService *services.ConnectionService
 	statusService     *services.StatusService
-	stop              chan struct{}
-	syncStop          sync.WaitGroup
+	// running the pluggable checks enabled in the configuration
+	checkScheduler *services.CheckScheduler
+	trackers       *services.Trackers
+	stop           chan struct{}
+	syncStop       sync.WaitGroup
 }
 
 var (
@@ -41,7 +46,7 @@ var (
 func NewCanaryManager(canaryConfig *config.CanaryConfig,
 	topicService *services.TopicService, producerService *services.ProducerService,
 	consumerService *services.ConsumerService, connectionService *services.ConnectionService,
-	statusService *services.StatusService) Worker {
+	statusService *services.StatusService, checkScheduler *services.CheckScheduler, trackers *services.Trackers) Worker {
 	cm := CanaryManager{
 		canaryConfig:      canaryConfig,
 		topicService:      topicService,
@@ -49,6 +54,8 @@ func NewCanaryManager(canaryConfig *config.CanaryConfig,
 		consumerService:   consumerService,
 		connectionService: connectionService,
 		statusService:     statusService,
+		checkScheduler:    checkScheduler,
+		trackers:          trackers,
 	}
 	return &cm
 }
@@ -60,18 +67,25 @@ func (cm *CanaryManager) Start() {
 	cm.stop = make(chan struct{})
 	cm.syncStop.Add(1)
 
-	cm.connectionService.Open()
+	// the connection and status checks don't depend on the canary topic, so they start before the first reconcile
 	cm.statusService.Open()
+	cm.checkScheduler.StartStartupChecks()
 
 	// using the same bootstrap configuration that makes sense during the canary start up
-	backoff := services.NewBackoff(cm.canaryConfig.BootstrapBackoffMaxAttempts, cm.canaryConfig.BootstrapBackoffScale*time.Millisecond, services.MaxDefault)
+	backoff := services.NewBootstrapBackoff(cm.canaryConfig)
 	for {
 		// start first reconcile immediately
 		if result, err := cm.topicService.Reconcile(); err == nil {
+			// if configured, the topics and consumer groups left by previous canary instances are deleted
+			cm.topicService.CleanupOrphans()
 			// consumer will subscribe to the topic so all partitions (even if we have less brokers)
-			cm.consumerService.Consume()
+			if err := cm.consumerService.Consume(); err != nil {
+				glog.Fatalf("Error starting the consumer: %v", err)
+			}
+			services.ExpirePartitionMetrics(cm.canaryConfig, result.Assignments)
 			// producer has to send to partitions assigned to brokers
 			cm.producerService.Send(result.Assignments)
+			cm.checkScheduler.Start()
 			break
 		} else if e, ok := err.(*services.ErrExpectedClusterSize); ok {
 			// if the "dynamic" reassignment is disabled, an error may occur with expected cluster size not met yet
@@ -83,18 +97,30 @@ func (cm *CanaryManager) Start() {
 			glog.Warningf("Error on expected cluster size. Retrying in %d ms", delay.Milliseconds())
 			time.Sleep(delay)
 		} else {
-			glog.Fatalf("Error starting canary manager: %v", err)
+			// the Kafka cluster could be not reachable yet (i.e. brokers rolling), so retrying
+			services.RecordBootstrapFailure(services.AdminBootstrapClient)
+			delay, backoffErr := backoff.Delay()
+			if backoffErr != nil {
+				glog.Fatalf("Error starting canary manager: %v", err)
+			}
+			glog.Warningf("Error starting canary manager, retrying in %d ms: %v", delay.Milliseconds(), err)
+			time.Sleep(delay)
 		}
 	}
 
-	ticker := time.NewTicker(cm.canaryConfig.ReconcileInterval * time.Millisecond)
+	// the interval between reconciles has a jitter and it's stretched while the cluster is degraded
+	interval := services.NewReconcileInterval(cm.canaryConfig, cm.trackers)
+	timer := time.NewTimer(interval.Next(false))
 	go func() {
 		for {
 			select {
-			case <-ticker.C:
-				cm.reconcile()
+			case tick := <-timer.C:
+				run := services.StartLoopRun(services.ReconcileLoop, tick)
+				degraded := cm.reconcile()
+				run.Done()
+				timer.Reset(interval.Next(degraded))
 			case <-cm.stop:
-				ticker.Stop()
+				timer.Stop()
 				defer cm.syncStop.Done()
 				glog.Infof("Stopping canary manager reconcile loop")
 				return
@@ -103,33 +129,57 @@ func (cm *CanaryManager) Start() {
 	}()
 }
 
-// Stop stops the services and the reconcile timer
-func (cm *CanaryManager) Stop() {
+// Stop stops the reconcile timer and the services
+//
+// The producer and consumer are drained until the context is done and, if configured, the canary topic and
+// consumer group are deleted
+func (cm *CanaryManager) Stop(ctx context.Context) {
 	glog.Infof("Stopping canary manager")
 
-	// ask to stop the ticker reconcile loop and wait
+	cm.checkScheduler.Stop()
+	// ask to stop the ticker reconcile loop and wait for the in progress reconcile
 	close(cm.stop)
-	cm.syncStop.Wait()
+	if err := util.WaitWithContext(ctx, cm.syncStop.Wait); err != nil {
+		glog.Warningf("Canary manager reconcile loop not stopped in time: %v", err)
+	}
 
-	cm.producerService.Close()
-	cm.consumerService.Close()
+	cm.producerService.Close(ctx)
+	cm.consumerService.Close(ctx)
+	if cm.canaryConfig.DeleteTopicOnShutdown {
+		if err := cm.topicService.DeleteTopic(); err != nil {
+			glog.Errorf("Error deleting the canary topic %s: %v", cm.canaryConfig.Topic, err)
+		}
+		// the committed offsets are not valid anymore for the deleted topic
+		if err := cm.topicService.DeleteConsumerGroup(); err != nil {
+			glog.Errorf("Error deleting the canary consumer group %s: %v", cm.canaryConfig.ConsumerGroupID, err)
+		}
+	}
 	cm.topicService.Close()
 	cm.connectionService.Close()
-	cm.statusService.Close()
 
 	glog.Infof("Canary manager closed")
 }
 
-func (cm *CanaryManager) reconcile() {
+// reconcile runs a reconcile cycle, returns true if the cluster is degraded because
+// the topic reconcile failed or all the sends failed
+func (cm *CanaryManager) reconcile() bool {
 	glog.Infof("Canary manager reconcile ...")
 
-	if result, err := cm.topicService.Reconcile(); err == nil {
+	result, err := cm.topicService.Reconcile()
+	if err == nil {
 		if result.RefreshMetadata {
 			cm.producerService.Refresh()
 		}
+		// partitions added externally to the canary topic have to be assigned to the consumer as well
+		if result.PartitionsChanged {
+			cm.consumerService.Refresh()
+		}
+		// metrics related to partitions no longer present have to be deleted
+		services.ExpirePartitionMetrics(cm.canaryConfig, result.Assignments)
 		// producer has to send to partitions assigned to brokers
 		cm.producerService.Send(result.Assignments)
 	}
 
 	glog.Infof("... reconcile done")
+	return err != nil || cm.producerService.Degraded()
 }
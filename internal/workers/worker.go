@@ -6,8 +6,11 @@
 // Package workers defines an interface for canary workers and related implementations
 package workers
 
+import "context"
+
 // Worker interface exposing main operations on canary workers
 type Worker interface {
 	Start()
-	Stop()
+	// Stop stops the worker, the context bounds the time for draining the in progress operations
+	Stop(ctx context.Context)
 }